@@ -16,11 +16,14 @@ package main
 
 import (
 	"context"
+	"errors"
 	"os"
 	"path/filepath"
 
 	"github.com/BurntSushi/toml"
 	wire "github.com/jeroenrinzema/psql-wire"
+	"github.com/jeroenrinzema/psql-wire/codes"
+	psqlerr "github.com/jeroenrinzema/psql-wire/errors"
 	"go.uber.org/zap"
 
 	"github.com/daviszhen/plan/pkg/plan"
@@ -60,17 +63,44 @@ func loadConfig() {
 }
 
 func main() {
+	if err := storage.Open(&runCfg.Storage); err != nil {
+		util.Error("open database failed", zap.Error(err))
+		os.Exit(1)
+	}
 	wire.ListenAndServe("127.0.0.1:5432", handler)
 }
 
-func handler(ctx context.Context, query string) (wire.PreparedStatements, error) {
+// toWireError attaches the SQLSTATE to the error sent to the client.
+func toWireError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var sqlErr *util.SQLError
+	if errors.As(err, &sqlErr) && len(sqlErr.Stack) != 0 {
+		util.Error("internal error",
+			zap.Error(err),
+			zap.String("stack", sqlErr.Stack))
+	}
+	return psqlerr.WithCode(err, codes.Code(util.GetSQLState(err)))
+}
+
+func handler(ctx context.Context, query string) (_ wire.PreparedStatements, err error) {
 	util.Info("incoming SQL :", zap.String("query", query))
-	var err error
 	txn, err := storage.GTxnMgr.NewTxn("handler")
 	if err != nil {
-		return nil, err
+		return nil, toWireError(err)
 	}
 	storage.BeginQuery(txn)
+	//the txn is rolled back if the statement is not prepared
+	defer func() {
+		if r := recover(); r != nil {
+			err = util.RecoverToError(r)
+		}
+		if err != nil {
+			storage.GTxnMgr.Rollback(txn)
+			err = toWireError(err)
+		}
+	}()
 
 	//init runner
 	run, err := plan.InitRunner(&runCfg, txn, query)
@@ -97,15 +127,18 @@ type ExecCtx struct {
 	run *plan.Runner
 }
 
-func (exec *ExecCtx) handleX(ctx context.Context, writer wire.DataWriter, parameters []wire.Parameter) error {
-	var err error
+func (exec *ExecCtx) handleX(ctx context.Context, writer wire.DataWriter, parameters []wire.Parameter) (err error) {
 	defer exec.run.Close()
 	defer func() {
+		if r := recover(); r != nil {
+			err = util.RecoverToError(r)
+		}
 		if err != nil {
 			storage.GTxnMgr.Rollback(exec.run.Txn)
 		} else {
 			err = storage.GTxnMgr.Commit(exec.run.Txn)
 		}
+		err = toWireError(err)
 	}()
 
 	//run stmt
@@ -25,12 +25,15 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/daviszhen/plan/pkg/plan"
+	"github.com/daviszhen/plan/pkg/storage"
 	"github.com/daviszhen/plan/pkg/util"
 )
 
 func init() {
 	cobra.OnInitialize(loadConfig)
+	initStorageFlags()
 	initTpch1gCmd()
+	initVerifyCmd()
 }
 
 var testerCfg = &util.Config{}
@@ -58,6 +61,32 @@ func initDebugOptions() {
 	testerCfg.Debug.Count = viper.GetInt("debug.count")
 }
 
+func initStorageOptions() {
+	testerCfg.Storage.Path = viper.GetString("storage.path")
+	testerCfg.Storage.ReadOnly = viper.GetBool("storage.readOnly")
+	testerCfg.Storage.WalArchiveDir = viper.GetString("storage.walArchiveDir")
+	testerCfg.Storage.Recover = viper.GetBool("storage.recover")
+	testerCfg.Storage.RecoveryTargetTime = viper.GetString("storage.recoveryTargetTime")
+	testerCfg.Storage.RecoveryTargetCommitId = viper.GetUint64("storage.recoveryTargetCommitId")
+}
+
+// initStorageFlags adds the options of the database loaded by
+// the tpch1g commands. the verify reads the file by its --path.
+func initStorageFlags() {
+	flags := RootCmd.PersistentFlags()
+	flags.String("db_path", "", "database file path")
+	flags.String("wal_archive_dir", "", "archive the wal into the dir on checkpoint")
+	flags.Bool("recover", false, "replay the archived wal up to the recovery target")
+	flags.String("recovery_target_time", "", "stop the recovery after the time in RFC3339")
+	flags.Uint64("recovery_target_commit_id", 0, "stop the recovery after the commit id")
+
+	viper.BindPFlag("storage.path", flags.Lookup("db_path"))
+	viper.BindPFlag("storage.walArchiveDir", flags.Lookup("wal_archive_dir"))
+	viper.BindPFlag("storage.recover", flags.Lookup("recover"))
+	viper.BindPFlag("storage.recoveryTargetTime", flags.Lookup("recovery_target_time"))
+	viper.BindPFlag("storage.recoveryTargetCommitId", flags.Lookup("recovery_target_commit_id"))
+}
+
 //tpch1g cmd
 
 var tpch1gInfo = "run tpch1g query"
@@ -67,12 +96,17 @@ var tpch1gCmd = &cobra.Command{
 	Long:  tpch1gInfo,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		initTpch1gCfg()
+		err := storage.Open(&testerCfg.Storage)
+		if err != nil {
+			return err
+		}
 		return plan.Run(testerCfg)
 	},
 }
 
 func initTpch1gCfg() {
 	initDebugOptions()
+	initStorageOptions()
 	testerCfg.Tpch1g.Query.QueryId = viper.GetUint("tpch1g.query.queryId")
 	testerCfg.Tpch1g.Query.Path = viper.GetString("tpch1g.query.path")
 	testerCfg.Tpch1g.Data.Path = viper.GetString("tpch1g.data.path")
@@ -90,6 +124,10 @@ var tpch1gDDLCmd = &cobra.Command{
 	RunE: func(cmd *cobra.Command, args []string) error {
 		initTpch1gDDLCfg()
 		initTpch1gCfg()
+		err := storage.Open(&testerCfg.Storage)
+		if err != nil {
+			return err
+		}
 		return plan.RunDDL(testerCfg)
 	},
 }
@@ -122,6 +160,32 @@ func initTpch1gCmd() {
 	viper.BindPFlag("tpch1g.ddl.ddl", tpch1gDDLCmd.Flags().Lookup("ddl"))
 }
 
+// verify cmd
+// the database is opened read only. the wal is not replayed.
+var verifyInfo = "verify the database file offline"
+var verifyPath string
+var verifyCmd = &cobra.Command{
+	Use:   "verify",
+	Short: verifyInfo,
+	Long:  verifyInfo,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		report, err := storage.VerifyDatabase(verifyPath)
+		if err != nil {
+			return err
+		}
+		fmt.Print(report.String())
+		if !report.OK() {
+			return fmt.Errorf("database %s is corrupted", verifyPath)
+		}
+		return nil
+	},
+}
+
+func initVerifyCmd() {
+	RootCmd.AddCommand(verifyCmd)
+	verifyCmd.Flags().StringVar(&verifyPath, "path", "/tmp/default", "database file path")
+}
+
 var defCfgFilePaths = []string{".", "etc/tpch/1g"}
 var cfgFileName = "tester.toml"
 
@@ -6,6 +6,7 @@ require (
 	github.com/BurntSushi/toml v1.4.1-0.20240526193622-a339e1f7089c
 	github.com/govalues/decimal v0.1.28
 	github.com/huandu/go-clone v1.7.2
+	github.com/jackc/pgx/v5 v5.4.3
 	github.com/jeroenrinzema/psql-wire v0.12.1
 	github.com/lib/pq v1.10.9
 	github.com/liyue201/gostl v1.2.0
@@ -32,7 +33,6 @@ require (
 	github.com/golang/snappy v0.0.4 // indirect
 	github.com/hashicorp/hcl v1.0.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/jackc/pgx/v5 v5.4.3 // indirect
 	github.com/kamstrup/intmap v0.5.1 // indirect
 	github.com/klauspost/compress v1.17.2 // indirect
 	github.com/magiconair/properties v1.8.7 // indirect
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"time"
 
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jeroenrinzema/psql-wire"
 	"go.uber.org/zap"
 
@@ -262,7 +264,7 @@ func (c *Chunk) SaveToWriter(writer wire.DataWriter) (err error) {
 	for i := 0; i < rowCnt; i++ {
 		for j := 0; j < colCnt; j++ {
 			val := c.Data[j].GetValue(i)
-			row[j] = val.String()
+			row[j] = wireValue(val)
 		}
 		err = writer.Row(row)
 		if err != nil {
@@ -272,6 +274,50 @@ func (c *Chunk) SaveToWriter(writer wire.DataWriter) (err error) {
 	return nil
 }
 
+// wireValue converts the value for the psql wire.
+func wireValue(val *Value) any {
+	switch val.Typ.Id {
+	case common.LTID_TIME, common.LTID_TIMESTAMP, common.LTID_TIMESTAMP_TZ, common.LTID_INTERVAL:
+		if val.IsNull {
+			return nil
+		}
+		return timeWireValue{val: val}
+	default:
+		return val.String()
+	}
+}
+
+// timeWireValue encodes the time types in the text format
+// and the binary format of the postgres.
+type timeWireValue struct {
+	val *Value
+}
+
+func (w timeWireValue) TextValue() (pgtype.Text, error) {
+	return pgtype.Text{String: w.val.String(), Valid: true}, nil
+}
+
+func (w timeWireValue) TimestampValue() (pgtype.Timestamp, error) {
+	return pgtype.Timestamp{Time: time.UnixMicro(w.val.I64).UTC(), Valid: true}, nil
+}
+
+func (w timeWireValue) TimestamptzValue() (pgtype.Timestamptz, error) {
+	return pgtype.Timestamptz{Time: time.UnixMicro(w.val.I64).UTC(), Valid: true}, nil
+}
+
+func (w timeWireValue) TimeValue() (pgtype.Time, error) {
+	return pgtype.Time{Microseconds: w.val.I64, Valid: true}, nil
+}
+
+func (w timeWireValue) IntervalValue() (pgtype.Interval, error) {
+	return pgtype.Interval{
+		Microseconds: w.val.I64_2,
+		Days:         int32(w.val.I64_1),
+		Months:       int32(w.val.I64),
+		Valid:        true,
+	}, nil
+}
+
 func (c *Chunk) Flatten() {
 	for i := 0; i < c.ColumnCount(); i++ {
 		c.Data[i].Flatten(c.Card())
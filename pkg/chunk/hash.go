@@ -15,6 +15,8 @@
 package chunk
 
 import (
+	"math"
+
 	"github.com/daviszhen/plan/pkg/common"
 	"github.com/daviszhen/plan/pkg/util"
 )
@@ -102,6 +104,49 @@ func (op HashOpInt8) operation(input int8, isNull bool) uint64 {
 	}
 }
 
+type HashFuncDouble struct {
+}
+
+func (hfun HashFuncDouble) fun(value float64) uint64 {
+	//-0 and 0 are same
+	if value == 0 {
+		value = 0
+	}
+	return murmurhash64(math.Float64bits(value))
+}
+
+type HashOpDouble struct {
+}
+
+func (op HashOpDouble) operation(input float64, isNull bool) uint64 {
+	if isNull {
+		return NULL_HASH
+	} else {
+		return HashFuncDouble{}.fun(input)
+	}
+}
+
+type HashFuncBool struct {
+}
+
+func (hfun HashFuncBool) fun(value bool) uint64 {
+	if value {
+		return murmurhash64(1)
+	}
+	return murmurhash64(0)
+}
+
+type HashOpBool struct {
+}
+
+func (op HashOpBool) operation(input bool, isNull bool) uint64 {
+	if isNull {
+		return NULL_HASH
+	} else {
+		return HashFuncBool{}.fun(input)
+	}
+}
+
 type HashFuncString struct {
 }
 
@@ -201,6 +246,10 @@ func HashTypeSwitch(
 		TemplatedLoopHash[common.Hugeint](input, result, rsel, count, hasRsel, HashOpHugeint{}, HashFuncHugeint{})
 	case common.DATE:
 		TemplatedLoopHash[common.Date](input, result, rsel, count, hasRsel, HashOpDate{}, HashFuncDate{})
+	case common.DOUBLE:
+		TemplatedLoopHash[float64](input, result, rsel, count, hasRsel, HashOpDouble{}, HashFuncDouble{})
+	case common.BOOL:
+		TemplatedLoopHash[bool](input, result, rsel, count, hasRsel, HashOpBool{}, HashFuncBool{})
 	default:
 		panic("Unknown input type")
 	}
@@ -289,6 +338,10 @@ func CombineHashTypeSwitch(
 		TemplatedLoopCombineHash[common.Decimal](input, hashes, rsel, count, hasRsel, HashOpDecimal{}, HashFuncDecimal{})
 	case common.VARCHAR:
 		TemplatedLoopCombineHash[common.String](input, hashes, rsel, count, hasRsel, HashOpString{}, HashFuncString{})
+	case common.DOUBLE:
+		TemplatedLoopCombineHash[float64](input, hashes, rsel, count, hasRsel, HashOpDouble{}, HashFuncDouble{})
+	case common.BOOL:
+		TemplatedLoopCombineHash[bool](input, hashes, rsel, count, hasRsel, HashOpBool{}, HashFuncBool{})
 	default:
 		panic("Unknown input type")
 	}
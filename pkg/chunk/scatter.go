@@ -154,6 +154,11 @@ func (scatter StringScatterOp) RandValue() common.String {
 }
 
 func (scatter StringScatterOp) Store(src common.String, rowLoc unsafe.Pointer, offsetInRow int, heapLoc *unsafe.Pointer) {
+	if src.Length() == 0 {
+		//the empty string or NULL needs no heap space
+		util.Store[common.String](common.String{}, util.PointerAdd(rowLoc, offsetInRow))
+		return
+	}
 	if heapLoc == nil || *heapLoc == nil {
 		panic("invalid heap location")
 	}
@@ -157,12 +157,24 @@ func (val Value) String() string {
 		return "NULL"
 	}
 	switch val.Typ.Id {
-	case common.LTID_INTEGER:
+	case common.LTID_TINYINT, common.LTID_SMALLINT, common.LTID_INTEGER:
 		return fmt.Sprintf("%d", val.I64)
 	case common.LTID_BOOLEAN:
 		return fmt.Sprintf("%v", val.Bool)
 	case common.LTID_VARCHAR:
 		return val.Str
+	case common.LTID_BLOB:
+		return common.FormatBlob([]byte(val.Str))
+	case common.LTID_TIMESTAMP:
+		return common.FormatTimestamp(val.I64, false)
+	case common.LTID_TIMESTAMP_TZ:
+		return common.FormatTimestamp(val.I64, true)
+	case common.LTID_TIME:
+		return common.FormatTime(val.I64)
+	case common.LTID_INTERVAL:
+		return common.FormatInterval(val.Interval())
+	case common.LTID_UUID:
+		return common.FormatUuid(common.Hugeint{Upper: val.I64, Lower: uint64(val.I64_1)})
 	case common.LTID_DECIMAL:
 		if len(val.Str) != 0 {
 			return val.Str
@@ -182,9 +194,9 @@ func (val Value) String() string {
 	case common.LTID_UBIGINT:
 		return fmt.Sprintf("0x%x %d", val.I64, val.I64)
 	case common.LTID_DOUBLE:
-		return fmt.Sprintf("%v", val.F64)
+		return common.FormatFloat(val.F64, 64)
 	case common.LTID_FLOAT:
-		return fmt.Sprintf("%v", val.F64)
+		return common.FormatFloat(val.F64, 32)
 	case common.LTID_POINTER:
 		return fmt.Sprintf("0x%x", val.I64)
 	case common.LTID_HUGEINT:
@@ -198,6 +210,15 @@ func (val Value) String() string {
 	}
 }
 
+// Interval returns the interval of the value from the GetValue.
+func (val Value) Interval() *common.Interval {
+	return &common.Interval{
+		Months: int32(val.I64),
+		Days:   int32(val.I64_1),
+		Micros: val.I64_2,
+	}
+}
+
 var (
 	POWERS_OF_TEN = []int64{
 		1,
@@ -231,8 +252,10 @@ func MaxValue(typ common.LType) *Value {
 		ret.Bool = true
 	case common.LTID_INTEGER:
 		ret.I64 = math.MaxInt32
-	case common.LTID_BIGINT:
+	case common.LTID_BIGINT, common.LTID_TIMESTAMP, common.LTID_TIMESTAMP_TZ:
 		ret.I64 = math.MaxInt64
+	case common.LTID_TIME:
+		ret.I64 = common.MicrosPerDay
 	case common.LTID_UBIGINT:
 		ret.U64 = math.MaxUint64
 	case common.LTID_DECIMAL:
@@ -256,8 +279,10 @@ func MinValue(typ common.LType) *Value {
 		ret.Bool = false
 	case common.LTID_INTEGER:
 		ret.I64 = math.MinInt32
-	case common.LTID_BIGINT:
+	case common.LTID_BIGINT, common.LTID_TIMESTAMP, common.LTID_TIMESTAMP_TZ:
 		ret.I64 = math.MinInt64
+	case common.LTID_TIME:
+		ret.I64 = 0
 	case common.LTID_UBIGINT:
 		ret.I64 = 0
 	case common.LTID_DECIMAL:
@@ -451,6 +476,8 @@ func (vec *Vector) ReferenceValue(val *Value) {
 	vec.Buf = NewConstBuffer(val.Typ)
 	vec.Aux = nil
 	vec.Data = GetDataInPhyFormatConst(vec)
+	//the mask may be shared with the referenced vector
+	vec.Mask = &util.Bitmap{}
 	vec.SetValue(0, val)
 }
 
@@ -495,13 +522,25 @@ func (vec *Vector) GetValue(idx int) *Value {
 			Typ: vec.Typ(),
 			I64: int64(data[idx]),
 		}
+	case common.LTID_TINYINT:
+		data := GetSliceInPhyFormatFlat[int8](vec)
+		return &Value{
+			Typ: vec.Typ(),
+			I64: int64(data[idx]),
+		}
+	case common.LTID_SMALLINT:
+		data := GetSliceInPhyFormatFlat[int16](vec)
+		return &Value{
+			Typ: vec.Typ(),
+			I64: int64(data[idx]),
+		}
 	case common.LTID_BOOLEAN:
 		data := GetSliceInPhyFormatFlat[bool](vec)
 		return &Value{
 			Typ:  vec.Typ(),
 			Bool: data[idx],
 		}
-	case common.LTID_VARCHAR:
+	case common.LTID_VARCHAR, common.LTID_BLOB:
 		data := GetSliceInPhyFormatFlat[common.String](vec)
 		return &Value{
 			Typ: vec.Typ(),
@@ -538,12 +577,21 @@ func (vec *Vector) GetValue(idx int) *Value {
 			Typ: vec.Typ(),
 			I64: int64(data[idx]),
 		}
-	case common.LTID_BIGINT:
+	case common.LTID_BIGINT, common.LTID_TIMESTAMP,
+		common.LTID_TIMESTAMP_TZ, common.LTID_TIME:
 		data := GetSliceInPhyFormatFlat[int64](vec)
 		return &Value{
 			Typ: vec.Typ(),
 			I64: data[idx],
 		}
+	case common.LTID_INTERVAL:
+		data := GetSliceInPhyFormatFlat[common.Interval](vec)
+		return &Value{
+			Typ:   vec.Typ(),
+			I64:   int64(data[idx].Months) + 12*int64(data[idx].Year),
+			I64_1: int64(data[idx].Days),
+			I64_2: data[idx].Micros,
+		}
 	case common.LTID_DOUBLE:
 		data := GetSliceInPhyFormatFlat[float64](vec)
 		return &Value{
@@ -562,7 +610,7 @@ func (vec *Vector) GetValue(idx int) *Value {
 			Typ: vec.Typ(),
 			I64: int64(uintptr(data[idx])),
 		}
-	case common.LTID_HUGEINT:
+	case common.LTID_HUGEINT, common.LTID_UUID:
 		data := GetSliceInPhyFormatFlat[common.Hugeint](vec)
 		return &Value{
 			Typ:   vec.Typ(),
@@ -583,8 +631,17 @@ func (vec *Vector) SetValue(idx int, val *Value) {
 	util.AssertFunc(val.Typ.Equal(vec.Typ()))
 	util.AssertFunc(val.Typ.GetInternalType() == vec.Typ().GetInternalType())
 	vec.Mask.Set(uint64(idx), !val.IsNull)
+	if val.IsNull {
+		return
+	}
 	pTyp := vec.Typ().GetInternalType()
 	switch pTyp {
+	case common.INT8:
+		slice := util.ToSlice[int8](vec.Data, pTyp.Size())
+		slice[idx] = int8(val.I64)
+	case common.INT16:
+		slice := util.ToSlice[int16](vec.Data, pTyp.Size())
+		slice[idx] = int16(val.I64)
 	case common.INT32:
 		slice := util.ToSlice[int32](vec.Data, pTyp.Size())
 		slice[idx] = int32(val.I64)
@@ -620,8 +677,9 @@ func (vec *Vector) SetValue(idx int, val *Value) {
 		case "day":
 			interVal.Days = int32(val.I64)
 			interVal.Unit = val.Str
-			//default:
-			//	panic("usp")
+		default:
+			//from the GetValue
+			interVal = *val.Interval()
 		}
 		slice[idx] = interVal
 	case common.DATE:
@@ -669,7 +727,8 @@ func (vec *Vector) SetValue(idx int, val *Value) {
 
 func (vec *Vector) Reset() {
 	vec._PhyFormat = PF_FLAT
-	vec.Mask.Reset()
+	//the mask may be shared with the referenced vector
+	vec.Mask = &util.Bitmap{}
 }
 
 func (vec *Vector) Print(rowCount int) {
@@ -0,0 +1,217 @@
+package common
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the text formats of the types are the same as the postgres.
+
+const (
+	MicrosPerSec  int64 = 1000000
+	MicrosPerDay  int64 = 24 * 60 * 60 * MicrosPerSec
+	TimestampText       = "2006-01-02 15:04:05.999999"
+	TimeText            = "15:04:05.999999"
+)
+
+var timestampLayouts = []string{
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05Z07",
+	"2006-01-02 15:04:05Z0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05Z07:00",
+	"2006-01-02T15:04:05Z07",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	time.DateOnly,
+}
+
+var timeLayouts = []string{
+	"15:04:05",
+	"15:04",
+}
+
+// ParseTimestamp parses the text into the micros since the epoch.
+// if withZone is false, the time zone in the text is ignored.
+// otherwise, the time is converted into the UTC.
+func ParseTimestamp(s string, withZone bool) (int64, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range timestampLayouts {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			continue
+		}
+		if !withZone {
+			y, m, d := t.Date()
+			t = time.Date(y, m, d, t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+		}
+		return t.UnixMicro(), true
+	}
+	return 0, false
+}
+
+// FormatTimestamp formats the micros since the epoch.
+func FormatTimestamp(micros int64, withZone bool) string {
+	ret := time.UnixMicro(micros).UTC().Format(TimestampText)
+	if withZone {
+		ret += "+00"
+	}
+	return ret
+}
+
+// ParseTime parses the text into the micros since the midnight.
+func ParseTime(s string) (int64, bool) {
+	s = strings.TrimSpace(s)
+	for _, layout := range timeLayouts {
+		t, err := time.Parse(layout, s)
+		if err != nil {
+			continue
+		}
+		return t.Sub(time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC)).Microseconds(), true
+	}
+	return 0, false
+}
+
+// FormatTime formats the micros since the midnight.
+func FormatTime(micros int64) string {
+	return time.UnixMicro(micros).UTC().Format(TimeText)
+}
+
+// ParseDate parses the text in the ISO 8601 format.
+func ParseDate(s string) (Date, bool) {
+	t, err := time.Parse(time.DateOnly, strings.TrimSpace(s))
+	if err != nil {
+		return Date{}, false
+	}
+	return DateFromTime(t), true
+}
+
+func DateFromTime(t time.Time) Date {
+	y, m, d := t.Date()
+	return Date{
+		Year:  int32(y),
+		Month: int32(m),
+		Day:   int32(d),
+	}
+}
+
+func FormatDate(d Date) string {
+	return d.ToDate().Format(time.DateOnly)
+}
+
+// ParseBool accepts the unique prefixes of the true, false, yes, no,
+// the on, off, 1 and 0.
+func ParseBool(s string) (bool, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if len(s) == 0 {
+		return false, false
+	}
+	switch {
+	case strings.HasPrefix("true", s), strings.HasPrefix("yes", s),
+		s == "on", s == "1":
+		return true, true
+	case strings.HasPrefix("false", s), strings.HasPrefix("no", s),
+		len(s) >= 2 && strings.HasPrefix("off", s), s == "0":
+		return false, true
+	}
+	return false, false
+}
+
+func FormatBool(b bool) string {
+	if b {
+		return "true"
+	}
+	return "false"
+}
+
+// FormatFloat formats the float in the shortest form.
+func FormatFloat(f float64, bitSize int) string {
+	switch {
+	case math.IsNaN(f):
+		return "NaN"
+	case math.IsInf(f, 1):
+		return "Infinity"
+	case math.IsInf(f, -1):
+		return "-Infinity"
+	}
+	return strconv.FormatFloat(f, 'g', -1, bitSize)
+}
+
+// ParseUuid accepts the 32 hex digits with optional hyphens and braces.
+func ParseUuid(s string) (Hugeint, bool) {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "{") && strings.HasSuffix(s, "}") {
+		s = s[1 : len(s)-1]
+	}
+	s = strings.ReplaceAll(s, "-", "")
+	if len(s) != 32 {
+		return Hugeint{}, false
+	}
+	upper, err := strconv.ParseUint(s[:16], 16, 64)
+	if err != nil {
+		return Hugeint{}, false
+	}
+	lower, err := strconv.ParseUint(s[16:], 16, 64)
+	if err != nil {
+		return Hugeint{}, false
+	}
+	return Hugeint{Upper: int64(upper), Lower: lower}, true
+}
+
+func FormatUuid(h Hugeint) string {
+	upper := uint64(h.Upper)
+	return fmt.Sprintf("%08x-%04x-%04x-%04x-%012x",
+		upper>>32,
+		(upper>>16)&0xffff,
+		upper&0xffff,
+		h.Lower>>48,
+		h.Lower&0xffffffffffff)
+}
+
+// ParseBlob accepts the hex format \x.. . other texts are the raw bytes.
+func ParseBlob(s string) ([]byte, bool) {
+	if strings.HasPrefix(s, `\x`) {
+		ret, err := hex.DecodeString(s[2:])
+		if err != nil {
+			return nil, false
+		}
+		return ret, true
+	}
+	return []byte(s), true
+}
+
+func FormatBlob(b []byte) string {
+	return `\x` + hex.EncodeToString(b)
+}
+
+// FormatInterval formats the interval in the postgres style.
+func FormatInterval(i *Interval) string {
+	parts := make([]string, 0, 4)
+	plural := func(n int32, unit string) {
+		if n == 0 {
+			return
+		}
+		if n == 1 {
+			parts = append(parts, fmt.Sprintf("%d %s", n, unit))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d %ss", n, unit))
+		}
+	}
+	plural(i.Year+i.Months/12, "year")
+	plural(i.Months%12, "mon")
+	plural(i.Days, "day")
+	if i.Micros != 0 || len(parts) == 0 {
+		micros := i.Micros
+		sign := ""
+		if micros < 0 {
+			sign = "-"
+			micros = -micros
+		}
+		parts = append(parts, sign+FormatTime(micros))
+	}
+	return strings.Join(parts, " ")
+}
@@ -341,7 +341,7 @@ func (h *Hugeint) Greater(lhs, rhs *Hugeint) bool {
 type Interval struct {
 	Months int32
 	Days   int32
-	Micros int32
+	Micros int64
 
 	Unit string
 	Year int32
@@ -428,6 +428,44 @@ func (d *Date) SubInterval(rhs *Interval) Date {
 	}
 }
 
+// Negate returns the interval in the opposite direction.
+func (i *Interval) Negate() Interval {
+	return Interval{
+		Months: -i.Months,
+		Days:   -i.Days,
+		Micros: -i.Micros,
+		Unit:   i.Unit,
+		Year:   -i.Year,
+	}
+}
+
+// TimestampAddInterval adds the interval to the micros since the epoch.
+// the months and days are added in the calendar like the postgres.
+func TimestampAddInterval(micros int64, i *Interval) int64 {
+	t := time.UnixMicro(micros).UTC()
+	t = t.AddDate(int(i.Year), int(i.Months), int(i.Days))
+	return t.UnixMicro() + i.Micros
+}
+
+// TimeAddInterval adds the time part of the interval to the micros since
+// the midnight. the result wraps around the midnight.
+func TimeAddInterval(micros int64, i *Interval) int64 {
+	ret := (micros + i.Micros) % MicrosPerDay
+	if ret < 0 {
+		ret += MicrosPerDay
+	}
+	return ret
+}
+
+// SubTimestamp returns the difference of two timestamps in days and micros.
+func SubTimestamp(left, right int64) Interval {
+	diff := left - right
+	return Interval{
+		Days:   int32(diff / MicrosPerDay),
+		Micros: diff % MicrosPerDay,
+	}
+}
+
 type String struct {
 	Len  int
 	Data unsafe.Pointer
@@ -530,6 +568,11 @@ func Numeric() []LType {
 	return ret
 }
 
+// Times returns the time and timestamp types. they are all int64 micros.
+func Times() []LType {
+	return []LType{TimeType(), TimestampType(), TimestampTzType()}
+}
+
 func (lt LType) Serialize(serial util.Serialize) error {
 	err := util.Write[int](int(lt.Id), serial)
 	if err != nil {
@@ -641,6 +684,18 @@ func TimestampType() LType {
 	return MakeLType(LTID_TIMESTAMP)
 }
 
+func TimestampTzType() LType {
+	return MakeLType(LTID_TIMESTAMP_TZ)
+}
+
+func BlobType() LType {
+	return MakeLType(LTID_BLOB)
+}
+
+func UuidType() LType {
+	return MakeLType(LTID_UUID)
+}
+
 func BooleanType() LType {
 	return MakeLType(LTID_BOOLEAN)
 }
@@ -649,6 +704,10 @@ func IntervalType() LType {
 	return MakeLType(LTID_INTERVAL)
 }
 
+func AnyType() LType {
+	return MakeLType(LTID_ANY)
+}
+
 func PointerType() LType {
 	return MakeLType(LTID_POINTER)
 }
@@ -682,6 +741,16 @@ func (lt LType) IsDate() bool {
 	return lt.Id == LTID_DATE
 }
 
+// IsTemporal reports whether the type is the date, time or timestamp.
+func (lt LType) IsTemporal() bool {
+	switch lt.Id {
+	case LTID_DATE, LTID_TIME, LTID_TIMESTAMP, LTID_TIMESTAMP_TZ:
+		return true
+	default:
+		return false
+	}
+}
+
 func (lt LType) IsInterval() bool {
 	return lt.Id == LTID_INTERVAL
 }
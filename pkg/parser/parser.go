@@ -15,13 +15,156 @@
 package parser
 
 import (
+	"regexp"
+	"strings"
+
 	pg_query "github.com/pganalyze/pg_query_go/v5"
 )
 
 func Parse(s string) ([]*pg_query.RawStmt, error) {
-	result, err := pg_query.Parse(s)
+	result, err := pg_query.Parse(RewriteTryCast(s))
 	if err != nil {
 		return nil, err
 	}
 	return result.Stmts, nil
 }
+
+const (
+	DatabaseStmtExport = iota + 1
+	DatabaseStmtImport
+)
+
+// DatabaseStmt is the EXPORT DATABASE or IMPORT DATABASE statement.
+// they are not in the postgres grammar.
+type DatabaseStmt struct {
+	Kind   int
+	Path   string
+	Format string
+}
+
+var databaseStmtRegex = regexp.MustCompile(
+	`(?is)^\s*(export|import)\s+database\s+'((?:[^']|'')*)'` +
+		`(?:\s*\(\s*format\s+'?(\w+)'?\s*\))?\s*;?\s*$`)
+
+// ParseDatabaseStmt returns nil if the s is not
+// the EXPORT DATABASE or IMPORT DATABASE.
+func ParseDatabaseStmt(s string) *DatabaseStmt {
+	matches := databaseStmtRegex.FindStringSubmatch(s)
+	if matches == nil {
+		return nil
+	}
+	ret := &DatabaseStmt{
+		Kind:   DatabaseStmtExport,
+		Path:   strings.ReplaceAll(matches[2], "''", "'"),
+		Format: strings.ToLower(matches[3]),
+	}
+	if strings.EqualFold(matches[1], "import") {
+		ret.Kind = DatabaseStmtImport
+	}
+	return ret
+}
+
+// TryCastFunc is the function that wraps the CAST rewritten from the TRY_CAST.
+const TryCastFunc = "try_cast"
+
+var tryCastRegex = regexp.MustCompile(`(?i)^try_cast\s*\(`)
+
+// RewriteTryCast rewrites the TRY_CAST(x AS t) into try_cast(CAST(x AS t)).
+// the TRY_CAST is not in the postgres grammar.
+func RewriteTryCast(s string) string {
+	if !strings.Contains(strings.ToLower(s), TryCastFunc) {
+		return s
+	}
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		if next := skipLiteral(s, i); next > i {
+			sb.WriteString(s[i:next])
+			i = next
+			continue
+		}
+		if !isIdentChar(s, i-1) {
+			if loc := tryCastRegex.FindStringIndex(s[i:]); loc != nil {
+				open := i + loc[1] - 1
+				if end := matchParen(s, open); end > 0 {
+					sb.WriteString(TryCastFunc)
+					sb.WriteString("(CAST(")
+					sb.WriteString(RewriteTryCast(s[open+1 : end]))
+					sb.WriteString("))")
+					i = end + 1
+					continue
+				}
+			}
+		}
+		sb.WriteByte(s[i])
+		i++
+	}
+	return sb.String()
+}
+
+func isIdentChar(s string, i int) bool {
+	if i < 0 || i >= len(s) {
+		return false
+	}
+	c := s[i]
+	return c == '_' || c == '$' || c >= '0' && c <= '9' ||
+		c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// skipLiteral returns the position after the string, quoted identifier
+// or comment at i. it returns i if there is none.
+func skipLiteral(s string, i int) int {
+	switch {
+	case s[i] == '\'' || s[i] == '"':
+		quote := s[i]
+		escape := quote == '\'' && i > 0 && (s[i-1] == 'e' || s[i-1] == 'E') &&
+			!isIdentChar(s, i-2)
+		for j := i + 1; j < len(s); j++ {
+			if escape && s[j] == '\\' {
+				j++
+				continue
+			}
+			if s[j] == quote {
+				if j+1 < len(s) && s[j+1] == quote {
+					j++
+					continue
+				}
+				return j + 1
+			}
+		}
+		return len(s)
+	case strings.HasPrefix(s[i:], "--"):
+		if end := strings.IndexByte(s[i:], '\n'); end >= 0 {
+			return i + end + 1
+		}
+		return len(s)
+	case strings.HasPrefix(s[i:], "/*"):
+		if end := strings.Index(s[i+2:], "*/"); end >= 0 {
+			return i + 2 + end + 2
+		}
+		return len(s)
+	}
+	return i
+}
+
+// matchParen returns the position of the ')' that matches the '(' at open.
+// it returns -1 if there is none.
+func matchParen(s string, open int) int {
+	level := 0
+	for i := open; i < len(s); {
+		if next := skipLiteral(s, i); next > i {
+			i = next
+			continue
+		}
+		switch s[i] {
+		case '(':
+			level++
+		case ')':
+			level--
+			if level == 0 {
+				return i
+			}
+		}
+		i++
+	}
+	return -1
+}
@@ -69,3 +69,45 @@ func TestSchema(t *testing.T) {
 		require.Equal(t, 1, len(stmts))
 	}
 }
+
+func TestDatabaseStmt(t *testing.T) {
+	stmt := ParseDatabaseStmt("export database '/tmp/it''s'")
+	require.NotNil(t, stmt)
+	require.Equal(t, DatabaseStmtExport, stmt.Kind)
+	require.Equal(t, "/tmp/it's", stmt.Path)
+	require.Equal(t, "", stmt.Format)
+
+	stmt = ParseDatabaseStmt("EXPORT DATABASE 'dir' (FORMAT csv);")
+	require.NotNil(t, stmt)
+	require.Equal(t, "csv", stmt.Format)
+
+	stmt = ParseDatabaseStmt("  import database 'dir'\n")
+	require.NotNil(t, stmt)
+	require.Equal(t, DatabaseStmtImport, stmt.Kind)
+	require.Equal(t, "dir", stmt.Path)
+
+	require.Nil(t, ParseDatabaseStmt("select 'export database'"))
+	require.Nil(t, ParseDatabaseStmt("export database dir"))
+}
+
+func TestRewriteTryCast(t *testing.T) {
+	kases := []struct {
+		sql  string
+		want string
+	}{
+		{"select 1", "select 1"},
+		{"select TRY_CAST(a AS int) from t",
+			"select try_cast(CAST(a AS int)) from t"},
+		{"select try_cast ( f(a, b) as numeric(10,2) ), 'try_cast(x)' from t",
+			"select try_cast(CAST( f(a, b) as numeric(10,2) )), 'try_cast(x)' from t"},
+		{"select try_cast(try_cast(a as text) as int)",
+			"select try_cast(CAST(try_cast(CAST(a as text)) as int))"},
+		{"select my_try_cast(a) from t", "select my_try_cast(a) from t"},
+	}
+	for _, kase := range kases {
+		assert.Equal(t, kase.want, RewriteTryCast(kase.sql), kase.sql)
+	}
+	stmts, err := Parse("select try_cast(a as int) from t")
+	require.NoError(t, err)
+	require.Equal(t, 1, len(stmts))
+}
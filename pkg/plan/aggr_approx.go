@@ -0,0 +1,376 @@
+// Copyright 2023-2024 daviszhen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"math"
+	"runtime/cgo"
+	"sort"
+	"unsafe"
+
+	hll "github.com/axiomhq/hyperloglog"
+
+	"github.com/daviszhen/plan/pkg/chunk"
+	"github.com/daviszhen/plan/pkg/common"
+	"github.com/daviszhen/plan/pkg/util"
+)
+
+// approx_count_distinct
+
+// hllState refers the HLL sketch in the go heap.
+// it is the same sketch as the distinct statistics of the storage.
+type hllState struct {
+	_handle cgo.Handle
+}
+
+func (state *hllState) get() *hll.Sketch {
+	if state._handle == 0 {
+		state._handle = cgo.NewHandle(hll.New14())
+	}
+	return state._handle.Value().(*hll.Sketch)
+}
+
+// take returns the sketch and releases the handle.
+func (state *hllState) take() *hll.Sketch {
+	if state._handle == 0 {
+		return nil
+	}
+	sketch := state._handle.Value().(*hll.Sketch)
+	state._handle.Delete()
+	state._handle = 0
+	return sketch
+}
+
+// ApproxCountDistinct counts the distinct values by the HLL sketch.
+// the values are hashed in the same way as the hash table.
+func ApproxCountDistinct(inputTyp common.LType) *FunctionV2 {
+	update := func(input *chunk.Vector, count int, state func(int) unsafe.Pointer) {
+		hashes := chunk.NewFlatVector(common.HashType(), util.DefaultVectorSize)
+		chunk.HashTypeSwitch(input, hashes, nil, count, false)
+		var idata, hdata chunk.UnifiedFormat
+		input.ToUnifiedFormat(count, &idata)
+		hashes.ToUnifiedFormat(count, &hdata)
+		hashSlice := chunk.GetSliceInPhyFormatUnifiedFormat[uint64](&hdata)
+		for i := 0; i < count; i++ {
+			if !idata.Mask.RowIsValid(uint64(idata.Sel.GetIndex(i))) {
+				continue
+			}
+			(*hllState)(state(i)).get().InsertHash(hashSlice[hdata.Sel.GetIndex(i)])
+		}
+	}
+	return &FunctionV2{
+		_name:      "approx_count_distinct",
+		_funcTyp:   AggregateFuncType,
+		_args:      []common.LType{inputTyp},
+		_retType:   common.BigintType(),
+		_stateSize: stateSizeOf[hllState],
+		_init: func(pointer unsafe.Pointer) {
+			(*hllState)(pointer)._handle = 0
+		},
+		_update: func(inputs []*chunk.Vector, _ *AggrInputData, inputCount int, states *chunk.Vector, count int) {
+			util.AssertFunc(inputCount == 1)
+			update(inputs[0], count, scatterStates(states, count))
+		},
+		_combine: func(source *chunk.Vector, target *chunk.Vector, _ *AggrInputData, count int) {
+			combineStates[hllState](source, target, count, func(src, target *hllState) {
+				if src._handle == 0 {
+					return
+				}
+				_ = target.get().Merge(src.take())
+			})
+		},
+		_finalize: func(states *chunk.Vector, data *AggrInputData, result *chunk.Vector, count int, offset int) {
+			finalizeStates[hllState, int64](states, data, result, count, offset,
+				func(s *hllState, ret *int64, _ *AggrFinalizeData) {
+					*ret = 0
+					if sketch := s.take(); sketch != nil {
+						*ret = int64(sketch.Estimate())
+					}
+				})
+		},
+		_simpleUpdate: func(inputs []*chunk.Vector, _ *AggrInputData, inputCount int, state unsafe.Pointer, count int) {
+			util.AssertFunc(inputCount == 1)
+			update(inputs[0], count, func(int) unsafe.Pointer { return state })
+		},
+	}
+}
+
+// t-digest
+
+type tdigestCentroid struct {
+	_mean   float64
+	_weight float64
+}
+
+// tdigest is the merging t-digest.
+// the centroids near the tails are smaller than the ones in the middle.
+type tdigest struct {
+	_compression float64
+	_centroids   []tdigestCentroid
+	_buffer      []tdigestCentroid
+	_count       float64
+	_min         float64
+	_max         float64
+}
+
+func newTDigest(compression float64) *tdigest {
+	return &tdigest{
+		_compression: compression,
+		_min:         math.Inf(1),
+		_max:         math.Inf(-1),
+	}
+}
+
+func (td *tdigest) add(x float64) {
+	td._buffer = append(td._buffer, tdigestCentroid{_mean: x, _weight: 1})
+	td._count++
+	td._min = min(td._min, x)
+	td._max = max(td._max, x)
+	if len(td._buffer) >= int(td._compression)*5 {
+		td.compress()
+	}
+}
+
+func (td *tdigest) merge(other *tdigest) {
+	td._buffer = append(td._buffer, other._centroids...)
+	td._buffer = append(td._buffer, other._buffer...)
+	td._count += other._count
+	td._min = min(td._min, other._min)
+	td._max = max(td._max, other._max)
+	td.compress()
+}
+
+// compress merges the buffer into the centroids.
+// the weight of the centroid at the quantile q is
+// bounded by 4*count*q*(1-q)/compression.
+func (td *tdigest) compress() {
+	if len(td._buffer) == 0 {
+		return
+	}
+	all := append(td._centroids, td._buffer...)
+	td._buffer = td._buffer[:0]
+	sort.Slice(all, func(i, j int) bool {
+		return all[i]._mean < all[j]._mean
+	})
+	merged := make([]tdigestCentroid, 0, len(all))
+	cur := all[0]
+	weightSoFar := 0.0
+	for _, c := range all[1:] {
+		proposed := cur._weight + c._weight
+		q0 := weightSoFar / td._count
+		q2 := (weightSoFar + proposed) / td._count
+		limit := 4 * td._count * min(q0*(1-q0), q2*(1-q2)) / td._compression
+		if proposed <= limit {
+			cur._mean += (c._mean - cur._mean) * c._weight / proposed
+			cur._weight = proposed
+		} else {
+			weightSoFar += cur._weight
+			merged = append(merged, cur)
+			cur = c
+		}
+	}
+	td._centroids = append(merged, cur)
+}
+
+// quantile interpolates between the centers of the centroids.
+func (td *tdigest) quantile(q float64) float64 {
+	td.compress()
+	cs := td._centroids
+	index := q * td._count
+	cum := 0.0
+	for i, c := range cs {
+		center := cum + c._weight/2
+		if index < center {
+			if i == 0 {
+				return td._min + (c._mean-td._min)*index/center
+			}
+			prev := cs[i-1]
+			prevCenter := cum - prev._weight/2
+			return prev._mean + (c._mean-prev._mean)*(index-prevCenter)/(center-prevCenter)
+		}
+		cum += c._weight
+	}
+	last := cs[len(cs)-1]
+	lastCenter := td._count - last._weight/2
+	if index >= td._count || lastCenter >= td._count {
+		return td._max
+	}
+	return last._mean + (td._max-last._mean)*(index-lastCenter)/(td._count-lastCenter)
+}
+
+// quantiles
+
+// checkFraction returns the fraction of the quantile.
+func checkFraction(val *chunk.Value) float64 {
+	if val.F64 < 0 || val.F64 > 1 || math.IsNaN(val.F64) {
+		panic(util.NewSQLError(util.SQLStateNumericValueOutOfRange,
+			"percentile value %g is not between 0 and 1", val.F64))
+	}
+	return val.F64
+}
+
+type quantileState struct {
+	_fraction    float64
+	_hasFraction bool
+	_values      []*chunk.Value
+}
+
+// quantileOp computes the quantile exactly by sorting the values.
+// the fraction is the second argument. median has no fraction argument.
+type quantileOp struct {
+	//discrete or continuous
+	_disc bool
+	//in the descending order
+	_desc bool
+}
+
+func (op quantileOp) Update(s *quantileState, args []*chunk.Value) {
+	if !s._hasFraction {
+		s._fraction = 0.5
+		if len(args) > 1 {
+			if args[1].IsNull {
+				return
+			}
+			s._fraction = checkFraction(args[1])
+		}
+		s._hasFraction = true
+	}
+	if args[0].IsNull {
+		return
+	}
+	s._values = append(s._values, args[0])
+}
+
+func (op quantileOp) Combine(src *quantileState, target *quantileState) {
+	if !target._hasFraction {
+		target._fraction = src._fraction
+		target._hasFraction = src._hasFraction
+	}
+	target._values = append(target._values, src._values...)
+}
+
+func (op quantileOp) Finalize(s *quantileState) *chunk.Value {
+	n := len(s._values)
+	if n == 0 {
+		return nil
+	}
+	sort.SliceStable(s._values, func(i, j int) bool {
+		cmp := compareValue(s._values[i], s._values[j])
+		if op._desc {
+			return cmp > 0
+		}
+		return cmp < 0
+	})
+	if op._disc {
+		//the first value whose position is not less than the fraction
+		idx := int(math.Ceil(s._fraction*float64(n))) - 1
+		return s._values[max(idx, 0)]
+	}
+	pos := s._fraction * float64(n-1)
+	lo := int(math.Floor(pos))
+	hi := int(math.Ceil(pos))
+	loVal, hiVal := s._values[lo].F64, s._values[hi].F64
+	return &chunk.Value{
+		Typ: common.DoubleType(),
+		F64: loVal + (hiVal-loVal)*(pos-float64(lo)),
+	}
+}
+
+// QuantileAggregate is the exact quantile aggregate.
+func QuantileAggregate(name string, args []common.LType, retTyp common.LType, disc, desc bool) *FunctionV2 {
+	return ValueAggregate[quantileState](
+		name,
+		args,
+		retTyp,
+		quantileOp{_disc: disc, _desc: desc},
+	)
+}
+
+type approxQuantileState struct {
+	_fraction    float64
+	_hasFraction bool
+	_digest      *tdigest
+}
+
+// approxQuantileOp computes the quantile by the t-digest.
+type approxQuantileOp struct {
+}
+
+func (op approxQuantileOp) Update(s *approxQuantileState, args []*chunk.Value) {
+	if !s._hasFraction {
+		if args[1].IsNull {
+			return
+		}
+		s._fraction = checkFraction(args[1])
+		s._hasFraction = true
+	}
+	if args[0].IsNull {
+		return
+	}
+	op.add(s, args[0].F64)
+}
+
+func (op approxQuantileOp) UpdateVectors(inputs []chunk.UnifiedFormat, count int, state func(int) *approxQuantileState) {
+	vals := chunk.GetSliceInPhyFormatUnifiedFormat[float64](&inputs[0])
+	fractions := chunk.GetSliceInPhyFormatUnifiedFormat[float64](&inputs[1])
+	for i := 0; i < count; i++ {
+		s := state(i)
+		if !s._hasFraction {
+			idx := inputs[1].Sel.GetIndex(i)
+			if !inputs[1].Mask.RowIsValid(uint64(idx)) {
+				continue
+			}
+			s._fraction = checkFraction(&chunk.Value{Typ: common.DoubleType(), F64: fractions[idx]})
+			s._hasFraction = true
+		}
+		idx := inputs[0].Sel.GetIndex(i)
+		if !inputs[0].Mask.RowIsValid(uint64(idx)) {
+			continue
+		}
+		op.add(s, vals[idx])
+	}
+}
+
+func (approxQuantileOp) add(s *approxQuantileState, val float64) {
+	if s._digest == nil {
+		s._digest = newTDigest(100)
+	}
+	s._digest.add(val)
+}
+
+func (approxQuantileOp) Combine(src *approxQuantileState, target *approxQuantileState) {
+	if !target._hasFraction {
+		target._fraction = src._fraction
+		target._hasFraction = src._hasFraction
+	}
+	if src._digest == nil {
+		return
+	}
+	if target._digest == nil {
+		target._digest = src._digest
+		return
+	}
+	target._digest.merge(src._digest)
+}
+
+func (approxQuantileOp) Finalize(s *approxQuantileState) *chunk.Value {
+	if s._digest == nil {
+		return nil
+	}
+	return &chunk.Value{
+		Typ: common.DoubleType(),
+		F64: s._digest.quantile(s._fraction),
+	}
+}
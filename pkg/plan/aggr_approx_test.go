@@ -0,0 +1,134 @@
+// Copyright 2023-2024 daviszhen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/daviszhen/plan/pkg/chunk"
+	"github.com/daviszhen/plan/pkg/common"
+	"github.com/daviszhen/plan/pkg/storage"
+	"github.com/daviszhen/plan/pkg/util"
+)
+
+func Test_tdigest(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	td := newTDigest(100)
+	other := newTDigest(100)
+	n := 100000
+	for i := 0; i < n; i++ {
+		if i%2 == 0 {
+			td.add(r.Float64())
+		} else {
+			other.add(r.Float64())
+		}
+	}
+	td.merge(other)
+	require.Less(t, len(td._centroids), 1000)
+	for _, q := range []float64{0.01, 0.1, 0.5, 0.9, 0.99} {
+		require.InDelta(t, q, td.quantile(q), 0.01, q)
+	}
+	require.Equal(t, td._min, td.quantile(0))
+	require.Equal(t, td._max, td.quantile(1))
+}
+
+func Test_approxCountDistinct(t *testing.T) {
+	fun := ApproxCountDistinct(common.IntegerType())
+	state := make([]byte, fun._stateSize())
+	statePtr := util.BytesSliceToPointer(state)
+	fun._init(statePtr)
+
+	//every value appears twice
+	distinct := 50000
+	input := chunk.NewFlatVector(common.IntegerType(), util.DefaultVectorSize)
+	data := chunk.GetSliceInPhyFormatFlat[int32](input)
+	for start := 0; start < 2*distinct; start += util.DefaultVectorSize {
+		count := min(util.DefaultVectorSize, 2*distinct-start)
+		for i := 0; i < count; i++ {
+			data[i] = int32((start + i) % distinct)
+		}
+		fun._simpleUpdate([]*chunk.Vector{input}, NewAggrInputData(), 1, statePtr, count)
+	}
+
+	states := chunk.NewFlatVector(common.PointerType(), 1)
+	chunk.GetSliceInPhyFormatFlat[unsafe.Pointer](states)[0] = statePtr
+	result := chunk.NewFlatVector(common.BigintType(), 1)
+	fun._finalize(states, NewAggrInputData(), result, 1, 0)
+	est := chunk.GetSliceInPhyFormatFlat[int64](result)[0]
+	require.Less(t, math.Abs(float64(est-int64(distinct))), float64(distinct)*0.02)
+}
+
+func Test_approxAggr(t *testing.T) {
+	txn, err := storage.GTxnMgr.NewTxn("approx aggr")
+	require.NoError(t, err)
+	defer storage.GTxnMgr.Rollback(txn)
+	execSQL(t, txn, "create table aa_t (g integer, i integer, d decimal(10,2), s varchar)")
+	execSQL(t, txn, "insert into aa_t values (1, 1, 1.5, 'a'), (1, 2, 2.5, 'b'), (2, 3, 3.5, 'c'), (2, 4, 4.5, 'z')")
+
+	kases := []struct {
+		expr string
+		want string
+	}{
+		{"approx_count_distinct(s)", "4"},
+		{"approx_count_distinct(g)", "2"},
+		{"approx_count_distinct(nullif(i, 2))", "3"},
+		{"percentile_cont(0.5) within group (order by i)", "2.5"},
+		{"percentile_cont(0.25) within group (order by i desc)", "3.25"},
+		{"percentile_disc(0.5) within group (order by i)", "2"},
+		{"percentile_disc(0.25) within group (order by i desc)", "4"},
+		{"percentile_disc(0.5) within group (order by s)", "b"},
+		{"mode() within group (order by g)", "1"},
+		{"median(i)", "2.5"},
+		{"quantile_cont(d, 0.75)", "3.75"},
+		{"quantile_disc(s, 1)", "z"},
+		{"approx_quantile(i, 0.5)", "2.5"},
+		{"approx_quantile(d, 1)", "4.5"},
+	}
+	for _, kase := range kases {
+		rows, err := querySQL(t, txn, "select "+kase.expr+" from aa_t")
+		require.NoError(t, err, kase.expr)
+		require.Equal(t, [][]string{{kase.want}}, rows, kase.expr)
+	}
+
+	rows, err := querySQL(t, txn, "select g, approx_count_distinct(s), percentile_cont(0.5) within group (order by d), median(i), approx_quantile(i, 0.5) from aa_t group by g order by g")
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"1", "2", "2", "1.5", "1.5"}, {"2", "2", "4", "3.5", "3.5"}}, rows)
+
+	errKases := []struct {
+		sql  string
+		code util.SQLState
+	}{
+		{"select percentile_cont(1.5) within group (order by i) from aa_t", util.SQLStateNumericValueOutOfRange},
+		{"select percentile_cont(i) from aa_t", util.SQLStateWrongObjectType},
+		{"select sum(i) within group (order by i) from aa_t", util.SQLStateWrongObjectType},
+	}
+	for _, kase := range errKases {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					err = util.RecoverToError(r)
+				}
+				require.Error(t, err, kase.sql)
+				require.Equal(t, kase.code, util.GetSQLState(err), kase.sql)
+			}()
+			_, err = querySQL(t, txn, kase.sql)
+		}()
+	}
+}
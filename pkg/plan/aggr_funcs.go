@@ -811,14 +811,11 @@ func (CountOp[ResultT, InputT]) Finalize(
 	s3 *State[ResultT],
 	target *ResultT,
 	data *AggrFinalizeData) {
-	if s3._count == 0 {
-		data.ReturnNull()
-	} else {
-		ret := common.Hugeint{
-			Lower: s3._count,
-		}
-		*target = any(ret).(ResultT)
+	//count is zero instead of NULL on no input
+	ret := common.Hugeint{
+		Lower: s3._count,
 	}
+	*target = any(ret).(ResultT)
 }
 
 func (CountOp[ResultT, InputT]) IgnoreNull() bool {
@@ -0,0 +1,836 @@
+// Copyright 2023-2024 daviszhen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"math"
+	"runtime/cgo"
+	"sort"
+	"strings"
+	"unsafe"
+
+	dec "github.com/govalues/decimal"
+
+	"github.com/daviszhen/plan/pkg/chunk"
+	"github.com/daviszhen/plan/pkg/common"
+	"github.com/daviszhen/plan/pkg/util"
+)
+
+// StateAggrOp is the aggregate with the fixed size state.
+// the rows with NULL input are skipped.
+type StateAggrOp[STATE any, InputT any, ResultT any] interface {
+	Init(*STATE)
+	Update(*STATE, *InputT)
+	Combine(src *STATE, target *STATE)
+	Finalize(*STATE, *ResultT, *AggrFinalizeData)
+}
+
+// BinaryStateAggrOp is the StateAggrOp with two inputs.
+// the rows with any NULL input are skipped.
+type BinaryStateAggrOp[STATE any, AT any, BT any, ResultT any] interface {
+	Init(*STATE)
+	Update(*STATE, *AT, *BT)
+	Combine(src *STATE, target *STATE)
+	Finalize(*STATE, *ResultT, *AggrFinalizeData)
+}
+
+func stateSizeOf[STATE any]() int {
+	var state STATE
+	return int(unsafe.Sizeof(state))
+}
+
+func StateAggregate[STATE any, InputT any, ResultT any](
+	name string,
+	inputTyp common.LType,
+	retTyp common.LType,
+	op StateAggrOp[STATE, InputT, ResultT],
+) *FunctionV2 {
+	update := func(input *chunk.Vector, count int, state func(int) unsafe.Pointer) {
+		var idata chunk.UnifiedFormat
+		input.ToUnifiedFormat(count, &idata)
+		inputSlice := chunk.GetSliceInPhyFormatUnifiedFormat[InputT](&idata)
+		for i := 0; i < count; i++ {
+			idx := idata.Sel.GetIndex(i)
+			if !idata.Mask.RowIsValid(uint64(idx)) {
+				continue
+			}
+			op.Update((*STATE)(state(i)), &inputSlice[idx])
+		}
+	}
+	return &FunctionV2{
+		_name:      name,
+		_funcTyp:   AggregateFuncType,
+		_args:      []common.LType{inputTyp},
+		_retType:   retTyp,
+		_stateSize: stateSizeOf[STATE],
+		_init: func(pointer unsafe.Pointer) {
+			op.Init((*STATE)(pointer))
+		},
+		_update: func(inputs []*chunk.Vector, _ *AggrInputData, inputCount int, states *chunk.Vector, count int) {
+			util.AssertFunc(inputCount == 1)
+			update(inputs[0], count, scatterStates(states, count))
+		},
+		_combine: func(source *chunk.Vector, target *chunk.Vector, _ *AggrInputData, count int) {
+			combineStates[STATE](source, target, count, op.Combine)
+		},
+		_finalize: func(states *chunk.Vector, data *AggrInputData, result *chunk.Vector, count int, offset int) {
+			finalizeStates[STATE, ResultT](states, data, result, count, offset, op.Finalize)
+		},
+		_simpleUpdate: func(inputs []*chunk.Vector, _ *AggrInputData, inputCount int, state unsafe.Pointer, count int) {
+			util.AssertFunc(inputCount == 1)
+			update(inputs[0], count, func(int) unsafe.Pointer { return state })
+		},
+	}
+}
+
+func BinaryStateAggregate[STATE any, AT any, BT any, ResultT any](
+	name string,
+	aTyp common.LType,
+	bTyp common.LType,
+	retTyp common.LType,
+	op BinaryStateAggrOp[STATE, AT, BT, ResultT],
+) *FunctionV2 {
+	update := func(inputs []*chunk.Vector, count int, state func(int) unsafe.Pointer) {
+		var adata, bdata chunk.UnifiedFormat
+		inputs[0].ToUnifiedFormat(count, &adata)
+		inputs[1].ToUnifiedFormat(count, &bdata)
+		aSlice := chunk.GetSliceInPhyFormatUnifiedFormat[AT](&adata)
+		bSlice := chunk.GetSliceInPhyFormatUnifiedFormat[BT](&bdata)
+		for i := 0; i < count; i++ {
+			aIdx := adata.Sel.GetIndex(i)
+			bIdx := bdata.Sel.GetIndex(i)
+			if !adata.Mask.RowIsValid(uint64(aIdx)) ||
+				!bdata.Mask.RowIsValid(uint64(bIdx)) {
+				continue
+			}
+			op.Update((*STATE)(state(i)), &aSlice[aIdx], &bSlice[bIdx])
+		}
+	}
+	return &FunctionV2{
+		_name:      name,
+		_funcTyp:   AggregateFuncType,
+		_args:      []common.LType{aTyp, bTyp},
+		_retType:   retTyp,
+		_stateSize: stateSizeOf[STATE],
+		_init: func(pointer unsafe.Pointer) {
+			op.Init((*STATE)(pointer))
+		},
+		_update: func(inputs []*chunk.Vector, _ *AggrInputData, inputCount int, states *chunk.Vector, count int) {
+			util.AssertFunc(inputCount == 2)
+			update(inputs, count, scatterStates(states, count))
+		},
+		_combine: func(source *chunk.Vector, target *chunk.Vector, _ *AggrInputData, count int) {
+			combineStates[STATE](source, target, count, op.Combine)
+		},
+		_finalize: func(states *chunk.Vector, data *AggrInputData, result *chunk.Vector, count int, offset int) {
+			finalizeStates[STATE, ResultT](states, data, result, count, offset, op.Finalize)
+		},
+		_simpleUpdate: func(inputs []*chunk.Vector, _ *AggrInputData, inputCount int, state unsafe.Pointer, count int) {
+			util.AssertFunc(inputCount == 2)
+			update(inputs, count, func(int) unsafe.Pointer { return state })
+		},
+	}
+}
+
+// scatterStates returns the state pointer of the i-th row.
+func scatterStates(states *chunk.Vector, count int) func(int) unsafe.Pointer {
+	var sdata chunk.UnifiedFormat
+	states.ToUnifiedFormat(count, &sdata)
+	statesSlice := chunk.GetSliceInPhyFormatUnifiedFormat[unsafe.Pointer](&sdata)
+	return func(i int) unsafe.Pointer {
+		return statesSlice[sdata.Sel.GetIndex(i)]
+	}
+}
+
+func combineStates[STATE any](source, target *chunk.Vector, count int, fn func(*STATE, *STATE)) {
+	util.AssertFunc(source.Typ().IsPointer())
+	util.AssertFunc(target.Typ().IsPointer())
+	sourceSlice := chunk.GetSliceInPhyFormatFlat[unsafe.Pointer](source)
+	targetSlice := chunk.GetSliceInPhyFormatFlat[unsafe.Pointer](target)
+	for i := 0; i < count; i++ {
+		fn((*STATE)(sourceSlice[i]), (*STATE)(targetSlice[i]))
+	}
+}
+
+func finalizeStates[STATE any, ResultT any](
+	states *chunk.Vector,
+	data *AggrInputData,
+	result *chunk.Vector,
+	count int,
+	offset int,
+	fn func(*STATE, *ResultT, *AggrFinalizeData),
+) {
+	statesSlice := chunk.GetSliceInPhyFormatFlat[unsafe.Pointer](states)
+	resultSlice := chunk.GetSliceInPhyFormatFlat[ResultT](result)
+	final := NewAggrFinalizeData(result, data)
+	if states.PhyFormat().IsConst() {
+		result.SetPhyFormat(chunk.PF_CONST)
+		fn((*STATE)(statesSlice[0]), &resultSlice[0], final)
+		return
+	}
+	util.AssertFunc(states.PhyFormat().IsFlat())
+	result.SetPhyFormat(chunk.PF_FLAT)
+	for i := 0; i < count; i++ {
+		final._resultIdx = i + offset
+		fn((*STATE)(statesSlice[i]), &resultSlice[final._resultIdx], final)
+	}
+}
+
+// ValueAggrOp is the aggregate on the values of the rows.
+// it keeps the variable size state S in the go heap.
+// the rows with NULL inputs are passed to the Update.
+type ValueAggrOp[S any] interface {
+	Update(state *S, args []*chunk.Value)
+	Combine(src *S, target *S)
+	//Finalize returns nil for the NULL
+	Finalize(state *S) *chunk.Value
+}
+
+// VectorAggrOp is the ValueAggrOp on the inputs of the fixed types.
+// it updates the states on the typed data of the inputs
+// instead of the values of the rows.
+type VectorAggrOp[S any] interface {
+	ValueAggrOp[S]
+	UpdateVectors(inputs []chunk.UnifiedFormat, count int, state func(int) *S)
+}
+
+// valueAggrState is the state of the ValueAggrOp in the row of the hash table.
+// the row memory is out of the go heap. the state refers
+// the go object by the handle.
+type valueAggrState[S any] struct {
+	_handle cgo.Handle
+}
+
+func (state *valueAggrState[S]) get() *S {
+	if state._handle == 0 {
+		state._handle = cgo.NewHandle(new(S))
+	}
+	return state._handle.Value().(*S)
+}
+
+// take returns the go object and releases the handle.
+func (state *valueAggrState[S]) take() *S {
+	if state._handle == 0 {
+		return new(S)
+	}
+	s := state._handle.Value().(*S)
+	state.release()
+	return s
+}
+
+// release deletes the handle. the go object is dropped.
+func (state *valueAggrState[S]) release() {
+	if state._handle == 0 {
+		return
+	}
+	state._handle.Delete()
+	state._handle = 0
+}
+
+func ValueAggregate[S any](
+	name string,
+	args []common.LType,
+	retTyp common.LType,
+	op ValueAggrOp[S],
+) *FunctionV2 {
+	update := func(inputs []*chunk.Vector, count int, state func(int) unsafe.Pointer) {
+		if vop, ok := op.(VectorAggrOp[S]); ok {
+			idata := make([]chunk.UnifiedFormat, len(inputs))
+			for j, input := range inputs {
+				input.ToUnifiedFormat(count, &idata[j])
+			}
+			vop.UpdateVectors(idata, count, func(i int) *S {
+				return (*valueAggrState[S])(state(i)).get()
+			})
+			return
+		}
+		values := make([]*chunk.Value, len(inputs))
+		for i := 0; i < count; i++ {
+			for j, input := range inputs {
+				values[j] = input.GetValue(i)
+			}
+			op.Update((*valueAggrState[S])(state(i)).get(), values)
+		}
+	}
+	return &FunctionV2{
+		_name:      name,
+		_funcTyp:   AggregateFuncType,
+		_args:      args,
+		_retType:   retTyp,
+		_stateSize: stateSizeOf[valueAggrState[S]],
+		_init: func(pointer unsafe.Pointer) {
+			(*valueAggrState[S])(pointer)._handle = 0
+		},
+		_update: func(inputs []*chunk.Vector, _ *AggrInputData, inputCount int, states *chunk.Vector, count int) {
+			update(inputs[:inputCount], count, scatterStates(states, count))
+		},
+		_combine: func(source *chunk.Vector, target *chunk.Vector, _ *AggrInputData, count int) {
+			combineStates[valueAggrState[S]](source, target, count, func(src, target *valueAggrState[S]) {
+				if src._handle == 0 {
+					return
+				}
+				op.Combine(src.take(), target.get())
+			})
+		},
+		_finalize: func(states *chunk.Vector, data *AggrInputData, result *chunk.Vector, count int, offset int) {
+			statesSlice := chunk.GetSliceInPhyFormatFlat[unsafe.Pointer](states)
+			final := NewAggrFinalizeData(result, data)
+			if states.PhyFormat().IsConst() {
+				result.SetPhyFormat(chunk.PF_CONST)
+				count = 1
+			} else {
+				result.SetPhyFormat(chunk.PF_FLAT)
+			}
+			for i := 0; i < count; i++ {
+				final._resultIdx = i + offset
+				val := op.Finalize((*valueAggrState[S])(statesSlice[i]).take())
+				if val == nil || val.IsNull {
+					final.ReturnNull()
+				} else {
+					result.SetValue(final._resultIdx, val)
+				}
+			}
+		},
+		_simpleUpdate: func(inputs []*chunk.Vector, _ *AggrInputData, inputCount int, state unsafe.Pointer, count int) {
+			update(inputs[:inputCount], count, func(int) unsafe.Pointer { return state })
+		},
+		_destroy: func(states *chunk.Vector, count int) {
+			statesSlice := chunk.GetSliceInPhyFormatFlat[unsafe.Pointer](states)
+			for i := 0; i < count; i++ {
+				(*valueAggrState[S])(statesSlice[i]).release()
+			}
+		},
+	}
+}
+
+// compareValue compares the values of the same type.
+// NULL is greater than others.
+func compareValue(a, b *chunk.Value) int {
+	switch {
+	case a.IsNull && b.IsNull:
+		return 0
+	case a.IsNull:
+		return 1
+	case b.IsNull:
+		return -1
+	}
+	switch a.Typ.GetInternalType() {
+	case common.BOOL:
+		return compareOrdered(boolToInt(a.Bool), boolToInt(b.Bool))
+	case common.FLOAT, common.DOUBLE:
+		return compareOrdered(a.F64, b.F64)
+	case common.VARCHAR:
+		return strings.Compare(a.Str, b.Str)
+	case common.DATE, common.INTERVAL:
+		if ret := compareOrdered(a.I64, b.I64); ret != 0 {
+			return ret
+		}
+		if ret := compareOrdered(a.I64_1, b.I64_1); ret != 0 {
+			return ret
+		}
+		return compareOrdered(a.I64_2, b.I64_2)
+	case common.INT128:
+		if ret := compareOrdered(a.I64, b.I64); ret != 0 {
+			return ret
+		}
+		return compareOrdered(uint64(a.I64_1), uint64(b.I64_1))
+	case common.DECIMAL:
+		return valueToDecimal(a).Cmp(valueToDecimal(b))
+	default:
+		return compareOrdered(a.I64, b.I64)
+	}
+}
+
+func compareOrdered[T int64 | uint64 | float64](a, b T) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func boolToInt(b bool) int64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func valueToDecimal(val *chunk.Value) dec.Decimal {
+	if len(val.Str) != 0 {
+		return dec.MustParse(val.Str)
+	}
+	ret, err := dec.NewFromInt64(val.I64, val.I64_1, val.Typ.Scale)
+	if err != nil {
+		panic(err)
+	}
+	return ret
+}
+
+// variance and standard deviation
+
+type varianceState struct {
+	_count int64
+	_mean  float64
+	_m2    float64
+}
+
+type varianceOp struct {
+	_sample bool
+	_sqrt   bool
+}
+
+func (varianceOp) Init(s *varianceState) {
+	*s = varianceState{}
+}
+
+func (varianceOp) Update(s *varianceState, input *float64) {
+	s._count++
+	delta := *input - s._mean
+	s._mean += delta / float64(s._count)
+	s._m2 += delta * (*input - s._mean)
+}
+
+func (varianceOp) Combine(src *varianceState, target *varianceState) {
+	if src._count == 0 {
+		return
+	}
+	if target._count == 0 {
+		*target = *src
+		return
+	}
+	count := src._count + target._count
+	delta := src._mean - target._mean
+	n1, n2 := float64(target._count), float64(src._count)
+	target._m2 += src._m2 + delta*delta*n1*n2/float64(count)
+	target._mean += delta * n2 / float64(count)
+	target._count = count
+}
+
+func (op varianceOp) Finalize(s *varianceState, result *float64, data *AggrFinalizeData) {
+	div := s._count
+	if op._sample {
+		div--
+	}
+	if s._count == 0 || div <= 0 {
+		data.ReturnNull()
+		return
+	}
+	*result = s._m2 / float64(div)
+	if op._sqrt {
+		*result = math.Sqrt(*result)
+	}
+}
+
+// covariance and correlation
+
+type covarState struct {
+	_count    int64
+	_meanX    float64
+	_meanY    float64
+	_coMoment float64
+	_m2X      float64
+	_m2Y      float64
+}
+
+type covarKind int
+
+const (
+	covarPop covarKind = iota
+	covarSamp
+	covarCorr
+)
+
+type covarOp struct {
+	_kind covarKind
+}
+
+func (covarOp) Init(s *covarState) {
+	*s = covarState{}
+}
+
+func (covarOp) Update(s *covarState, y *float64, x *float64) {
+	s._count++
+	n := float64(s._count)
+	dx := *x - s._meanX
+	dy := *y - s._meanY
+	s._meanX += dx / n
+	s._meanY += dy / n
+	s._coMoment += dx * (*y - s._meanY)
+	s._m2X += dx * (*x - s._meanX)
+	s._m2Y += dy * (*y - s._meanY)
+}
+
+func (covarOp) Combine(src *covarState, target *covarState) {
+	if src._count == 0 {
+		return
+	}
+	if target._count == 0 {
+		*target = *src
+		return
+	}
+	n1, n2 := float64(target._count), float64(src._count)
+	n := n1 + n2
+	dx := src._meanX - target._meanX
+	dy := src._meanY - target._meanY
+	target._coMoment += src._coMoment + dx*dy*n1*n2/n
+	target._m2X += src._m2X + dx*dx*n1*n2/n
+	target._m2Y += src._m2Y + dy*dy*n1*n2/n
+	target._meanX += dx * n2 / n
+	target._meanY += dy * n2 / n
+	target._count += src._count
+}
+
+func (op covarOp) Finalize(s *covarState, result *float64, data *AggrFinalizeData) {
+	switch {
+	case s._count == 0:
+		data.ReturnNull()
+	case op._kind == covarPop:
+		*result = s._coMoment / float64(s._count)
+	case op._kind == covarSamp:
+		if s._count < 2 {
+			data.ReturnNull()
+			return
+		}
+		*result = s._coMoment / float64(s._count-1)
+	default:
+		div := math.Sqrt(s._m2X * s._m2Y)
+		if div == 0 {
+			data.ReturnNull()
+			return
+		}
+		*result = s._coMoment / div
+	}
+}
+
+// bool_and and bool_or
+
+type boolAggrState struct {
+	_isset bool
+	_value bool
+}
+
+type boolAggrOp struct {
+	_and bool
+}
+
+func (boolAggrOp) Init(s *boolAggrState) {
+	*s = boolAggrState{}
+}
+
+func (op boolAggrOp) Update(s *boolAggrState, input *bool) {
+	if !s._isset {
+		s._isset = true
+		s._value = *input
+	} else if op._and {
+		s._value = s._value && *input
+	} else {
+		s._value = s._value || *input
+	}
+}
+
+func (op boolAggrOp) Combine(src *boolAggrState, target *boolAggrState) {
+	if src._isset {
+		op.Update(target, &src._value)
+	}
+}
+
+func (boolAggrOp) Finalize(s *boolAggrState, result *bool, data *AggrFinalizeData) {
+	if !s._isset {
+		data.ReturnNull()
+		return
+	}
+	*result = s._value
+}
+
+// string_agg
+
+type stringAggrState struct {
+	_isset bool
+	//the separator before the first value
+	_firstSep string
+	_buf      strings.Builder
+}
+
+type stringAggrOp struct {
+}
+
+func (op stringAggrOp) Update(s *stringAggrState, args []*chunk.Value) {
+	if args[0].IsNull {
+		return
+	}
+	sep := ","
+	if len(args) > 1 {
+		sep = ""
+		if !args[1].IsNull {
+			sep = args[1].Str
+		}
+	}
+	op.add(s, args[0].Str, sep)
+}
+
+func (op stringAggrOp) UpdateVectors(inputs []chunk.UnifiedFormat, count int, state func(int) *stringAggrState) {
+	strs := chunk.GetSliceInPhyFormatUnifiedFormat[common.String](&inputs[0])
+	var seps []common.String
+	if len(inputs) > 1 {
+		seps = chunk.GetSliceInPhyFormatUnifiedFormat[common.String](&inputs[1])
+	}
+	for i := 0; i < count; i++ {
+		idx := inputs[0].Sel.GetIndex(i)
+		if !inputs[0].Mask.RowIsValid(uint64(idx)) {
+			continue
+		}
+		sep := ","
+		if seps != nil {
+			sep = ""
+			sepIdx := inputs[1].Sel.GetIndex(i)
+			if inputs[1].Mask.RowIsValid(uint64(sepIdx)) {
+				sep = seps[sepIdx].String()
+			}
+		}
+		op.add(state(i), strs[idx].String(), sep)
+	}
+}
+
+func (stringAggrOp) add(s *stringAggrState, str, sep string) {
+	if !s._isset {
+		s._isset = true
+		s._firstSep = sep
+	} else {
+		s._buf.WriteString(sep)
+	}
+	s._buf.WriteString(str)
+}
+
+func (stringAggrOp) Combine(src *stringAggrState, target *stringAggrState) {
+	if !src._isset {
+		return
+	}
+	if !target._isset {
+		target._isset = true
+		target._firstSep = src._firstSep
+	} else {
+		target._buf.WriteString(src._firstSep)
+	}
+	target._buf.WriteString(src._buf.String())
+}
+
+func (stringAggrOp) Finalize(s *stringAggrState) *chunk.Value {
+	if !s._isset {
+		return nil
+	}
+	return &chunk.Value{
+		Typ: common.VarcharType(),
+		Str: s._buf.String(),
+	}
+}
+
+// first and any_value
+
+type firstAggrState struct {
+	_isset bool
+	_value *chunk.Value
+}
+
+type firstAggrOp struct {
+	//skip the NULL values
+	_skipNull bool
+}
+
+func (op firstAggrOp) Update(s *firstAggrState, args []*chunk.Value) {
+	if s._isset || (op._skipNull && args[0].IsNull) {
+		return
+	}
+	s._isset = true
+	s._value = args[0]
+}
+
+func (firstAggrOp) Combine(src *firstAggrState, target *firstAggrState) {
+	if !target._isset {
+		*target = *src
+	}
+}
+
+func (firstAggrOp) Finalize(s *firstAggrState) *chunk.Value {
+	return s._value
+}
+
+// arg_min and arg_max
+
+type argMinMaxState struct {
+	_arg *chunk.Value
+	_val *chunk.Value
+}
+
+type argMinMaxOp struct {
+	_max bool
+}
+
+func (op argMinMaxOp) better(val *chunk.Value, s *argMinMaxState) bool {
+	if s._val == nil {
+		return true
+	}
+	cmp := compareValue(val, s._val)
+	if op._max {
+		return cmp > 0
+	}
+	return cmp < 0
+}
+
+func (op argMinMaxOp) Update(s *argMinMaxState, args []*chunk.Value) {
+	if args[1].IsNull || !op.better(args[1], s) {
+		return
+	}
+	s._arg = args[0]
+	s._val = args[1]
+}
+
+func (op argMinMaxOp) Combine(src *argMinMaxState, target *argMinMaxState) {
+	if src._val != nil && op.better(src._val, target) {
+		*target = *src
+	}
+}
+
+func (argMinMaxOp) Finalize(s *argMinMaxState) *chunk.Value {
+	return s._arg
+}
+
+// mode
+
+type modeState struct {
+	_counts map[chunk.Value]int
+}
+
+type modeOp struct {
+}
+
+func (modeOp) Update(s *modeState, args []*chunk.Value) {
+	if args[0].IsNull {
+		return
+	}
+	if s._counts == nil {
+		s._counts = make(map[chunk.Value]int)
+	}
+	s._counts[*args[0]]++
+}
+
+func (modeOp) Combine(src *modeState, target *modeState) {
+	if target._counts == nil {
+		target._counts = make(map[chunk.Value]int)
+	}
+	for val, cnt := range src._counts {
+		target._counts[val] += cnt
+	}
+}
+
+// Finalize returns the most frequent value.
+// the smallest one wins on the tie.
+func (modeOp) Finalize(s *modeState) *chunk.Value {
+	var ret *chunk.Value
+	best := 0
+	for val, cnt := range s._counts {
+		val := val
+		if cnt > best || (cnt == best && compareValue(&val, ret) < 0) {
+			ret = &val
+			best = cnt
+		}
+	}
+	return ret
+}
+
+// the aggregate with the ORDER BY
+
+type aggrOrder struct {
+	_desc bool
+}
+
+type sortedAggrState struct {
+	_rows [][]*chunk.Value
+}
+
+// sortedAggrOp buffers the arguments and the sort keys of the rows.
+// it sorts the rows and feeds them into the inner aggregate in the Finalize.
+type sortedAggrOp struct {
+	_inner  *FunctionV2
+	_orders []aggrOrder
+}
+
+func (op *sortedAggrOp) Update(s *sortedAggrState, args []*chunk.Value) {
+	s._rows = append(s._rows, util.CopyTo(args))
+}
+
+func (op *sortedAggrOp) Combine(src *sortedAggrState, target *sortedAggrState) {
+	target._rows = append(target._rows, src._rows...)
+}
+
+func (op *sortedAggrOp) Finalize(s *sortedAggrState) *chunk.Value {
+	argCount := len(op._inner._args)
+	sort.SliceStable(s._rows, func(i, j int) bool {
+		for k, order := range op._orders {
+			a, b := s._rows[i][argCount+k], s._rows[j][argCount+k]
+			cmp := compareValue(a, b)
+			if cmp == 0 {
+				continue
+			}
+			//NULLS LAST for ASC and NULLS FIRST for DESC
+			if order._desc {
+				return cmp > 0
+			}
+			return cmp < 0
+		}
+		return false
+	})
+
+	state := make([]byte, op._inner._stateSize())
+	statePtr := util.BytesSliceToPointer(state)
+	op._inner._init(statePtr)
+	inputs := make([]*chunk.Vector, argCount)
+	for start := 0; start < len(s._rows); start += util.DefaultVectorSize {
+		end := min(start+util.DefaultVectorSize, len(s._rows))
+		for j := 0; j < argCount; j++ {
+			inputs[j] = chunk.NewFlatVector(op._inner._args[j], util.DefaultVectorSize)
+			for i := start; i < end; i++ {
+				inputs[j].SetValue(i-start, s._rows[i][j])
+			}
+		}
+		op._inner._simpleUpdate(inputs, NewAggrInputData(), argCount, statePtr, end-start)
+	}
+
+	states := chunk.NewFlatVector(common.PointerType(), 1)
+	chunk.GetSliceInPhyFormatFlat[unsafe.Pointer](states)[0] = statePtr
+	result := chunk.NewFlatVector(op._inner._retType, 1)
+	op._inner._finalize(states, NewAggrInputData(), result, 1, 0)
+	return result.GetValue(0)
+}
+
+// SortedAggregate wraps the aggregate to aggregate the rows in the order.
+// the sort keys follow the arguments of the aggregate.
+func SortedAggregate(inner *FunctionV2, keyTypes []common.LType, orders []aggrOrder) *FunctionV2 {
+	args := util.CopyTo(inner._args)
+	args = append(args, keyTypes...)
+	fun := ValueAggregate[sortedAggrState](
+		inner._name,
+		args,
+		inner._retType,
+		&sortedAggrOp{
+			_inner:  inner,
+			_orders: orders,
+		},
+	)
+	return fun
+}
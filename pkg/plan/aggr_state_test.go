@@ -0,0 +1,150 @@
+// Copyright 2023-2024 daviszhen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unsafe"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/daviszhen/plan/pkg/chunk"
+	"github.com/daviszhen/plan/pkg/storage"
+	"github.com/daviszhen/plan/pkg/util"
+)
+
+func Test_statsAggr(t *testing.T) {
+	txn, err := storage.GTxnMgr.NewTxn("stats aggr")
+	require.NoError(t, err)
+	defer storage.GTxnMgr.Rollback(txn)
+	execSQL(t, txn, "create table sa_t (g integer, i integer, d decimal(10,2), s varchar)")
+	execSQL(t, txn, "insert into sa_t values (1, 1, 1.5, 'a'), (1, 2, 2.5, 'b'), (2, 3, 3.5, 'c'), (2, 4, 4.5, 'z')")
+
+	kases := []struct {
+		expr string
+		want string
+	}{
+		{"round(stddev(i), 4)", "1.291"},
+		{"round(stddev_pop(i), 4)", "1.118"},
+		{"round(var_samp(i), 4)", "1.6667"},
+		{"var_pop(i)", "1.25"},
+		{"round(var_pop(nullif(i, 2)), 4)", "1.5556"},
+		{"covar_pop(i, d)", "1.25"},
+		{"round(covar_samp(i, d), 4)", "1.6667"},
+		{"corr(i, i * 2)", "1"},
+		{"bool_and(i > 0)", "true"},
+		{"bool_and(i > 1)", "false"},
+		{"bool_or(i > 3)", "true"},
+		{"string_agg(s, '-' order by s desc)", "z-c-b-a"},
+		{"listagg(s order by i)", "a,b,c,z"},
+		{"var_pop(distinct g)", "0.25"},
+		{"first(s order by i desc)", "z"},
+		{"first(nullif(s, 'a') order by i)", "NULL"},
+		{"any_value(nullif(s, 'a') order by i)", "b"},
+		{"arg_min(s, i)", "a"},
+		{"arg_max(s, d)", "z"},
+		{"mode(g)", "1"},
+	}
+	for _, kase := range kases {
+		rows, err := querySQL(t, txn, "select "+kase.expr+" from sa_t")
+		require.NoError(t, err, kase.expr)
+		require.Equal(t, [][]string{{kase.want}}, rows, kase.expr)
+	}
+
+	rows, err := querySQL(t, txn, "select g, string_agg(s, '' order by s desc), arg_max(s, i), var_pop(i) from sa_t group by g order by g")
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"1", "ba", "b", "0.25"}, {"2", "zc", "z", "0.25"}}, rows)
+
+	//several distinct aggrs over different inputs
+	rows, err = querySQL(t, txn, "select g, sum(i), count(distinct s), count(distinct i) from sa_t group by g order by g")
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"1", "3", "2", "2"}, {"2", "7", "2", "2"}}, rows)
+
+	rows, err = querySQL(t, txn, "select i > 2, count(*) from sa_t group by i > 2")
+	require.NoError(t, err)
+	require.ElementsMatch(t, [][]string{{"false", "2"}, {"true", "2"}}, rows)
+
+	func() {
+		defer func() {
+			if r := recover(); r != nil {
+				err = util.RecoverToError(r)
+			}
+			require.Error(t, err)
+			require.Equal(t, util.SQLStateWrongObjectType, util.GetSQLState(err))
+		}()
+		_, err = querySQL(t, txn, "select lower(s order by s) from sa_t")
+	}()
+}
+
+func Test_valueAggrDestroy(t *testing.T) {
+	txn, err := storage.GTxnMgr.NewTxn("value aggr destroy")
+	require.NoError(t, err)
+	defer storage.GTxnMgr.Rollback(txn)
+	execSQL(t, txn, "create table vad_t (g integer, s varchar, i integer)")
+	values := make([]string, 0)
+	for i := 0; i < 5000; i++ {
+		values = append(values, fmt.Sprintf("(%d, 's%d', %d)", i%3000, i, i))
+	}
+	execSQL(t, txn, "insert into vad_t values "+strings.Join(values, ","))
+
+	//stop after the first chunk of the groups
+	run, err := InitRunner(&util.Config{}, txn,
+		"select g, string_agg(s, '-'), approx_quantile(i, 0.5) from vad_t group by g")
+	require.NoError(t, err)
+	output := &chunk.Chunk{}
+	output.SetCap(util.DefaultVectorSize)
+	result, err := run.Execute(nil, output, run.state)
+	require.NoError(t, err)
+	require.Equal(t, haveMoreOutput, result)
+
+	var aggr *Runner
+	var find func(*Runner)
+	find = func(r *Runner) {
+		if r.op.Typ == POT_Agg {
+			aggr = r
+		}
+		for _, child := range r.children {
+			find(child)
+		}
+	}
+	find(run)
+	require.NotNil(t, aggr)
+	aht := aggr.hAggr._groupings[0]._tableData._finalizedHT
+	liveStates := func() int {
+		cnt := 0
+		offsets := aht._layout.offsets()
+		aht.scanRows(func(rows *chunk.Vector, n int) {
+			pointers := chunk.GetSliceInPhyFormatFlat[unsafe.Pointer](rows)
+			for i := 0; i < n; i++ {
+				for j := range aht._layout._aggregates {
+					state := util.PointerAdd(pointers[i], offsets[aht._layout.aggrIdx()+j])
+					if (*valueAggrState[struct{}])(state)._handle != 0 {
+						cnt++
+					}
+				}
+			}
+		})
+		return cnt
+	}
+	require.Greater(t, liveStates(), 0)
+	require.NoError(t, run.Close())
+	require.Equal(t, 0, liveStates())
+
+	rows, err := querySQL(t, txn, "select g, string_agg(s, '-'), approx_quantile(i, 0.5) from vad_t where g < 2 group by g order by g")
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"0", "s0-s3000", "1500"}, {"1", "s1-s3001", "1501"}}, rows)
+}
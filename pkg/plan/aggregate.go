@@ -18,6 +18,7 @@ import (
 	"fmt"
 	"math"
 	"sort"
+	"strings"
 	"unsafe"
 
 	"github.com/daviszhen/plan/pkg/chunk"
@@ -67,6 +68,14 @@ func (gs GroupingSet) count() int {
 	return len(gs)
 }
 
+func groupingSetsString(sets []GroupingSet) string {
+	strs := make([]string, 0, len(sets))
+	for _, set := range sets {
+		strs = append(strs, fmt.Sprint(set.ordered()))
+	}
+	return strings.Join(strs, " ")
+}
+
 type GroupedAggrData struct {
 	_groups              []*Expr
 	_groupingFuncs       [][]int //GROUPING functions
@@ -443,10 +452,31 @@ func (haggr *HashAggr) SinkDistinctGrouping(
 		if radixTable == nil {
 			continue
 		}
-		radixTable.Sink(data, dump, childrenOutput, []int{})
+		radixTable.Sink(haggr.distinctInput(data, idx), dump, childrenOutput, []int{})
 	}
 }
 
+// distinctInput returns the group by columns followed by
+// the children of the distinct aggr idx.
+func (haggr *HashAggr) distinctInput(data *chunk.Chunk, idx int) *chunk.Chunk {
+	aggregates := haggr._distinctCollectionInfo._aggregates
+	groupCount := len(haggr._groupedAggrData._groupTypes)
+	payloadIdx := groupCount
+	for _, aggr := range aggregates[:idx] {
+		payloadIdx += len(aggr.Children)
+	}
+	if payloadIdx == groupCount {
+		return data
+	}
+	childCount := len(aggregates[idx].Children)
+	input := &chunk.Chunk{}
+	input.Data = make([]*chunk.Vector, 0, groupCount+childCount)
+	input.Data = append(input.Data, data.Data[:groupCount]...)
+	input.Data = append(input.Data, data.Data[payloadIdx:payloadIdx+childCount]...)
+	input.SetCard(data.Card())
+	return input
+}
+
 func (haggr *HashAggr) SinkDistinct(chunk, childrenOutput *chunk.Chunk) {
 	for i := 0; i < len(haggr._groupings); i++ {
 		haggr.SinkDistinctGrouping(chunk, childrenOutput, i)
@@ -710,6 +740,23 @@ func NewAggrHTAppendState() *AggrHTAppendState {
 	return ret
 }
 
+// Destroy releases the aggr states that are not finalized.
+func (haggr *HashAggr) Destroy() {
+	for _, grouping := range haggr._groupings {
+		if grouping._tableData != nil {
+			grouping._tableData.Destroy()
+		}
+		if grouping._distinctData == nil {
+			continue
+		}
+		for _, radixTable := range grouping._distinctData._radixTables {
+			if radixTable != nil {
+				radixTable.Destroy()
+			}
+		}
+	}
+}
+
 type RadixPartitionedHashTable struct {
 	_groupingSet     GroupingSet
 	_nullGroups      []int
@@ -743,7 +790,7 @@ func NewRadixPartitionedHashTable(
 		ret._groupTypes = append(ret._groupTypes, common.TinyintType())
 	}
 
-	for ent := range ret._groupingSet.ordered() {
+	for _, ent := range ret._groupingSet.ordered() {
 		util.AssertFunc(ent < len(ret._groupedAggrData._groupTypes))
 		ret._groupTypes = append(ret._groupTypes,
 			ret._groupedAggrData._groupTypes[ent])
@@ -756,19 +803,18 @@ func (rpht *RadixPartitionedHashTable) SetGroupingValues() {
 	groupFuncs := rpht._groupedAggrData._groupingFuncs
 	for _, group := range groupFuncs {
 		util.AssertFunc(len(group) < 64)
+		groupingValue := int64(0)
 		for i, gval := range group {
-			groupingValue := int64(0)
 			if !rpht._groupingSet.find(gval) {
 				//do not group on this value
 				groupingValue += 1 << (len(group) - (i + 1))
 			}
-			rpht._groupingValues = append(rpht._groupingValues,
-				&chunk.Value{
-					Typ: common.BigintType(),
-					I64: groupingValue,
-				})
 		}
-
+		rpht._groupingValues = append(rpht._groupingValues,
+			&chunk.Value{
+				Typ: common.BigintType(),
+				I64: groupingValue,
+			})
 	}
 }
 
@@ -790,6 +836,12 @@ func (rpht *RadixPartitionedHashTable) Sink(data, payload, childrenOutput *chunk
 	}
 	groupChunk := &chunk.Chunk{}
 	groupChunk.Init(rpht._groupTypes, util.DefaultVectorSize)
+	if rpht._groupingSet.empty() {
+		//all rows are in the same group
+		groupChunk.Data[0].ReferenceValue(&chunk.Value{
+			Typ: common.TinyintType(),
+		})
+	}
 	for i, idx := range rpht._groupingSet.ordered() {
 		groupChunk.Data[i].Reference(data.Data[idx])
 	}
@@ -842,7 +894,7 @@ func (rpht *RadixPartitionedHashTable) GetData(state *TupleDataScanState, output
 		output.Data[ent].Reference(scanChunk.Data[i])
 	}
 
-	for ent := range rpht._nullGroups {
+	for _, ent := range rpht._nullGroups {
 		output.Data[ent].SetPhyFormat(chunk.PF_CONST)
 		chunk.SetNullInPhyFormatConst(output.Data[ent], true)
 	}
@@ -876,6 +928,13 @@ func (rpht *RadixPartitionedHashTable) Finalize() {
 	rpht._finalizedHT.Finalize()
 }
 
+func (rpht *RadixPartitionedHashTable) Destroy() {
+	if rpht._finalizedHT == nil {
+		return
+	}
+	rpht._finalizedHT.Destroy()
+}
+
 type TupleDataScanState struct {
 	_colIds []int
 	//_rowLocs *Vector
@@ -927,6 +986,8 @@ type AggrObject struct {
 	_payloadSize int
 	_aggrType    AggrType
 	_retType     common.PhyType
+	//the last child is the FILTER
+	_filtered bool
 }
 
 func NewAggrObject(aggr *Expr) *AggrObject {
@@ -934,6 +995,7 @@ func NewAggrObject(aggr *Expr) *AggrObject {
 	ret := new(AggrObject)
 	ret._childCount = len(aggr.Children)
 	ret._aggrType = aggr.AggrTyp
+	ret._filtered = aggr.AggrFilter
 	ret._retType = aggr.DataTyp.GetInternalType()
 	ret._name = aggr.Svalue
 	ret._func = aggr.FunImpl
@@ -1458,6 +1520,47 @@ func (aht *GroupedAggrHashTable) Finalize() {
 	aht._finalized = true
 }
 
+// Destroy releases the aggr states that are not finalized.
+func (aht *GroupedAggrHashTable) Destroy() {
+	if !hasDestroy(aht._layout) {
+		return
+	}
+	aht.scanRows(func(rows *chunk.Vector, cnt int) {
+		DestroyStates(aht._layout, rows, cnt)
+	})
+}
+
+// scanRows passes the row locations of the groups chunk by chunk.
+func (aht *GroupedAggrHashTable) scanRows(fn func(rows *chunk.Vector, cnt int)) {
+	if aht._dataCollection.Count() == 0 {
+		return
+	}
+	state := NewTupleDataScanState(aht._layout.columnCount(), PIN_PRRP_KEEP_PINNED)
+	aht._dataCollection.InitScan(state)
+	var segIdx, chunkIdx int
+	for aht._dataCollection.NextScanIndex(state, &segIdx, &chunkIdx) {
+		seg := aht._dataCollection._segments[segIdx]
+		seg._allocator.InitChunkState(
+			seg,
+			&state._pinState,
+			&state._chunkState,
+			chunkIdx,
+			false,
+		)
+		fn(state._chunkState._rowLocations, int(seg._chunks[chunkIdx]._count))
+	}
+	aht._dataCollection.FinalizePinState(&state._pinState)
+}
+
+func hasDestroy(layout *TupleDataLayout) bool {
+	for _, aggr := range layout._aggregates {
+		if aggr._func._destroy != nil {
+			return true
+		}
+	}
+	return false
+}
+
 func InitStates(
 	layout *TupleDataLayout,
 	addresses *chunk.Vector,
@@ -1482,6 +1585,33 @@ func InitStates(
 	}
 }
 
+// DestroyStates releases the aggr states of the rows.
+func DestroyStates(
+	layout *TupleDataLayout,
+	addresses *chunk.Vector,
+	cnt int,
+) {
+	if cnt == 0 {
+		return
+	}
+
+	pointers := chunk.GetSliceInPhyFormatFlat[unsafe.Pointer](addresses)
+	offsets := layout.offsets()
+	aggrIdx := layout.aggrIdx()
+
+	states := chunk.NewFlatVector(common.PointerType(), util.DefaultVectorSize)
+	statesSlice := chunk.GetSliceInPhyFormatFlat[unsafe.Pointer](states)
+	for _, aggr := range layout._aggregates {
+		if aggr._func._destroy != nil {
+			for i := 0; i < cnt; i++ {
+				statesSlice[i] = util.PointerAdd(pointers[i], offsets[aggrIdx])
+			}
+			aggr._func._destroy(states, cnt)
+		}
+		aggrIdx++
+	}
+}
+
 func UpdateStates(
 	aggr *AggrObject,
 	addresses *chunk.Vector,
@@ -1492,13 +1622,54 @@ func UpdateStates(
 	inputData := &AggrInputData{}
 	var input []*chunk.Vector
 	if aggr._childCount != 0 {
-		input = []*chunk.Vector{payload.Data[argIdx]}
+		input = payload.Data[argIdx : argIdx+aggr._childCount]
+	}
+	childCount := aggr._childCount
+	if aggr._filtered {
+		childCount--
+		addresses, input, cnt = filterStates(addresses, input[:childCount], input[childCount], cnt)
+		if cnt == 0 {
+			return
+		}
 	}
 	aggr._func._update(
 		input,
 		inputData,
-		aggr._childCount,
+		childCount,
 		addresses,
 		cnt,
 	)
 }
+
+// filterStates keeps the rows that the FILTER is true.
+func filterStates(
+	addresses *chunk.Vector,
+	input []*chunk.Vector,
+	filter *chunk.Vector,
+	cnt int,
+) (*chunk.Vector, []*chunk.Vector, int) {
+	var filterData chunk.UnifiedFormat
+	filter.ToUnifiedFormat(cnt, &filterData)
+	filterSlice := chunk.GetSliceInPhyFormatUnifiedFormat[bool](&filterData)
+	addrSlice := chunk.GetSliceInPhyFormatFlat[unsafe.Pointer](addresses)
+
+	sel := chunk.NewSelectVector(util.DefaultVectorSize)
+	retAddresses := chunk.NewFlatVector(common.PointerType(), util.DefaultVectorSize)
+	retAddrSlice := chunk.GetSliceInPhyFormatFlat[unsafe.Pointer](retAddresses)
+	selCnt := 0
+	for i := 0; i < cnt; i++ {
+		idx := filterData.Sel.GetIndex(i)
+		if filterData.Mask.RowIsValid(uint64(idx)) && filterSlice[idx] {
+			sel.SetIndex(selCnt, i)
+			retAddrSlice[selCnt] = addrSlice[i]
+			selCnt++
+		}
+	}
+
+	retInput := make([]*chunk.Vector, len(input))
+	for i, vec := range input {
+		retInput[i] = chunk.NewVector(vec.Typ(), false, 0)
+		retInput[i].Slice(vec, sel, selCnt)
+	}
+	return retAddresses, retInput, selCnt
+}
@@ -0,0 +1,219 @@
+// Copyright 2023-2024 daviszhen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/daviszhen/plan/pkg/storage"
+	"github.com/daviszhen/plan/pkg/util"
+)
+
+func Test_groupingSets(t *testing.T) {
+	txn, err := storage.GTxnMgr.NewTxn("grouping sets")
+	require.NoError(t, err)
+	defer storage.GTxnMgr.Rollback(txn)
+	execSQL(t, txn, "create table gs_t (g integer, i integer, s varchar)")
+	execSQL(t, txn, "insert into gs_t values (1, 1, 'a'), (1, 2, 'b'), (2, 3, 'c'), (2, 4, 'z')")
+
+	kases := []struct {
+		sql  string
+		want [][]string
+	}{
+		{
+			"select g, s, sum(i), grouping(g, s) from gs_t group by rollup(g, s)",
+			[][]string{
+				{"1", "a", "1", "0"}, {"1", "b", "2", "0"}, {"2", "c", "3", "0"}, {"2", "z", "4", "0"},
+				{"1", "NULL", "3", "1"}, {"2", "NULL", "7", "1"},
+				{"NULL", "NULL", "10", "3"},
+			},
+		},
+		{
+			"select g, s, count(*) from gs_t group by cube(g, s)",
+			[][]string{
+				{"1", "a", "1"}, {"1", "b", "1"}, {"2", "c", "1"}, {"2", "z", "1"},
+				{"1", "NULL", "2"}, {"2", "NULL", "2"},
+				{"NULL", "a", "1"}, {"NULL", "b", "1"}, {"NULL", "c", "1"}, {"NULL", "z", "1"},
+				{"NULL", "NULL", "4"},
+			},
+		},
+		{
+			"select g, sum(i), grouping(g) from gs_t group by grouping sets ((g), ())",
+			[][]string{{"1", "3", "0"}, {"2", "7", "0"}, {"NULL", "10", "1"}},
+		},
+		{
+			"select g, s, count(*) from gs_t group by g, grouping sets ((s), ())",
+			[][]string{
+				{"1", "a", "1"}, {"1", "b", "1"}, {"2", "c", "1"}, {"2", "z", "1"},
+				{"1", "NULL", "2"}, {"2", "NULL", "2"},
+			},
+		},
+		{
+			"select g + 1, sum(i) from gs_t group by rollup(g + 1)",
+			[][]string{{"2", "3"}, {"3", "7"}, {"NULL", "10"}},
+		},
+		{
+			"select g, count(distinct s) from gs_t group by rollup(g)",
+			[][]string{{"1", "2"}, {"2", "2"}, {"NULL", "4"}},
+		},
+		{
+			"select g, sum(i) from gs_t group by rollup(g) having g is null",
+			[][]string{{"NULL", "10"}},
+		},
+		{
+			"select g, sum(i) from gs_t group by rollup(g) having grouping(g) = 0 and sum(i) > 3",
+			[][]string{{"2", "7"}},
+		},
+		{
+			"select count(*), grouping(g) from gs_t group by g",
+			[][]string{{"2", "0"}, {"2", "0"}},
+		},
+	}
+	for _, kase := range kases {
+		rows, err := querySQL(t, txn, kase.sql)
+		require.NoError(t, err, kase.sql)
+		require.ElementsMatch(t, kase.want, rows, kase.sql)
+	}
+
+	rows, err := querySQL(t, txn, "select g, sum(i) from gs_t group by rollup(g) order by grouping(g), g")
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"1", "3"}, {"2", "7"}, {"NULL", "10"}}, rows)
+
+	errKases := []struct {
+		sql   string
+		state util.SQLState
+	}{
+		{"select g from gs_t where grouping(g) = 0 group by g", util.SQLStateGroupingError},
+		{"select grouping(i) from gs_t group by rollup(g)", util.SQLStateGroupingError},
+		{"select count(*) from gs_t group by cube(g, i, s, g, i, s, g, i, s, g, i, s, g)", util.SQLStateProgramLimitExceeded},
+	}
+	for _, kase := range errKases {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					err = util.RecoverToError(r)
+				}
+				require.Error(t, err, kase.sql)
+				require.Equal(t, kase.state, util.GetSQLState(err), kase.sql)
+			}()
+			_, err = querySQL(t, txn, kase.sql)
+		}()
+	}
+}
+
+func Test_distinct(t *testing.T) {
+	txn, err := storage.GTxnMgr.NewTxn("distinct")
+	require.NoError(t, err)
+	defer storage.GTxnMgr.Rollback(txn)
+	execSQL(t, txn, "create table dt_t (g integer, i integer, s varchar)")
+	execSQL(t, txn, "insert into dt_t values (1, 1, 'a'), (1, 2, 'b'), (2, 3, 'c'), (2, 4, 'z'), (2, 3, 'c')")
+	execSQL(t, txn, "create table dt_r (g integer, c varchar)")
+	execSQL(t, txn, "insert into dt_r values (1, 'x'), (1, 'x'), (2, 'y')")
+
+	kases := []struct {
+		sql  string
+		want [][]string
+	}{
+		{"select distinct g from dt_t order by g", [][]string{{"1"}, {"2"}}},
+		{"select distinct g, s from dt_t order by g desc, s", [][]string{{"2", "c"}, {"2", "z"}, {"1", "a"}, {"1", "b"}}},
+		{"select distinct g as x from dt_t order by x desc", [][]string{{"2"}, {"1"}}},
+		{"select distinct g + 1 from dt_t order by g + 1 desc", [][]string{{"3"}, {"2"}}},
+		{"select count(*) from (select distinct g, i, s from dt_t) t", [][]string{{"4"}}},
+		{"select distinct dt_t.g, dt_r.c from dt_t, dt_r where dt_t.g = dt_r.g order by dt_t.g", [][]string{{"1", "x"}, {"2", "y"}}},
+		{"select distinct on (g) g, s from dt_t order by g, i desc", [][]string{{"1", "b"}, {"2", "z"}}},
+		{"select distinct on (g) g, s, i from dt_t order by g, s", [][]string{{"1", "a", "1"}, {"2", "c", "3"}}},
+		{"select distinct on (g + 1) g + 1 as x, s from dt_t order by x desc, s desc", [][]string{{"3", "z"}, {"2", "b"}}},
+		{"select distinct on (g) g, s from dt_t order by g, s desc limit 1", [][]string{{"1", "b"}}},
+		{"select distinct on (dt_t.g) dt_t.g, s, c from dt_t, dt_r where dt_t.g = dt_r.g order by dt_t.g, s desc", [][]string{{"1", "b", "x"}, {"2", "z", "y"}}},
+	}
+	for _, kase := range kases {
+		rows, err := querySQL(t, txn, kase.sql)
+		require.NoError(t, err, kase.sql)
+		require.Equal(t, kase.want, rows, kase.sql)
+	}
+
+	rows, err := querySQL(t, txn, "select distinct on (g) g from dt_t")
+	require.NoError(t, err)
+	require.ElementsMatch(t, [][]string{{"1"}, {"2"}}, rows)
+
+	errKases := []string{
+		"select distinct g from dt_t order by i",
+		"select distinct on (g) g, s from dt_t order by s",
+	}
+	for _, sql := range errKases {
+		_, err = querySQL(t, txn, sql)
+		require.Error(t, err, sql)
+		require.Equal(t, util.SQLStateInvalidColumnReference, util.GetSQLState(err), sql)
+	}
+}
+
+func Test_aggrFilter(t *testing.T) {
+	txn, err := storage.GTxnMgr.NewTxn("aggr filter")
+	require.NoError(t, err)
+	defer storage.GTxnMgr.Rollback(txn)
+	execSQL(t, txn, "create table af_t (g integer, i integer, s varchar)")
+	execSQL(t, txn, "insert into af_t values (1, 1, 'a'), (1, 2, 'b'), (2, 3, 'c'), (2, 4, 'z')")
+
+	kases := []struct {
+		sql  string
+		want [][]string
+	}{
+		{
+			"select count(*) filter (where i > 1), sum(i) filter (where g = 2), count(*) from af_t",
+			[][]string{{"3", "7", "4"}},
+		},
+		{
+			"select g, count(*) filter (where s <> 'a'), sum(i) filter (where i % 2 = 0) from af_t group by g order by g",
+			[][]string{{"1", "1", "2"}, {"2", "2", "4"}},
+		},
+		{
+			"select g, string_agg(s, ',' order by i desc) filter (where i > 1) from af_t group by g order by g",
+			[][]string{{"1", "b"}, {"2", "z,c"}},
+		},
+		{
+			"select g, sum(i) filter (where i > 100), count(i) filter (where i > 100) from af_t group by g order by g",
+			[][]string{{"1", "NULL", "0"}, {"2", "NULL", "0"}},
+		},
+		{
+			"select percentile_disc(0.5) within group (order by i) filter (where g = 2) from af_t",
+			[][]string{{"3"}},
+		},
+		{
+			"select g from af_t group by g having count(*) filter (where s = 'z') > 0",
+			[][]string{{"2"}},
+		},
+	}
+	for _, kase := range kases {
+		rows, err := querySQL(t, txn, kase.sql)
+		require.NoError(t, err, kase.sql)
+		require.Equal(t, kase.want, rows, kase.sql)
+	}
+
+	errKases := []struct {
+		sql   string
+		state util.SQLState
+	}{
+		{"select lower(s) filter (where i > 1) from af_t", util.SQLStateWrongObjectType},
+		{"select count(*) filter (where sum(i) > 1) from af_t", util.SQLStateGroupingError},
+		{"select sum(i) filter (where i) from af_t", util.SQLStateDatatypeMismatch},
+	}
+	for _, kase := range errKases {
+		_, err = querySQL(t, txn, kase.sql)
+		require.Error(t, err, kase.sql)
+		require.Equal(t, kase.state, util.GetSQLState(err), kase.sql)
+	}
+}
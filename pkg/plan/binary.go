@@ -115,7 +115,12 @@ func binInt32DateAddOp(left *int32, right *common.Date, result *common.Date) {
 
 //lint:ignore U1000
 func binIntervalIntervalAddOp(left *common.Interval, right *common.Interval, result *common.Interval) {
-	panic("usp")
+	*result = common.Interval{
+		Months: left.Months + right.Months,
+		Days:   left.Days + right.Days,
+		Micros: left.Micros + right.Micros,
+		Year:   left.Year + right.Year,
+	}
 }
 
 //lint:ignore U1000
@@ -137,6 +142,24 @@ func binDecimalDecimalAddOp(left *common.Decimal, right *common.Decimal, result
 	result.Decimal = d
 }
 
+// timestamp + interval
+func binTimestampInterAddOp(left *int64, right *common.Interval, result *int64) {
+	*result = common.TimestampAddInterval(*left, right)
+}
+
+func binInterTimestampAddOp(left *common.Interval, right *int64, result *int64) {
+	*result = common.TimestampAddInterval(*right, left)
+}
+
+// time + interval
+func binTimeInterAddOp(left *int64, right *common.Interval, result *int64) {
+	*result = common.TimeAddInterval(*left, right)
+}
+
+func binInterTimeAddOp(left *common.Interval, right *int64, result *int64) {
+	*result = common.TimeAddInterval(*right, left)
+}
+
 //lint:ignore U1000
 func binInt32Int32AddOp(left *int32, right *int32, result *int32) {
 	*result = *left + *right
@@ -150,6 +173,28 @@ func binDateInterSubOp(left *common.Date, right *common.Interval, result *common
 	*result = res
 }
 
+// timestamp - interval
+func binTimestampInterSubOp(left *int64, right *common.Interval, result *int64) {
+	neg := right.Negate()
+	*result = common.TimestampAddInterval(*left, &neg)
+}
+
+// time - interval
+func binTimeInterSubOp(left *int64, right *common.Interval, result *int64) {
+	neg := right.Negate()
+	*result = common.TimeAddInterval(*left, &neg)
+}
+
+// timestamp - timestamp
+func binTimestampSubOp(left, right *int64, result *common.Interval) {
+	*result = common.SubTimestamp(*left, *right)
+}
+
+// time - time
+func binTimeSubOp(left, right *int64, result *common.Interval) {
+	*result = common.Interval{Micros: *left - *right}
+}
+
 //lint:ignore U1000
 func binFloat32Float32SubOp(left *float32, right *float32, result *float32) {
 	*result = *left - *right
@@ -192,11 +237,25 @@ func binDecimalDecimalMulOp(left *common.Decimal, right *common.Decimal, result
 //
 //lint:ignore U1000
 func binFloat32DivOp(left, right *float32, result *float32) {
+	if *right == 0 {
+		panic(util.NewSQLError(util.SQLStateDivisionByZero, "division by zero"))
+	}
+	*result = *left / *right
+}
+
+//lint:ignore U1000
+func binFloat64DivOp(left, right *float64, result *float64) {
+	if *right == 0 {
+		panic(util.NewSQLError(util.SQLStateDivisionByZero, "division by zero"))
+	}
 	*result = *left / *right
 }
 
 //lint:ignore U1000
 func binDecimalDivOp(left, right *common.Decimal, result *common.Decimal) {
+	if right.Decimal.IsZero() {
+		panic(util.NewSQLError(util.SQLStateDivisionByZero, "division by zero"))
+	}
 	quo, err := left.Decimal.Quo(right.Decimal)
 	if err != nil {
 		panic(err)
@@ -223,6 +282,26 @@ func binStringEqualOp(left, right *common.String, result *bool) {
 	*result = left.Equal(right)
 }
 
+// <> int32
+func binInt32NotEqualOp(left, right *int32, result *bool) {
+	*result = *left != *right
+}
+
+// <> string
+func binStringNotEqualOp(left, right *common.String, result *bool) {
+	*result = !left.Equal(right)
+}
+
+// = date
+func binDateEqualOp(left, right *common.Date, result *bool) {
+	*result = left.Equal(right)
+}
+
+// <> date
+func binDateNotEqualOp(left, right *common.Date, result *bool) {
+	*result = !left.Equal(right)
+}
+
 // > int32
 //
 //lint:ignore U1000
@@ -251,15 +330,13 @@ func binStringLikeOp(left, right *common.String, result *bool) {
 	*result = WildcardMatch(right, left)
 }
 
+func binStringNotLikeOp(left, right *common.String, result *bool) {
+	*result = !WildcardMatch(right, left)
+}
+
 // extract
-//
-//lint:ignore U1000
 func binStringInt32ExtractOp(left *common.String, right *common.Date, result *int32) {
-	if left.String() == "year" {
-		*result = right.Year
-	} else {
-		panic("usp")
-	}
+	*result = int32(timestampPart(left.String(), dateToTime(right), "date"))
 }
 
 func binaryExecSwitch[T any, S any, R any](
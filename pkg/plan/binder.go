@@ -17,10 +17,13 @@ package plan
 import (
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	pg_query "github.com/pganalyze/pg_query_go/v5"
 
 	"github.com/daviszhen/plan/pkg/common"
+	"github.com/daviszhen/plan/pkg/parser"
 	"github.com/daviszhen/plan/pkg/util"
 )
 
@@ -41,7 +44,7 @@ func (b *Builder) bindExpr(
 	//var child *Expr
 	switch realExpr := expr.GetNode().(type) {
 	case *pg_query.Node_ResTarget:
-		panic("usp")
+		return nil, util.NewSQLError(util.SQLStateFeatureNotSupported, "unsupport expression %T right now", realExpr)
 	case *pg_query.Node_ColumnRef:
 		tableName, colName := getTableColumn(realExpr.ColumnRef)
 		switch iwc {
@@ -58,11 +61,20 @@ func (b *Builder) bindExpr(
 		default:
 			panic(fmt.Sprintf("usp iwc %d", iwc))
 		}
+		//the merged column of the USING join
+		if len(tableName) == 0 {
+			if node := ctx.GetUsing(colName); node != nil {
+				return b.bindExpr(ctx, iwc, node, depth)
+			}
+		}
 		bind, d, err := ctx.GetMatchingBinding(tableName, colName)
 		if err != nil {
 			return nil, err
 		}
 		colIdx := bind.HasColumn(colName)
+		if colIdx < 0 {
+			return nil, util.NewSQLError(util.SQLStateUndefinedColumn, "table %s does not have column %s", bind.alias, colName)
+		}
 
 		switch bind.typ {
 		case BT_TABLE:
@@ -94,15 +106,30 @@ func (b *Builder) bindExpr(
 	case *pg_query.Node_SortBy:
 		ret, err = b.bindSortBy(ctx, iwc, realExpr.SortBy, depth)
 	case *pg_query.Node_TypeCast:
-		ret, err = b.bindTypeCast(ctx, iwc, realExpr.TypeCast, depth)
+		ret, err = b.bindTypeCast(ctx, iwc, realExpr.TypeCast, depth, false)
 	case *pg_query.Node_List:
 		ret, err = b.bindList(ctx, iwc, realExpr.List, depth)
 	case *pg_query.Node_CaseExpr:
 		ret, err = b.bindCaseExpr(ctx, iwc, realExpr.CaseExpr, depth)
 	case *pg_query.Node_CaseWhen:
 		ret, err = b.bindCaseWhen(ctx, iwc, realExpr.CaseWhen, depth)
+	case *pg_query.Node_NullTest:
+		ret, err = b.bindNullTest(ctx, iwc, realExpr.NullTest, depth)
+	case *pg_query.Node_BooleanTest:
+		ret, err = b.bindBooleanTest(ctx, iwc, realExpr.BooleanTest, depth)
+	case *pg_query.Node_CoalesceExpr:
+		ret, err = b.bindCoalesceExpr(ctx, iwc, realExpr.CoalesceExpr, depth)
+	case *pg_query.Node_MinMaxExpr:
+		ret, err = b.bindMinMaxExpr(ctx, iwc, realExpr.MinMaxExpr, depth)
+	case *pg_query.Node_NullIfExpr:
+		args := realExpr.NullIfExpr.Args
+		ret, err = b.bindNullCompare(ctx, iwc, ET_NullIf, args[0], args[1], realExpr.NullIfExpr.String(), depth)
+	case *pg_query.Node_SqlvalueFunction:
+		ret, err = b.bindSQLValueFunction(realExpr.SqlvalueFunction)
+	case *pg_query.Node_GroupingFunc:
+		ret, err = b.bindGroupingFunc(ctx, iwc, realExpr.GroupingFunc, depth)
 	default:
-		panic(fmt.Sprintf("bindExpr: unexpected node type %T", realExpr))
+		return nil, util.NewSQLError(util.SQLStateFeatureNotSupported, "unsupport expression %T right now", realExpr)
 	}
 	return ret, err
 }
@@ -122,7 +149,7 @@ func (b *Builder) bindList(ctx *BindContext, iwc InWhichClause, expr *pg_query.L
 	}, nil
 }
 
-func (b *Builder) bindTypeCast(ctx *BindContext, iwc InWhichClause, expr *pg_query.TypeCast, depth int) (*Expr, error) {
+func (b *Builder) bindTypeCast(ctx *BindContext, iwc InWhichClause, expr *pg_query.TypeCast, depth int, tryCast bool) (*Expr, error) {
 	var retExpr *Expr
 	var err error
 	var resultTyp common.LType
@@ -132,29 +159,112 @@ func (b *Builder) bindTypeCast(ctx *BindContext, iwc InWhichClause, expr *pg_que
 	}
 
 	//find type
-	typName := ""
-	for _, name := range expr.TypeName.Names {
-		if name.GetString_().Sval == "pg_catalog" {
+	resultTyp, err = typeNameToLType(expr.TypeName)
+	if err != nil {
+		return nil, err
+	}
+
+	//cast
+	if resultTyp.Id != common.LTID_VARCHAR || resultTyp.Width == 0 {
+		return AddCastToType(retExpr, resultTyp, tryCast)
+	}
+	retExpr, err = AddCastToType(retExpr, common.VarcharType(), tryCast)
+	if err != nil {
+		return nil, err
+	}
+	//truncate to the width
+	castInfo := StringCastToSwitch(nil, retExpr.DataTyp, resultTyp)
+	return newCastExpr(retExpr, resultTyp, castInfo, tryCast), nil
+}
+
+// typeNameToLType maps the postgres type name to the logical type.
+func typeNameToLType(typName *pg_query.TypeName) (common.LType, error) {
+	name := ""
+	for _, node := range typName.Names {
+		if node.GetString_().GetSval() == "pg_catalog" {
 			continue
 		}
-		typName = name.GetString_().GetSval()
+		name = strings.ToLower(node.GetString_().GetSval())
+	}
+	if len(typName.ArrayBounds) != 0 {
+		return common.LType{}, util.NewSQLError(util.SQLStateFeatureNotSupported,
+			"unsupport array type %s right now", name)
+	}
+	typMods := make([]int, 0, len(typName.Typmods))
+	for _, mod := range typName.Typmods {
+		ival := mod.GetAConst().GetIval()
+		if ival == nil {
+			return common.LType{}, util.NewSQLError(util.SQLStateSyntaxError,
+				"invalid type modifier of type %s", name)
+		}
+		typMods = append(typMods, int(ival.GetIval()))
 	}
 
-	switch typName {
+	switch name {
+	case "bool", "boolean":
+		return common.BooleanType(), nil
+	case "int2", "smallint":
+		return common.SmallintType(), nil
+	case "int4", "int", "integer":
+		return common.IntegerType(), nil
+	case "int8", "bigint":
+		return common.BigintType(), nil
+	case "float4", "real":
+		return common.FloatType(), nil
+	case "float8", "double":
+		return common.DoubleType(), nil
+	case "numeric", "decimal":
+		//the default is the same as the duckdb
+		width, scale := 18, 3
+		switch len(typMods) {
+		case 0:
+		case 1:
+			width, scale = typMods[0], 0
+		case 2:
+			width, scale = typMods[0], typMods[1]
+		default:
+			return common.LType{}, util.NewSQLError(util.SQLStateSyntaxError,
+				"invalid type modifier of type %s", name)
+		}
+		if width < 1 || width > common.DecimalMaxWidth {
+			return common.LType{}, util.NewSQLError(util.SQLStateInvalidParameterValue,
+				"NUMERIC precision %d must be between 1 and %d", width, common.DecimalMaxWidth)
+		}
+		if scale < 0 || scale > width {
+			return common.LType{}, util.NewSQLError(util.SQLStateInvalidParameterValue,
+				"NUMERIC scale %d must be between 0 and precision %d", scale, width)
+		}
+		return common.DecimalType(width, scale), nil
+	case "text", "varchar", "bpchar", "char":
+		width := 0
+		if len(typMods) > 0 {
+			width = typMods[0]
+		} else if name == "bpchar" || name == "char" {
+			width = 1
+		}
+		if len(typMods) > 0 && width < 1 {
+			return common.LType{}, util.NewSQLError(util.SQLStateInvalidParameterValue,
+				"length for type %s must be at least 1", name)
+		}
+		return common.VarcharType2(width), nil
+	case "bytea":
+		return common.BlobType(), nil
+	case "uuid":
+		return common.UuidType(), nil
 	case "date":
-		resultTyp = common.DateType()
+		return common.DateType(), nil
+	case "time":
+		return common.TimeType(), nil
+	case "timestamp":
+		return common.TimestampType(), nil
+	case "timestamptz":
+		return common.TimestampTzType(), nil
 	case "interval":
-		resultTyp = common.IntervalType()
+		return common.IntervalType(), nil
 	default:
-		panic(fmt.Sprintf("usp typename %v", expr.TypeName))
-	}
-
-	//cast
-	retExpr, err = AddCastToType(retExpr, resultTyp, resultTyp.Id == common.LTID_ENUM)
-	if err != nil {
-		return nil, err
+		return common.LType{}, util.NewSQLError(util.SQLStateFeatureNotSupported,
+			"unsupport type %s right now", name)
 	}
-	return retExpr, nil
 }
 
 func (b *Builder) bindSortBy(ctx *BindContext, iwc InWhichClause, expr *pg_query.SortBy, depth int) (*Expr, error) {
@@ -171,7 +281,7 @@ func (b *Builder) bindSortBy(ctx *BindContext, iwc InWhichClause, expr *pg_query
 	case pg_query.SortByDir_SORTBY_DESC:
 		desc = true
 	default:
-		panic(fmt.Sprintf("usp orderbydir %v", expr.SortbyDir))
+		return nil, util.NewSQLError(util.SQLStateFeatureNotSupported, "unsupport order by direction %v right now", expr.SortbyDir)
 	}
 
 	ret := &Expr{
@@ -200,28 +310,19 @@ func (b *Builder) bindFuncCall(ctx *BindContext, iwc InWhichClause, expr *pg_que
 	var err error
 	//real function
 	name := getFuncName(expr)
-	if name == "count" {
-		if expr.AggStar {
-			//replace * by the column 0 of the first table
-			//TODO: refine
-			colName := b.rootCtx.bindingsList[0].names[0]
-			expr.Args = []*pg_query.Node{
-				{
-					Node: &pg_query.Node_ColumnRef{
-						ColumnRef: &pg_query.ColumnRef{
-							Fields: []*pg_query.Node{
-								{
-									Node: &pg_query.Node_String_{
-										String_: &pg_query.String{
-											Sval: colName,
-										},
-									},
-								},
-							},
-						},
-					},
-				},
-			}
+	if name == parser.TryCastFunc && len(expr.Args) == 1 &&
+		expr.Args[0].GetTypeCast() != nil {
+		return b.bindTypeCast(ctx, iwc, expr.Args[0].GetTypeCast(), depth, true)
+	}
+	if name == "count" && expr.AggStar {
+		//count(*) counts the rows. it is same as the count(1)
+		expr.AggStar = false
+		expr.Args = []*pg_query.Node{pg_query.MakeAConstIntNode(1, -1)}
+	}
+	switch name {
+	case "now", "transaction_timestamp":
+		if len(expr.Args) == 0 {
+			return b.bindCurrentTime(common.TimestampTzType())
 		}
 	}
 	args := make([]*Expr, 0)
@@ -234,25 +335,154 @@ func (b *Builder) bindFuncCall(ctx *BindContext, iwc InWhichClause, expr *pg_que
 		args = append(args, child)
 		argsTypes = append(argsTypes, child.DataTyp)
 	}
+	if name == "age" && len(args) == 1 {
+		//age(x) is age(current_date, x)
+		child, err = b.bindCurrentDate(args[0].DataTyp)
+		if err != nil {
+			return nil, err
+		}
+		args = append([]*Expr{child}, args...)
+		argsTypes = append([]common.LType{child.DataTyp}, argsTypes...)
+	}
+
+	var filter *Expr
+	if expr.AggFilter != nil {
+		filter, err = b.bindAggrFilter(ctx, iwc, name, expr, depth)
+		if err != nil {
+			return nil, err
+		}
+	}
 
-	ret, err = b.bindFunc(
-		name,
-		ET_SubFunc,
-		expr.String(),
-		args,
-		argsTypes,
-		expr.AggDistinct)
+	if expr.AggWithinGroup {
+		ret, err = b.bindWithinGroup(ctx, iwc, name, expr, args, depth)
+	} else if aggrName, has := orderedSetAggrs[name]; has && aggrName != name {
+		return nil, util.NewSQLError(util.SQLStateWrongObjectType,
+			"WITHIN GROUP is required for ordered-set aggregate %s", name)
+	} else if len(expr.AggOrder) != 0 {
+		ret, err = b.bindSortedAggr(ctx, iwc, name, expr, args, depth)
+	} else {
+		ret, err = b.bindFunc(
+			name,
+			ET_SubFunc,
+			expr.String(),
+			args,
+			argsTypes,
+			expr.AggDistinct)
+	}
 	if err != nil {
 		return nil, err
 	}
+	if filter != nil {
+		//the FILTER is the last child of the aggregate
+		aggr := b.aggs[ret.ColRef.column()]
+		aggr.Children = append(aggr.Children, filter)
+		aggr.AggrFilter = true
+	}
 	return ret, nil
 }
 
+// bindAggrFilter binds the FILTER (WHERE ...) of the aggregate.
+func (b *Builder) bindAggrFilter(ctx *BindContext, iwc InWhichClause, name string, expr *pg_query.FuncCall, depth int) (*Expr, error) {
+	if _, has := orderedSetAggrs[name]; !has && !IsAgg(name) {
+		return nil, util.NewSQLError(util.SQLStateWrongObjectType,
+			"FILTER specified, but %s is not an aggregate function", name)
+	}
+	if expr.AggDistinct {
+		return nil, util.NewSQLError(util.SQLStateFeatureNotSupported,
+			"unsupport DISTINCT with FILTER in the aggregate right now")
+	}
+	aggCount := len(b.aggs)
+	filter, err := b.bindExpr(ctx, iwc, expr.AggFilter, depth)
+	if err != nil {
+		return nil, err
+	}
+	if len(b.aggs) != aggCount {
+		return nil, util.NewSQLError(util.SQLStateGroupingError,
+			"aggregate functions are not allowed in FILTER")
+	}
+	if filter.DataTyp.Id != common.LTID_BOOLEAN {
+		return nil, util.NewSQLError(util.SQLStateDatatypeMismatch,
+			"argument of FILTER must be type boolean, not type %s", filter.DataTyp)
+	}
+	return filter, nil
+}
+
+// beginTime returns the begin time of the txn.
+// the current date and time are stable in the txn.
+func (b *Builder) beginTime() time.Time {
+	if b.txn == nil {
+		return time.Now().UTC()
+	}
+	return b.txn.BeginTime()
+}
+
+// bindCurrentTime binds the begin time of the txn as the constant of the type.
+func (b *Builder) bindCurrentTime(typ common.LType) (*Expr, error) {
+	now := b.beginTime()
+	var text string
+	switch typ.Id {
+	case common.LTID_DATE:
+		text = now.Format(time.DateOnly)
+	case common.LTID_TIME:
+		text = common.FormatTime(timeOfDay(now))
+	case common.LTID_TIMESTAMP:
+		text = common.FormatTimestamp(now.UnixMicro(), false)
+	default:
+		text = common.FormatTimestamp(now.UnixMicro(), true)
+	}
+	return AddCastToType(&Expr{
+		Typ:     ET_SConst,
+		DataTyp: common.VarcharType(),
+		Svalue:  text,
+	}, typ, false)
+}
+
+// bindCurrentDate binds the midnight of the current date.
+// it is the timestamptz if typ is. otherwise the timestamp.
+func (b *Builder) bindCurrentDate(typ common.LType) (*Expr, error) {
+	if typ.Id != common.LTID_TIMESTAMP_TZ {
+		typ = common.TimestampType()
+	}
+	return AddCastToType(&Expr{
+		Typ:     ET_SConst,
+		DataTyp: common.VarcharType(),
+		Svalue:  b.beginTime().Format(time.DateOnly),
+	}, typ, false)
+}
+
+func (b *Builder) bindSQLValueFunction(expr *pg_query.SQLValueFunction) (*Expr, error) {
+	switch expr.Op {
+	case pg_query.SQLValueFunctionOp_SVFOP_CURRENT_DATE:
+		return b.bindCurrentTime(common.DateType())
+	case pg_query.SQLValueFunctionOp_SVFOP_CURRENT_TIME,
+		pg_query.SQLValueFunctionOp_SVFOP_CURRENT_TIME_N,
+		pg_query.SQLValueFunctionOp_SVFOP_LOCALTIME,
+		pg_query.SQLValueFunctionOp_SVFOP_LOCALTIME_N:
+		return b.bindCurrentTime(common.TimeType())
+	case pg_query.SQLValueFunctionOp_SVFOP_CURRENT_TIMESTAMP,
+		pg_query.SQLValueFunctionOp_SVFOP_CURRENT_TIMESTAMP_N:
+		return b.bindCurrentTime(common.TimestampTzType())
+	case pg_query.SQLValueFunctionOp_SVFOP_LOCALTIMESTAMP,
+		pg_query.SQLValueFunctionOp_SVFOP_LOCALTIMESTAMP_N:
+		return b.bindCurrentTime(common.TimestampType())
+	default:
+		return nil, util.NewSQLError(util.SQLStateFeatureNotSupported,
+			"unsupport sql value function %s right now", expr.Op)
+	}
+}
+
 func (b *Builder) bindAConst(ctx *BindContext, iwc InWhichClause, expr *pg_query.A_Const, depth int) (*Expr, error) {
 	var ret *Expr
 	var fval float64
 	var err error
 
+	if expr.Isnull {
+		return &Expr{
+			Typ:     ET_NConst,
+			DataTyp: common.Null(),
+		}, nil
+	}
+
 	switch realExpr := expr.GetVal().(type) {
 	case *pg_query.A_Const_Sval:
 		ret = &Expr{
@@ -276,9 +506,15 @@ func (b *Builder) bindAConst(ctx *BindContext, iwc InWhichClause, expr *pg_query
 			DataTyp: common.IntegerType(),
 			Ivalue:  int64(realExpr.Ival.Ival),
 		}
+	case *pg_query.A_Const_Boolval:
+		ret = &Expr{
+			Typ:     ET_BConst,
+			DataTyp: common.BooleanType(),
+			Bvalue:  realExpr.Boolval.Boolval,
+		}
 
 	default:
-		panic(fmt.Errorf("bindExpr: unexpected node type %T", realExpr))
+		return nil, util.NewSQLError(util.SQLStateFeatureNotSupported, "unsupport constant %T right now", realExpr)
 	}
 	return ret, err
 }
@@ -294,9 +530,19 @@ func (b *Builder) bindAExpr(ctx *BindContext, iwc InWhichClause, expr *pg_query.
 		return b.bindInExpr(ctx, iwc, expr, depth)
 	case pg_query.A_Expr_Kind_AEXPR_BETWEEN:
 		return b.bindBetweenExpr(ctx, iwc, expr, depth)
+	case pg_query.A_Expr_Kind_AEXPR_DISTINCT:
+		return b.bindNullCompare(ctx, iwc, ET_IsDistinctFrom, expr.Lexpr, expr.Rexpr, expr.String(), depth)
+	case pg_query.A_Expr_Kind_AEXPR_NOT_DISTINCT:
+		return b.bindNullCompare(ctx, iwc, ET_IsNotDistinctFrom, expr.Lexpr, expr.Rexpr, expr.String(), depth)
+	case pg_query.A_Expr_Kind_AEXPR_NULLIF:
+		return b.bindNullCompare(ctx, iwc, ET_NullIf, expr.Lexpr, expr.Rexpr, expr.String(), depth)
 	default:
 	}
 
+	if expr.Lexpr == nil {
+		return b.bindUnaryAExpr(ctx, iwc, expr, depth)
+	}
+
 	left, err = b.bindExpr(ctx, iwc, expr.Lexpr, depth)
 	if err != nil {
 		return nil, err
@@ -317,7 +563,28 @@ func (b *Builder) bindAExpr(ctx *BindContext, iwc InWhichClause, expr *pg_query.
 		case "!~~":
 			et = ET_NotLike
 		default:
-			panic(fmt.Errorf("bindExpr: unexpected operator %q", opName))
+			return nil, util.NewSQLError(util.SQLStateFeatureNotSupported, "unsupport operator %s right now", opName)
+		}
+	case pg_query.A_Expr_Kind_AEXPR_ILIKE:
+		opName := expr.Name[0].GetString_().GetSval()
+		switch opName {
+		case "~~*":
+			et = ET_ILike
+		case "!~~*":
+			et = ET_NotILike
+		default:
+			return nil, util.NewSQLError(util.SQLStateFeatureNotSupported, "unsupport operator %s right now", opName)
+		}
+	case pg_query.A_Expr_Kind_AEXPR_SIMILAR:
+		//the pattern has been converted into the regular expression by the similar_to_escape
+		opName := expr.Name[0].GetString_().GetSval()
+		switch opName {
+		case "~":
+			et = ET_RegexpMatch
+		case "!~":
+			et = ET_NotRegexpMatch
+		default:
+			return nil, util.NewSQLError(util.SQLStateFeatureNotSupported, "unsupport operator %s right now", opName)
 		}
 
 	case pg_query.A_Expr_Kind_AEXPR_OP:
@@ -343,11 +610,33 @@ func (b *Builder) bindAExpr(ctx *BindContext, iwc InWhichClause, expr *pg_query.
 			et = ET_Less
 		case "<=":
 			et = ET_LessEqual
+		case "||":
+			et = ET_Concat
+		case "~":
+			et = ET_RegexpMatch
+		case "!~":
+			et = ET_NotRegexpMatch
+		case "~*":
+			et = ET_RegexpIMatch
+		case "!~*":
+			et = ET_NotRegexpIMatch
+		case "%":
+			et = ET_Mod
+		case "&":
+			et = ET_BitAnd
+		case "|":
+			et = ET_BitOr
+		case "#":
+			et = ET_BitXor
+		case "<<":
+			et = ET_ShiftLeft
+		case ">>":
+			et = ET_ShiftRight
 		default:
-			panic(fmt.Errorf("bindAExpr: unexpected operator '%s'", expr.Name[0].GetString_().GetSval()))
+			return nil, util.NewSQLError(util.SQLStateFeatureNotSupported, "unsupport operator %s right now", opName)
 		}
 	default:
-		panic(fmt.Sprintf("bindExpr: unexpected kind %v", expr.Kind))
+		return nil, util.NewSQLError(util.SQLStateFeatureNotSupported, "unsupport expression kind %v right now", expr.Kind)
 	}
 
 	if et == ET_Add &&
@@ -364,6 +653,19 @@ func (b *Builder) bindAExpr(ctx *BindContext, iwc InWhichClause, expr *pg_query.
 		right.DataTyp.IsInterval() {
 		//date - interval => date
 		et = ET_DateSub
+	} else if (et == ET_Add || et == ET_Sub) && isTimeArith(et, left.DataTyp, right.DataTyp) {
+		//time|timestamp +- interval => time|timestamp
+		//time|timestamp - time|timestamp => interval
+	} else if et == ET_Concat {
+		//the operands of the || are converted into the varchar
+		left, err = AddCastToType(left, common.VarcharType(), false)
+		if err != nil {
+			return nil, err
+		}
+		right, err = AddCastToType(right, common.VarcharType(), false)
+		if err != nil {
+			return nil, err
+		}
 	} else {
 		resultTyp = decideResultType(left.DataTyp, right.DataTyp)
 
@@ -385,6 +687,160 @@ func (b *Builder) bindAExpr(ctx *BindContext, iwc InWhichClause, expr *pg_query.
 	return bindFunc, nil
 }
 
+// bindUnaryAExpr binds the prefix operator.
+func (b *Builder) bindUnaryAExpr(ctx *BindContext, iwc InWhichClause, expr *pg_query.A_Expr, depth int) (*Expr, error) {
+	opName := expr.Name[0].GetString_().GetSval()
+	var et ET_SubTyp
+	switch opName {
+	case "~":
+		et = ET_BitNot
+	default:
+		return nil, util.NewSQLError(util.SQLStateFeatureNotSupported, "unsupport prefix operator %s right now", opName)
+	}
+	child, err := b.bindExpr(ctx, iwc, expr.Rexpr, depth)
+	if err != nil {
+		return nil, err
+	}
+	return b.bindFunc(et.String(), et, expr.String(), []*Expr{child}, []common.LType{child.DataTyp}, false)
+}
+
+func (b *Builder) bindNullTest(ctx *BindContext, iwc InWhichClause, expr *pg_query.NullTest, depth int) (*Expr, error) {
+	child, err := b.bindExpr(ctx, iwc, expr.Arg, depth)
+	if err != nil {
+		return nil, err
+	}
+	et := ET_IsNull
+	if expr.Nulltesttype == pg_query.NullTestType_IS_NOT_NULL {
+		et = ET_IsNotNull
+	}
+	return b.bindFunc(et.String(), et, expr.String(), []*Expr{child}, []common.LType{child.DataTyp}, false)
+}
+
+// bindBooleanTest rewrites the IS [NOT] TRUE, IS [NOT] FALSE into
+// the IS [NOT] DISTINCT FROM and the IS [NOT] UNKNOWN into the IS [NOT] NULL.
+func (b *Builder) bindBooleanTest(ctx *BindContext, iwc InWhichClause, expr *pg_query.BooleanTest, depth int) (*Expr, error) {
+	child, err := b.bindExpr(ctx, iwc, expr.Arg, depth)
+	if err != nil {
+		return nil, err
+	}
+	child, err = AddCastToType(child, common.BooleanType(), false)
+	if err != nil {
+		return nil, err
+	}
+	var et ET_SubTyp
+	var val bool
+	switch expr.Booltesttype {
+	case pg_query.BoolTestType_IS_UNKNOWN:
+		et = ET_IsNull
+	case pg_query.BoolTestType_IS_NOT_UNKNOWN:
+		et = ET_IsNotNull
+	case pg_query.BoolTestType_IS_TRUE:
+		et, val = ET_IsNotDistinctFrom, true
+	case pg_query.BoolTestType_IS_NOT_TRUE:
+		et, val = ET_IsDistinctFrom, true
+	case pg_query.BoolTestType_IS_FALSE:
+		et, val = ET_IsNotDistinctFrom, false
+	case pg_query.BoolTestType_IS_NOT_FALSE:
+		et, val = ET_IsDistinctFrom, false
+	default:
+		return nil, util.NewSQLError(util.SQLStateFeatureNotSupported, "unsupport boolean test %v right now", expr.Booltesttype)
+	}
+	args := []*Expr{child}
+	if et == ET_IsDistinctFrom || et == ET_IsNotDistinctFrom {
+		args = append(args, &Expr{
+			Typ:     ET_BConst,
+			DataTyp: common.BooleanType(),
+			Bvalue:  val,
+		})
+	}
+	argsTypes := make([]common.LType, 0, len(args))
+	for _, arg := range args {
+		argsTypes = append(argsTypes, arg.DataTyp)
+	}
+	return b.bindFunc(et.String(), et, expr.String(), args, argsTypes, false)
+}
+
+// bindNullCompare binds the IS [NOT] DISTINCT FROM and the NULLIF.
+// the arguments are cast to the common type.
+func (b *Builder) bindNullCompare(ctx *BindContext, iwc InWhichClause, et ET_SubTyp, lexpr, rexpr *pg_query.Node, astStr string, depth int) (*Expr, error) {
+	left, err := b.bindExpr(ctx, iwc, lexpr, depth)
+	if err != nil {
+		return nil, err
+	}
+	right, err := b.bindExpr(ctx, iwc, rexpr, depth)
+	if err != nil {
+		return nil, err
+	}
+	resultTyp := decideResultType(left.DataTyp, right.DataTyp)
+	if resultTyp.Id == common.LTID_NULL {
+		resultTyp = common.VarcharType()
+	}
+	left, err = AddCastToType(left, resultTyp, false)
+	if err != nil {
+		return nil, err
+	}
+	right, err = AddCastToType(right, resultTyp, false)
+	if err != nil {
+		return nil, err
+	}
+	return b.bindFunc(et.String(), et, astStr, []*Expr{left, right}, []common.LType{left.DataTyp, right.DataTyp}, false)
+}
+
+func (b *Builder) bindCoalesceExpr(ctx *BindContext, iwc InWhichClause, expr *pg_query.CoalesceExpr, depth int) (*Expr, error) {
+	args := make([]*Expr, 0, len(expr.Args))
+	retTyp := common.Null()
+	for _, arg := range expr.Args {
+		child, err := b.bindExpr(ctx, iwc, arg, depth)
+		if err != nil {
+			return nil, err
+		}
+		retTyp = common.MaxLType(retTyp, child.DataTyp)
+		args = append(args, child)
+	}
+	if retTyp.Id == common.LTID_NULL {
+		retTyp = common.VarcharType()
+	}
+	var err error
+	for i := range args {
+		args[i], err = AddCastToType(args[i], retTyp, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	funBinder := FunctionBinder{}
+	return funBinder.BindScalarFunc2(CoalesceFunc{}.Func(retTyp, len(args)), args, ET_Coalesce, false), nil
+}
+
+// bindMinMaxExpr binds the GREATEST and the LEAST.
+func (b *Builder) bindMinMaxExpr(ctx *BindContext, iwc InWhichClause, expr *pg_query.MinMaxExpr, depth int) (*Expr, error) {
+	et := ET_Greatest
+	if expr.Op == pg_query.MinMaxOp_IS_LEAST {
+		et = ET_Least
+	}
+	args := make([]*Expr, 0, len(expr.Args))
+	retTyp := common.Null()
+	for _, arg := range expr.Args {
+		child, err := b.bindExpr(ctx, iwc, arg, depth)
+		if err != nil {
+			return nil, err
+		}
+		retTyp = decideResultType(retTyp, child.DataTyp)
+		args = append(args, child)
+	}
+	if retTyp.Id == common.LTID_NULL {
+		retTyp = common.VarcharType()
+	}
+	var err error
+	for i := range args {
+		args[i], err = AddCastToType(args[i], retTyp, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	funBinder := FunctionBinder{}
+	return funBinder.BindScalarFunc2(GreatestFunc{}.Func(et, retTyp, len(args)), args, et, false), nil
+}
+
 func (b *Builder) bindBoolExpr(ctx *BindContext, iwc InWhichClause, expr *pg_query.BoolExpr, depth int) (*Expr, error) {
 	var err error
 	var resultTyp common.LType
@@ -471,21 +927,148 @@ func (b *Builder) bindFunc(name string, subTyp ET_SubTyp, astStr string, args []
 		if distinct {
 			ret.AggrTyp = DISTINCT
 		}
-		b.aggs = append(b.aggs, ret)
-		ret = &Expr{
-			Typ:     ET_Column,
-			DataTyp: ret.DataTyp,
-			Table:   fmt.Sprintf("AggNode_%v", b.aggTag),
-			Name:    astStr,
-			ColRef:  ColumnBind{uint64(b.aggTag), uint64(len(b.aggs) - 1)},
-			Depth:   0,
-		}
-		return ret, nil
+		return b.addAggr(ret, astStr), nil
 	} else {
 		return funBinder.BindScalarFunc(name, args, subTyp, subTyp.isOperator()), nil
 	}
 }
 
+// bindGroupingFunc binds the GROUPING function. the bit of the argument is 1
+// if the argument is not in the grouping set of the row.
+func (b *Builder) bindGroupingFunc(ctx *BindContext, iwc InWhichClause, expr *pg_query.GroupingFunc, depth int) (*Expr, error) {
+	switch iwc {
+	case IWC_SELECT, IWC_HAVING, IWC_ORDER:
+	default:
+		return nil, util.NewSQLError(util.SQLStateGroupingError,
+			"grouping operations are not allowed in %v", iwc)
+	}
+	if len(expr.Args) > 31 {
+		return nil, util.NewSQLError(util.SQLStateProgramLimitExceeded,
+			"GROUPING must have fewer than 32 arguments")
+	}
+	args := make([]int, 0, len(expr.Args))
+	for _, arg := range expr.Args {
+		argExpr, err := b.bindExpr(ctx, iwc, arg, depth)
+		if err != nil {
+			return nil, err
+		}
+		idx := -1
+		for i, group := range b.groupbyExprs {
+			if group.equal(argExpr) {
+				idx = i
+				break
+			}
+		}
+		if idx < 0 {
+			return nil, util.NewSQLError(util.SQLStateGroupingError,
+				"arguments to GROUPING must be grouping expressions of the associated query level")
+		}
+		args = append(args, idx)
+	}
+	b.groupingFuncs = append(b.groupingFuncs, args)
+	return b.groupRef(
+		common.BigintType(),
+		"grouping",
+		len(b.groupbyExprs)+len(b.groupingFuncs)-1,
+	), nil
+}
+
+// addAggr puts the aggregate into the aggregate node and
+// returns the reference to it.
+func (b *Builder) addAggr(aggr *Expr, astStr string) *Expr {
+	b.aggs = append(b.aggs, aggr)
+	return &Expr{
+		Typ:     ET_Column,
+		DataTyp: aggr.DataTyp,
+		Table:   fmt.Sprintf("AggNode_%v", b.aggTag),
+		Name:    astStr,
+		ColRef:  ColumnBind{uint64(b.aggTag), uint64(len(b.aggs) - 1)},
+		Depth:   0,
+	}
+}
+
+// bindSortedAggr binds the aggregate with the ORDER BY.
+// the sort keys are appended to the arguments of the aggregate.
+func (b *Builder) bindSortedAggr(ctx *BindContext, iwc InWhichClause, name string, expr *pg_query.FuncCall, args []*Expr, depth int) (*Expr, error) {
+	if !IsAgg(name) {
+		return nil, util.NewSQLError(util.SQLStateWrongObjectType,
+			"ORDER BY specified, but %s is not an aggregate function", name)
+	}
+	if expr.AggDistinct {
+		return nil, util.NewSQLError(util.SQLStateFeatureNotSupported,
+			"unsupport DISTINCT with ORDER BY in the aggregate right now")
+	}
+	keys := make([]*Expr, 0, len(expr.AggOrder))
+	keyTypes := make([]common.LType, 0, len(expr.AggOrder))
+	orders := make([]aggrOrder, 0, len(expr.AggOrder))
+	for _, node := range expr.AggOrder {
+		key, err := b.bindSortBy(ctx, iwc, node.GetSortBy(), depth)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key.Children[0])
+		keyTypes = append(keyTypes, key.DataTyp)
+		orders = append(orders, aggrOrder{_desc: key.Desc})
+	}
+	funBinder := FunctionBinder{}
+	ret := funBinder.BindAggrFunc(name, args, ET_SubFunc, false)
+	ret.FunImpl = SortedAggregate(ret.FunImpl, keyTypes, orders)
+	ret.Children = append(ret.Children, keys...)
+	return b.addAggr(ret, expr.String()), nil
+}
+
+// orderedSetAggrs maps the ordered-set aggregate to
+// the aggregate on the sort key.
+var orderedSetAggrs = map[string]string{
+	"percentile_cont": "quantile_cont",
+	"percentile_disc": "quantile_disc",
+	"mode":            "mode",
+}
+
+// bindWithinGroup binds the ordered-set aggregate.
+// the sort key becomes the first argument of the aggregate.
+func (b *Builder) bindWithinGroup(ctx *BindContext, iwc InWhichClause, name string, expr *pg_query.FuncCall, args []*Expr, depth int) (*Expr, error) {
+	aggrName, has := orderedSetAggrs[name]
+	if !has {
+		return nil, util.NewSQLError(util.SQLStateWrongObjectType,
+			"%s is not an ordered-set aggregate, so it cannot have WITHIN GROUP", name)
+	}
+	if len(expr.AggOrder) != 1 {
+		return nil, util.NewSQLError(util.SQLStateUndefinedFunction,
+			"function %s with %d sort keys does not exist", name, len(expr.AggOrder))
+	}
+	key, err := b.bindSortBy(ctx, iwc, expr.AggOrder[0].GetSortBy(), depth)
+	if err != nil {
+		return nil, err
+	}
+	args = append([]*Expr{key.Children[0]}, args...)
+	funBinder := FunctionBinder{}
+	ret := funBinder.BindAggrFunc(aggrName, args, ET_SubFunc, false)
+	if key.Desc && aggrName != "mode" {
+		fun := ret.FunImpl
+		ret.FunImpl = QuantileAggregate(fun._name, fun._args, fun._retType, aggrName == "quantile_disc", true)
+	}
+	return b.addAggr(ret, expr.String()), nil
+}
+
+// isTimeArith checks the operands are the time or timestamp with the interval,
+// or the same time types on both sides of the subtraction.
+func isTimeArith(et ET_SubTyp, left, right common.LType) bool {
+	isTime := func(typ common.LType) bool {
+		return typ.IsTemporal() && !typ.IsDate()
+	}
+	switch {
+	case isTime(left) && right.IsInterval():
+		return true
+	case et == ET_Add && left.IsInterval() && isTime(right):
+		return true
+	case et == ET_Sub && isTime(left) && left.Id == right.Id:
+		return true
+	default:
+		return false
+	}
+}
+
 func decideResultType(left common.LType, right common.LType) common.LType {
 	resultTyp := common.MaxLType(left, right)
 	//adjust final result type
@@ -513,6 +1096,12 @@ func decideResultType(left common.LType, right common.LType) common.LType {
 		} else if right.IsNumeric() || right.Id == common.LTID_BOOLEAN {
 			panic("usp")
 		}
+		//the string literal is compared as the date or time
+		if left.IsTemporal() {
+			return left
+		} else if right.IsTemporal() {
+			return right
+		}
 		return resultTyp
 	default:
 		return resultTyp
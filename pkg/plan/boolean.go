@@ -15,6 +15,8 @@
 package plan
 
 import (
+	"cmp"
+
 	"github.com/daviszhen/plan/pkg/chunk"
 	"github.com/daviszhen/plan/pkg/common"
 	"github.com/daviszhen/plan/pkg/util"
@@ -200,8 +202,22 @@ func (e lessFloat64Op) operation(left, right *float64) bool {
 	return util.GreaterFloat[float64](*right, *left)
 }
 
+type lessOp[T cmp.Ordered] struct {
+}
+
+func (e lessOp[T]) operation(left, right *T) bool {
+	return *left < *right
+}
+
 // <=
 
+type lessEqualOp[T cmp.Ordered] struct {
+}
+
+func (e lessEqualOp[T]) operation(left, right *T) bool {
+	return *left <= *right
+}
+
 // int32
 //
 //lint:ignore U1000
@@ -234,6 +250,27 @@ func (e lessEqualDateOp) operation(left, right *common.Date) bool {
 
 //>
 
+type greatOp[T cmp.Ordered] struct {
+}
+
+func (e greatOp[T]) operation(left, right *T) bool {
+	return *left > *right
+}
+
+type greatBoolOp struct {
+}
+
+func (e greatBoolOp) operation(left, right *bool) bool {
+	return *left && !*right
+}
+
+type greatStrOp struct {
+}
+
+func (e greatStrOp) operation(left, right *common.String) bool {
+	return right.Less(left)
+}
+
 // float32
 //
 //lint:ignore U1000
@@ -300,6 +337,13 @@ func (e greatDateOp) operation(left, right *common.Date) bool {
 
 // >=
 
+type greatEqualOp[T cmp.Ordered] struct {
+}
+
+func (e greatEqualOp[T]) operation(left, right *T) bool {
+	return *left >= *right
+}
+
 // int32
 //
 //lint:ignore U1000
@@ -398,11 +442,15 @@ func selectOperation(left, right *chunk.Vector, sel *chunk.SelectVector, count i
 		switch left.Typ().GetInternalType() {
 		case common.INT32:
 			return selectBinary[int32](left, right, sel, count, trueSel, falseSel, equalOp[int32]{})
+		case common.INT64:
+			return selectBinary[int64](left, right, sel, count, trueSel, falseSel, equalOp[int64]{})
+		case common.DATE:
+			return selectBinary[common.Date](left, right, sel, count, trueSel, falseSel, equalDateOp{})
 		case common.VARCHAR:
 			return selectBinary[common.String](left, right, sel, count, trueSel, falseSel, equalStrOp{})
 		case common.BOOL:
 			return selectBinary[bool](left, right, sel, count, trueSel, falseSel, equalOp[bool]{})
-		case common.UINT8, common.INT8, common.UINT16, common.INT16, common.UINT32, common.UINT64, common.INT64, common.FLOAT, common.DOUBLE, common.INTERVAL, common.LIST, common.STRUCT, common.INT128, common.UNKNOWN, common.BIT, common.INVALID:
+		case common.UINT8, common.INT8, common.UINT16, common.INT16, common.UINT32, common.UINT64, common.FLOAT, common.DOUBLE, common.INTERVAL, common.LIST, common.STRUCT, common.INT128, common.UNKNOWN, common.BIT, common.INVALID:
 			panic("usp")
 		default:
 			panic("usp")
@@ -411,9 +459,13 @@ func selectOperation(left, right *chunk.Vector, sel *chunk.SelectVector, count i
 		switch left.Typ().GetInternalType() {
 		case common.INT32:
 			return selectBinary[int32](left, right, sel, count, trueSel, falseSel, notEqualOp[int32]{})
+		case common.INT64:
+			return selectBinary[int64](left, right, sel, count, trueSel, falseSel, notEqualOp[int64]{})
+		case common.DATE:
+			return selectBinary[common.Date](left, right, sel, count, trueSel, falseSel, notEqualOp[common.Date]{})
 		case common.VARCHAR:
 			return selectBinary[common.String](left, right, sel, count, trueSel, falseSel, notEqualStrOp{})
-		case common.BOOL, common.UINT8, common.INT8, common.UINT16, common.INT16, common.UINT32, common.UINT64, common.INT64, common.FLOAT, common.DOUBLE, common.INTERVAL, common.LIST, common.STRUCT, common.INT128, common.UNKNOWN, common.BIT, common.INVALID:
+		case common.BOOL, common.UINT8, common.INT8, common.UINT16, common.INT16, common.UINT32, common.UINT64, common.FLOAT, common.DOUBLE, common.INTERVAL, common.LIST, common.STRUCT, common.INT128, common.UNKNOWN, common.BIT, common.INVALID:
 			panic("usp")
 		default:
 			panic("usp")
@@ -433,6 +485,8 @@ func selectOperation(left, right *chunk.Vector, sel *chunk.SelectVector, count i
 		switch left.Typ().GetInternalType() {
 		case common.INT32:
 			return selectBinary[int32](left, right, sel, count, trueSel, falseSel, greatInt32Op{})
+		case common.INT64:
+			return selectBinary[int64](left, right, sel, count, trueSel, falseSel, greatOp[int64]{})
 		case common.INT128:
 			return selectBinary[common.Hugeint](left, right, sel, count, trueSel, falseSel, greatHugeintOp{})
 		case common.DATE:
@@ -441,7 +495,7 @@ func selectOperation(left, right *chunk.Vector, sel *chunk.SelectVector, count i
 			return selectBinary[float32](left, right, sel, count, trueSel, falseSel, greatFloat32Op{})
 		case common.DECIMAL:
 			return selectBinary[common.Decimal](left, right, sel, count, trueSel, falseSel, greatDecimalOp{})
-		case common.BOOL, common.UINT8, common.INT8, common.UINT16, common.INT16, common.UINT32, common.UINT64, common.INT64, common.DOUBLE, common.INTERVAL, common.LIST, common.STRUCT, common.VARCHAR, common.UNKNOWN, common.BIT, common.INVALID:
+		case common.BOOL, common.UINT8, common.INT8, common.UINT16, common.INT16, common.UINT32, common.UINT64, common.DOUBLE, common.INTERVAL, common.LIST, common.STRUCT, common.VARCHAR, common.UNKNOWN, common.BIT, common.INVALID:
 			panic("usp")
 		default:
 			panic("usp")
@@ -450,11 +504,13 @@ func selectOperation(left, right *chunk.Vector, sel *chunk.SelectVector, count i
 		switch left.Typ().GetInternalType() {
 		case common.INT32:
 			return selectBinary[int32](left, right, sel, count, trueSel, falseSel, greatEqualInt32Op{})
+		case common.INT64:
+			return selectBinary[int64](left, right, sel, count, trueSel, falseSel, greatEqualOp[int64]{})
 		case common.DATE:
 			return selectBinary[common.Date](left, right, sel, count, trueSel, falseSel, greatEqualDateOp{})
 		case common.FLOAT:
 			return selectBinary[float32](left, right, sel, count, trueSel, falseSel, greatEqualFloat32Op{})
-		case common.BOOL, common.UINT8, common.INT8, common.UINT16, common.INT16, common.UINT32, common.UINT64, common.INT64, common.DOUBLE, common.INTERVAL, common.LIST, common.STRUCT, common.VARCHAR, common.INT128, common.UNKNOWN, common.BIT, common.INVALID:
+		case common.BOOL, common.UINT8, common.INT8, common.UINT16, common.INT16, common.UINT32, common.UINT64, common.DOUBLE, common.INTERVAL, common.LIST, common.STRUCT, common.VARCHAR, common.INT128, common.UNKNOWN, common.BIT, common.INVALID:
 			panic("usp")
 		default:
 			panic("usp")
@@ -463,11 +519,13 @@ func selectOperation(left, right *chunk.Vector, sel *chunk.SelectVector, count i
 		switch left.Typ().GetInternalType() {
 		case common.INT32:
 			return selectBinary[int32](left, right, sel, count, trueSel, falseSel, lessInt32Op{})
+		case common.INT64:
+			return selectBinary[int64](left, right, sel, count, trueSel, falseSel, lessOp[int64]{})
 		case common.DATE:
 			return selectBinary[common.Date](left, right, sel, count, trueSel, falseSel, lessDateOp{})
 		case common.DOUBLE:
 			return selectBinary[float64](left, right, sel, count, trueSel, falseSel, lessFloat64Op{})
-		case common.BOOL, common.UINT8, common.INT8, common.UINT16, common.INT16, common.UINT32, common.UINT64, common.INT64, common.FLOAT, common.INTERVAL, common.LIST, common.STRUCT, common.VARCHAR, common.INT128, common.UNKNOWN, common.BIT, common.INVALID:
+		case common.BOOL, common.UINT8, common.INT8, common.UINT16, common.INT16, common.UINT32, common.UINT64, common.FLOAT, common.INTERVAL, common.LIST, common.STRUCT, common.VARCHAR, common.INT128, common.UNKNOWN, common.BIT, common.INVALID:
 			panic("usp")
 		default:
 			panic("usp")
@@ -476,11 +534,13 @@ func selectOperation(left, right *chunk.Vector, sel *chunk.SelectVector, count i
 		switch left.Typ().GetInternalType() {
 		case common.INT32:
 			return selectBinary[int32](left, right, sel, count, trueSel, falseSel, lessEqualInt32Op{})
+		case common.INT64:
+			return selectBinary[int64](left, right, sel, count, trueSel, falseSel, lessEqualOp[int64]{})
 		case common.DATE:
 			return selectBinary[common.Date](left, right, sel, count, trueSel, falseSel, lessEqualDateOp{})
 		case common.FLOAT:
 			return selectBinary[float32](left, right, sel, count, trueSel, falseSel, lessEqualFloat32Op{})
-		case common.BOOL, common.UINT8, common.INT8, common.UINT16, common.INT16, common.UINT32, common.UINT64, common.INT64, common.DOUBLE, common.INTERVAL, common.LIST, common.STRUCT, common.VARCHAR, common.INT128, common.UNKNOWN, common.BIT, common.INVALID:
+		case common.BOOL, common.UINT8, common.INT8, common.UINT16, common.INT16, common.UINT32, common.UINT64, common.DOUBLE, common.INTERVAL, common.LIST, common.STRUCT, common.VARCHAR, common.INT128, common.UNKNOWN, common.BIT, common.INVALID:
 			panic("usp")
 		default:
 			panic("usp")
@@ -17,6 +17,7 @@ package plan
 import (
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
 
 	pg_query "github.com/pganalyze/pg_query_go/v5"
@@ -24,6 +25,7 @@ import (
 
 	"github.com/daviszhen/plan/pkg/chunk"
 	"github.com/daviszhen/plan/pkg/common"
+	"github.com/daviszhen/plan/pkg/parser"
 	"github.com/daviszhen/plan/pkg/storage"
 	"github.com/daviszhen/plan/pkg/util"
 )
@@ -36,6 +38,7 @@ const (
 	BT_DUMMY
 	BT_CATALOG_ENTRY
 	BT_Subquery
+	BT_CTE
 )
 
 func (bt BindingType) String() string {
@@ -50,6 +53,8 @@ func (bt BindingType) String() string {
 		return "catalog_entry"
 	case BT_Subquery:
 		return "subquery"
+	case BT_CTE:
+		return "cte"
 	default:
 		panic(fmt.Sprintf("usp binding type %d", bt))
 	}
@@ -63,6 +68,8 @@ type Binding struct {
 	typs     []common.LType
 	names    []string
 	nameMap  map[string]int
+	//count of the references to the work table
+	refs int
 }
 
 func (b *Binding) Format(ctx *FormatCtx) {
@@ -92,7 +99,7 @@ func (b *Binding) Bind(table, column string, depth int) (*Expr, error) {
 		}
 		return exp, nil
 	}
-	return nil, fmt.Errorf("table %s does not have column %s", table, column)
+	return nil, util.NewSQLError(util.SQLStateUndefinedColumn, "table %s does not have column %s", table, column)
 }
 
 func (b *Binding) HasColumn(column string) int {
@@ -102,12 +109,32 @@ func (b *Binding) HasColumn(column string) int {
 	return -1
 }
 
+// sharedCte is the cte query bound once and
+// shared by the references to the cte.
+type sharedCte struct {
+	//columns of the cte. refs counts the references.
+	bind  *Binding
+	query *Expr
+	//MATERIALIZED
+	materialized bool
+	//plan of the materialized cte query
+	root *LogicalOperator
+}
+
 type BindContext struct {
 	parent       *BindContext
 	bindings     map[string]*Binding
 	bindingsList []*Binding
 	ctes         map[string]*pg_query.CommonTableExpr
 	cteBindings  map[string]*Binding
+	//cte name -> the cte query shared by the references
+	sharedCtes map[string]*sharedCte
+	//column name -> merged column of the USING join.
+	//nil means the merged column is ambiguous.
+	usings     map[string]*pg_query.Node
+	usingNames []string
+	//some columns are found in the parent contexts
+	referParent bool
 }
 
 func NewBindContext(parent *BindContext) *BindContext {
@@ -116,6 +143,8 @@ func NewBindContext(parent *BindContext) *BindContext {
 		bindings:    make(map[string]*Binding, 0),
 		ctes:        make(map[string]*pg_query.CommonTableExpr, 0),
 		cteBindings: make(map[string]*Binding),
+		sharedCtes:  make(map[string]*sharedCte),
+		usings:      make(map[string]*pg_query.Node),
 	}
 }
 
@@ -148,9 +177,60 @@ func (bc *BindContext) AddContext(obc *BindContext) error {
 		bc.bindings[alias] = ob
 	}
 	bc.bindingsList = append(bc.bindingsList, obc.bindingsList...)
+	for _, name := range obc.usingNames {
+		if _, has := bc.usings[name]; has {
+			bc.usings[name] = nil
+			continue
+		}
+		bc.AddUsing(name, obc.usings[name])
+	}
+	return nil
+}
+
+// AddUsing sets the merged column of the USING join.
+func (bc *BindContext) AddUsing(column string, node *pg_query.Node) {
+	if _, has := bc.usings[column]; !has {
+		bc.usingNames = append(bc.usingNames, column)
+	}
+	bc.usings[column] = node
+}
+
+// GetUsing returns the merged column that the unqualified column refers to.
+// the column in the inner context hides the one in the outer context.
+func (bc *BindContext) GetUsing(column string) *pg_query.Node {
+	for p := bc; p != nil; p = p.parent {
+		if node, has := p.usings[column]; has {
+			return node
+		}
+		for _, b := range p.bindings {
+			if b.HasColumn(column) >= 0 {
+				return nil
+			}
+		}
+	}
 	return nil
 }
 
+// ColumnNames returns the column names in the order of the star.
+// the merged columns are the first and appear once.
+func (bc *BindContext) ColumnNames() []string {
+	ret := make([]string, 0)
+	for _, name := range bc.usingNames {
+		if bc.usings[name] != nil {
+			ret = append(ret, name)
+		}
+	}
+	for _, b := range bc.bindingsList {
+		for _, name := range b.names {
+			if bc.usings[name] != nil {
+				continue
+			}
+			ret = append(ret, name)
+		}
+	}
+	return ret
+}
+
 func (bc *BindContext) RemoveContext(obList []*Binding) {
 	for _, ob := range obList {
 		found := -1
@@ -173,7 +253,7 @@ func (bc *BindContext) GetBinding(name string) (*Binding, error) {
 	if b, ok := bc.bindings[name]; ok {
 		return b, nil
 	}
-	return nil, fmt.Errorf("table %s does not exists", name)
+	return nil, util.NewSQLError(util.SQLStateUndefinedTable, "table %s does not exists", name)
 }
 
 func (bc *BindContext) GetMatchingBinding(table, column string) (*Binding, int, error) {
@@ -184,7 +264,7 @@ func (bc *BindContext) GetMatchingBinding(table, column string) (*Binding, int,
 		for _, b := range bc.bindings {
 			if b.HasColumn(column) >= 0 {
 				if ret != nil {
-					return nil, 0, fmt.Errorf("ambiguous column %s in %s or %s", column, ret.alias, b.alias)
+					return nil, 0, util.NewSQLError(util.SQLStateAmbiguousColumn, "ambiguous column %s in %s or %s", column, ret.alias, b.alias)
 				}
 				ret = b
 			}
@@ -205,10 +285,11 @@ func (bc *BindContext) GetMatchingBinding(table, column string) (*Binding, int,
 	}
 
 	if ret == nil {
-		return nil, 0, fmt.Errorf("no table find column %s", column)
+		return nil, 0, util.NewSQLError(util.SQLStateUndefinedColumn, "no table find column %s", column)
 	}
 	if parDepth != -1 {
 		depth = parDepth + 1
+		bc.referParent = true
 	}
 	return ret, depth, nil
 }
@@ -225,6 +306,8 @@ func (bc *BindContext) BindColumn(table, column string, depth int) (*Expr, error
 func (bc *BindContext) GetCteBinding(name string) *Binding {
 	if b, ok := bc.cteBindings[name]; ok {
 		return b
+	} else if bc.parent != nil {
+		return bc.parent.GetCteBinding(name)
 	} else {
 		return nil
 	}
@@ -250,10 +333,19 @@ type Builder struct {
 	whereExpr    *Expr
 	aggs         []*Expr
 	groupbyExprs []*Expr
-	havingExpr   *Expr
-	orderbyExprs []*Expr
-	limitCount   *Expr
-	limitOffset  *Expr
+	//the indices of the group by exprs in each grouping set.
+	//nil for the plain GROUP BY.
+	groupingSets []GroupingSet
+	//the indices of the arguments of the GROUPING functions
+	groupingFuncs [][]int
+	havingExpr    *Expr
+	//SELECT DISTINCT
+	distinct bool
+	//the exprs in DISTINCT ON
+	distinctOnExprs []*Expr
+	orderbyExprs    []*Expr
+	limitCount      *Expr
+	limitOffset     *Expr
 
 	//for insert
 	expectedTypes []common.LType
@@ -263,15 +355,24 @@ type Builder struct {
 	columnCount int      // count of the select exprs (after expanding star)
 	phyId       int
 	txn         *storage.Txn
+
+	//cte index -> work table of the recursive cte
+	workTables map[uint64]*ColumnDataCollection
+	//cte index -> the materialized cte
+	materializedCtes map[uint64]*MaterializedCTE
+	//the row count of the build side from which
+	//the sort-merge join replaces the hash join
+	sortMergeJoinRows uint64
 }
 
 func NewBuilder(txn *storage.Txn) *Builder {
 	return &Builder{
-		tag:        new(int),
-		rootCtx:    NewBindContext(nil),
-		aliasMap:   make(map[string]int),
-		projectMap: make(map[string]int),
-		txn:        txn,
+		tag:               new(int),
+		rootCtx:           NewBindContext(nil),
+		aliasMap:          make(map[string]int),
+		projectMap:        make(map[string]int),
+		txn:               txn,
+		sortMergeJoinRows: util.DefaultSortMergeJoinRows,
 	}
 }
 
@@ -395,28 +496,26 @@ func (b *Builder) expandStar(expr *pg_query.ResTarget) ([]*pg_query.ResTarget, e
 				return nil, errors.New("no table")
 			}
 			ret := make([]*pg_query.ResTarget, 0)
-			for _, bind := range b.rootCtx.bindingsList {
-				for _, name := range bind.names {
-					colNode := &pg_query.Node_String_{
-						String_: &pg_query.String{
-							Sval: name,
-						},
-					}
+			for _, name := range b.rootCtx.ColumnNames() {
+				colNode := &pg_query.Node_String_{
+					String_: &pg_query.String{
+						Sval: name,
+					},
+				}
 
-					ret = append(ret, &pg_query.ResTarget{
-						Val: &pg_query.Node{
-							Node: &pg_query.Node_ColumnRef{
-								ColumnRef: &pg_query.ColumnRef{
-									Fields: []*pg_query.Node{
-										{
-											Node: colNode,
-										},
+				ret = append(ret, &pg_query.ResTarget{
+					Val: &pg_query.Node{
+						Node: &pg_query.Node_ColumnRef{
+							ColumnRef: &pg_query.ColumnRef{
+								Fields: []*pg_query.Node{
+									{
+										Node: colNode,
 									},
 								},
 							},
 						},
-					})
-				}
+					},
+				})
 			}
 			return ret, nil
 		}
@@ -449,7 +548,7 @@ func (b *Builder) buildSelect(sel *pg_query.SelectStmt, ctx *BindContext, depth
 			return err
 		}
 	} else {
-		return fmt.Errorf("usp table ref")
+		return util.NewSQLError(util.SQLStateFeatureNotSupported, "unsupport select without from right now")
 	}
 
 	//expand star
@@ -520,13 +619,9 @@ func (b *Builder) buildSelect(sel *pg_query.SelectStmt, ctx *BindContext, depth
 
 	//group by
 	if len(sel.GroupClause) != 0 {
-		var retExpr *Expr
-		for _, expr := range sel.GroupClause {
-			retExpr, err = b.bindExpr(ctx, IWC_GROUP, expr, depth)
-			if err != nil {
-				return err
-			}
-			b.groupbyExprs = append(b.groupbyExprs, retExpr)
+		err = b.bindGroupBy(ctx, sel.GroupClause, depth)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -537,7 +632,7 @@ func (b *Builder) buildSelect(sel *pg_query.SelectStmt, ctx *BindContext, depth
 		if err != nil {
 			return err
 		}
-		b.havingExpr = retExpr
+		b.havingExpr = b.bindToGroup(retExpr)
 	}
 	//select exprs
 	var retExpr *Expr
@@ -546,6 +641,7 @@ func (b *Builder) buildSelect(sel *pg_query.SelectStmt, ctx *BindContext, depth
 		if err != nil {
 			return err
 		}
+		retExpr = b.bindToGroup(retExpr)
 		retExpr.Alias = b.names[i]
 		b.projectExprs = append(b.projectExprs, retExpr)
 	}
@@ -557,7 +653,15 @@ func (b *Builder) buildSelect(sel *pg_query.SelectStmt, ctx *BindContext, depth
 			if err != nil {
 				return err
 			}
-			b.orderbyExprs = append(b.orderbyExprs, retExpr)
+			b.orderbyExprs = append(b.orderbyExprs, b.bindToGroup(retExpr))
+		}
+	}
+
+	//distinct
+	if len(sel.DistinctClause) != 0 {
+		err = b.bindDistinct(ctx, sel.DistinctClause, depth)
+		if err != nil {
+			return err
 		}
 	}
 
@@ -572,16 +676,272 @@ func (b *Builder) buildSelect(sel *pg_query.SelectStmt, ctx *BindContext, depth
 	return err
 }
 
-func (b *Builder) findCte(name string, skip bool, ctx *BindContext) *pg_query.CommonTableExpr {
+// bindDistinct binds the DISTINCT or DISTINCT ON clause.
+// the plain DISTINCT is the list with a nil node.
+func (b *Builder) bindDistinct(ctx *BindContext, clause []*pg_query.Node, depth int) error {
+	if len(clause) == 1 && clause[0].GetNode() == nil {
+		b.distinct = true
+		//the order by exprs must be in the select list.
+		for _, order := range b.orderbyExprs {
+			idx := b.findInSelectList(order.Children[0])
+			if idx < 0 {
+				return util.NewSQLError(util.SQLStateInvalidColumnReference,
+					"for SELECT DISTINCT, ORDER BY expressions must appear in select list")
+			}
+			order.Children[0] = b.bindToSelectList(nil, idx, b.names[idx])
+		}
+		return nil
+	}
+
+	for _, expr := range clause {
+		retExpr, err := b.bindExpr(ctx, IWC_ORDER, expr, depth)
+		if err != nil {
+			return err
+		}
+		b.distinctOnExprs = append(b.distinctOnExprs, b.bindToGroup(retExpr))
+	}
+
+	//the leftmost order by exprs must be the DISTINCT ON exprs.
+	covered := make(map[int]bool)
+	for _, order := range b.orderbyExprs {
+		if len(covered) == len(b.distinctOnExprs) {
+			break
+		}
+		idx := slices.IndexFunc(b.distinctOnExprs, func(e *Expr) bool {
+			return b.sameSelectExpr(e, order.Children[0])
+		})
+		if idx < 0 {
+			return util.NewSQLError(util.SQLStateInvalidColumnReference,
+				"SELECT DISTINCT ON expressions must match initial ORDER BY expressions")
+		}
+		covered[idx] = true
+	}
+	return nil
+}
+
+// findInSelectList returns the index of the select expr
+// that is same as the expr.
+func (b *Builder) findInSelectList(e *Expr) int {
+	if e.Typ == ET_Column && e.ColRef.table() == uint64(b.projectTag) {
+		return int(e.ColRef.column())
+	}
+	for i, proj := range b.projectExprs {
+		if b.sameSelectExpr(proj, e) {
+			return i
+		}
+	}
+	return -1
+}
+
+// sameSelectExpr decides the two exprs are same after replacing the
+// reference to the select list with the select expr.
+func (b *Builder) sameSelectExpr(e, o *Expr) bool {
+	resolve := func(e *Expr) Expr {
+		if e.Typ == ET_Column && e.ColRef.table() == uint64(b.projectTag) {
+			e = b.projectExprs[e.ColRef.column()]
+		}
+		ret := *e
+		ret.Alias = ""
+		return ret
+	}
+	re, ro := resolve(e), resolve(o)
+	return re.equal(&ro)
+}
+
+// maxGroupingSets is the limit of the count of the grouping sets.
+const maxGroupingSets = 4096
+
+// bindGroupBy binds the GROUP BY list. GROUPING SETS, ROLLUP and CUBE
+// are expanded into the grouping sets. the items of the list are
+// combined by the cross product of their grouping sets.
+func (b *Builder) bindGroupBy(ctx *BindContext, clause []*pg_query.Node, depth int) error {
+	sets := []GroupingSet{make(GroupingSet)}
+	plain := true
+	for _, item := range clause {
+		if item.GetGroupingSet() != nil {
+			plain = false
+		}
+		itemSets, err := b.bindGroupingSets(ctx, item, depth)
+		if err != nil {
+			return err
+		}
+		product := make([]GroupingSet, 0, len(sets)*len(itemSets))
+		for _, set := range sets {
+			for _, itemSet := range itemSets {
+				product = append(product, unionGroupingSet(set, itemSet))
+			}
+		}
+		if len(product) > maxGroupingSets {
+			return util.NewSQLError(util.SQLStateProgramLimitExceeded,
+				"too many grouping sets present (maximum %d)", maxGroupingSets)
+		}
+		sets = product
+	}
+	if !plain && len(b.groupbyExprs) != 0 {
+		b.groupingSets = sets
+	}
+	return nil
+}
+
+// bindGroupingSets returns the grouping sets of the item in the GROUP BY list.
+func (b *Builder) bindGroupingSets(ctx *BindContext, item *pg_query.Node, depth int) ([]GroupingSet, error) {
+	gset := item.GetGroupingSet()
+	if gset == nil {
+		//the expr or the parenthesized list
+		set := make(GroupingSet)
+		exprs := []*pg_query.Node{item}
+		if row := item.GetRowExpr(); row != nil {
+			exprs = row.Args
+		}
+		for _, expr := range exprs {
+			idx, err := b.bindGroupExpr(ctx, expr, depth)
+			if err != nil {
+				return nil, err
+			}
+			set.insert(idx)
+		}
+		return []GroupingSet{set}, nil
+	}
+
+	switch gset.Kind {
+	case pg_query.GroupingSetKind_GROUPING_SET_EMPTY:
+		return []GroupingSet{make(GroupingSet)}, nil
+	case pg_query.GroupingSetKind_GROUPING_SET_SIMPLE:
+		set := make(GroupingSet)
+		for _, expr := range gset.Content {
+			idx, err := b.bindGroupExpr(ctx, expr, depth)
+			if err != nil {
+				return nil, err
+			}
+			set.insert(idx)
+		}
+		return []GroupingSet{set}, nil
+	case pg_query.GroupingSetKind_GROUPING_SET_SETS:
+		ret := make([]GroupingSet, 0)
+		for _, content := range gset.Content {
+			sets, err := b.bindGroupingSets(ctx, content, depth)
+			if err != nil {
+				return nil, err
+			}
+			ret = append(ret, sets...)
+		}
+		return ret, nil
+	case pg_query.GroupingSetKind_GROUPING_SET_ROLLUP, pg_query.GroupingSetKind_GROUPING_SET_CUBE:
+		elems := make([]GroupingSet, 0, len(gset.Content))
+		for _, content := range gset.Content {
+			sets, err := b.bindGroupingSets(ctx, content, depth)
+			if err != nil {
+				return nil, err
+			}
+			util.AssertFunc(len(sets) == 1)
+			elems = append(elems, sets[0])
+		}
+		ret := make([]GroupingSet, 0)
+		if gset.Kind == pg_query.GroupingSetKind_GROUPING_SET_ROLLUP {
+			//(a, b, c) => (a, b, c), (a, b), (a), ()
+			for n := len(elems); n >= 0; n-- {
+				set := make(GroupingSet)
+				for _, elem := range elems[:n] {
+					set = unionGroupingSet(set, elem)
+				}
+				ret = append(ret, set)
+			}
+			return ret, nil
+		}
+		//(a, b) => (a, b), (a), (b), ()
+		if len(elems) > 12 {
+			return nil, util.NewSQLError(util.SQLStateProgramLimitExceeded,
+				"CUBE is limited to 12 elements")
+		}
+		for mask := (1 << len(elems)) - 1; mask >= 0; mask-- {
+			set := make(GroupingSet)
+			for i, elem := range elems {
+				if mask&(1<<(len(elems)-1-i)) != 0 {
+					set = unionGroupingSet(set, elem)
+				}
+			}
+			ret = append(ret, set)
+		}
+		return ret, nil
+	default:
+		return nil, util.NewSQLError(util.SQLStateFeatureNotSupported,
+			"unsupport grouping set %v right now", gset.Kind)
+	}
+}
+
+// bindGroupExpr binds the expr in the GROUP BY list and
+// returns the index of it in the group by exprs.
+func (b *Builder) bindGroupExpr(ctx *BindContext, expr *pg_query.Node, depth int) (int, error) {
+	retExpr, err := b.bindExpr(ctx, IWC_GROUP, expr, depth)
+	if err != nil {
+		return 0, err
+	}
+	for i, group := range b.groupbyExprs {
+		if group.equal(retExpr) {
+			return i, nil
+		}
+	}
+	b.groupbyExprs = append(b.groupbyExprs, retExpr)
+	return len(b.groupbyExprs) - 1, nil
+}
+
+func unionGroupingSet(a, b GroupingSet) GroupingSet {
+	ret := make(GroupingSet)
+	for id := range a {
+		ret.insert(id)
+	}
+	for id := range b {
+		ret.insert(id)
+	}
+	return ret
+}
+
+// bindToGroup replaces the exprs in the group by list with
+// the references to the groups. the group absent in the grouping set is NULL.
+func (b *Builder) bindToGroup(e *Expr) *Expr {
+	if e == nil || b.groupingSets == nil {
+		return e
+	}
+	for i, group := range b.groupbyExprs {
+		if e.equal(group) {
+			return b.groupRef(group.DataTyp, group.String(), i)
+		}
+	}
+	for i, child := range e.Children {
+		e.Children[i] = b.bindToGroup(child)
+	}
+	return e
+}
+
+// groupRef returns the reference to the output of the aggregate node.
+// the groups are followed by the GROUPING functions.
+func (b *Builder) groupRef(typ common.LType, name string, idx int) *Expr {
+	return &Expr{
+		Typ:     ET_Column,
+		DataTyp: typ,
+		Table:   fmt.Sprintf("GroupNode_%v", b.groupTag),
+		Name:    name,
+		ColRef:  ColumnBind{uint64(b.groupTag), uint64(idx)},
+	}
+}
+
+// hasGroupingSets decides the aggregate node outputs the groups
+// that can be null or the GROUPING functions.
+func hasGroupingSets(root *LogicalOperator) bool {
+	return len(root.GroupingSets) > 0 || len(root.GroupingFuncs) > 0
+}
+
+// findCte returns the cte and the context it belongs to.
+func (b *Builder) findCte(name string, skip bool, ctx *BindContext) (*pg_query.CommonTableExpr, *BindContext) {
 	if val, has := ctx.ctes[name]; has {
 		if !skip {
-			return val
+			return val, ctx
 		}
 	}
 	if ctx.parent != nil {
 		return b.findCte(name, name == b.alias, ctx.parent)
 	}
-	return nil
+	return nil, nil
 }
 
 func (b *Builder) addCte(cte *pg_query.CommonTableExpr, ctx *BindContext) error {
@@ -595,7 +955,13 @@ func (b *Builder) addCte(cte *pg_query.CommonTableExpr, ctx *BindContext) error
 
 func (b *Builder) buildWith(with *pg_query.WithClause, ctx *BindContext, depth int) (*Expr, error) {
 	for _, cte := range with.Ctes {
-		err := b.addCte(cte.GetCommonTableExpr(), ctx)
+		cteAst := cte.GetCommonTableExpr()
+		//the parser does not mark the recursive cte
+		if with.Recursive &&
+			cteAst.Ctequery.GetSelectStmt().GetOp() == pg_query.SetOperation_SETOP_UNION {
+			cteAst.Cterecursive = true
+		}
+		err := b.addCte(cteAst, ctx)
 		if err != nil {
 			return nil, err
 		}
@@ -603,6 +969,278 @@ func (b *Builder) buildWith(with *pg_query.WithClause, ctx *BindContext, depth i
 	return nil, nil
 }
 
+// newCteBinding collects the columns of the cte from the select list.
+func newCteBinding(cte *pg_query.CommonTableExpr, index uint64, exprs []*Expr) (*Binding, error) {
+	if len(cte.Aliascolnames) > len(exprs) {
+		return nil, util.NewSQLError(util.SQLStateSyntaxError,
+			"query %s has %d columns available but %d columns specified",
+			cte.Ctename, len(exprs), len(cte.Aliascolnames))
+	}
+	bind := &Binding{
+		typ:     BT_CTE,
+		alias:   cte.Ctename,
+		index:   index,
+		nameMap: make(map[string]int),
+	}
+	for i, expr := range exprs {
+		name := expr.Name
+		if len(expr.Alias) != 0 {
+			name = expr.Alias
+		}
+		if i < len(cte.Aliascolnames) {
+			name = cte.Aliascolnames[i].GetString_().GetSval()
+		}
+		bind.typs = append(bind.typs, expr.DataTyp)
+		bind.names = append(bind.names, name)
+		bind.nameMap[name] = i
+	}
+	return bind, nil
+}
+
+// bindCteRef adds the binding of the reference to the cte.
+func (b *Builder) bindCteRef(cteBind *Binding, tableAst *pg_query.RangeVar, ctx *BindContext) (*Binding, error) {
+	alias := tableAst.Relname
+	if tableAst.Alias != nil {
+		alias = tableAst.Alias.Aliasname
+	}
+	bind := &Binding{
+		typ:     BT_CTE,
+		alias:   alias,
+		index:   uint64(b.GetTag()),
+		typs:    util.CopyTo(cteBind.typs),
+		names:   util.CopyTo(cteBind.names),
+		nameMap: make(map[string]int),
+	}
+	for idx, name := range bind.names {
+		bind.nameMap[name] = idx
+	}
+	err := ctx.AddBinding(alias, bind)
+	if err != nil {
+		return nil, err
+	}
+	return bind, nil
+}
+
+// shareCte decides whether the references to the cte share
+// the cte query. The cte in the outermost query is shared by default
+// as it can not refer the outer query. The shared cte query
+// is materialized if it is referenced more than once.
+func shareCte(cte *pg_query.CommonTableExpr, cteCtx *BindContext) bool {
+	switch cte.Ctematerialized {
+	case pg_query.CTEMaterialize_CTEMaterializeAlways:
+		return true
+	case pg_query.CTEMaterialize_CTEMaterializeNever:
+		return false
+	default:
+		return cteCtx.parent == nil
+	}
+}
+
+// inlineCte rewrites the reference to the cte into the subquery.
+// The subquery is named by the cte so that the references to the
+// cte with different aliases have different bindings.
+func inlineCte(cte *pg_query.CommonTableExpr, tableAst *pg_query.RangeVar) *pg_query.Node {
+	node := &pg_query.Node{
+		Node: &pg_query.Node_RangeSubselect{
+			RangeSubselect: &pg_query.RangeSubselect{
+				Alias: &pg_query.Alias{
+					Aliasname: cte.Ctename,
+					Colnames:  cte.Aliascolnames,
+				},
+				Subquery: cte.Ctequery,
+			},
+		},
+	}
+	if tableAst.Alias == nil || tableAst.Alias.Aliasname == cte.Ctename {
+		return node
+	}
+	//select * from (cte query) cte_name alias
+	return &pg_query.Node{
+		Node: &pg_query.Node_RangeSubselect{
+			RangeSubselect: &pg_query.RangeSubselect{
+				Alias: tableAst.Alias,
+				Subquery: &pg_query.Node{
+					Node: &pg_query.Node_SelectStmt{
+						SelectStmt: &pg_query.SelectStmt{
+							TargetList: []*pg_query.Node{
+								pg_query.MakeResTargetNodeWithVal(
+									pg_query.MakeColumnRefNode([]*pg_query.Node{pg_query.MakeAStarNode()}, -1),
+									-1,
+								),
+							},
+							FromClause: []*pg_query.Node{node},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// buildSharedCte binds the reference to the shared cte.
+// The cte query is bound at the first reference.
+func (b *Builder) buildSharedCte(cte *pg_query.CommonTableExpr, cteCtx *BindContext, tableAst *pg_query.RangeVar, ctx *BindContext) (*Expr, error) {
+	shared := cteCtx.sharedCtes[cte.Ctename]
+	if shared == nil {
+		//the tables in the query are invisible to the cte query
+		scope := NewBindContext(cteCtx.parent)
+		scope.ctes = cteCtx.ctes
+		scope.sharedCtes = cteCtx.sharedCtes
+
+		subBuilder := NewBuilder(b.txn)
+		subBuilder.tag = b.tag
+		subBuilder.rootCtx.parent = scope
+		subBuilder.alias = cte.Ctename
+		err := subBuilder.buildSelect(cte.Ctequery.GetSelectStmt(), subBuilder.rootCtx, 0)
+		if err != nil {
+			return nil, err
+		}
+		//the cte query runs only once. it can not be
+		//materialized if it refers the outer query.
+		if scope.referParent {
+			return nil, util.NewSQLError(util.SQLStateFeatureNotSupported,
+				"unsupport the materialized cte %s that refers the outer query right now", cte.Ctename)
+		}
+		cteBind, err := newCteBinding(cte, uint64(b.GetTag()), subBuilder.projectExprs)
+		if err != nil {
+			return nil, err
+		}
+		shared = &sharedCte{
+			bind: cteBind,
+			query: &Expr{
+				Typ:        ET_Subquery,
+				SubBuilder: subBuilder,
+				SubCtx:     subBuilder.rootCtx,
+			},
+			materialized: cte.Ctematerialized == pg_query.CTEMaterialize_CTEMaterializeAlways,
+		}
+		cteCtx.sharedCtes[cte.Ctename] = shared
+	}
+
+	bind, err := b.bindCteRef(shared.bind, tableAst, ctx)
+	if err != nil {
+		return nil, err
+	}
+	shared.bind.refs++
+	return &Expr{
+		Typ:         ET_CTE,
+		Index:       bind.index,
+		Table:       cte.Ctename,
+		Alias:       bind.alias,
+		BelongCtx:   ctx,
+		CTEIndex:    shared.bind.index,
+		Types:       bind.typs,
+		Names:       bind.names,
+		ColName2Idx: bind.nameMap,
+		SharedCte:   shared,
+	}, nil
+}
+
+// buildWorkTable binds the reference to the recursive cte
+// in the recursive term. It reads the work table.
+func (b *Builder) buildWorkTable(cteBind *Binding, tableAst *pg_query.RangeVar, ctx *BindContext) (*Expr, error) {
+	bind, err := b.bindCteRef(cteBind, tableAst, ctx)
+	if err != nil {
+		return nil, err
+	}
+	cteBind.refs++
+	return &Expr{
+		Typ:         ET_CTE,
+		Index:       bind.index,
+		Table:       tableAst.Relname,
+		Alias:       bind.alias,
+		BelongCtx:   ctx,
+		CTEIndex:    cteBind.index,
+		Types:       bind.typs,
+		Names:       bind.names,
+		ColName2Idx: bind.nameMap,
+	}, nil
+}
+
+// buildRecursiveCte binds the non-recursive term and the recursive term
+// of the recursive cte. The recursive term reads the rows produced by
+// the last iteration from the work table.
+func (b *Builder) buildRecursiveCte(cte *pg_query.CommonTableExpr, tableAst *pg_query.RangeVar, ctx *BindContext, depth int) (*Expr, error) {
+	sel := cte.Ctequery.GetSelectStmt()
+	if len(sel.SortClause) != 0 || sel.LimitCount != nil || sel.LimitOffset != nil {
+		return nil, util.NewSQLError(util.SQLStateFeatureNotSupported,
+			"unsupport ORDER BY or LIMIT in the recursive query %s right now", cte.Ctename)
+	}
+
+	//non-recursive term
+	anchor := NewBuilder(b.txn)
+	anchor.tag = b.tag
+	anchor.rootCtx.parent = ctx
+	anchor.alias = cte.Ctename
+	err := anchor.buildSelect(sel.Larg, anchor.rootCtx, 0)
+	if err != nil {
+		return nil, err
+	}
+	cteBind, err := newCteBinding(cte, uint64(b.GetTag()), anchor.projectExprs)
+	if err != nil {
+		return nil, err
+	}
+
+	//recursive term
+	recursive := NewBuilder(b.txn)
+	recursive.tag = b.tag
+	recursive.rootCtx.parent = ctx
+	recursive.rootCtx.cteBindings[cte.Ctename] = cteBind
+	err = recursive.buildSelect(sel.Rarg, recursive.rootCtx, 0)
+	if err != nil {
+		return nil, err
+	}
+	if cteBind.refs == 0 {
+		return nil, util.NewSQLError(util.SQLStateFeatureNotSupported,
+			"unsupport UNION without the recursive reference to %s right now", cte.Ctename)
+	}
+	if len(recursive.projectExprs) != len(anchor.projectExprs) {
+		return nil, util.NewSQLError(util.SQLStateSyntaxError,
+			"each UNION query must have the same number of columns")
+	}
+	for i, expr := range recursive.projectExprs {
+		if expr.DataTyp.Equal(cteBind.typs[i]) {
+			continue
+		}
+		castExpr, err := AddCastToType(expr, cteBind.typs[i], false)
+		if err != nil {
+			return nil, err
+		}
+		castExpr.Alias = expr.Alias
+		recursive.projectExprs[i] = castExpr
+	}
+
+	bind, err := b.bindCteRef(cteBind, tableAst, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Expr{
+		Typ:         ET_RecursiveCTE,
+		Index:       bind.index,
+		Table:       cte.Ctename,
+		Alias:       bind.alias,
+		BelongCtx:   ctx,
+		CTEIndex:    cteBind.index,
+		UnionAll:    sel.All,
+		Types:       bind.typs,
+		Names:       bind.names,
+		ColName2Idx: bind.nameMap,
+		Children: []*Expr{
+			{
+				Typ:        ET_Subquery,
+				SubBuilder: anchor,
+				SubCtx:     anchor.rootCtx,
+			},
+			{
+				Typ:        ET_Subquery,
+				SubBuilder: recursive,
+				SubCtx:     recursive.rootCtx,
+			},
+		},
+	}, nil
+}
+
 func (b *Builder) buildTable(table *pg_query.Node, ctx *BindContext, depth int) (*Expr, error) {
 	if table == nil {
 		panic("need table")
@@ -614,39 +1252,19 @@ func (b *Builder) buildTable(table *pg_query.Node, ctx *BindContext, depth int)
 		tableAst := rangeNode.RangeVar
 		db := tableAst.GetSchemaname()
 		tableName := tableAst.Relname
-		cte := b.findCte(tableName, tableName == b.alias, ctx)
+		cte, cteCtx := b.findCte(tableName, tableName == b.alias, ctx)
 		if cte != nil {
 			//find cte binding
 			cteBind := ctx.GetCteBinding(tableName)
 			if cteBind != nil {
-				panic("usp")
-				//index := b.GetTag()
-				//alias := tableName
-				//if len(table.Expr.Alias.alias) != 0 {
-				//	alias = table.Expr.Alias.alias
-				//}
-				//return &Expr{
-				//	Typ:       ET_CTE,
-				//	Index:     uint64(index),
-				//	Database:  db,
-				//	Table:     tableName,
-				//	Alias:     alias,
-				//	BelongCtx: ctx,
-				//	CTEIndex:  cteBind.index,
-				//}, nil
+				//reference to the work table in the recursive term
+				return b.buildWorkTable(cteBind, tableAst, ctx)
+			} else if cte.Cterecursive {
+				return b.buildRecursiveCte(cte, tableAst, ctx, depth)
+			} else if shareCte(cte, cteCtx) {
+				return b.buildSharedCte(cte, cteCtx, tableAst, ctx)
 			} else {
-				//TODO:refine it
-				nodeRangeSub := &pg_query.Node_RangeSubselect{}
-				nodeRangeSub.RangeSubselect = &pg_query.RangeSubselect{
-					Alias: &pg_query.Alias{
-						Aliasname: cte.Ctename,
-					},
-					Subquery: cte.Ctequery,
-				}
-				node := &pg_query.Node{
-					Node: nodeRangeSub,
-				}
-				return b.buildTable(node, ctx, depth)
+				return b.buildTable(inlineCte(cte, tableAst), ctx, depth)
 			}
 		}
 		{
@@ -655,7 +1273,7 @@ func (b *Builder) buildTable(table *pg_query.Node, ctx *BindContext, depth int)
 			}
 			tabEnt := storage.GCatalog.GetEntry(b.txn, storage.CatalogTypeTable, db, tableName)
 			if tabEnt == nil {
-				return nil, fmt.Errorf("no table %s in schema %s", tableName, db)
+				return nil, util.NewSQLError(util.SQLStateUndefinedTable, "no table %s in schema %s", tableName, db)
 			}
 			alias := tableName
 			if tableAst.Alias != nil {
@@ -728,65 +1346,85 @@ func (b *Builder) buildTable(table *pg_query.Node, ctx *BindContext, depth int)
 	case *pg_query.Node_JoinExpr:
 		return b.buildJoinTable(rangeNode.JoinExpr, ctx, depth)
 	case *pg_query.Node_RangeSubselect:
-		subqueryAst := rangeNode.RangeSubselect
-		subBuilder := NewBuilder(b.txn)
-		subBuilder.tag = b.tag
-		subBuilder.rootCtx.parent = ctx
-		if len(subqueryAst.Alias.Aliasname) == 0 {
-			return nil, errors.New("need alias for subquery")
-		}
-		subBuilder.alias = subqueryAst.Alias.Aliasname
-		err := subBuilder.buildSelect(subqueryAst.Subquery.GetSelectStmt(), subBuilder.rootCtx, 0)
-		if err != nil {
-			return nil, err
-		}
+		return b.buildSubqueryTable(rangeNode.RangeSubselect, ctx, ctx, depth)
+	default:
+		return nil, fmt.Errorf("usp table type %v", table.String())
+	}
+}
 
-		if len(subBuilder.projectExprs) == 0 {
-			panic("subquery must have project list")
-		}
-		subTypes := make([]common.LType, 0)
-		subNames := make([]string, 0)
-		for i, expr := range subBuilder.projectExprs {
-			subTypes = append(subTypes, expr.DataTyp)
-			name := expr.Name
-			if len(expr.Alias) != 0 {
-				name = expr.Alias
-			}
-			if i < len(subqueryAst.Alias.Colnames) {
-				name = subqueryAst.Alias.Colnames[i].GetString_().GetSval()
-			}
-			subNames = append(subNames, name)
-		}
+// buildSubqueryTable binds the subquery in the FROM.
+// the columns in the outerCtx are visible in the subquery.
+func (b *Builder) buildSubqueryTable(subqueryAst *pg_query.RangeSubselect, ctx, outerCtx *BindContext, depth int) (*Expr, error) {
+	subBuilder := NewBuilder(b.txn)
+	subBuilder.tag = b.tag
+	subBuilder.rootCtx.parent = outerCtx
+	if subqueryAst.Alias == nil || len(subqueryAst.Alias.Aliasname) == 0 {
+		return nil, errors.New("need alias for subquery")
+	}
+	subBuilder.alias = subqueryAst.Alias.Aliasname
+	err := subBuilder.buildSelect(subqueryAst.Subquery.GetSelectStmt(), subBuilder.rootCtx, 0)
+	if err != nil {
+		return nil, err
+	}
 
-		bind := &Binding{
-			typ:   BT_Subquery,
-			alias: subqueryAst.Alias.Aliasname,
-			//bind index of subquery is equal to the projectTag of subquery
-			index:   uint64(subBuilder.projectTag),
-			typs:    subTypes,
-			names:   subNames,
-			nameMap: make(map[string]int),
+	if len(subBuilder.projectExprs) == 0 {
+		panic("subquery must have project list")
+	}
+	subTypes := make([]common.LType, 0)
+	subNames := make([]string, 0)
+	for i, expr := range subBuilder.projectExprs {
+		subTypes = append(subTypes, expr.DataTyp)
+		name := expr.Name
+		if len(expr.Alias) != 0 {
+			name = expr.Alias
 		}
-		for idx, name := range bind.names {
-			bind.nameMap[name] = idx
-		}
-		err = ctx.AddBinding(bind.alias, bind)
-		if err != nil {
-			return nil, err
+		if i < len(subqueryAst.Alias.Colnames) {
+			name = subqueryAst.Alias.Colnames[i].GetString_().GetSval()
 		}
+		subNames = append(subNames, name)
+	}
 
-		return &Expr{
-			Typ:        ET_Subquery,
-			Index:      bind.index,
-			Database:   "",
-			Table:      bind.alias,
-			SubBuilder: subBuilder,
-			SubCtx:     subBuilder.rootCtx,
-			BelongCtx:  ctx,
-		}, err
-	default:
-		return nil, fmt.Errorf("usp table type %v", table.String())
+	bind := &Binding{
+		typ:   BT_Subquery,
+		alias: subqueryAst.Alias.Aliasname,
+		//bind index of subquery is equal to the projectTag of subquery
+		index:   uint64(subBuilder.projectTag),
+		typs:    subTypes,
+		names:   subNames,
+		nameMap: make(map[string]int),
+	}
+	for idx, name := range bind.names {
+		bind.nameMap[name] = idx
+	}
+	err = ctx.AddBinding(bind.alias, bind)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Expr{
+		Typ:        ET_Subquery,
+		Index:      bind.index,
+		Database:   "",
+		Table:      bind.alias,
+		SubBuilder: subBuilder,
+		SubCtx:     subBuilder.rootCtx,
+		BelongCtx:  ctx,
+	}, err
+}
+
+// buildRightTable binds the right side of the join.
+// the lateral subquery can refer the columns in the left side.
+func (b *Builder) buildRightTable(table *pg_query.Node, leftCtx, rightCtx *BindContext, depth int) (*Expr, error) {
+	sub := table.GetRangeSubselect()
+	if sub == nil || !sub.Lateral {
+		return b.buildTable(table, rightCtx, depth)
 	}
+	ret, err := b.buildSubqueryTable(sub, rightCtx, leftCtx, depth)
+	if err != nil {
+		return nil, err
+	}
+	ret.SubqueryTyp = ET_SubqueryTypeLateral
+	return ret, err
 }
 
 func (b *Builder) buildTables(tables []*pg_query.Node, ctx *BindContext, depth int) (*Expr, error) {
@@ -804,7 +1442,7 @@ func (b *Builder) buildTables(tables []*pg_query.Node, ctx *BindContext, depth i
 
 		rightCtx := NewBindContext(ctx)
 		//right
-		right, err := b.buildTable(tables[1], rightCtx, depth)
+		right, err := b.buildRightTable(tables[1], leftCtx, rightCtx, depth)
 		if err != nil {
 			return nil, err
 		}
@@ -828,7 +1466,7 @@ func (b *Builder) buildTables(tables []*pg_query.Node, ctx *BindContext, depth i
 
 		rightCtx := NewBindContext(ctx)
 		//right
-		right, err := b.buildTable(tables[nodeCnt-1], rightCtx, depth)
+		right, err := b.buildRightTable(tables[nodeCnt-1], leftCtx, rightCtx, depth)
 		if err != nil {
 			return nil, err
 		}
@@ -851,7 +1489,7 @@ func (b *Builder) mergeTwoTable(
 	ctx *BindContext, depth int) (*Expr, error) {
 
 	switch jt {
-	case ET_JoinTypeCross, ET_JoinTypeLeft, ET_JoinTypeInner:
+	case ET_JoinTypeCross, ET_JoinTypeLeft, ET_JoinTypeInner, ET_JoinTypeRight, ET_JoinTypeFull:
 	default:
 		return nil, fmt.Errorf("usp join type %d", jt)
 	}
@@ -887,7 +1525,7 @@ func (b *Builder) buildJoinTable(join *pg_query.JoinExpr, ctx *BindContext, dept
 
 	rightCtx := NewBindContext(ctx)
 	//right
-	right, err := b.buildTable(join.Rarg, rightCtx, depth)
+	right, err := b.buildRightTable(join.Rarg, leftCtx, rightCtx, depth)
 	if err != nil {
 		return nil, err
 	}
@@ -895,15 +1533,23 @@ func (b *Builder) buildJoinTable(join *pg_query.JoinExpr, ctx *BindContext, dept
 	var jt ET_JoinType
 	switch join.Jointype {
 	case pg_query.JoinType_JOIN_FULL:
-		jt = ET_JoinTypeCross
+		jt = ET_JoinTypeFull
 	case pg_query.JoinType_JOIN_LEFT:
 		jt = ET_JoinTypeLeft
+	case pg_query.JoinType_JOIN_RIGHT:
+		jt = ET_JoinTypeRight
 	case pg_query.JoinType_JOIN_INNER:
 		jt = ET_JoinTypeInner
 	default:
 		return nil, fmt.Errorf("usp join type %d", join.Jointype)
 	}
 
+	if right.SubqueryTyp == ET_SubqueryTypeLateral &&
+		(jt == ET_JoinTypeRight || jt == ET_JoinTypeFull) {
+		return nil, util.NewSQLError(util.SQLStateInvalidColumnReference,
+			"the join type must be INNER or LEFT for the lateral subquery %s", right.Table)
+	}
+
 	err = ctx.AddContext(leftCtx)
 	if err != nil {
 		return nil, err
@@ -914,9 +1560,17 @@ func (b *Builder) buildJoinTable(join *pg_query.JoinExpr, ctx *BindContext, dept
 		return nil, err
 	}
 
+	quals := join.Quals
+	if join.IsNatural || len(join.UsingClause) != 0 {
+		quals, err = b.buildUsing(join, jt, leftCtx, rightCtx, ctx)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	var onExpr *Expr
-	if join.Quals != nil {
-		onExpr, err = b.bindExpr(ctx, IWC_JOINON, join.Quals, depth)
+	if quals != nil {
+		onExpr, err = b.bindExpr(ctx, IWC_JOINON, quals, depth)
 		if err != nil {
 			return nil, err
 		}
@@ -933,6 +1587,104 @@ func (b *Builder) buildJoinTable(join *pg_query.JoinExpr, ctx *BindContext, dept
 	return ret, err
 }
 
+// buildUsing converts the USING or NATURAL into the equal conditions
+// and adds the merged columns into the ctx.
+func (b *Builder) buildUsing(join *pg_query.JoinExpr, jt ET_JoinType, leftCtx, rightCtx, ctx *BindContext) (*pg_query.Node, error) {
+	names := make([]string, 0)
+	if join.IsNatural {
+		rightNames := make(map[string]bool)
+		for _, name := range rightCtx.ColumnNames() {
+			rightNames[name] = true
+		}
+		for _, name := range leftCtx.ColumnNames() {
+			if rightNames[name] && !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	} else {
+		for _, node := range join.UsingClause {
+			name := node.GetString_().GetSval()
+			if slices.Contains(names, name) {
+				return nil, util.NewSQLError(util.SQLStateDuplicateColumn,
+					"column name %s appears more than once in USING clause", name)
+			}
+			names = append(names, name)
+		}
+	}
+
+	conds := make([]*pg_query.Node, 0)
+	for _, name := range names {
+		left, err := usingColumnNode(leftCtx, name, "left")
+		if err != nil {
+			return nil, err
+		}
+		right, err := usingColumnNode(rightCtx, name, "right")
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, pg_query.MakeAExprNode(
+			pg_query.A_Expr_Kind_AEXPR_OP,
+			[]*pg_query.Node{pg_query.MakeStrNode("=")},
+			left,
+			right,
+			-1))
+
+		//the value of the merged column comes from the side that is not null supplying
+		merged := left
+		switch jt {
+		case ET_JoinTypeRight:
+			merged = right
+		case ET_JoinTypeFull:
+			merged = &pg_query.Node{
+				Node: &pg_query.Node_CoalesceExpr{
+					CoalesceExpr: &pg_query.CoalesceExpr{
+						Args: []*pg_query.Node{left, right},
+					},
+				},
+			}
+		}
+		ctx.AddUsing(name, merged)
+	}
+
+	switch len(conds) {
+	case 0:
+		//natural join without common columns
+		return nil, nil
+	case 1:
+		return conds[0], nil
+	default:
+		return pg_query.MakeBoolExprNode(pg_query.BoolExprType_AND_EXPR, conds, -1), nil
+	}
+}
+
+// usingColumnNode returns the node that refers the column in one side of the join.
+func usingColumnNode(ctx *BindContext, column string, side string) (*pg_query.Node, error) {
+	if node := ctx.usings[column]; node != nil {
+		return node, nil
+	}
+	var found *Binding
+	for _, bind := range ctx.bindingsList {
+		if bind.HasColumn(column) < 0 {
+			continue
+		}
+		if found != nil {
+			return nil, util.NewSQLError(util.SQLStateAmbiguousColumn,
+				"common column name %s appears more than once in %s table", column, side)
+		}
+		found = bind
+	}
+	if found == nil {
+		return nil, util.NewSQLError(util.SQLStateUndefinedColumn,
+			"column %s specified in USING clause does not exist in %s table", column, side)
+	}
+	return pg_query.MakeColumnRefNode(
+		[]*pg_query.Node{
+			pg_query.MakeStrNode(found.alias),
+			pg_query.MakeStrNode(column),
+		},
+		-1), nil
+}
+
 //////////////////////////////////////////////
 // create plan
 //////////////////////////////////////////////
@@ -950,7 +1702,7 @@ func (b *Builder) CreatePlan(ctx *BindContext, root *LogicalOperator) (*LogicalO
 	}
 
 	//aggregates or group by
-	if len(b.aggs) > 0 || len(b.groupbyExprs) > 0 {
+	if len(b.aggs) > 0 || len(b.groupbyExprs) > 0 || len(b.groupingFuncs) > 0 {
 		root, err = b.createAggGroup(root)
 	}
 
@@ -964,6 +1716,11 @@ func (b *Builder) CreatePlan(ctx *BindContext, root *LogicalOperator) (*LogicalO
 		root, err = b.createProject(root)
 	}
 
+	//distinct
+	if b.distinct || len(b.distinctOnExprs) > 0 {
+		root, err = b.createDistinct(root)
+	}
+
 	//order bys
 	if len(b.orderbyExprs) > 0 {
 		root, err = b.createOrderby(root)
@@ -990,7 +1747,7 @@ func (b *Builder) createFrom(expr *Expr, root *LogicalOperator) (*LogicalOperato
 		{
 			tabEnt := storage.GCatalog.GetEntry(b.txn, storage.CatalogTypeTable, expr.Database, expr.Table)
 			if tabEnt == nil {
-				return nil, fmt.Errorf("no table %s in schema %s", expr.Database, expr.Table)
+				return nil, util.NewSQLError(util.SQLStateUndefinedTable, "no table %s in schema %s", expr.Database, expr.Table)
 			}
 			stats := convertStats(tabEnt.GetStats())
 			return &LogicalOperator{
@@ -1024,6 +1781,9 @@ func (b *Builder) createFrom(expr *Expr, root *LogicalOperator) (*LogicalOperato
 		if err != nil {
 			return nil, err
 		}
+		if expr.Children[1].SubqueryTyp == ET_SubqueryTypeLateral {
+			return b.createLateralJoin(expr, left)
+		}
 		right, err = b.createFrom(expr.Children[1], root)
 		if err != nil {
 			return nil, err
@@ -1034,6 +1794,10 @@ func (b *Builder) createFrom(expr *Expr, root *LogicalOperator) (*LogicalOperato
 			jt = LOT_JoinTypeInner
 		case ET_JoinTypeLeft:
 			jt = LOT_JoinTypeLeft
+		case ET_JoinTypeRight:
+			jt = LOT_JoinTypeRight
+		case ET_JoinTypeFull:
+			jt = LOT_JoinTypeOUTER
 		default:
 			panic(fmt.Sprintf("usp join type %d", jt))
 		}
@@ -1059,23 +1823,172 @@ func (b *Builder) createFrom(expr *Expr, root *LogicalOperator) (*LogicalOperato
 		return &LogicalOperator{
 			Typ:         LOT_Scan,
 			Index:       expr.Index,
-			Database:    expr.Database,
+			Database:    expr.Database,
+			Table:       expr.Table,
+			Alias:       expr.Alias,
+			BelongCtx:   expr.BelongCtx,
+			Stats:       &Stats{},
+			TableIndex:  int(expr.Index),
+			ScanTyp:     ScanTypeValuesList,
+			Types:       expr.Types,
+			Names:       expr.Names,
+			Values:      expr.Values,
+			ColName2Idx: expr.ColName2Idx,
+		}, err
+	case ET_CTE:
+		if expr.SharedCte != nil {
+			return b.createSharedCte(expr)
+		}
+		//is the work table of the recursive cte
+		return &LogicalOperator{
+			Typ:         LOT_Scan,
+			Index:       expr.Index,
 			Table:       expr.Table,
 			Alias:       expr.Alias,
 			BelongCtx:   expr.BelongCtx,
 			Stats:       &Stats{},
 			TableIndex:  int(expr.Index),
-			ScanTyp:     ScanTypeValuesList,
+			ScanTyp:     ScanTypeWorkTable,
+			CTEIndex:    expr.CTEIndex,
 			Types:       expr.Types,
 			Names:       expr.Names,
-			Values:      expr.Values,
 			ColName2Idx: expr.ColName2Idx,
 		}, err
+	case ET_RecursiveCTE:
+		return b.createRecursiveCte(expr)
 	default:
 		panic("usp")
 	}
 }
 
+// createSharedCte inlines the cte query referenced once.
+// Otherwise, the cte query is planned once and materialized.
+// The references scan the rows of it.
+func (b *Builder) createSharedCte(expr *Expr) (*LogicalOperator, error) {
+	shared := expr.SharedCte
+	query := shared.query
+	if !shared.materialized && shared.bind.refs == 1 {
+		subRoot, err := query.SubBuilder.CreatePlan(query.SubCtx, nil)
+		if err != nil {
+			return nil, err
+		}
+		projects := make([]*Expr, 0)
+		for i, typ := range expr.Types {
+			projects = append(projects, &Expr{
+				Typ:     ET_Column,
+				DataTyp: typ,
+				Table:   expr.Table,
+				Name:    expr.Names[i],
+				ColRef:  ColumnBind{uint64(query.SubBuilder.projectTag), uint64(i)},
+			})
+		}
+		return &LogicalOperator{
+			Typ:      LOT_Project,
+			Index:    expr.Index,
+			Projects: projects,
+			Children: []*LogicalOperator{subRoot},
+		}, nil
+	}
+
+	if shared.root == nil {
+		root, err := query.SubBuilder.CreatePlan(query.SubCtx, nil)
+		if err != nil {
+			return nil, err
+		}
+		shared.root, err = query.SubBuilder.Optimize(query.SubCtx, root)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &LogicalOperator{
+		Typ:         LOT_Scan,
+		Index:       expr.Index,
+		Table:       expr.Table,
+		Alias:       expr.Alias,
+		BelongCtx:   expr.BelongCtx,
+		Stats:       &Stats{},
+		TableIndex:  int(expr.Index),
+		ScanTyp:     ScanTypeCTE,
+		CTEIndex:    expr.CTEIndex,
+		Types:       expr.Types,
+		Names:       expr.Names,
+		ColName2Idx: expr.ColName2Idx,
+		CTERoot:     shared.root,
+	}, nil
+}
+
+// createRecursiveCte plans the non-recursive term and the recursive term.
+// They are optimized alone as the recursive cte is a leaf
+// in the outer query.
+func (b *Builder) createRecursiveCte(expr *Expr) (*LogicalOperator, error) {
+	children := make([]*LogicalOperator, 0)
+	for _, term := range expr.Children {
+		root, err := term.SubBuilder.CreatePlan(term.SubCtx, nil)
+		if err != nil {
+			return nil, err
+		}
+		root, err = term.SubBuilder.Optimize(term.SubCtx, root)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, root)
+	}
+	return &LogicalOperator{
+		Typ:         LOT_RecursiveCTE,
+		Index:       expr.Index,
+		Table:       expr.Table,
+		Alias:       expr.Alias,
+		BelongCtx:   expr.BelongCtx,
+		CTEIndex:    expr.CTEIndex,
+		UnionAll:    expr.UnionAll,
+		Types:       expr.Types,
+		Names:       expr.Names,
+		ColName2Idx: expr.ColName2Idx,
+		Children:    children,
+	}, nil
+}
+
+// createLateralJoin flattens the lateral subquery in the right side of the join.
+// the correlated filters in the subquery become the join conditions.
+func (b *Builder) createLateralJoin(expr *Expr, left *LogicalOperator) (*LogicalOperator, error) {
+	sub := expr.Children[1]
+	subRoot, err := sub.SubBuilder.CreatePlan(sub.SubCtx, nil)
+	if err != nil {
+		return nil, err
+	}
+	//the select list is evaluated below the join where
+	//the columns of the left side are invisible.
+	if len(findExpr(sub.SubBuilder.projectExprs, referOuterQuery)) != 0 {
+		return nil, util.NewSQLError(util.SQLStateFeatureNotSupported,
+			"unsupport the correlated column in the select list of the lateral subquery %s right now", sub.Table)
+	}
+
+	_, newRoot, err := b.apply(sub, left, subRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	//the join is under the filter that refers the outer query
+	join := newRoot
+	if join.Typ == LOT_Filter {
+		join = join.Children[0]
+	}
+	join.JoinTyp = LOT_JoinTypeInner
+	if expr.JoinTyp == ET_JoinTypeLeft {
+		join.JoinTyp = LOT_JoinTypeLeft
+		//the filter above the left join removes the rows
+		//that have no match. it must be the join condition.
+		if newRoot != join {
+			join.OnConds = append(join.OnConds, newRoot.Filters...)
+			newRoot = join
+		}
+	}
+	if expr.On != nil {
+		join.OnConds = append(join.OnConds, distributeExpr(expr.On.copy()))
+	}
+	return newRoot, err
+}
+
 func (b *Builder) createWhere(expr *Expr, root *LogicalOperator) (*LogicalOperator, error) {
 	var err error
 	var newFilter *Expr
@@ -1278,7 +2191,7 @@ func (b *Builder) createSubquery(expr *Expr, root *LogicalOperator) (*Expr, *Log
 		}
 	case ET_Column:
 		return expr, root, nil
-	case ET_IConst, ET_SConst, ET_DateConst, ET_IntervalConst, ET_FConst, ET_DecConst, ET_NConst:
+	case ET_IConst, ET_SConst, ET_DateConst, ET_IntervalConst, ET_FConst, ET_DecConst, ET_BConst, ET_NConst:
 		return expr, root, nil
 	default:
 		panic(fmt.Sprintf("usp %v", expr.Typ))
@@ -1364,6 +2277,16 @@ func (b *Builder) apply(expr *Expr, root, subRoot *LogicalOperator) (*Expr, *Log
 					root, newSub,
 				},
 			}
+		case ET_SubqueryTypeLateral:
+			newSub = &LogicalOperator{
+				Typ:     LOT_JOIN,
+				Index:   uint64(b.GetTag()),
+				JoinTyp: LOT_JoinTypeInner,
+				OnConds: nonCorrExprs,
+				Children: []*LogicalOperator{
+					root, newSub,
+				},
+			}
 		default:
 			panic("usp")
 		}
@@ -1379,6 +2302,9 @@ func (b *Builder) apply(expr *Expr, root, subRoot *LogicalOperator) (*Expr, *Log
 		}
 
 		switch expr.SubqueryTyp {
+		case ET_SubqueryTypeLateral:
+			//the columns of the subquery are referred by the binding
+			return nil, newSub, nil
 		case ET_SubqueryTypeScalar:
 			//TODO: may have multi columns
 			subBuilder := expr.SubBuilder
@@ -1411,7 +2337,7 @@ func (b *Builder) apply(expr *Expr, root, subRoot *LogicalOperator) (*Expr, *Log
 			newRoot = subRoot
 		} else {
 			switch expr.SubqueryTyp {
-			case ET_SubqueryTypeScalar:
+			case ET_SubqueryTypeScalar, ET_SubqueryTypeLateral:
 				newRoot = &LogicalOperator{
 					Typ:     LOT_JOIN,
 					Index:   uint64(b.GetTag()),
@@ -1547,12 +2473,14 @@ func (b *Builder) replaceCorrFiltersInAggr(subRoot *LogicalOperator, filter *Exp
 
 func (b *Builder) createAggGroup(root *LogicalOperator) (*LogicalOperator, error) {
 	return &LogicalOperator{
-		Typ:      LOT_AggGroup,
-		Index:    uint64(b.groupTag),
-		Index2:   uint64(b.aggTag),
-		Aggs:     b.aggs,
-		GroupBys: b.groupbyExprs,
-		Children: []*LogicalOperator{root},
+		Typ:           LOT_AggGroup,
+		Index:         uint64(b.groupTag),
+		Index2:        uint64(b.aggTag),
+		Aggs:          b.aggs,
+		GroupBys:      b.groupbyExprs,
+		GroupingSets:  b.groupingSets,
+		GroupingFuncs: b.groupingFuncs,
+		Children:      []*LogicalOperator{root},
 	}, nil
 }
 
@@ -1575,6 +2503,35 @@ func (b *Builder) createProject(root *LogicalOperator) (*LogicalOperator, error)
 	}, nil
 }
 
+// createDistinct creates the aggregate node without aggregate functions
+// on the select exprs or the DISTINCT ON exprs. for DISTINCT ON, the rows
+// are sorted by the ORDER BY before the aggregate node so that the first
+// row of each group is kept.
+func (b *Builder) createDistinct(root *LogicalOperator) (*LogicalOperator, error) {
+	var groups []*Expr
+	if b.distinct {
+		for i := range b.projectExprs {
+			groups = append(groups, b.bindToSelectList(nil, i, b.names[i]))
+		}
+	} else {
+		groups = copyExprs(b.distinctOnExprs...)
+		if len(b.orderbyExprs) > 0 {
+			root = &LogicalOperator{
+				Typ:      LOT_Order,
+				OrderBys: copyExprs(b.orderbyExprs...),
+				Children: []*LogicalOperator{root},
+			}
+		}
+	}
+	return &LogicalOperator{
+		Typ:      LOT_AggGroup,
+		Index:    uint64(b.GetTag()),
+		Index2:   uint64(b.GetTag()),
+		GroupBys: groups,
+		Children: []*LogicalOperator{root},
+	}, nil
+}
+
 func (b *Builder) createOrderby(root *LogicalOperator) (*LogicalOperator, error) {
 	return &LogicalOperator{
 		Typ:      LOT_Order,
@@ -1604,6 +2561,22 @@ func collectCorrFilter(root *LogicalOperator) []*Expr {
 	return ret
 }
 
+// referOuterQuery checks the expr refers the column of the outer query.
+func referOuterQuery(expr *Expr) bool {
+	if expr == nil {
+		return false
+	}
+	if expr.Typ == ET_Column {
+		return expr.Depth > 0
+	}
+	for _, child := range expr.Children {
+		if referOuterQuery(child) {
+			return true
+		}
+	}
+	return false
+}
+
 func hasCorrCol(expr *Expr) bool {
 	switch expr.Typ {
 	case ET_Column:
@@ -1630,6 +2603,9 @@ func (b *Builder) Optimize(ctx *BindContext, root *LogicalOperator) (*LogicalOpe
 
 	//fmt.Println("before optimize", root.String())
 
+	//0. rewrite the LIKE into the cheaper functions
+	rewriteLikes(root)
+
 	//1. pushdown filter
 	root, left, err = b.pushdownFilters(root, nil)
 	if err != nil {
@@ -1706,6 +2682,9 @@ func (b *Builder) pushdownFilters(root *LogicalOperator, filters []*Expr) (*Logi
 				left = append(left, f)
 			}
 		}
+	case LOT_RecursiveCTE:
+		//the terms of the recursive cte are optimized already.
+		left = filters
 	case LOT_JOIN:
 		needs = filters
 		leftTags := make(map[uint64]bool)
@@ -1713,8 +2692,18 @@ func (b *Builder) pushdownFilters(root *LogicalOperator, filters []*Expr) (*Logi
 		collectTags(root.Children[0], leftTags)
 		collectTags(root.Children[1], rightTags)
 
+		//the outer join is reduced when a filter in the WHERE
+		//rejects the NULL from the null-supplying side.
+		for _, f := range filters {
+			if isNullRejecting(f) {
+				root.JoinTyp = reduceOuterJoin(root.JoinTyp, decideSide(f, leftTags, rightTags))
+			}
+		}
+		nullSide := nullSupplyingSide(root.JoinTyp)
+
 		root.OnConds = splitExprsByAnd(root.OnConds)
-		if root.JoinTyp == LOT_JoinTypeInner || root.JoinTyp == LOT_JoinTypeLeft {
+		switch root.JoinTyp {
+		case LOT_JoinTypeInner, LOT_JoinTypeLeft, LOT_JoinTypeRight, LOT_JoinTypeOUTER:
 			for _, on := range root.OnConds {
 				needs = append(needs, splitExprByAnd(on)...)
 			}
@@ -1728,7 +2717,30 @@ func (b *Builder) pushdownFilters(root *LogicalOperator, filters []*Expr) (*Logi
 
 		leftNeeds := make([]*Expr, 0)
 		rightNeeds := make([]*Expr, 0)
+		//the conditions on both sides except the equality
+		others := make([]*Expr, 0)
 		for i, nd := range needs {
+			if i < len(filters) && whichSides[i]&nullSide != 0 && !isNullRejecting(nd) {
+				//the filter in the WHERE can not be pushed into the null-supplying side
+				//of the outer join when it may be true on the NULL. (e.g. IS NULL)
+				left = append(left, nd)
+				continue
+			}
+			if isTrueConst(nd) {
+				continue
+			}
+			if root.JoinTyp == LOT_JoinTypeRight || root.JoinTyp == LOT_JoinTypeOUTER {
+				if !pushdownOuterJoinCond(root, nd, whichSides[i], i < len(filters), &leftNeeds, &rightNeeds) {
+					left = append(left, nd)
+				}
+				continue
+			}
+			if root.JoinTyp == LOT_JoinTypeLeft && i >= len(filters) && whichSides[i] != RightSide {
+				//the condition in the ON of the left join can not remove
+				//the rows of the preserved side. the join evaluates it.
+				root.OnConds = append(root.OnConds, nd)
+				continue
+			}
 			switch whichSides[i] {
 			case NoneSide:
 				switch root.JoinTyp {
@@ -1752,12 +2764,27 @@ func (b *Builder) pushdownFilters(root *LogicalOperator, filters []*Expr) (*Logi
 						break
 					}
 				}
+				if root.JoinTyp == LOT_JoinTypeInner || root.JoinTyp == LOT_JoinTypeCross {
+					others = append(others, nd)
+					break
+				}
 				left = append(left, nd)
 			default:
 				panic(fmt.Sprintf("usp side %d", whichSides[i]))
 			}
 		}
 
+		if len(others) > 0 {
+			if len(root.OnConds) == 0 {
+				//the join without the equality evaluates the others
+				//instead of the filter above the cross product.
+				root.OnConds = others
+				root.JoinTyp = LOT_JoinTypeInner
+			} else {
+				left = append(left, others...)
+			}
+		}
+
 		childRoot, childLeft, err = b.pushdownFilters(root.Children[0], leftNeeds)
 		if err != nil {
 			return nil, nil, err
@@ -1789,6 +2816,10 @@ func (b *Builder) pushdownFilters(root *LogicalOperator, filters []*Expr) (*Logi
 			if referTo(f, root.Index2) {
 				//expr that refer to the agg exprs can not be pushdown.
 				root.Filters = append(root.Filters, f)
+			} else if hasGroupingSets(root) && referTo(f, root.Index) {
+				//the group by exprs are null in the rows of the
+				//grouping sets that do not include them.
+				root.Filters = append(root.Filters, f)
 			} else {
 				//restore the real expr for the expr that refer to the expr in the group by.
 				needs = append(needs, restoreExpr(f, root.Index, root.GroupBys))
@@ -2077,6 +3108,81 @@ func collectTags(root *LogicalOperator, set map[uint64]bool) {
 	}
 }
 
+// reduceOuterJoin returns the join type after the NULL
+// from the side is rejected above the join.
+func reduceOuterJoin(jt LOT_JoinType, side int) LOT_JoinType {
+	if side&RightSide != 0 {
+		//the unmatched rows from the left side are removed
+		switch jt {
+		case LOT_JoinTypeLeft:
+			jt = LOT_JoinTypeInner
+		case LOT_JoinTypeOUTER:
+			jt = LOT_JoinTypeRight
+		}
+	}
+	if side&LeftSide != 0 {
+		//the unmatched rows from the right side are removed
+		switch jt {
+		case LOT_JoinTypeRight:
+			jt = LOT_JoinTypeInner
+		case LOT_JoinTypeOUTER:
+			jt = LOT_JoinTypeLeft
+		}
+	}
+	return jt
+}
+
+// nullSupplyingSide returns the side that is padded with NULL
+// for the unmatched rows of the other side.
+func nullSupplyingSide(jt LOT_JoinType) int {
+	switch jt {
+	case LOT_JoinTypeLeft:
+		return RightSide
+	case LOT_JoinTypeRight:
+		return LeftSide
+	case LOT_JoinTypeOUTER:
+		return BothSide
+	default:
+		return NoneSide
+	}
+}
+
+// pushdownOuterJoinCond decides where the filter goes for the right or full join.
+// the condition in the ON can only be pushed into the null-supplying side.
+// the rest conditions in the ON are evaluated by the join.
+// it returns false when the filter stays above the join.
+func pushdownOuterJoinCond(root *LogicalOperator, nd *Expr, side int, inWhere bool, leftNeeds, rightNeeds *[]*Expr) bool {
+	nullSide := nullSupplyingSide(root.JoinTyp)
+	switch {
+	case inWhere:
+		//the filter on the preserved side
+		switch side {
+		case LeftSide:
+			*leftNeeds = append(*leftNeeds, nd)
+			return true
+		case RightSide:
+			*rightNeeds = append(*rightNeeds, nd)
+			return true
+		}
+		return false
+	case side != NoneSide && side&^nullSide == 0 && nullSide != BothSide:
+		//the condition on the null-supplying side
+		if nullSide == LeftSide {
+			*leftNeeds = append(*leftNeeds, nd)
+		} else {
+			*rightNeeds = append(*rightNeeds, nd)
+		}
+		return true
+	}
+	root.OnConds = append(root.OnConds, nd)
+	return true
+}
+
+// isTrueConst checks the expr is the constant true.
+func isTrueConst(e *Expr) bool {
+	return e.Typ == ET_BConst && e.Bvalue
+}
+
 const (
 	NoneSide       = 0
 	LeftSide       = 1 << 1
@@ -2139,6 +3245,11 @@ func (b *Builder) CreatePhyPlan(root *LogicalOperator) (*PhysicalOperator, error
 		if err != nil {
 			return nil, err
 		}
+	case LOT_RecursiveCTE:
+		proot, err = b.createPhyRecursiveCte(root, children)
+		if err != nil {
+			return nil, err
+		}
 	case LOT_CreateSchema:
 		proot, err = b.createPhyCreateSchema(root, children)
 		if err != nil {
@@ -2154,6 +3265,16 @@ func (b *Builder) CreatePhyPlan(root *LogicalOperator) (*PhysicalOperator, error
 		if err != nil {
 			return nil, err
 		}
+	case LOT_Vacuum:
+		proot, err = b.createPhyVacuum(root, children)
+		if err != nil {
+			return nil, err
+		}
+	case LOT_Export, LOT_Import:
+		proot, err = b.createPhyDatabase(root, children)
+		if err != nil {
+			return nil, err
+		}
 	default:
 		panic("usp")
 	}
@@ -2222,19 +3343,270 @@ func (b *Builder) createPhyScan(root *LogicalOperator, children []*PhysicalOpera
 	case ScanTypeTable:
 	case ScanTypeCopyFrom:
 		ret.ScanInfo = root.ScanInfo
+	case ScanTypeWorkTable:
+		ret.CTEIndex = root.CTEIndex
+		ret.collection = b.workTable(root.CTEIndex, root.Types)
+	case ScanTypeCTE:
+		var err error
+		ret.CTEIndex = root.CTEIndex
+		ret.cte, err = b.materializedCte(root)
+		if err != nil {
+			return nil, err
+		}
+		ret.collection = ret.cte._collection
 	}
 
 	return ret, nil
 }
 
-func (b *Builder) createPhyJoin(root *LogicalOperator, children []*PhysicalOperator) (*PhysicalOperator, error) {
+// materializedCte returns the materialized cte shared by
+// the scans on it.
+func (b *Builder) materializedCte(root *LogicalOperator) (*MaterializedCTE, error) {
+	if b.materializedCtes == nil {
+		b.materializedCtes = make(map[uint64]*MaterializedCTE)
+	}
+	if cte, has := b.materializedCtes[root.CTEIndex]; has {
+		return cte, nil
+	}
+	plan, err := b.CreatePhyPlan(root.CTERoot)
+	if err != nil {
+		return nil, err
+	}
+	cte := NewMaterializedCTE(plan, root.Types)
+	b.materializedCtes[root.CTEIndex] = cte
+	return cte, nil
+}
+
+func (b *Builder) createPhyRecursiveCte(root *LogicalOperator, children []*PhysicalOperator) (*PhysicalOperator, error) {
 	return &PhysicalOperator{
+		Typ:        POT_RecursiveCTE,
+		Index:      root.Index,
+		Table:      root.Table,
+		Alias:      root.Alias,
+		Outputs:    root.Outputs,
+		CTEIndex:   root.CTEIndex,
+		UnionAll:   root.UnionAll,
+		Types:      root.Types,
+		collection: b.workTable(root.CTEIndex, root.Types),
+		Children:   children,
+	}, nil
+}
+
+// workTable returns the work table of the recursive cte.
+// It is shared by the recursive cte and the scans on it.
+func (b *Builder) workTable(cteIndex uint64, typs []common.LType) *ColumnDataCollection {
+	if b.workTables == nil {
+		b.workTables = make(map[uint64]*ColumnDataCollection)
+	}
+	if wt, has := b.workTables[cteIndex]; has {
+		return wt
+	}
+	wt := NewColumnDataCollection(typs)
+	b.workTables[cteIndex] = wt
+	return wt
+}
+
+func (b *Builder) createPhyJoin(root *LogicalOperator, children []*PhysicalOperator) (*PhysicalOperator, error) {
+	proot := &PhysicalOperator{
 		Typ:      POT_Join,
 		Index:    root.Index,
 		JoinTyp:  root.JoinTyp,
 		OnConds:  root.OnConds,
 		Outputs:  root.Outputs,
-		Children: children}, nil
+		Children: children}
+	proot.JoinAlgo = chooseJoinAlgo(root.JoinTyp, root.OnConds)
+	if proot.JoinAlgo != JoinAlgoCross && canSortMerge(proot) {
+		//sorting is cheap for the ordered inputs.
+		//the hash table of the large build side does not fit in memory.
+		if orderedOnJoinKeys(proot) ||
+			root.Children[1].EstimatedCard(b.txn) >= b.sortMergeJoinRows {
+			proot.JoinAlgo = JoinAlgoSortMerge
+		}
+	}
+	return proot, nil
+}
+
+// chooseJoinAlgo decides the join algorithm by the shape of the join conditions.
+// the hash join for the equalities. the piecewise merge join for the inequality.
+// the nested loop join for the others.
+func chooseJoinAlgo(jt LOT_JoinType, conds []*Expr) JoinAlgo {
+	switch jt {
+	case LOT_JoinTypeSEMI, LOT_JoinTypeANTI, LOT_JoinTypeMARK, LOT_JoinTypeAntiMARK, LOT_JoinTypeSINGLE:
+		return JoinAlgoHash
+	}
+	if len(conds) == 0 {
+		if jt == LOT_JoinTypeCross || jt == LOT_JoinTypeInner {
+			return JoinAlgoCross
+		}
+		return JoinAlgoNestedLoop
+	}
+	hashable := true
+	for _, cond := range conds {
+		if !isMergeKey(cond) {
+			hashable = false
+		}
+	}
+	if hashable {
+		return JoinAlgoHash
+	}
+	if findRangeCond(conds) != -1 {
+		return JoinAlgoPiecewiseMerge
+	}
+	return JoinAlgoNestedLoop
+}
+
+// canSortMerge checks the join has the equalities as the merge keys
+// and the types of the inputs can be sorted.
+func canSortMerge(op *PhysicalOperator) bool {
+	switch op.JoinTyp {
+	case LOT_JoinTypeInner, LOT_JoinTypeLeft, LOT_JoinTypeSEMI, LOT_JoinTypeANTI:
+	default:
+		return false
+	}
+	keyCnt := 0
+	for _, cond := range op.OnConds {
+		if !isMergeKey(cond) {
+			continue
+		}
+		lTyp := cond.Children[0].DataTyp
+		rTyp := cond.Children[1].DataTyp
+		if lTyp.Id != rTyp.Id || !canRadixSort(lTyp) {
+			return false
+		}
+		keyCnt++
+	}
+	if keyCnt == 0 {
+		return false
+	}
+	for _, child := range op.Children {
+		for _, output := range child.Outputs {
+			if !canRadixSort(output.DataTyp) &&
+				output.DataTyp.GetInternalType() != common.DOUBLE {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// canRadixSort checks the type can be the key of the LocalSort.
+func canRadixSort(typ common.LType) bool {
+	switch typ.GetInternalType() {
+	case common.INT32, common.INT64, common.INT128, common.DECIMAL, common.DATE, common.VARCHAR:
+		return true
+	default:
+		return false
+	}
+}
+
+// isMergeKey checks the condition is the equality between
+// the left and right child.
+func isMergeKey(cond *Expr) bool {
+	return isJoinKey(cond) && (cond.SubTyp == ET_Equal || cond.SubTyp == ET_In)
+}
+
+// orderedOnJoinKeys checks both children are sorted
+// in ascending order on the first merge key.
+func orderedOnJoinKeys(op *PhysicalOperator) bool {
+	for _, cond := range op.OnConds {
+		if !isMergeKey(cond) {
+			continue
+		}
+		for i, child := range op.Children {
+			keyOp, key := exprOrigin(op, cond.Children[i])
+			if !orderedOn(child, keyOp, key) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// orderedOn checks the rows of the op are sorted in ascending order
+// on the key produced by the keyOp. the filter, project and limit
+// keep the order of the child. the inner sort-merge join outputs
+// the rows in the order of the first merge key.
+func orderedOn(op, keyOp *PhysicalOperator, key *Expr) bool {
+	switch op.Typ {
+	case POT_Order, POT_TopN:
+		if op.OrderBys[0].Desc {
+			return false
+		}
+		orderOp, order := exprOrigin(op, op.OrderBys[0].Children[0])
+		return orderOp == keyOp && order.equal(key)
+	case POT_Filter, POT_Project, POT_Limit:
+		return orderedOn(op.Children[0], keyOp, key)
+	case POT_Join:
+		if op.JoinAlgo != JoinAlgoSortMerge || op.JoinTyp != LOT_JoinTypeInner {
+			return false
+		}
+		for _, cond := range op.OnConds {
+			if !isMergeKey(cond) {
+				continue
+			}
+			for _, side := range cond.Children {
+				mergeOp, merge := exprOrigin(op, side)
+				if mergeOp == keyOp && merge.equal(key) {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return false
+}
+
+// exprOrigin follows the column reference down to the operator
+// that produces the column.
+func exprOrigin(op *PhysicalOperator, e *Expr) (*PhysicalOperator, *Expr) {
+	if e.Typ != ET_Column || e.Depth != 0 {
+		return op, e
+	}
+	col := e.ColRef.column()
+	switch SourceType(e.ColRef.table()) {
+	case LeftChild:
+		if len(op.Children) > 0 && col < uint64(len(op.Children[0].Outputs)) {
+			return exprOrigin(op.Children[0], op.Children[0].Outputs[col])
+		}
+	case RightChild:
+		if len(op.Children) > 1 && col < uint64(len(op.Children[1].Outputs)) {
+			return exprOrigin(op.Children[1], op.Children[1].Outputs[col])
+		}
+	case ThisNode:
+		if op.Typ == POT_Project && col < uint64(len(op.Projects)) {
+			return exprOrigin(op, op.Projects[col])
+		}
+	}
+	return op, e
+}
+
+// isJoinKey checks the operands of the comparison come from
+// the left and right child respectively.
+func isJoinKey(cond *Expr) bool {
+	if !isComparison(cond) {
+		return false
+	}
+	lset := make(ColumnBindSet)
+	rset := make(ColumnBindSet)
+	collectColRefs(cond.Children[0], lset)
+	collectColRefs(cond.Children[1], rset)
+	return lset.hasTableId(LeftChild) && !lset.hasTableId(RightChild) &&
+		rset.hasTableId(RightChild) && !rset.hasTableId(LeftChild)
+}
+
+// findRangeCond returns the first inequality that can be the sort key
+// of the piecewise merge join.
+func findRangeCond(conds []*Expr) int {
+	for i, cond := range conds {
+		switch cond.SubTyp {
+		case ET_Less, ET_LessEqual, ET_Greater, ET_GreaterEqual:
+			if isJoinKey(cond) {
+				return i
+			}
+		}
+	}
+	return -1
 }
 
 func (b *Builder) createPhyOrder(root *LogicalOperator, children []*PhysicalOperator) (*PhysicalOperator, error) {
@@ -2247,17 +3619,32 @@ func (b *Builder) createPhyOrder(root *LogicalOperator, children []*PhysicalOper
 
 func (b *Builder) createPhyAgg(root *LogicalOperator, children []*PhysicalOperator) (*PhysicalOperator, error) {
 	return &PhysicalOperator{
-		Typ:      POT_Agg,
-		Index:    root.Index,
-		Index2:   root.Index2,
-		Filters:  root.Filters,
-		Aggs:     root.Aggs,
-		GroupBys: root.GroupBys,
-		Outputs:  root.Outputs,
-		Children: children}, nil
+		Typ:           POT_Agg,
+		Index:         root.Index,
+		Index2:        root.Index2,
+		Filters:       root.Filters,
+		Aggs:          root.Aggs,
+		GroupBys:      root.GroupBys,
+		GroupingSets:  root.GroupingSets,
+		GroupingFuncs: root.GroupingFuncs,
+		Outputs:       root.Outputs,
+		Children:      children}, nil
 }
 
 func (b *Builder) createPhyLimit(root *LogicalOperator, children []*PhysicalOperator) (*PhysicalOperator, error) {
+	//the limit directly above the order is replaced by the TopN
+	//that only keeps the best limit+offset rows.
+	if root.Limit != nil && children[0].Typ == POT_Order {
+		order := children[0]
+		return &PhysicalOperator{
+			Typ:      POT_TopN,
+			Outputs:  root.Outputs,
+			Projects: order.Outputs,
+			OrderBys: order.OrderBys,
+			Limit:    root.Limit,
+			Offset:   root.Offset,
+			Children: order.Children}, nil
+	}
 	return &PhysicalOperator{
 		Typ:      POT_Limit,
 		Outputs:  root.Outputs,
@@ -2286,6 +3673,27 @@ func (b *Builder) createPhyCreateTable(root *LogicalOperator, children []*Physic
 	}, nil
 }
 
+func (b *Builder) createPhyVacuum(root *LogicalOperator, children []*PhysicalOperator) (*PhysicalOperator, error) {
+	return &PhysicalOperator{
+		Typ:      POT_Vacuum,
+		Database: root.Database,
+		Table:    root.Table,
+		Children: children,
+	}, nil
+}
+
+func (b *Builder) createPhyDatabase(root *LogicalOperator, children []*PhysicalOperator) (*PhysicalOperator, error) {
+	typ := POT_Export
+	if root.Typ == LOT_Import {
+		typ = POT_Import
+	}
+	return &PhysicalOperator{
+		Typ:      typ,
+		ScanInfo: root.ScanInfo,
+		Children: children,
+	}, nil
+}
+
 func (b *Builder) buildDDL(txn *storage.Txn, ddl *pg_query.RawStmt, ctx *BindContext, depth int) (*LogicalOperator, error) {
 	switch impl := ddl.GetStmt().GetNode().(type) {
 	case *pg_query.Node_CreateSchemaStmt:
@@ -2304,6 +3712,8 @@ func (b *Builder) buildDDL(txn *storage.Txn, ddl *pg_query.RawStmt, ctx *BindCon
 		return b.buildInsert(txn, impl.InsertStmt, ctx, depth)
 	case *pg_query.Node_CopyStmt:
 		return b.buildCopy(txn, impl.CopyStmt, ctx, depth)
+	case *pg_query.Node_VacuumStmt:
+		return b.buildVacuum(txn, impl.VacuumStmt, ctx, depth)
 	case *pg_query.Node_SelectStmt:
 		err := b.buildSelect(impl.SelectStmt, b.rootCtx, 0)
 		if err != nil {
@@ -2346,6 +3756,64 @@ func (b *Builder) buildCreateSchema(
 	}, nil
 }
 
+func (b *Builder) buildVacuum(
+	txn *storage.Txn,
+	stmt *pg_query.VacuumStmt,
+	ctx *BindContext,
+	depth int) (*LogicalOperator, error) {
+	if !stmt.GetIsVacuumcmd() {
+		return nil, fmt.Errorf("unsupport analyze right now")
+	}
+	if len(stmt.GetOptions()) != 0 {
+		return nil, fmt.Errorf("unsupport vacuum options right now")
+	}
+	ret := &LogicalOperator{
+		Typ: LOT_Vacuum,
+	}
+	//no table means all tables
+	rels := stmt.GetRels()
+	if len(rels) > 1 {
+		return nil, fmt.Errorf("unsupport vacuum multiple tables right now")
+	}
+	if len(rels) == 1 {
+		rel := rels[0].GetVacuumRelation()
+		if len(rel.GetVaCols()) != 0 {
+			return nil, fmt.Errorf("unsupport vacuum columns right now")
+		}
+		ret.Database = rel.GetRelation().GetSchemaname()
+		ret.Table = rel.GetRelation().GetRelname()
+	}
+	return ret, nil
+}
+
+func (b *Builder) buildDatabase(
+	txn *storage.Txn,
+	stmt *parser.DatabaseStmt,
+	ctx *BindContext,
+	depth int) (*LogicalOperator, error) {
+	ret := &LogicalOperator{
+		Typ: LOT_Export,
+		ScanInfo: &ScanInfo{
+			FilePath: stmt.Path,
+			Format:   stmt.Format,
+		},
+	}
+	if stmt.Kind == parser.DatabaseStmtImport {
+		ret.Typ = LOT_Import
+		if len(stmt.Format) != 0 {
+			return nil, fmt.Errorf("unsupport import options right now")
+		}
+		return ret, nil
+	}
+	if len(ret.ScanInfo.Format) == 0 {
+		ret.ScanInfo.Format = "csv"
+	}
+	if ret.ScanInfo.Format != "csv" {
+		return nil, fmt.Errorf("unsupport export format %s right now", ret.ScanInfo.Format)
+	}
+	return ret, nil
+}
+
 func (b *Builder) buildCreateTable(
 	txn *storage.Txn,
 	stmt *pg_query.CreateStmt,
@@ -2396,8 +3864,16 @@ func (b *Builder) buildCreateTable(
 				colDefExpr.Type = common.DecimalType(int(width), int(pres))
 			case "date":
 				colDefExpr.Type = common.DateType()
+			case "time":
+				colDefExpr.Type = common.TimeType()
+			case "timestamp":
+				colDefExpr.Type = common.TimestampType()
+			case "timestamptz":
+				colDefExpr.Type = common.TimestampTzType()
 			default:
-				panic("")
+				//interval has no storage format
+				return nil, util.NewSQLError(util.SQLStateFeatureNotSupported,
+					"unsupport the column type %s right now", typName)
 			}
 
 			//column constraint
@@ -2423,7 +3899,7 @@ func (b *Builder) buildCreateTable(
 			cons := nodeImpl.Constraint
 			pkNames := make([]string, 0)
 			switch cons.GetContype() {
-			case pg_query.ConstrType_CONSTR_PRIMARY:
+			case pg_query.ConstrType_CONSTR_PRIMARY, pg_query.ConstrType_CONSTR_UNIQUE:
 				for _, key := range cons.GetKeys() {
 					kname := key.GetString_().GetSval()
 					pkNames = append(pkNames, kname)
@@ -2431,7 +3907,8 @@ func (b *Builder) buildCreateTable(
 			default:
 				panic("usp")
 			}
-			tableCons = append(tableCons, storage.NewUniqueIndexConstraint2(pkNames, true))
+			tableCons = append(tableCons, storage.NewUniqueIndexConstraint2(pkNames,
+				cons.GetContype() == pg_query.ConstrType_CONSTR_PRIMARY))
 		default:
 			panic("usp")
 		}
@@ -2479,7 +3956,7 @@ func (b *Builder) buildInsertInternal(
 		name,
 	)
 	if tabEnt == nil {
-		return nil, fmt.Errorf("no table %s in schema '%s'",
+		return nil, util.NewSQLError(util.SQLStateUndefinedTable, "no table %s in schema '%s'",
 			name, schema)
 	}
 	insert := &LogicalOperator{
@@ -2511,12 +3988,12 @@ func (b *Builder) buildInsertInternal(
 		for i, col := range Cols {
 			colName := strings.ToLower(getNameFun(col))
 			if _, has := columnNameMap[colName]; has {
-				return nil, fmt.Errorf("duplicate column name %s in INSERT", colName)
+				return nil, util.NewSQLError(util.SQLStateDuplicateColumn, "duplicate column name %s in INSERT", colName)
 			}
 			columnNameMap[colName] = i
 			colIdx := tabEnt.GetColumnIndex(colName)
 			if colIdx == -1 {
-				return nil, fmt.Errorf("invalid column %s", colIdx)
+				return nil, util.NewSQLError(util.SQLStateUndefinedColumn, "invalid column %s", colName)
 			}
 			colDef := tabEnt.GetColumn(colIdx)
 			insert.ExpectedTypes = append(insert.ExpectedTypes, colDef.Type)
@@ -2782,7 +4259,7 @@ func (b *Builder) buildCopyFrom(
 	}
 	tabEnt := storage.GCatalog.GetEntry(txn, storage.CatalogTypeTable, schema, name)
 	if tabEnt == nil {
-		return nil, fmt.Errorf("no table %s in schema %s", name, schema)
+		return nil, util.NewSQLError(util.SQLStateUndefinedTable, "no table %s in schema %s", name, schema)
 	}
 	var expectedNames []string
 	if len(insert.ColumnIndexMap) != 0 {
@@ -17,180 +17,516 @@ package plan
 import (
 	"fmt"
 	"math"
+	"math/big"
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
+	"unsafe"
 
 	dec "github.com/govalues/decimal"
 
 	"github.com/daviszhen/plan/pkg/chunk"
 	"github.com/daviszhen/plan/pkg/common"
+	"github.com/daviszhen/plan/pkg/storage"
 	"github.com/daviszhen/plan/pkg/util"
 )
 
-func tryCastInt32ToInt32(input *int32, result *int32, _ bool) bool {
-	*result = int32(*input)
-	return true
+// castNumber is the go types of the numeric types except the
+// hugeint and the decimal.
+type castNumber interface {
+	int8 | int16 | int32 | int64 |
+		uint8 | uint16 | uint32 | uint64 |
+		float32 | float64
 }
 
-func tryCastInt32ToInt64(input *int32, result *int64, _ bool) bool {
-	*result = int64(*input)
-	return true
+func isFloat[T castNumber]() bool {
+	switch any(T(0)).(type) {
+	case float32, float64:
+		return true
+	}
+	return false
 }
 
-func tryCastInt32ToHugeint(input *int32, result *common.Hugeint, _ bool) bool {
-	result.Upper = 0
-	result.Lower = uint64(*input)
-	return true
+// integralRange returns the range [lo, hi) of the integral type.
+func integralRange[T castNumber]() (float64, float64) {
+	var zero T
+	bits := int(unsafe.Sizeof(zero)) * 8
+	if zero-1 < 0 {
+		return -math.Ldexp(1, bits-1), math.Ldexp(1, bits-1)
+	}
+	return 0, math.Ldexp(1, bits)
 }
 
-func tryCastInt32ToFloat32(input *int32, result *float32, _ bool) bool {
-	*result = float32(*input)
+// tryCastNumber fails on the overflow.
+// the float is rounded to the nearest integral.
+func tryCastNumber[T, R castNumber](input *T, result *R, _ bool) bool {
+	if isFloat[T]() {
+		f := float64(*input)
+		if isFloat[R]() {
+			r := R(f)
+			if !math.IsInf(f, 0) && math.IsInf(float64(r), 0) {
+				return false
+			}
+			*result = r
+			return true
+		}
+		f = math.RoundToEven(f)
+		lo, hi := integralRange[R]()
+		if math.IsNaN(f) || f < lo || f >= hi {
+			return false
+		}
+		*result = R(f)
+		return true
+	}
+	r := R(*input)
+	if !isFloat[R]() && (T(r) != *input || (*input < 0) != (r < 0)) {
+		return false
+	}
+	*result = r
 	return true
 }
 
-func tryCastInt32ToFloat64(input *int32, result *float64, _ bool) bool {
-	*result = float64(*input)
+func tryCastNumberToBool[T castNumber](input *T, result *bool, _ bool) bool {
+	*result = *input != 0
 	return true
 }
 
-func tryCastInt32ToDecimal(input *int32, result *common.Decimal, tScale int, _ bool) bool {
-	nDec, err := dec.NewFromInt64(int64(*input), 0, tScale)
-	if err != nil {
-		panic(err)
+func tryCastBoolToNumber[R castNumber](input *bool, result *R, _ bool) bool {
+	if *input {
+		*result = 1
+	} else {
+		*result = 0
 	}
+	return true
+}
 
-	*result = common.Decimal{
-		Decimal: nDec,
+func tryCastNumberToHugeint[T castNumber](input *T, result *common.Hugeint, strict bool) bool {
+	if isFloat[T]() {
+		f := math.RoundToEven(float64(*input))
+		if math.IsNaN(f) || math.IsInf(f, 0) || math.Abs(f) >= math.Ldexp(1, 127) {
+			return false
+		}
+		bf := new(big.Float).SetFloat64(f)
+		bi, _ := bf.Int(nil)
+		return bigToHugeint(bi, result)
+	}
+	if *input < 0 {
+		result.Upper = -1
+	} else {
+		result.Upper = 0
+	}
+	if _, ok := any(*input).(uint64); ok {
+		result.Lower = uint64(*input)
+	} else {
+		var v int64
+		tryCastNumber[T, int64](input, &v, strict)
+		result.Lower = uint64(v)
 	}
 	return true
 }
 
-func tryCastDecimalToFloat32(input *common.Decimal, result *float32, _ bool) bool {
-	v, ok := input.Float64()
-	util.AssertFunc(ok)
-	*result = float32(v)
-	return true
+func hugeintToBig(input *common.Hugeint) *big.Int {
+	ret := big.NewInt(input.Upper)
+	ret.Lsh(ret, 64)
+	return ret.Add(ret, new(big.Int).SetUint64(input.Lower))
 }
 
-func tryCastBigintToInt32(input *common.Hugeint, result *int32, _ bool) bool {
-	val := int32(input.Lower)
-	if uint64(val) != input.Lower {
-		fmt.Println(input)
+func bigToHugeint(input *big.Int, result *common.Hugeint) bool {
+	if input.BitLen() > 127 {
+		return false
 	}
-	*result = val
+	lower := new(big.Int).And(input, new(big.Int).SetUint64(math.MaxUint64))
+	upper := new(big.Int).Rsh(input, 64)
+	result.Upper = upper.Int64()
+	result.Lower = lower.Uint64()
 	return true
 }
 
-func tryCastBigintToFloat32(input *common.Hugeint, result *float32, _ bool) bool {
-	switch input.Upper {
-	case -1:
-		*result = -float32(math.MaxUint64-input.Lower) - 1
-	default:
-		*result = float32(input.Lower) +
-			float32(input.Upper)*float32(math.MaxUint64)
+func tryCastHugeintToNumber[R castNumber](input *common.Hugeint, result *R, strict bool) bool {
+	bi := hugeintToBig(input)
+	if isFloat[R]() {
+		f, _ := new(big.Float).SetInt(bi).Float64()
+		return tryCastNumber[float64, R](&f, result, strict)
+	}
+	if bi.IsInt64() {
+		v := bi.Int64()
+		return tryCastNumber[int64, R](&v, result, strict)
 	}
+	if bi.IsUint64() {
+		v := bi.Uint64()
+		return tryCastNumber[uint64, R](&v, result, strict)
+	}
+	return false
+}
+
+func tryCastHugeintToBool(input *common.Hugeint, result *bool, _ bool) bool {
+	*result = input.Upper != 0 || input.Lower != 0
 	return true
 }
 
-func tryCastBigintToDecimal(input *common.Hugeint, result *common.Decimal, _ bool) bool {
-	panic("usp")
+func tryCastHugeintToDecimal(input *common.Hugeint, result *common.Decimal, width, scale int) bool {
+	d, err := dec.Parse(hugeintToBig(input).String())
+	if err != nil {
+		return false
+	}
+	return fitDecimal(d, width, scale, result)
 }
 
-func tryCastDecimalToDecimal(input *common.Decimal, result *common.Decimal, srcScale, dstScale int, _ bool) bool {
-	//if numCast.srcScale == numCast.dstScale {
-	//	*result = *input
-	//} else if numCast.srcScale > numCast.dstScale {
-	//
-	//} else {
-	//
-	//}
-	w, f, ok := input.Int64(dstScale)
-	if ok {
-		ndec, err := dec.NewFromInt64(w, f, dstScale)
-		if err != nil {
-			panic(err)
+// roundDecimal rounds half away from zero like the postgres.
+func roundDecimal(d dec.Decimal, scale int) dec.Decimal {
+	if scale >= d.Scale() {
+		return d
+	}
+	ret := d.Trunc(scale)
+	rest, err := d.Sub(ret)
+	if err != nil {
+		return d.Round(scale)
+	}
+	half, err := dec.New(5, scale+1)
+	if err != nil {
+		return d.Round(scale)
+	}
+	if rest.Abs().Cmp(half) < 0 {
+		return ret
+	}
+	ulp, err := dec.New(1, scale)
+	if err != nil {
+		return d.Round(scale)
+	}
+	if d.IsNeg() {
+		ulp = ulp.Neg()
+	}
+	ret, err = ret.Add(ulp)
+	if err != nil {
+		return d.Round(scale)
+	}
+	return ret
+}
+
+// fitDecimal rounds the decimal to the scale and fails if the integral
+// digits are more than the width - scale.
+func fitDecimal(d dec.Decimal, width, scale int, result *common.Decimal) bool {
+	d = roundDecimal(d, scale)
+	if width > 0 && d.Prec()-d.Scale() > width-scale {
+		return false
+	}
+	if d.Scale() < scale {
+		d = d.Pad(scale)
+		if d.Scale() < scale {
+			return false
+		}
+	}
+	result.Decimal = d
+	return true
+}
+
+func tryCastNumberToDecimal[T castNumber](input *T, result *common.Decimal, width, scale int) bool {
+	var d dec.Decimal
+	var err error
+	if isFloat[T]() {
+		f := float64(*input)
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return false
 		}
-		result.Decimal = ndec
+		d, err = dec.NewFromFloat64(f)
+	} else if v, ok := any(*input).(uint64); ok {
+		d, err = dec.Parse(strconv.FormatUint(v, 10))
 	} else {
+		d, err = dec.New(int64(*input), 0)
+	}
+	if err != nil {
+		return false
+	}
+	return fitDecimal(d, width, scale, result)
+}
 
-		ndec, err := dec.ParseExact(input.String(), dstScale)
-		if err != nil {
-			panic(err)
+func tryCastDecimalToNumber[R castNumber](input *common.Decimal, result *R, strict bool) bool {
+	if isFloat[R]() {
+		f, ok := input.Float64()
+		if !ok {
+			return false
 		}
-		result.Decimal = ndec
+		return tryCastNumber[float64, R](&f, result, strict)
 	}
-	return true
+	w, _, ok := roundDecimal(input.Decimal, 0).Int64(0)
+	if !ok {
+		return false
+	}
+	return tryCastNumber[int64, R](&w, result, strict)
 }
 
-func tryCastFloat32ToInt32(input *float32, result *int32, _ bool) bool {
-	*result = int32(*input)
+func tryCastDecimalToBool(input *common.Decimal, result *bool, _ bool) bool {
+	*result = !input.IsZero()
 	return true
 }
 
-func tryCastFloat32ToFloat64(input *float32, result *float64, _ bool) bool {
-	*result = float64(*input)
-	return true
+func tryCastDecimalToDecimal(input *common.Decimal, result *common.Decimal, width, scale int) bool {
+	return fitDecimal(input.Decimal, width, scale, result)
+}
+
+func tryCastDecimalToHugeint(input *common.Decimal, result *common.Hugeint, _ bool) bool {
+	bi, ok := new(big.Int).SetString(roundDecimal(input.Decimal, 0).Trunc(0).String(), 10)
+	if !ok {
+		return false
+	}
+	return bigToHugeint(bi, result)
+}
+
+// string to others
+
+func tryCastVarcharToNumber[R castNumber](input *common.String, result *R, strict bool) bool {
+	s := strings.TrimSpace(input.String())
+	if isFloat[R]() {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return false
+		}
+		return tryCastNumber[float64, R](&f, result, strict)
+	}
+	if v, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return tryCastNumber[int64, R](&v, result, strict)
+	}
+	if v, err := strconv.ParseUint(s, 10, 64); err == nil {
+		return tryCastNumber[uint64, R](&v, result, strict)
+	}
+	return false
 }
 
-func tryCastFloat32ToDecimal(input *float32, result *common.Decimal, _ bool) bool {
-	res, err := dec.NewFromFloat64(float64(*input))
+func tryCastVarcharToHugeint(input *common.String, result *common.Hugeint, _ bool) bool {
+	bi, ok := new(big.Int).SetString(strings.TrimSpace(input.String()), 10)
+	if !ok {
+		return false
+	}
+	return bigToHugeint(bi, result)
+}
+
+func tryCastVarcharToDecimal(input *common.String, result *common.Decimal, width, scale int) bool {
+	d, err := dec.Parse(strings.TrimSpace(input.String()))
 	if err != nil {
-		panic(err)
+		return false
 	}
-	result.Decimal = res
-	return true
+	return fitDecimal(d, width, scale, result)
+}
+
+func tryCastVarcharToBool(input *common.String, result *bool, _ bool) bool {
+	var ok bool
+	*result, ok = common.ParseBool(input.String())
+	return ok
 }
 
 func tryCastVarcharToDate(input *common.String, result *common.Date, _ bool) bool {
-	ti, err := time.Parse(time.DateOnly, input.String())
-	if err != nil {
-		panic(err)
+	var ok bool
+	*result, ok = common.ParseDate(input.String())
+	return ok
+}
+
+func tryCastVarcharToTimestamp(input *common.String, result *int64, _ bool) bool {
+	var ok bool
+	*result, ok = common.ParseTimestamp(input.String(), false)
+	return ok
+}
+
+func tryCastVarcharToTimestampTz(input *common.String, result *int64, _ bool) bool {
+	var ok bool
+	*result, ok = common.ParseTimestamp(input.String(), true)
+	return ok
+}
+
+func tryCastVarcharToTime(input *common.String, result *int64, _ bool) bool {
+	var ok bool
+	*result, ok = common.ParseTime(input.String())
+	return ok
+}
+
+func tryCastVarcharToUuid(input *common.String, result *common.Hugeint, _ bool) bool {
+	var ok bool
+	*result, ok = common.ParseUuid(input.String())
+	return ok
+}
+
+func tryCastVarcharToBlob(input *common.String, result *common.String, _ bool) bool {
+	b, ok := common.ParseBlob(input.String())
+	if !ok {
+		return false
 	}
-	y, m, d := ti.Date()
-	*result = common.Date{
-		Year:  int32(y),
-		Month: int32(m),
-		Day:   int32(d),
+	newString(string(b), result)
+	return true
+}
+
+// tryCastVarcharToVarchar truncates the string to the width characters.
+func tryCastVarcharToVarchar(input *common.String, result *common.String, width int) bool {
+	s := input.String()
+	if utf8.RuneCountInString(s) <= width {
+		*result = *input
+		return true
 	}
+	cnt := 0
+	for i := range s {
+		if cnt == width {
+			s = s[:i]
+			break
+		}
+		cnt++
+	}
+	newString(s, result)
 	return true
 }
 
+// tryCastVarcharToInterval parses the postgres style interval like
+// '1 year 2 mons 3 days 04:05:06' or '2 hours 30 minutes'.
 func tryCastVarcharToInterval(input *common.String, result *common.Interval, _ bool) bool {
-	is := input.String()
-	seps := strings.Split(is, " ")
-	if len(seps) != 2 {
-		panic(fmt.Sprintf("invalid interval string %v", is))
-	}
-	switch strings.ToLower(seps[1]) {
-	case "year":
-		result.Unit = "year"
-		parseInt, err := strconv.ParseInt(seps[0], 10, 64)
-		if err != nil {
-			panic(fmt.Sprintf("invalid interval string %v", is))
+	seps := strings.Fields(input.String())
+	if len(seps) == 0 {
+		return false
+	}
+	*result = common.Interval{}
+	for i := 0; i < len(seps); i++ {
+		if strings.Contains(seps[i], ":") {
+			text, neg := strings.CutPrefix(seps[i], "-")
+			micros, ok := common.ParseTime(text)
+			if !ok {
+				return false
+			}
+			if neg {
+				micros = -micros
+			}
+			result.Micros += micros
+			continue
 		}
-		result.Year = int32(parseInt)
-	case "month":
-		result.Unit = "month"
-		parseInt, err := strconv.ParseInt(seps[0], 10, 64)
-		if err != nil {
-			panic(fmt.Sprintf("invalid interval string %v", is))
+		if i+1 >= len(seps) {
+			return false
 		}
-		result.Months = int32(parseInt)
-
-	case "day":
-		result.Unit = "day"
-		parseInt, err := strconv.ParseInt(seps[0], 10, 64)
+		parseInt, err := strconv.ParseInt(seps[i], 10, 32)
 		if err != nil {
-			panic(fmt.Sprintf("invalid interval string %v", is))
+			return false
+		}
+		i++
+		switch strings.TrimSuffix(strings.ToLower(seps[i]), "s") {
+		case "year":
+			result.Unit = "year"
+			result.Year += int32(parseInt)
+		case "month", "mon":
+			result.Unit = "month"
+			result.Months += int32(parseInt)
+		case "week":
+			result.Days += int32(parseInt) * 7
+		case "day":
+			result.Unit = "day"
+			result.Days += int32(parseInt)
+		case "hour":
+			result.Micros += parseInt * 3600 * common.MicrosPerSec
+		case "minute", "min":
+			result.Micros += parseInt * 60 * common.MicrosPerSec
+		case "second", "sec":
+			result.Micros += parseInt * common.MicrosPerSec
+		case "millisecond":
+			result.Micros += parseInt * 1000
+		case "microsecond":
+			result.Micros += parseInt
+		default:
+			return false
 		}
-		result.Days = int32(parseInt)
-	default:
-		panic(fmt.Sprintf("invalid interval unit %v", is))
 	}
 	return true
 }
 
+// others to string
+
+func newString(s string, result *common.String) {
+	if len(s) == 0 {
+		*result = common.String{}
+		return
+	}
+	result.Data = util.CMalloc(len(s))
+	result.Len = len(s)
+	copy(result.DataSlice(), s)
+}
+
+func tryCastToVarchar[T any](format func(*T) string) CastOp[T, common.String] {
+	return func(input *T, result *common.String, _ bool) bool {
+		newString(format(input), result)
+		return true
+	}
+}
+
+func formatNumber[T castNumber](input *T) string {
+	switch v := any(*input).(type) {
+	case float32:
+		return common.FormatFloat(float64(v), 32)
+	case float64:
+		return common.FormatFloat(v, 64)
+	case uint64:
+		return strconv.FormatUint(v, 10)
+	}
+	return strconv.FormatInt(int64(*input), 10)
+}
+
+func formatBool(input *bool) string {
+	return common.FormatBool(*input)
+}
+
+func formatDecimal(input *common.Decimal) string {
+	return input.String()
+}
+
+func formatHugeint(input *common.Hugeint) string {
+	return hugeintToBig(input).String()
+}
+
+func formatDate(input *common.Date) string {
+	return common.FormatDate(*input)
+}
+
+func formatTimestamp(input *int64) string {
+	return common.FormatTimestamp(*input, false)
+}
+
+func formatTimestampTz(input *int64) string {
+	return common.FormatTimestamp(*input, true)
+}
+
+func formatTime(input *int64) string {
+	return common.FormatTime(*input)
+}
+
+func formatInterval(input *common.Interval) string {
+	return common.FormatInterval(input)
+}
+
+func formatUuid(input *common.Hugeint) string {
+	return common.FormatUuid(*input)
+}
+
+func formatBlob(input *common.String) string {
+	return common.FormatBlob(input.DataSlice())
+}
+
+// date and time
+
+func tryCastDateToTimestamp(input *common.Date, result *int64, _ bool) bool {
+	*result = input.ToDate().UnixMicro()
+	return true
+}
+
+func tryCastTimestampToDate(input *int64, result *common.Date, _ bool) bool {
+	*result = common.DateFromTime(time.UnixMicro(*input).UTC())
+	return true
+}
+
+func tryCastTimestampToTime(input *int64, result *int64, _ bool) bool {
+	*result = *input % common.MicrosPerDay
+	if *result < 0 {
+		*result += common.MicrosPerDay
+	}
+	return true
+}
+
+func tryCastTimestampToTimestamp(input *int64, result *int64, _ bool) bool {
+	*result = *input
+	return true
+}
+
 func castExec(
 	source, result *chunk.Vector,
 	count int,
@@ -198,15 +534,29 @@ func castExec(
 	panic("usp")
 }
 
+// AddCastToType casts the expr to the dstTyp.
+// the try cast returns NULL instead of the error on the failure.
 func AddCastToType(expr *Expr, dstTyp common.LType, tryCast bool) (*Expr, error) {
-	var err error
-	var retExpr *Expr
 	if expr.DataTyp.Equal(dstTyp) {
 		return expr, nil
 	}
+	if expr.Typ == ET_NConst {
+		//NULL is the value of any type
+		return &Expr{
+			Typ:     ET_NConst,
+			DataTyp: dstTyp,
+		}, nil
+	}
 
 	castInfo := castFuncs.GetCastFunc(expr.DataTyp, dstTyp)
+	if castInfo == nil {
+		return nil, util.NewSQLError(util.SQLStateFeatureNotSupported,
+			"unsupport cast from %s to %s", sqlTypeName(expr.DataTyp), sqlTypeName(dstTyp))
+	}
+	return newCastExpr(expr, dstTyp, castInfo, tryCast), nil
+}
 
+func newCastExpr(expr *Expr, dstTyp common.LType, castInfo *BoundCastInfo, tryCast bool) *Expr {
 	args := []*Expr{
 		expr, //expr to be cast
 		//target type saved in DataTyp field
@@ -216,7 +566,7 @@ func AddCastToType(expr *Expr, dstTyp common.LType, tryCast bool) (*Expr, error)
 		},
 	}
 
-	retExpr = &Expr{
+	return &Expr{
 		Typ:        ET_Func,
 		SubTyp:     ET_SubFunc,
 		Svalue:     ET_Cast.String(),
@@ -230,10 +580,36 @@ func AddCastToType(expr *Expr, dstTyp common.LType, tryCast bool) (*Expr, error)
 			_retType:       dstTyp,
 			_funcTyp:       ScalarFuncType,
 			_boundCastInfo: castInfo,
+			_tryCast:       tryCast,
 		},
 	}
+}
 
-	return retExpr, err
+// sqlTypeName returns the type name in the error message.
+func sqlTypeName(typ common.LType) string {
+	name, err := storage.SQLTypeName(typ)
+	if err != nil {
+		return typ.String()
+	}
+	return name
+}
+
+// castValueString returns the text of the value in the error message.
+func castValueString[T any](input *T) string {
+	if str, ok := any(input).(fmt.Stringer); ok {
+		return str.String()
+	}
+	return fmt.Sprint(*input)
+}
+
+// castError is the error on the value that can not be cast.
+func castError(src, dst common.LType, val string) error {
+	if src.Id == common.LTID_VARCHAR {
+		return util.NewSQLError(util.SQLStateInvalidTextRepresentation,
+			"invalid input syntax for type %s: \"%s\"", sqlTypeName(dst), val)
+	}
+	return util.NewSQLError(util.SQLStateNumericValueOutOfRange,
+		"%s value %s out of range for type %s", sqlTypeName(src), val, sqlTypeName(dst))
 }
 
 //lint:ignore U1000
@@ -287,7 +663,7 @@ func (castSet *CastFunctionSet) GetCastFunc(src, dst common.LType) *BoundCastInf
 			return ret
 		}
 	}
-	panic("usp")
+	return nil
 }
 
 func NewCastFunctionSet() *CastFunctionSet {
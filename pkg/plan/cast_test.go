@@ -0,0 +1,116 @@
+// Copyright 2023-2024 daviszhen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/daviszhen/plan/pkg/chunk"
+	"github.com/daviszhen/plan/pkg/storage"
+	"github.com/daviszhen/plan/pkg/util"
+)
+
+// querySQL runs the query and returns the text of the rows.
+func querySQL(t *testing.T, txn *storage.Txn, sql string) ([][]string, error) {
+	return querySQLWithConfig(t, &util.Config{}, txn, sql)
+}
+
+func querySQLWithConfig(t *testing.T, cfg *util.Config, txn *storage.Txn, sql string) ([][]string, error) {
+	run, err := InitRunner(cfg, txn, sql)
+	if err != nil {
+		return nil, err
+	}
+	defer run.Close()
+	rows := make([][]string, 0)
+	for {
+		output := &chunk.Chunk{}
+		output.SetCap(util.DefaultVectorSize)
+		result, err := run.Execute(nil, output, run.state)
+		if err != nil {
+			return nil, err
+		}
+		if result == Done {
+			break
+		}
+		for i := 0; i < output.Card(); i++ {
+			row := make([]string, 0, output.ColumnCount())
+			for j := 0; j < output.ColumnCount(); j++ {
+				row = append(row, output.Data[j].GetValue(i).String())
+			}
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
+
+func Test_cast(t *testing.T) {
+	txn, err := storage.GTxnMgr.NewTxn("cast")
+	require.NoError(t, err)
+	defer storage.GTxnMgr.Rollback(txn)
+	execSQL(t, txn, "create table cast_t (i integer, s varchar, d decimal(10,2), dt date)")
+	execSQL(t, txn, "insert into cast_t values (1, '12', 3.45, '2024-01-02')")
+
+	kases := []struct {
+		expr string
+		want string
+	}{
+		{"i::bigint", "1"},
+		{"i::smallint", "1"},
+		{"i::float8", "1"},
+		{"i::boolean", "true"},
+		{"i::text", "1"},
+		{"s::integer", "12"},
+		{"s::varchar(1)", "1"},
+		{"d::integer", "3"},
+		{"d::numeric(5,1)", "3.5"},
+		{"d::text", "3.45"},
+		{"dt::timestamp", "2024-01-02 00:00:00"},
+		{"dt::text", "2024-01-02"},
+		{"'2024-01-02 03:04:05'::timestamp::date", "2024-01-02"},
+		{"'2024-01-02 03:04:05'::timestamp::time", "03:04:05"},
+		{"'2024-01-02 03:04:05+02'::timestamptz", "2024-01-02 01:04:05+00"},
+		{"'t'::boolean", "true"},
+		{"'1.5'::float8", "1.5"},
+		{"'3.456'::numeric(5,2)", "3.46"},
+		{"'a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11'::uuid", "a0eebc99-9c0b-4ef8-bb6d-6bb9bd380a11"},
+		{`'\x0102'::bytea`, `\x0102`},
+		{"'2 months'::interval", "2 mons"},
+		{"try_cast(s as integer)", "12"},
+		{"try_cast('x' as integer)", "NULL"},
+		{"try_cast(100000 as smallint)", "NULL"},
+	}
+	for _, kase := range kases {
+		rows, err := querySQL(t, txn, "select "+kase.expr+" from cast_t")
+		require.NoError(t, err, kase.expr)
+		require.Equal(t, [][]string{{kase.want}}, rows, kase.expr)
+	}
+
+	errKases := []struct {
+		expr string
+		code util.SQLState
+	}{
+		{"'x'::integer", util.SQLStateInvalidTextRepresentation},
+		{"100000::smallint", util.SQLStateNumericValueOutOfRange},
+		{"d::numeric(2,2)", util.SQLStateNumericValueOutOfRange},
+		{"dt::uuid", util.SQLStateFeatureNotSupported},
+	}
+	for _, kase := range errKases {
+		_, err = querySQL(t, txn, "select "+kase.expr+" from cast_t")
+		require.Error(t, err, kase.expr)
+		require.Equal(t, kase.code, util.GetSQLState(err), kase.expr)
+	}
+}
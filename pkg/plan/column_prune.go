@@ -383,7 +383,9 @@ func (cp *ColumnPrune) prune(root *LogicalOperator) (*LogicalOperator, error) {
 			root.Aggs = util.Erase(root.Aggs, removed[i])
 		}
 		cp.colRefs.replaceAll(cmap)
-		if len(root.Aggs) == 0 {
+		//the aggregate node without aggregate functions
+		//still removes the duplicate groups.
+		if len(root.Aggs) == 0 && len(root.GroupBys) == 0 {
 			return root.Children[0], nil
 		}
 		return root, nil
@@ -408,7 +410,7 @@ func (cp *ColumnPrune) prune(root *LogicalOperator) (*LogicalOperator, error) {
 			//	}
 			//	columns = catalogTable.Columns
 			//}
-		case ScanTypeValuesList:
+		case ScanTypeValuesList, ScanTypeWorkTable, ScanTypeCTE:
 			columns = root.Names
 		case ScanTypeCopyFrom:
 			columns = root.ScanInfo.Names
@@ -430,6 +432,9 @@ func (cp *ColumnPrune) prune(root *LogicalOperator) (*LogicalOperator, error) {
 
 		cp.colRefs.replaceAll(cmap)
 		return root, nil
+	case LOT_RecursiveCTE:
+		//the terms of the recursive cte are pruned already.
+		return root, nil
 	case LOT_Filter:
 		cp.colRefs.addExpr(root.Filters...)
 	default:
@@ -510,7 +515,10 @@ func (update *countsUpdater) generateCounts(root *LogicalOperator, upCounts Colu
 		resCounts.removeNotIn(upCounts)
 		for bind := range bSet {
 			counts.removeColumnBind(bind)
-			resCounts.removeColumnBind(bind)
+			//the colRef on this node is not from the children
+			if _, has := colRefOnThisNode[bind]; !has {
+				resCounts.removeColumnBind(bind)
+			}
 		}
 
 		root.Counts = resCounts
@@ -553,7 +561,7 @@ func (update *countsUpdater) generateCounts(root *LogicalOperator, upCounts Colu
 		if err != nil {
 			return nil, err
 		}
-	case LOT_Scan:
+	case LOT_Scan, LOT_RecursiveCTE:
 		resCounts = upCounts.copy()
 		resCounts.removeByTableIdx(root.Index, false)
 		resCounts.removeZeroCount()
@@ -774,10 +782,27 @@ func (update *outputsUpdater) generateOutputs(root *LogicalOperator) (*LogicalOp
 		replaceColRef3(root.Aggs, root.Children[0].ColRefToPos, LeftChild)
 		replaceColRef3(root.Filters, root.Children[0].ColRefToPos, LeftChild)
 
+		if hasGroupingSets(root) {
+			//the filters are evaluated on the groups, aggs and
+			//the GROUPING functions.
+			for _, f := range root.Filters {
+				replaceGroupRefs(f, root)
+			}
+		}
+
 		binds := root.ColRefToPos.sortByColumnBind()
 		for _, bind := range binds {
-			colIdx := len(root.Outputs)
 			if bind.table() == root.Index {
+				if bind.column() >= uint64(len(root.GroupBys)) {
+					//GROUPING function
+					root.Outputs = append(root.Outputs, &Expr{
+						Typ:     ET_Column,
+						DataTyp: common.BigintType(),
+						Name:    "grouping",
+						ColRef:  ColumnBind{uint64(ThisNode), groupRefPos(root, bind)},
+					})
+					continue
+				}
 				groupby := root.GroupBys[bind.column()]
 				root.Outputs = append(root.Outputs, &Expr{
 					Typ:      ET_Column,
@@ -785,16 +810,14 @@ func (update *outputsUpdater) generateOutputs(root *LogicalOperator) (*LogicalOp
 					Database: groupby.Database,
 					Table:    groupby.Table,
 					Name:     groupby.Name,
-					ColRef:   ColumnBind{uint64(ThisNode), uint64(colIdx)},
+					ColRef:   ColumnBind{uint64(ThisNode), bind.column()},
 				})
 				continue
 			}
 		}
-		rIdx := 0
 		for _, bind := range binds {
 			if bind.table() == root.Index2 {
-				colIdx := len(root.GroupBys) + rIdx
-				rIdx++
+				colIdx := uint64(len(root.GroupBys)) + bind.column()
 				agg := root.Aggs[bind.column()]
 				root.Outputs = append(root.Outputs, &Expr{
 					Typ:      ET_Column,
@@ -802,7 +825,7 @@ func (update *outputsUpdater) generateOutputs(root *LogicalOperator) (*LogicalOp
 					Database: agg.Database,
 					Table:    agg.Table,
 					Name:     agg.Name,
-					ColRef:   ColumnBind{uint64(ThisNode), uint64(colIdx)},
+					ColRef:   ColumnBind{uint64(ThisNode), colIdx},
 				})
 				continue
 			}
@@ -847,39 +870,18 @@ func (update *outputsUpdater) generateOutputs(root *LogicalOperator) (*LogicalOp
 		replaceColRef3(root.OnConds, root.Children[1].ColRefToPos, RightChild)
 
 		//switch onConds left & right
-		for _, cond := range root.OnConds {
+		for i, cond := range root.OnConds {
+			if !isComparison(cond) {
+				//evaluated as a whole by the join
+				continue
+			}
 			lset := make(ColumnBindSet)
 			rset := make(ColumnBindSet)
-			switch cond.Typ {
-			case ET_Func:
-				switch cond.SubTyp {
-				case ET_In, ET_NotIn:
-					collectColRefs(cond.Children[0], lset)
-					collectColRefs(cond.Children[1], rset)
-				case ET_SubFunc:
-				case ET_And, ET_Or, ET_Equal, ET_NotEqual, ET_Like, ET_NotLike, ET_GreaterEqual, ET_Less, ET_Greater:
-					collectColRefs(cond.Children[0], lset)
-					collectColRefs(cond.Children[1], rset)
-				default:
-					panic(fmt.Sprintf("usp %v", cond.SubTyp))
-				}
-			default:
-				panic(fmt.Sprintf("usp operator type %d", cond.Typ))
-			}
-
-			lLeftYes := lset.hasTableId(LeftChild)
-			lRightYes := lset.hasTableId(RightChild)
-			rLeftYes := rset.hasTableId(LeftChild)
-			rRightYes := rset.hasTableId(RightChild)
-			if lLeftYes && lRightYes {
-				panic("left child has two data source")
-			}
-			if rLeftYes && rRightYes {
-				panic("right child has two data source")
-			}
-			if lRightYes && rLeftYes {
-				//switch
-				cond.Children[0], cond.Children[1] = cond.Children[1], cond.Children[0]
+			collectColRefs(cond.Children[0], lset)
+			collectColRefs(cond.Children[1], rset)
+			if lset.hasTableId(RightChild) && !lset.hasTableId(LeftChild) &&
+				rset.hasTableId(LeftChild) && !rset.hasTableId(RightChild) {
+				root.OnConds[i] = commuteComparison(cond)
 			}
 		}
 
@@ -954,7 +956,7 @@ func (update *outputsUpdater) generateOutputs(root *LogicalOperator) (*LogicalOp
 				//column2Idx = catalogTable.Column2Idx
 				//columnTyps = catalogTable.Types
 			}
-		case ScanTypeValuesList:
+		case ScanTypeValuesList, ScanTypeWorkTable, ScanTypeCTE:
 			column2Idx = root.ColName2Idx
 			columnTyps = root.Types
 		case ScanTypeCopyFrom:
@@ -981,6 +983,21 @@ func (update *outputsUpdater) generateOutputs(root *LogicalOperator) (*LogicalOp
 		}
 		root.Outputs = outputs
 
+	case LOT_RecursiveCTE:
+		binds := root.ColRefToPos.sortByColumnBind()
+		outputs := make([]*Expr, 0)
+		for _, bind := range binds {
+			e := &Expr{
+				Typ:     ET_Column,
+				DataTyp: root.Types[bind.column()],
+				Table:   root.Table,
+				Name:    root.Names[bind.column()],
+				ColRef:  ColumnBind{uint64(ThisNode), bind.column()},
+			}
+			outputs = append(outputs, e)
+		}
+		root.Outputs = outputs
+
 	case LOT_Filter:
 		err = genChildren()
 		if err != nil {
@@ -1022,3 +1039,29 @@ func (update *outputsUpdater) generateOutputs(root *LogicalOperator) (*LogicalOp
 	checkColRefPosInNode(root)
 	return root, nil
 }
+
+// groupRefPos returns the position of the group, agg or GROUPING function
+// in the output of the aggregate node with grouping sets.
+func groupRefPos(root *LogicalOperator, bind ColumnBind) uint64 {
+	nGroups := uint64(len(root.GroupBys))
+	if bind.table() == root.Index2 {
+		return nGroups + bind.column()
+	}
+	if bind.column() < nGroups {
+		return bind.column()
+	}
+	return nGroups + uint64(len(root.Aggs)) + bind.column() - nGroups
+}
+
+func replaceGroupRefs(e *Expr, root *LogicalOperator) {
+	if e == nil {
+		return
+	}
+	if e.Typ == ET_Column &&
+		(e.ColRef.table() == root.Index || e.ColRef.table() == root.Index2) {
+		e.ColRef = ColumnBind{uint64(ThisNode), groupRefPos(root, e.ColRef)}
+	}
+	for _, child := range e.Children {
+		replaceGroupRefs(child, root)
+	}
+}
@@ -522,6 +522,24 @@ func (copy *varcharValueCopy) Operation(dst, src *common.String) {
 	dst.Len = src.Length()
 }
 
+// fixedValueCopy copies the value of the fixed size type.
+type fixedValueCopy[T any] struct {
+}
+
+func (copy *fixedValueCopy[T]) Assign(
+	metaData *ColumnDataMetaData,
+	dst, src unsafe.Pointer,
+	dstIdx, srcIdx int) {
+	size := int(unsafe.Sizeof(*new(T)))
+	dPtr := util.PointerAdd(dst, dstIdx*size)
+	sPtr := util.PointerAdd(src, srcIdx*size)
+	copy.Operation((*T)(dPtr), (*T)(sPtr))
+}
+
+func (copy *fixedValueCopy[T]) Operation(dst, src *T) {
+	*dst = *src
+}
+
 func ColumnDataCopySwitch(
 	metaData *ColumnDataMetaData,
 	srcData *chunk.UnifiedFormat,
@@ -593,6 +611,51 @@ func ColumnDataCopySwitch(
 			count,
 			&hugeintValueCopy{},
 		)
+	case common.BOOL:
+		TemplatedColumnDataCopy[bool](
+			metaData,
+			srcData,
+			src,
+			offset,
+			count,
+			&fixedValueCopy[bool]{},
+		)
+	case common.INT8:
+		TemplatedColumnDataCopy[int8](
+			metaData,
+			srcData,
+			src,
+			offset,
+			count,
+			&fixedValueCopy[int8]{},
+		)
+	case common.INT16:
+		TemplatedColumnDataCopy[int16](
+			metaData,
+			srcData,
+			src,
+			offset,
+			count,
+			&fixedValueCopy[int16]{},
+		)
+	case common.DOUBLE:
+		TemplatedColumnDataCopy[float64](
+			metaData,
+			srcData,
+			src,
+			offset,
+			count,
+			&fixedValueCopy[float64]{},
+		)
+	case common.INTERVAL:
+		TemplatedColumnDataCopy[common.Interval](
+			metaData,
+			srcData,
+			src,
+			offset,
+			count,
+			&fixedValueCopy[common.Interval]{},
+		)
 	default:
 		panic("usp")
 	}
@@ -0,0 +1,557 @@
+// Copyright 2023-2024 daviszhen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/daviszhen/plan/pkg/common"
+	"github.com/daviszhen/plan/pkg/util"
+)
+
+// the date and time functions.
+// the timestamps are the micros since the epoch in UTC.
+
+const (
+	microsPerMinute = 60 * common.MicrosPerSec
+	microsPerHour   = 60 * microsPerMinute
+)
+
+func microsToTime(micros int64) time.Time {
+	return time.UnixMicro(micros).UTC()
+}
+
+func dateToTime(d *common.Date) time.Time {
+	return d.ToDate()
+}
+
+func timeToDate(t time.Time) common.Date {
+	y, m, d := t.Date()
+	return common.Date{
+		Year:  int32(y),
+		Month: int32(m),
+		Day:   int32(d),
+	}
+}
+
+func unitNotRecognized(unit string, typ string) error {
+	return util.NewSQLError(util.SQLStateInvalidParameterValue,
+		"unit \"%s\" not recognized for type %s", unit, typ)
+}
+
+// date_part
+
+// timestampPart extracts the field of the timestamp like the postgres.
+func timestampPart(unit string, t time.Time, typ string) float64 {
+	switch strings.ToLower(unit) {
+	case "millennium", "millenniums":
+		return float64((t.Year() + 999) / 1000)
+	case "century", "centuries":
+		return float64((t.Year() + 99) / 100)
+	case "decade", "decades":
+		return float64(t.Year() / 10)
+	case "year", "years":
+		return float64(t.Year())
+	case "quarter":
+		return float64((int(t.Month())-1)/3 + 1)
+	case "month", "months", "mon":
+		return float64(t.Month())
+	case "week", "weeks":
+		_, week := t.ISOWeek()
+		return float64(week)
+	case "day", "days":
+		return float64(t.Day())
+	case "dow":
+		return float64(t.Weekday())
+	case "isodow":
+		if t.Weekday() == time.Sunday {
+			return 7
+		}
+		return float64(t.Weekday())
+	case "doy":
+		return float64(t.YearDay())
+	case "epoch":
+		return float64(t.UnixMicro()) / float64(common.MicrosPerSec)
+	default:
+		return timePart(unit, timeOfDay(t), typ)
+	}
+}
+
+func timeOfDay(t time.Time) int64 {
+	return int64(t.Hour())*microsPerHour +
+		int64(t.Minute())*microsPerMinute +
+		int64(t.Second())*common.MicrosPerSec +
+		int64(t.Nanosecond()/1000)
+}
+
+// timePart extracts the field of the micros since the midnight.
+func timePart(unit string, micros int64, typ string) float64 {
+	switch strings.ToLower(unit) {
+	case "hour", "hours":
+		return float64(micros / microsPerHour)
+	case "minute", "minutes", "min":
+		return float64(micros % microsPerHour / microsPerMinute)
+	case "second", "seconds", "sec":
+		return float64(micros%microsPerMinute) / float64(common.MicrosPerSec)
+	case "millisecond", "milliseconds":
+		return float64(micros%microsPerMinute) / 1000
+	case "microsecond", "microseconds":
+		return float64(micros % microsPerMinute)
+	case "epoch":
+		if typ == "time" {
+			return float64(micros) / float64(common.MicrosPerSec)
+		}
+		fallthrough
+	default:
+		panic(unitNotRecognized(unit, typ))
+	}
+}
+
+// intervalPart extracts the field of the interval.
+func intervalPart(unit string, i *common.Interval) float64 {
+	months := int64(i.Year)*12 + int64(i.Months)
+	switch strings.ToLower(unit) {
+	case "year", "years":
+		return float64(months / 12)
+	case "month", "months", "mon":
+		return float64(months % 12)
+	case "day", "days":
+		return float64(i.Days)
+	case "epoch":
+		secs := float64(months/12)*365.25*86400 +
+			float64(months%12)*30*86400 +
+			float64(i.Days)*86400
+		return secs + float64(i.Micros)/float64(common.MicrosPerSec)
+	default:
+		return timePart(unit, i.Micros, "interval")
+	}
+}
+
+func datePartDateOp(unit *common.String, d *common.Date, result *float64) {
+	*result = timestampPart(unit.String(), dateToTime(d), "date")
+}
+
+func datePartTimestampOp(unit *common.String, ts *int64, result *float64) {
+	*result = timestampPart(unit.String(), microsToTime(*ts), "timestamp")
+}
+
+func datePartTimeOp(unit *common.String, t *int64, result *float64) {
+	*result = timePart(unit.String(), *t, "time")
+}
+
+func datePartIntervalOp(unit *common.String, i *common.Interval, result *float64) {
+	*result = intervalPart(unit.String(), i)
+}
+
+// date_trunc
+
+// truncTimestamp truncates the timestamp to the precision of the unit.
+func truncTimestamp(unit string, t time.Time) time.Time {
+	y, m, d := t.Date()
+	switch strings.ToLower(unit) {
+	case "millennium":
+		return time.Date((y-1)/1000*1000+1, 1, 1, 0, 0, 0, 0, time.UTC)
+	case "century":
+		return time.Date((y-1)/100*100+1, 1, 1, 0, 0, 0, 0, time.UTC)
+	case "decade":
+		return time.Date(y/10*10, 1, 1, 0, 0, 0, 0, time.UTC)
+	case "year":
+		return time.Date(y, 1, 1, 0, 0, 0, 0, time.UTC)
+	case "quarter":
+		return time.Date(y, (m-1)/3*3+1, 1, 0, 0, 0, 0, time.UTC)
+	case "month":
+		return time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	case "week":
+		//the monday of the week
+		offset := (int(t.Weekday()) + 6) % 7
+		return time.Date(y, m, d-offset, 0, 0, 0, 0, time.UTC)
+	case "day":
+		return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	case "hour":
+		return t.Truncate(time.Hour)
+	case "minute":
+		return t.Truncate(time.Minute)
+	case "second":
+		return t.Truncate(time.Second)
+	case "milliseconds":
+		return t.Truncate(time.Millisecond)
+	case "microseconds":
+		return t.Truncate(time.Microsecond)
+	default:
+		panic(unitNotRecognized(unit, "timestamp"))
+	}
+}
+
+func dateTruncTimestampOp(unit *common.String, ts *int64, result *int64) {
+	*result = truncTimestamp(unit.String(), microsToTime(*ts)).UnixMicro()
+}
+
+func dateTruncDateOp(unit *common.String, d *common.Date, result *int64) {
+	*result = truncTimestamp(unit.String(), dateToTime(d)).UnixMicro()
+}
+
+func dateTruncIntervalOp(unit *common.String, i *common.Interval, result *common.Interval) {
+	months := i.Year*12 + i.Months
+	ret := common.Interval{}
+	switch strings.ToLower(unit.String()) {
+	case "millennium":
+		ret.Months = months / 12000 * 12000
+	case "century":
+		ret.Months = months / 1200 * 1200
+	case "decade":
+		ret.Months = months / 120 * 120
+	case "year":
+		ret.Months = months / 12 * 12
+	case "quarter":
+		ret.Months = months / 3 * 3
+	case "month":
+		ret.Months = months
+	case "day":
+		ret.Months, ret.Days = months, i.Days
+	case "hour":
+		ret.Months, ret.Days = months, i.Days
+		ret.Micros = i.Micros / microsPerHour * microsPerHour
+	case "minute":
+		ret.Months, ret.Days = months, i.Days
+		ret.Micros = i.Micros / microsPerMinute * microsPerMinute
+	case "second":
+		ret.Months, ret.Days = months, i.Days
+		ret.Micros = i.Micros / common.MicrosPerSec * common.MicrosPerSec
+	case "milliseconds":
+		ret.Months, ret.Days = months, i.Days
+		ret.Micros = i.Micros / 1000 * 1000
+	case "microseconds":
+		ret.Months, ret.Days, ret.Micros = months, i.Days, i.Micros
+	default:
+		panic(unitNotRecognized(unit.String(), "interval"))
+	}
+	*result = ret
+}
+
+// age
+
+// ageInterval returns the symbolic difference end - start in
+// years, months, days and time like the postgres.
+func ageInterval(end, start time.Time) common.Interval {
+	if end.Before(start) {
+		ret := ageInterval(start, end)
+		return ret.Negate()
+	}
+	micros := timeOfDay(end) - timeOfDay(start)
+	days := end.Day() - start.Day()
+	months := int(end.Month()) - int(start.Month())
+	years := end.Year() - start.Year()
+	if micros < 0 {
+		micros += common.MicrosPerDay
+		days--
+	}
+	if days < 0 {
+		//borrow the days of the month of the start
+		days += daysInMonth(start.Year(), start.Month())
+		months--
+	}
+	if months < 0 {
+		months += 12
+		years--
+	}
+	return common.Interval{
+		Months: int32(years*12 + months),
+		Days:   int32(days),
+		Micros: micros,
+	}
+}
+
+func daysInMonth(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func ageTimestampOp(end, start *int64, result *common.Interval) {
+	*result = ageInterval(microsToTime(*end), microsToTime(*start))
+}
+
+// date_diff
+
+// dateDiff counts the unit boundaries between the start and the end.
+func dateDiff(unit string, start, end time.Time) int64 {
+	monthsOf := func(t time.Time) int64 {
+		return int64(t.Year())*12 + int64(t.Month()) - 1
+	}
+	truncDiff := func(unit string, width int64) int64 {
+		s := truncTimestamp(unit, start).UnixMicro()
+		e := truncTimestamp(unit, end).UnixMicro()
+		return (e - s) / width
+	}
+	switch strings.ToLower(unit) {
+	case "millennium":
+		return int64(end.Year()/1000 - start.Year()/1000)
+	case "century":
+		return int64(end.Year()/100 - start.Year()/100)
+	case "decade":
+		return int64(end.Year()/10 - start.Year()/10)
+	case "year", "years":
+		return int64(end.Year() - start.Year())
+	case "quarter":
+		return monthsOf(end)/3 - monthsOf(start)/3
+	case "month", "months":
+		return monthsOf(end) - monthsOf(start)
+	case "week", "weeks":
+		return truncDiff("week", 7*common.MicrosPerDay)
+	case "day", "days":
+		return truncDiff("day", common.MicrosPerDay)
+	case "hour", "hours":
+		return truncDiff("hour", microsPerHour)
+	case "minute", "minutes":
+		return truncDiff("minute", microsPerMinute)
+	case "second", "seconds":
+		return truncDiff("second", common.MicrosPerSec)
+	case "millisecond", "milliseconds":
+		return truncDiff("milliseconds", 1000)
+	case "microsecond", "microseconds":
+		return end.UnixMicro() - start.UnixMicro()
+	default:
+		panic(unitNotRecognized(unit, "timestamp"))
+	}
+}
+
+func dateDiffTimestampOp(unit *common.String, start, end *int64, result *int64) {
+	*result = dateDiff(unit.String(), microsToTime(*start), microsToTime(*end))
+}
+
+func dateDiffDateOp(unit *common.String, start, end *common.Date, result *int64) {
+	*result = dateDiff(unit.String(), dateToTime(start), dateToTime(end))
+}
+
+// make_date
+
+func makeDateOp(year, month, day *int32, result *common.Date) {
+	t := time.Date(int(*year), time.Month(*month), int(*day), 0, 0, 0, 0, time.UTC)
+	ret := timeToDate(t)
+	if *year == 0 || ret.Year != *year || ret.Month != *month || ret.Day != *day {
+		panic(util.NewSQLError(util.SQLStateDatetimeFieldOverflow,
+			"date field value out of range: %d-%02d-%02d", *year, *month, *day))
+	}
+	*result = ret
+}
+
+// to_char, strftime, to_date and strptime.
+
+// dateToken is the pattern in the format text.
+// layout is the equivalent of the go time layout.
+// format is used for the pattern that the go layout can not express.
+type dateToken struct {
+	pattern string
+	layout  string
+	format  func(t time.Time) string
+}
+
+func upperLayout(layout string) func(t time.Time) string {
+	return func(t time.Time) string {
+		return strings.ToUpper(t.Format(layout))
+	}
+}
+
+func lowerLayout(layout string) func(t time.Time) string {
+	return func(t time.Time) string {
+		return strings.ToLower(t.Format(layout))
+	}
+}
+
+func fractionOf(digits int) func(t time.Time) string {
+	return func(t time.Time) string {
+		return t.Format("." + strings.Repeat("0", digits))[1:]
+	}
+}
+
+// the patterns of the postgres to_char. the longer pattern first.
+var toCharTokens = []dateToken{
+	{pattern: "YYYY", layout: "2006"},
+	{pattern: "YY", layout: "06"},
+	{pattern: "MONTH", layout: "January", format: upperLayout("January")},
+	{pattern: "Month", layout: "January"},
+	{pattern: "month", layout: "January", format: lowerLayout("January")},
+	{pattern: "MON", layout: "Jan", format: upperLayout("Jan")},
+	{pattern: "Mon", layout: "Jan"},
+	{pattern: "mon", layout: "Jan", format: lowerLayout("Jan")},
+	{pattern: "MM", layout: "01"},
+	{pattern: "DAY", layout: "Monday", format: upperLayout("Monday")},
+	{pattern: "Day", layout: "Monday"},
+	{pattern: "day", layout: "Monday", format: lowerLayout("Monday")},
+	{pattern: "DY", layout: "Mon", format: upperLayout("Mon")},
+	{pattern: "Dy", layout: "Mon"},
+	{pattern: "dy", layout: "Mon", format: lowerLayout("Mon")},
+	{pattern: "DDD", layout: "002"},
+	{pattern: "DD", layout: "02"},
+	{pattern: "D", format: func(t time.Time) string {
+		return fmt.Sprint(int(t.Weekday()) + 1)
+	}},
+	{pattern: "HH24", layout: "15"},
+	{pattern: "HH12", layout: "03"},
+	{pattern: "HH", layout: "03"},
+	{pattern: "MI", layout: "04"},
+	{pattern: "SS", layout: "05"},
+	{pattern: "MS", format: fractionOf(3)},
+	{pattern: "US", format: fractionOf(6)},
+	{pattern: "AM", layout: "PM"},
+	{pattern: "PM", layout: "PM"},
+	{pattern: "am", layout: "pm"},
+	{pattern: "pm", layout: "pm"},
+	{pattern: "Q", format: func(t time.Time) string {
+		return fmt.Sprint((int(t.Month())-1)/3 + 1)
+	}},
+}
+
+// the patterns of the strftime and the strptime.
+var strftimeTokens = []dateToken{
+	{pattern: "%Y", layout: "2006"},
+	{pattern: "%y", layout: "06"},
+	{pattern: "%m", layout: "01"},
+	{pattern: "%d", layout: "02"},
+	{pattern: "%e", layout: "_2"},
+	{pattern: "%j", layout: "002"},
+	{pattern: "%H", layout: "15"},
+	{pattern: "%I", layout: "03"},
+	{pattern: "%M", layout: "04"},
+	{pattern: "%S", layout: "05"},
+	{pattern: "%f", format: fractionOf(6)},
+	{pattern: "%p", layout: "PM"},
+	{pattern: "%a", layout: "Mon"},
+	{pattern: "%A", layout: "Monday"},
+	{pattern: "%b", layout: "Jan"},
+	{pattern: "%B", layout: "January"},
+	{pattern: "%z", layout: "-0700"},
+	{pattern: "%Z", layout: "MST"},
+	{pattern: "%%", layout: "%"},
+}
+
+// dateFormat is the set of the patterns in the format text.
+// the text in the double quotes is the literal if quoted is true.
+type dateFormat struct {
+	tokens []dateToken
+	quoted bool
+}
+
+var (
+	toCharFormat   = dateFormat{tokens: toCharTokens, quoted: true}
+	strftimeFormat = dateFormat{tokens: strftimeTokens}
+)
+
+// split splits the format text into the tokens and the literals.
+func (df *dateFormat) split(format string, fn func(tok *dateToken, literal string)) {
+	for i := 0; i < len(format); {
+		if df.quoted && format[i] == '"' {
+			end := strings.IndexByte(format[i+1:], '"')
+			if end < 0 {
+				end = len(format) - i - 1
+			}
+			fn(nil, format[i+1:i+1+end])
+			i += end + 2
+			continue
+		}
+		matched := false
+		for j := range df.tokens {
+			if strings.HasPrefix(format[i:], df.tokens[j].pattern) {
+				fn(&df.tokens[j], "")
+				i += len(df.tokens[j].pattern)
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			fn(nil, format[i:i+1])
+			i++
+		}
+	}
+}
+
+func (df *dateFormat) format(t time.Time, format string) string {
+	sb := strings.Builder{}
+	df.split(format, func(tok *dateToken, literal string) {
+		switch {
+		case tok == nil:
+			sb.WriteString(literal)
+		case tok.format != nil:
+			sb.WriteString(tok.format(t))
+		default:
+			sb.WriteString(t.Format(tok.layout))
+		}
+	})
+	return sb.String()
+}
+
+// parse converts the format into the go layout and parses the text.
+// the fraction of the second is parsed after the second field.
+func (df *dateFormat) parse(text, format string) (time.Time, error) {
+	sb := strings.Builder{}
+	var err error
+	df.split(format, func(tok *dateToken, literal string) {
+		switch {
+		case tok == nil:
+			sb.WriteString(literal)
+		case tok.layout != "":
+			sb.WriteString(tok.layout)
+		case tok.pattern == "MS" || tok.pattern == "US" || tok.pattern == "%f":
+			//go parses the fraction after the seconds
+			layout := strings.TrimSuffix(sb.String(), ".")
+			sb.Reset()
+			sb.WriteString(layout)
+		default:
+			err = util.NewSQLError(util.SQLStateFeatureNotSupported,
+				"unsupport format pattern %s in parsing", tok.pattern)
+		}
+	})
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, err := time.Parse(sb.String(), strings.TrimSpace(text))
+	if err != nil {
+		return time.Time{}, util.NewSQLError(util.SQLStateInvalidDatetimeFormat,
+			"invalid value \"%s\" for format \"%s\"", text, format)
+	}
+	return t.UTC(), nil
+}
+
+func toCharTimestampOp(ts *int64, format *common.String, result *common.String) {
+	newString(toCharFormat.format(microsToTime(*ts), format.String()), result)
+}
+
+func strftimeTimestampOp(ts *int64, format *common.String, result *common.String) {
+	newString(strftimeFormat.format(microsToTime(*ts), format.String()), result)
+}
+
+func toDateOp(text, format *common.String, result *common.Date) {
+	t, err := toCharFormat.parse(text.String(), format.String())
+	if err != nil {
+		panic(err)
+	}
+	*result = timeToDate(t)
+}
+
+func toTimestampOp(text, format *common.String, result *int64) {
+	t, err := toCharFormat.parse(text.String(), format.String())
+	if err != nil {
+		panic(err)
+	}
+	*result = t.UnixMicro()
+}
+
+func strptimeOp(text, format *common.String, result *int64) {
+	t, err := strftimeFormat.parse(text.String(), format.String())
+	if err != nil {
+		panic(err)
+	}
+	*result = t.UnixMicro()
+}
@@ -0,0 +1,100 @@
+// Copyright 2023-2024 daviszhen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/daviszhen/plan/pkg/common"
+	"github.com/daviszhen/plan/pkg/storage"
+	"github.com/daviszhen/plan/pkg/util"
+)
+
+func Test_datetime(t *testing.T) {
+	txn, err := storage.GTxnMgr.NewTxn("datetime")
+	require.NoError(t, err)
+	defer storage.GTxnMgr.Rollback(txn)
+	execSQL(t, txn, "create table dt_t (d date, ts timestamp, tz timestamptz, t time)")
+	execSQL(t, txn, "insert into dt_t values ('2024-02-29', '2024-05-17 13:45:30.25', '2024-05-17 13:45:30+00', '13:45:30')")
+
+	kases := []struct {
+		expr string
+		want string
+	}{
+		{"date_trunc('month', ts)", "2024-05-01 00:00:00"},
+		{"date_trunc('hour', tz)", "2024-05-17 13:00:00+00"},
+		{"date_trunc('week', ts)", "2024-05-13 00:00:00"},
+		{"date_trunc('quarter', d)", "2024-01-01 00:00:00"},
+		{"date_trunc('day', interval '3 days 04:05:06')", "3 days"},
+		{"date_part('year', d)", "2024"},
+		{"date_part('dow', ts)", "5"},
+		{"date_part('second', ts)", "30.25"},
+		{"date_part('minute', t)", "45"},
+		{"date_part('day', interval '3 days 04:05:06')", "3"},
+		{"extract(year from d)", "2024"},
+		{"extract(month from ts)", "5"},
+		{"extract(hour from tz)", "13"},
+		{"age(ts, '2023-01-01'::timestamp)", "1 year 4 mons 16 days 13:45:30.25"},
+		{"age('2024-03-01'::timestamp, '2024-02-29 12:00'::timestamp)", "12:00:00"},
+		{"date_diff('day', d, '2024-03-02'::date)", "2"},
+		{"date_diff('month', ts, '2025-01-01'::timestamp)", "8"},
+		{"date_diff('hour', tz, '2024-05-18 00:00:00+00'::timestamptz)", "11"},
+		{"make_date(2024, 2, 29)", "2024-02-29"},
+		{"to_char(ts, 'YYYY-MM-DD HH24:MI:SS.MS')", "2024-05-17 13:45:30.250"},
+		{"to_char(tz, 'Dy, DD Mon YYYY \"at\" HH12 AM')", "Fri, 17 May 2024 at 01 PM"},
+		{"strftime(ts, '%Y/%m/%d %H:%M')", "2024/05/17 13:45"},
+		{"to_date('17.05.2024', 'DD.MM.YYYY')", "2024-05-17"},
+		{"to_timestamp('2024-05-17 13:45', 'YYYY-MM-DD HH24:MI')", "2024-05-17 13:45:00+00"},
+		{"strptime('17/05/2024 13:45:30', '%d/%m/%Y %H:%M:%S')", "2024-05-17 13:45:30"},
+		{"now() = current_timestamp", "true"},
+		{"current_date = now()::date", "true"},
+		{"localtimestamp = now()::timestamp", "true"},
+		{"age(current_date::timestamp)", "00:00:00"},
+	}
+	for _, kase := range kases {
+		rows, err := querySQL(t, txn, "select "+kase.expr+" from dt_t")
+		require.NoError(t, err, kase.expr)
+		require.Equal(t, [][]string{{kase.want}}, rows, kase.expr)
+	}
+
+	//now() is the begin time of the txn
+	rows, err := querySQL(t, txn, "select now() from dt_t")
+	require.NoError(t, err)
+	require.Equal(t, common.FormatTimestamp(txn.BeginTime().UnixMicro(), true), rows[0][0])
+
+	errKases := []struct {
+		expr string
+		code util.SQLState
+	}{
+		{"date_trunc('fortnight', ts)", util.SQLStateInvalidParameterValue},
+		{"date_part('dow', t)", util.SQLStateInvalidParameterValue},
+		{"make_date(2023, 2, 29)", util.SQLStateDatetimeFieldOverflow},
+		{"to_date('2024-13-01', 'YYYY-MM-DD')", util.SQLStateInvalidDatetimeFormat},
+	}
+	for _, kase := range errKases {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					err = util.RecoverToError(r)
+				}
+				require.Error(t, err, kase.expr)
+				require.Equal(t, kase.code, util.GetSQLState(err), kase.expr)
+			}()
+			_, err = querySQL(t, txn, "select "+kase.expr+" from dt_t")
+		}()
+	}
+}
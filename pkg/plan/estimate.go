@@ -164,10 +164,13 @@ func (est *CardinalityEstimator) InitCardinalityEstimatorProps(nodeOps []*NodeOp
 	for _, nodeOp := range nodeOps {
 		joinNode := nodeOp.node
 		op := nodeOp.op
-		joinNode.setBaseCard(float64(op.EstimatedCard(est.txn)))
+		//the stats do not count the rows that are not committed.
+		//the empty relation is estimated as one row.
+		joinNode.setBaseCard(float64(max(op.EstimatedCard(est.txn), 1)))
 		if op.Typ == LOT_JOIN {
-			if op.JoinTyp == LOT_JoinTypeLeft {
-				panic("usp left join here")
+			switch op.JoinTyp {
+			case LOT_JoinTypeLeft, LOT_JoinTypeRight, LOT_JoinTypeOUTER:
+				panic("usp " + op.JoinTyp.String() + " join here")
 			}
 		}
 		est.EstimateBaseTableCard(joinNode, op)
@@ -295,11 +298,66 @@ func (est *CardinalityEstimator) EstimateBaseTableCard(node *JoinNode, op *Logic
 		if hasLogicalFilter {
 			cardAfterFilters = cardAfterFilters * defaultSelectivity
 		}
+		switch est.nullTestsSelectivity(op) {
+		case 0:
+			cardAfterFilters = min(cardAfterFilters, 1)
+		case 1:
+			//the filters are always true
+			cardAfterFilters = node.getBaseCard()
+		}
 		lowestCardFound = min(cardAfterFilters, lowestCardFound)
 	}
 	node.setEstimatedCard(lowestCardFound)
 }
 
+// nullTestsSelectivity decides the IS [NOT] NULL on the column by the stats.
+// it returns 0 if any of them is always false, 1 if all of them are always true.
+// otherwise, it returns -1.
+func (est *CardinalityEstimator) nullTestsSelectivity(op *LogicalOperator) float64 {
+	filters := splitExprsByAnd(op.Filters)
+	if len(filters) == 0 {
+		return -1
+	}
+	ret := float64(1)
+	for _, filter := range filters {
+		if filter.Typ != ET_Func ||
+			filter.SubTyp != ET_IsNull && filter.SubTyp != ET_IsNotNull {
+			ret = -1
+			continue
+		}
+		col := filter.Children[0]
+		if col.Typ != ET_Column {
+			ret = -1
+			continue
+		}
+		get := getLogicalGet(op, col.ColRef.table())
+		if get == nil || get.Index != col.ColRef.table() || get.TableEnt == nil {
+			ret = -1
+			continue
+		}
+		stats := get.TableEnt.GetStats2(int(col.ColRef.column()))
+		if stats == nil {
+			ret = -1
+			continue
+		}
+		isNull := filter.SubTyp == ET_IsNull
+		if !stats.HasNull() {
+			//no NULL in the column
+			if isNull {
+				return 0
+			}
+		} else if !stats.HasNoNull() {
+			//all are NULL
+			if !isNull {
+				return 0
+			}
+		} else {
+			ret = -1
+		}
+	}
+	return ret
+}
+
 func (est *CardinalityEstimator) GetTableFilters(op *LogicalOperator, tableIndex uint64) *TableFilterSet {
 	get := getLogicalGet(op, tableIndex)
 	if get != nil {
@@ -312,6 +370,10 @@ func (est *CardinalityEstimator) GetTableFilters(op *LogicalOperator, tableIndex
 
 func (est *CardinalityEstimator) UpdateTotalDomains(node *JoinNode, op *LogicalOperator) error {
 	relId := node.set.relations[0]
+	//the relation without the join conditions
+	if _, has := est.relationAttributes[relId]; !has {
+		est.relationAttributes[relId] = NewRelationAttributes()
+	}
 	est.relationAttributes[relId].cardinality = node.getCard()
 	distinctCount := uint64(node.getBaseCard())
 	var get *LogicalOperator
@@ -420,7 +482,7 @@ func (est *CardinalityEstimator) AddRelationColumnMapping(
 		//	}
 		//}
 
-	case ScanTypeValuesList:
+	case ScanTypeValuesList, ScanTypeWorkTable, ScanTypeCTE:
 		for i := range get.Names {
 			key := ColumnBind{relId, uint64(i)}
 			value := ColumnBind{get.Index, uint64(i)}
@@ -596,7 +658,8 @@ func getLogicalGet(op *LogicalOperator, tableIndex uint64) *LogicalOperator {
 	case LOT_Project:
 		return getLogicalGet(op.Children[0], tableIndex)
 	case LOT_JOIN:
-		if op.JoinTyp == LOT_JoinTypeMARK || op.JoinTyp == LOT_JoinTypeLeft {
+		switch op.JoinTyp {
+		case LOT_JoinTypeMARK, LOT_JoinTypeLeft, LOT_JoinTypeRight, LOT_JoinTypeOUTER:
 			panic("usp " + op.JoinTyp.String())
 		}
 	default:
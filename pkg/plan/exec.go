@@ -30,6 +30,7 @@ type ExprState struct {
 	_types              []common.LType
 	_interChunk         *chunk.Chunk
 	_trueSel, _falseSel *chunk.SelectVector //for CASE WHEN
+	_funcState          any                 //for the function. e.g. the compiled regex
 }
 
 func NewExprState(expr *Expr, eeState *ExprExecState) *ExprState {
@@ -267,11 +268,12 @@ func (exec *ExprExec) executeConst(expr *Expr, state *ExprState, sel *chunk.Sele
 	switch expr.Typ {
 	case ET_IConst, ET_SConst, ET_FConst, ET_BConst, ET_NConst, ET_DecConst:
 		val := &chunk.Value{
-			Typ:  expr.DataTyp,
-			I64:  expr.Ivalue,
-			F64:  expr.Fvalue,
-			Str:  expr.Svalue,
-			Bool: expr.Bvalue,
+			Typ:    expr.DataTyp,
+			IsNull: expr.Typ == ET_NConst,
+			I64:    expr.Ivalue,
+			F64:    expr.Fvalue,
+			Str:    expr.Svalue,
+			Bool:   expr.Bvalue,
 		}
 		result.ReferenceValue(val)
 	case ET_DateConst:
@@ -317,7 +319,11 @@ func (exec *ExprExec) executeFunc(expr *Expr, eState *ExprState, sel *chunk.Sele
 	eState._interChunk.SetCard(count)
 	if expr.FunImpl._boundCastInfo != nil {
 		params := &CastParams{}
-		expr.FunImpl._boundCastInfo._fun(eState._interChunk.Data[0], result, count, params)
+		src := eState._interChunk.Data[0]
+		if !expr.FunImpl._boundCastInfo._fun(src, result, count, params) &&
+			!expr.FunImpl._tryCast {
+			return castError(src.Typ(), result.Typ(), params._errorMsg)
+		}
 	} else {
 		expr.FunImpl._scalar(eState._interChunk, eState, result)
 	}
@@ -371,10 +377,16 @@ func (exec *ExprExec) execSelectExpr(expr *Expr, eState *ExprState, sel *chunk.S
 			return exec.execSelectAnd(expr, eState, sel, count, trueSel, falseSel)
 		case ET_Or:
 			return exec.execSelectOr(expr, eState, sel, count, trueSel, falseSel)
+		case ET_IsNull, ET_IsNotNull:
+			return exec.execSelectNullTest(expr, eState, sel, count, trueSel, falseSel)
+		case ET_IsDistinctFrom, ET_IsNotDistinctFrom, ET_SubFunc,
+			ET_ILike, ET_NotILike, ET_RegexpMatch, ET_NotRegexpMatch, ET_RegexpIMatch, ET_NotRegexpIMatch:
+			return exec.execSelectBool(expr, eState, sel, count, trueSel, falseSel)
 		default:
 			panic("usp")
 		}
-
+	case ET_BConst, ET_Column:
+		return exec.execSelectBool(expr, eState, sel, count, trueSel, falseSel)
 	default:
 		panic("usp")
 	}
@@ -425,6 +437,26 @@ func (exec *ExprExec) execSelectCompare(expr *Expr, eState *ExprState, sel *chun
 
 }
 
+func (exec *ExprExec) execSelectNullTest(expr *Expr, eState *ExprState, sel *chunk.SelectVector, count int, trueSel, falseSel *chunk.SelectVector) (int, error) {
+	eState._interChunk.Reset()
+	child := eState._interChunk.Data[0]
+	err := exec.execute(expr.Children[0], eState._children[0], sel, count, child)
+	if err != nil {
+		return 0, err
+	}
+	return selectNullTest(child, sel, count, trueSel, falseSel, expr.SubTyp == ET_IsNotNull), nil
+}
+
+// execSelectBool evaluates the boolean expr and selects the true rows.
+func (exec *ExprExec) execSelectBool(expr *Expr, eState *ExprState, sel *chunk.SelectVector, count int, trueSel, falseSel *chunk.SelectVector) (int, error) {
+	result := chunk.NewFlatVector(common.BooleanType(), util.DefaultVectorSize)
+	err := exec.execute(expr, eState, sel, count, result)
+	if err != nil {
+		return 0, err
+	}
+	return selectBool(result, sel, count, trueSel, falseSel), nil
+}
+
 func (exec *ExprExec) execSelectAnd(expr *Expr, eState *ExprState, sel *chunk.SelectVector, count int, trueSel, falseSel *chunk.SelectVector) (int, error) {
 	var err error
 	curSel := sel
@@ -0,0 +1,262 @@
+// Copyright 2023-2024 daviszhen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	pg_query "github.com/pganalyze/pg_query_go/v5"
+
+	"github.com/daviszhen/plan/pkg/chunk"
+	"github.com/daviszhen/plan/pkg/parser"
+	"github.com/daviszhen/plan/pkg/storage"
+	"github.com/daviszhen/plan/pkg/util"
+)
+
+const (
+	//ddl of the schemas and tables
+	exportSchemaFile = "schema.sql"
+	//copy statements that load the data files
+	exportLoadFile = "load.sql"
+	//null string in the data files. the backslashes in the values
+	//are doubled, so no value is written as it.
+	exportNull = `\N`
+	//the backslash in the values
+	exportEscape = `\`
+)
+
+func (run *Runner) exportInit() error {
+	return nil
+}
+
+// exportExec writes the catalog and the data visible to the txn
+// into the directory.
+func (run *Runner) exportExec(output *chunk.Chunk, state *OperatorState) (OperatorResult, error) {
+	dir := run.op.ScanInfo.FilePath
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return InvalidOpResult, err
+	}
+
+	schemaSQL := strings.Builder{}
+	loadSQL := strings.Builder{}
+	//the file systems may be case insensitive
+	files := make(map[string]string)
+	storage.GCatalog.ScanSchemasWithTxn(run.Txn, func(schEnt *storage.CatalogEntry) {
+		if err != nil {
+			return
+		}
+		schemaSQL.WriteString(schEnt.CreateSchemaSQL())
+		schemaSQL.WriteString("\n")
+		schEnt.ScanWithTxn(run.Txn, storage.CatalogTypeTable, func(tabEnt *storage.CatalogEntry) {
+			if err != nil {
+				return
+			}
+			var ddl string
+			ddl, err = tabEnt.CreateTableSQL()
+			if err != nil {
+				return
+			}
+			schemaSQL.WriteString(ddl)
+			schemaSQL.WriteString("\n")
+
+			fname := exportFileName(schEnt.GetName(), tabEnt.GetName())
+			name := schEnt.GetName() + "." + tabEnt.GetName()
+			if prev, has := files[strings.ToLower(fname)]; has {
+				err = fmt.Errorf("tables %s and %s have the same data file %s", prev, name, fname)
+				return
+			}
+			files[strings.ToLower(fname)] = name
+			err = exportTable(run.Txn, tabEnt, filepath.Join(dir, fname))
+			if err != nil {
+				return
+			}
+			loadSQL.WriteString(fmt.Sprintf("COPY %s.%s FROM '%s' WITH (FORMAT csv, NULL '%s');\n",
+				storage.QuoteIdent(schEnt.GetName()),
+				storage.QuoteIdent(tabEnt.GetName()),
+				fname,
+				exportNull,
+			))
+		})
+	})
+	if err != nil {
+		return InvalidOpResult, err
+	}
+
+	err = os.WriteFile(filepath.Join(dir, exportSchemaFile), []byte(schemaSQL.String()), 0644)
+	if err != nil {
+		return InvalidOpResult, err
+	}
+	err = os.WriteFile(filepath.Join(dir, exportLoadFile), []byte(loadSQL.String()), 0644)
+	if err != nil {
+		return InvalidOpResult, err
+	}
+	return Done, nil
+}
+
+func (run *Runner) exportClose() error {
+	return nil
+}
+
+// exportFileName keeps the letters and digits of the names and
+// converts the other bytes into _xx in hex. The different names
+// have the different file names.
+func exportFileName(schema, table string) string {
+	escape := func(name string) string {
+		bb := strings.Builder{}
+		for i := 0; i < len(name); i++ {
+			c := name[i]
+			if c >= 'a' && c <= 'z' ||
+				c >= 'A' && c <= 'Z' ||
+				c >= '0' && c <= '9' {
+				bb.WriteByte(c)
+			} else {
+				bb.WriteString(fmt.Sprintf("_%02x", c))
+			}
+		}
+		return bb.String()
+	}
+	return escape(schema) + "." + escape(table) + ".csv"
+}
+
+// exportTable writes the rows of the table visible to the txn into the csv file.
+func exportTable(txn *storage.Txn, tabEnt *storage.CatalogEntry, fpath string) error {
+	file, err := os.OpenFile(fpath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	writer := csv.NewWriter(file)
+
+	table := tabEnt.GetStorage()
+	typs := tabEnt.GetTypes()
+	colIds := make([]storage.IdxType, 0, len(typs))
+	for i := range typs {
+		colIds = append(colIds, storage.IdxType(i))
+	}
+	scanState := storage.NewTableScanState()
+	table.InitScan(txn, scanState, colIds)
+	record := make([]string, len(typs))
+	for {
+		data := &chunk.Chunk{}
+		data.Init(typs, util.DefaultVectorSize)
+		table.Scan(txn, data, scanState)
+		if data.Card() == 0 {
+			break
+		}
+		for i := 0; i < data.Card(); i++ {
+			for j := range typs {
+				val := data.Data[j].GetValue(i)
+				if val.IsNull {
+					record[j] = exportNull
+				} else {
+					record[j] = strings.ReplaceAll(val.String(), exportEscape, exportEscape+exportEscape)
+				}
+			}
+			err = writer.Write(record)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	writer.Flush()
+	if err = writer.Error(); err != nil {
+		return err
+	}
+	return file.Sync()
+}
+
+func (run *Runner) importInit() error {
+	return nil
+}
+
+// importExec runs the ddl and the copy statements in the exported directory.
+func (run *Runner) importExec(output *chunk.Chunk, state *OperatorState) (OperatorResult, error) {
+	dir := run.op.ScanInfo.FilePath
+	for _, fname := range []string{exportSchemaFile, exportLoadFile} {
+		sql, err := os.ReadFile(filepath.Join(dir, fname))
+		if err != nil {
+			return InvalidOpResult, err
+		}
+		stmts, err := parser.Parse(string(sql))
+		if err != nil {
+			return InvalidOpResult, err
+		}
+		for _, stmt := range stmts {
+			//the data files are relative to the directory
+			if copyStmt := stmt.GetStmt().GetCopyStmt(); copyStmt != nil &&
+				!filepath.IsAbs(copyStmt.GetFilename()) {
+				copyStmt.Filename = filepath.Join(dir, copyStmt.GetFilename())
+			}
+			err = run.execStmt(stmt, fname == exportLoadFile)
+			if err != nil {
+				return InvalidOpResult, err
+			}
+		}
+	}
+	return Done, nil
+}
+
+func (run *Runner) importClose() error {
+	return nil
+}
+
+// execStmt runs the statement in the txn of the runner.
+// The copy statements read the exported data files if exported is true.
+func (run *Runner) execStmt(stmt *pg_query.RawStmt, exported bool) error {
+	root, err := genDDLPhyPlan(run.cfg, run.Txn, stmt)
+	if err != nil {
+		return err
+	}
+	if exported {
+		markExported(root)
+	}
+	stmtRun := &Runner{
+		op:    root,
+		state: &OperatorState{},
+		cfg:   run.cfg,
+		Txn:   run.Txn,
+	}
+	err = stmtRun.Init()
+	if err != nil {
+		return err
+	}
+	defer stmtRun.Close()
+	for {
+		output := &chunk.Chunk{}
+		output.SetCap(util.DefaultVectorSize)
+		result, err := stmtRun.Execute(nil, output, stmtRun.state)
+		if err != nil {
+			return err
+		}
+		if result == Done {
+			return nil
+		}
+	}
+}
+
+// markExported marks the copy scans reading the exported data files.
+func markExported(root *PhysicalOperator) {
+	if root.Typ == POT_Scan && root.ScanTyp == ScanTypeCopyFrom {
+		root.ScanInfo.Exported = true
+	}
+	for _, child := range root.Children {
+		markExported(child)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2023-2024 daviszhen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/daviszhen/plan/pkg/chunk"
+	"github.com/daviszhen/plan/pkg/storage"
+	"github.com/daviszhen/plan/pkg/util"
+)
+
+func execSQL(t *testing.T, txn *storage.Txn, sql string) {
+	run, err := InitRunner(&util.Config{}, txn, sql)
+	require.NoError(t, err)
+	defer run.Close()
+	for {
+		output := &chunk.Chunk{}
+		output.SetCap(util.DefaultVectorSize)
+		result, err := run.Execute(nil, output, run.state)
+		require.NoError(t, err)
+		if result == Done {
+			break
+		}
+	}
+}
+
+// keepSchema keeps the statements of the schema in the sql file.
+func keepSchema(t *testing.T, fpath string, schema string) {
+	sql, err := os.ReadFile(fpath)
+	require.NoError(t, err)
+	kept := make([]string, 0)
+	for _, stmt := range strings.SplitAfter(string(sql), ";\n") {
+		if strings.Contains(stmt, storage.QuoteIdent(schema)) {
+			kept = append(kept, stmt)
+		}
+	}
+	require.NoError(t, os.WriteFile(fpath, []byte(strings.Join(kept, "")), 0644))
+}
+
+func Test_export_import_database(t *testing.T) {
+	schema := fmt.Sprintf("exp%d", time.Now().UnixNano())
+	fname := exportFileName(schema, "t")
+	dir := t.TempDir()
+	dir2 := t.TempDir()
+
+	//1. create, insert and export in one txn
+	txn, err := storage.GTxnMgr.NewTxn("export")
+	require.NoError(t, err)
+	storage.BeginQuery(txn)
+	execSQL(t, txn, fmt.Sprintf("create schema %s", schema))
+	execSQL(t, txn, fmt.Sprintf(`create table %s.t (
+		a integer, b bigint, c varchar, d decimal(15,2), e date, primary key (a))`, schema))
+	execSQL(t, txn, fmt.Sprintf(`insert into %s.t values
+		(1, 10, 'x,y', 1.50, '2024-01-02'),
+		(2, 20, 'it''s', 2.25, '2024-02-03'),
+		(3, 30, '\N', 3.00, '2024-03-04'),
+		(4, 40, 'a\\b', 4.00, '2024-04-05'),
+		(5, null, null, null, null)`, schema))
+	execSQL(t, txn, fmt.Sprintf("export database '%s'", dir))
+	storage.GTxnMgr.Rollback(txn)
+
+	ddl, err := os.ReadFile(filepath.Join(dir, exportSchemaFile))
+	require.NoError(t, err)
+	require.Contains(t, string(ddl), fmt.Sprintf(`CREATE TABLE "%s"."t"`, schema))
+	require.Contains(t, string(ddl), `PRIMARY KEY ("a")`)
+	data, err := os.ReadFile(filepath.Join(dir, fname))
+	require.NoError(t, err)
+	require.Equal(t, "1,10,\"x,y\",1.5,2024-01-02\n"+
+		"2,20,it's,2.25,2024-02-03\n"+
+		"3,30,\\\\N,3,2024-03-04\n"+
+		"4,40,a\\\\\\\\b,4,2024-04-05\n"+
+		"5,\\N,\\N,\\N,\\N\n", string(data))
+
+	//2. import the schema and export it again
+	keepSchema(t, filepath.Join(dir, exportSchemaFile), schema)
+	keepSchema(t, filepath.Join(dir, exportLoadFile), schema)
+	txn, err = storage.GTxnMgr.NewTxn("import")
+	require.NoError(t, err)
+	storage.BeginQuery(txn)
+	defer storage.GTxnMgr.Rollback(txn)
+	execSQL(t, txn, fmt.Sprintf("import database '%s'", dir))
+	execSQL(t, txn, fmt.Sprintf("export database '%s'", dir2))
+
+	data2, err := os.ReadFile(filepath.Join(dir2, fname))
+	require.NoError(t, err)
+	require.Equal(t, string(data), string(data2))
+
+	//the value \N is not the NULL
+	rows, err := querySQL(t, txn, fmt.Sprintf("select a from %s.t where c = '\\N'", schema))
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"3"}}, rows)
+	//the NULL is not the empty string
+	rows, err = querySQL(t, txn, fmt.Sprintf(
+		"select a from %s.t where b is null and c is null and d is null and e is null", schema))
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"5"}}, rows)
+
+	//the other copy keeps the backslashes
+	fpath := filepath.Join(t.TempDir(), "copy.csv")
+	require.NoError(t, os.WriteFile(fpath, []byte("6,60,a\\\\b,6,2024-06-07\n"), 0644))
+	execSQL(t, txn, fmt.Sprintf("copy %s.t from '%s' with (format csv)", schema, fpath))
+	rows, err = querySQL(t, txn, fmt.Sprintf("select c from %s.t where a = 6", schema))
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"a\\\\b"}}, rows)
+}
+
+func Test_exportFileName(t *testing.T) {
+	kases := [][2][2]string{
+		{{"a.b_c", "t"}, {"a_b.c", "t"}},
+		{{"a-b", "t"}, {"a_b", "t"}},
+		{{"a", "b.c"}, {"a.b", "c"}},
+	}
+	for _, kase := range kases {
+		require.NotEqual(t,
+			exportFileName(kase[0][0], kase[0][1]),
+			exportFileName(kase[1][0], kase[1][1]))
+	}
+	require.Equal(t, "a_2eb_5fc.t.csv", exportFileName("a.b_c", "t"))
+}
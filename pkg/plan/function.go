@@ -25,11 +25,35 @@ import (
 )
 
 var aggNames = map[string]int{
-	"min":   1,
-	"count": 1,
-	"sum":   1,
-	"max":   1,
-	"avg":   1,
+	"min":         1,
+	"count":       1,
+	"sum":         1,
+	"max":         1,
+	"avg":         1,
+	"stddev":      1,
+	"stddev_samp": 1,
+	"stddev_pop":  1,
+	"variance":    1,
+	"var_samp":    1,
+	"var_pop":     1,
+	"corr":        1,
+	"covar_pop":   1,
+	"covar_samp":  1,
+	"bool_and":    1,
+	"bool_or":     1,
+	"string_agg":  1,
+	"listagg":     1,
+	"first":       1,
+	"any_value":   1,
+	"arg_min":     1,
+	"arg_max":     1,
+	"mode":        1,
+	//approximate aggregates and quantiles
+	"approx_count_distinct": 1,
+	"approx_quantile":       1,
+	"quantile_cont":         1,
+	"quantile_disc":         1,
+	"median":                1,
 }
 
 func IsAgg(name string) bool {
@@ -62,8 +86,11 @@ const (
 )
 
 type FunctionV2 struct {
-	_name         string
-	_args         []common.LType
+	_name string
+	_args []common.LType
+	//the type of the arguments after the _args.
+	//invalid if the function has no variable arguments.
+	_varArgs      common.LType
 	_retType      common.LType
 	_funcTyp      FuncType
 	_sideEffects  FuncSideEffects
@@ -72,6 +99,8 @@ type FunctionV2 struct {
 	_scalar        ScalarFunc
 	_bind          bindScalarFunc
 	_boundCastInfo *BoundCastInfo
+	//the cast returns NULL on the failure
+	_tryCast bool
 
 	_stateSize aggrStateSize
 	_init      aggrInit
@@ -81,12 +110,16 @@ type FunctionV2 struct {
 	//_func         aggrFunction
 	_simpleUpdate aggrSimpleUpdate
 	//_window       aggrWindow
+	//releases the states that are not finalized. nil if
+	//the states hold nothing out of the row.
+	_destroy aggrDestroy
 }
 
 func (fun *FunctionV2) Copy() *FunctionV2 {
 	ret := &FunctionV2{
 		_name:         fun._name,
 		_args:         util.CopyTo(fun._args),
+		_varArgs:      fun._varArgs,
 		_retType:      fun._retType,
 		_funcTyp:      fun._funcTyp,
 		_sideEffects:  fun._sideEffects,
@@ -101,10 +134,19 @@ func (fun *FunctionV2) Copy() *FunctionV2 {
 		//_func:         fun._func,
 		_simpleUpdate: fun._simpleUpdate,
 		//_window:       fun._window,
+		_destroy: fun._destroy,
 	}
 	return ret
 }
 
+// argType returns the type of the i-th argument.
+func (fun *FunctionV2) argType(i int) common.LType {
+	if i < len(fun._args) {
+		return fun._args[i]
+	}
+	return fun._varArgs
+}
+
 type ScalarFunc func(*chunk.Chunk, *ExprState, *chunk.Vector)
 
 type aggrStateSize func() int
@@ -115,6 +157,7 @@ type aggrFinalize func(*chunk.Vector, *AggrInputData, *chunk.Vector, int, int)
 
 // type aggrFunction func(*AggrFunc, []*Expr)
 type aggrSimpleUpdate func([]*chunk.Vector, *AggrInputData, int, unsafe.Pointer, int)
+type aggrDestroy func(*chunk.Vector, int)
 
 //type aggrWindow func([]*Vector, *Bitmap, *AggrInputData)
 
@@ -188,6 +231,7 @@ func (binder *FunctionBinder) BindScalarFunc(
 	}
 
 	fun := fset.GetFunc(best)
+	binder.CastToFuncArgs(fun, args)
 	return binder.BindScalarFunc2(fun, args, subTyp, isOperator)
 }
 
@@ -217,7 +261,7 @@ func (binder *FunctionBinder) BindScalarFunc2(
 func (binder *FunctionBinder) CastToFuncArgs(fun *FunctionV2, args []*Expr) {
 	var err error
 	for i := 0; i < len(args); i++ {
-		targetType := fun._args[i]
+		targetType := fun.argType(i)
 		if args[i].DataTyp.Id == common.LTID_LAMBDA {
 			continue
 		}
@@ -361,13 +405,14 @@ func (binder *FunctionBinder) BindFuncCost(
 	fun *FunctionV2,
 	args []common.LType,
 ) int64 {
-	if len(fun._args) != len(args) {
+	if len(fun._args) != len(args) &&
+		(fun._varArgs.Id == common.LTID_INVALID || len(args) < len(fun._args)) {
 		return -1
 	}
 
 	cost := int64(0)
 	for i, arg := range args {
-		castCost := castFuncs.ImplicitCastCost(arg, fun._args[i])
+		castCost := castFuncs.ImplicitCastCost(arg, fun.argType(i))
 		if castCost >= 0 {
 			cost += castCost
 		} else {
@@ -416,7 +461,53 @@ func RegisterOps() {
 	LessEqualFunc{}.Register(scalarFuncs)
 	CaseFunc{}.Register(scalarFuncs)
 	ExtractFunc{}.Register(scalarFuncs)
+	DateTruncFunc{}.Register(scalarFuncs)
+	DatePartFunc{}.Register(scalarFuncs)
+	AgeFunc{}.Register(scalarFuncs)
+	DateDiffFunc{}.Register(scalarFuncs)
+	MakeDateFunc{}.Register(scalarFuncs)
+	DateFormatFunc{}.Register(scalarFuncs)
+	DateParseFunc{}.Register(scalarFuncs)
 	SubstringFunc{}.Register(scalarFuncs)
+	IsNullFunc{}.Register(scalarFuncs)
+	IsNotNullFunc{}.Register(scalarFuncs)
+	IsDistinctFromFunc{}.Register(scalarFuncs)
+	IsNotDistinctFromFunc{}.Register(scalarFuncs)
+	NullIfFunc{}.Register(scalarFuncs)
+	LengthFunc{}.Register(scalarFuncs)
+	LowerFunc{}.Register(scalarFuncs)
+	UpperFunc{}.Register(scalarFuncs)
+	TrimFunc{}.Register(scalarFuncs)
+	ConcatFunc{}.Register(scalarFuncs)
+	ReplaceFunc{}.Register(scalarFuncs)
+	StrposFunc{}.Register(scalarFuncs)
+	LeftFunc{}.Register(scalarFuncs)
+	RightFunc{}.Register(scalarFuncs)
+	PadFunc{}.Register(scalarFuncs)
+	SplitPartFunc{}.Register(scalarFuncs)
+	StartsWithFunc{}.Register(scalarFuncs)
+	ReverseFunc{}.Register(scalarFuncs)
+	RepeatFunc{}.Register(scalarFuncs)
+	ILikeFunc{}.Register(scalarFuncs)
+	StringMatchFunc{}.Register(scalarFuncs)
+	RegexpMatchFunc{}.Register(scalarFuncs)
+	SimilarToEscapeFunc{}.Register(scalarFuncs)
+	RegexpMatchesFunc{}.Register(scalarFuncs)
+	RegexpReplaceFunc{}.Register(scalarFuncs)
+	RegexpExtractFunc{}.Register(scalarFuncs)
+	AbsFunc{}.Register(scalarFuncs)
+	SignFunc{}.Register(scalarFuncs)
+	CeilFunc{}.Register(scalarFuncs)
+	FloorFunc{}.Register(scalarFuncs)
+	RoundFunc{}.Register(scalarFuncs)
+	TruncFunc{}.Register(scalarFuncs)
+	ModFunc{}.Register(scalarFuncs)
+	PowerFunc{}.Register(scalarFuncs)
+	SqrtFunc{}.Register(scalarFuncs)
+	ExpFunc{}.Register(scalarFuncs)
+	LnFunc{}.Register(scalarFuncs)
+	LogFunc{}.Register(scalarFuncs)
+	BitwiseFunc{}.Register(scalarFuncs)
 }
 
 func RegisterAggrs() {
@@ -425,4 +516,13 @@ func RegisterAggrs() {
 	CountFunc{}.Register(aggrFuncs)
 	MaxFunc{}.Register(aggrFuncs)
 	MinFunc{}.Register(aggrFuncs)
+	StddevFunc{}.Register(aggrFuncs)
+	CovarFunc{}.Register(aggrFuncs)
+	BoolAggrFunc{}.Register(aggrFuncs)
+	StringAggFunc{}.Register(aggrFuncs)
+	FirstFunc{}.Register(aggrFuncs)
+	ArgMinMaxFunc{}.Register(aggrFuncs)
+	ModeFunc{}.Register(aggrFuncs)
+	ApproxCountDistinctFunc{}.Register(aggrFuncs)
+	QuantileFunc{}.Register(aggrFuncs)
 }
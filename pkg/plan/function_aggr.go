@@ -158,3 +158,227 @@ func (MinFunc) Register(funcList FunctionList) {
 
 	funcList.Add("min", set)
 }
+
+type StddevFunc struct {
+}
+
+func (StddevFunc) Register(funcList FunctionList) {
+	for name, op := range map[string]varianceOp{
+		"stddev":      {_sample: true, _sqrt: true},
+		"stddev_samp": {_sample: true, _sqrt: true},
+		"stddev_pop":  {_sqrt: true},
+		"variance":    {_sample: true},
+		"var_samp":    {_sample: true},
+		"var_pop":     {},
+	} {
+		set := NewFunctionSet(name, AggregateFuncType)
+		set.Add(StateAggregate[varianceState, float64, float64](
+			name,
+			common.DoubleType(),
+			common.DoubleType(),
+			op,
+		))
+		funcList.Add(name, set)
+	}
+}
+
+type CovarFunc struct {
+}
+
+func (CovarFunc) Register(funcList FunctionList) {
+	for name, kind := range map[string]covarKind{
+		"covar_pop":  covarPop,
+		"covar_samp": covarSamp,
+		"corr":       covarCorr,
+	} {
+		set := NewFunctionSet(name, AggregateFuncType)
+		set.Add(BinaryStateAggregate[covarState, float64, float64, float64](
+			name,
+			common.DoubleType(),
+			common.DoubleType(),
+			common.DoubleType(),
+			covarOp{_kind: kind},
+		))
+		funcList.Add(name, set)
+	}
+}
+
+type BoolAggrFunc struct {
+}
+
+func (BoolAggrFunc) Register(funcList FunctionList) {
+	for name, op := range map[string]boolAggrOp{
+		"bool_and": {_and: true},
+		"bool_or":  {},
+	} {
+		set := NewFunctionSet(name, AggregateFuncType)
+		set.Add(StateAggregate[boolAggrState, bool, bool](
+			name,
+			common.BooleanType(),
+			common.BooleanType(),
+			op,
+		))
+		funcList.Add(name, set)
+	}
+}
+
+type StringAggFunc struct {
+}
+
+func (StringAggFunc) Register(funcList FunctionList) {
+	for _, name := range []string{"string_agg", "listagg"} {
+		set := NewFunctionSet(name, AggregateFuncType)
+		//the separator is comma by default
+		set.Add(ValueAggregate[stringAggrState](
+			name,
+			[]common.LType{common.VarcharType()},
+			common.VarcharType(),
+			stringAggrOp{},
+		))
+		set.Add(ValueAggregate[stringAggrState](
+			name,
+			[]common.LType{common.VarcharType(), common.VarcharType()},
+			common.VarcharType(),
+			stringAggrOp{},
+		))
+		funcList.Add(name, set)
+	}
+}
+
+type FirstFunc struct {
+}
+
+func (FirstFunc) Register(funcList FunctionList) {
+	for name, op := range map[string]firstAggrOp{
+		"first":     {},
+		"any_value": {_skipNull: true},
+	} {
+		set := NewFunctionSet(name, AggregateFuncType)
+		fun := ValueAggregate[firstAggrState](
+			name,
+			[]common.LType{common.AnyType()},
+			common.AnyType(),
+			op,
+		)
+		fun._bind = BindAnyAggr
+		set.Add(fun)
+		funcList.Add(name, set)
+	}
+}
+
+// BindAnyAggr binds the aggregate on any type.
+// the result type is the type of the first argument.
+func BindAnyAggr(fun *FunctionV2, args []*Expr) *FunctionData {
+	for i, arg := range args {
+		fun._args[i] = arg.DataTyp
+	}
+	fun._retType = args[0].DataTyp
+	return nil
+}
+
+type ArgMinMaxFunc struct {
+}
+
+func (ArgMinMaxFunc) Register(funcList FunctionList) {
+	for name, op := range map[string]argMinMaxOp{
+		"arg_min": {},
+		"arg_max": {_max: true},
+	} {
+		set := NewFunctionSet(name, AggregateFuncType)
+		fun := ValueAggregate[argMinMaxState](
+			name,
+			[]common.LType{common.AnyType(), common.AnyType()},
+			common.AnyType(),
+			op,
+		)
+		fun._bind = BindAnyAggr
+		set.Add(fun)
+		funcList.Add(name, set)
+	}
+}
+
+type ModeFunc struct {
+}
+
+func (ModeFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("mode", AggregateFuncType)
+	fun := ValueAggregate[modeState](
+		"mode",
+		[]common.LType{common.AnyType()},
+		common.AnyType(),
+		modeOp{},
+	)
+	fun._bind = BindAnyAggr
+	set.Add(fun)
+	funcList.Add("mode", set)
+}
+
+type ApproxCountDistinctFunc struct {
+}
+
+func (ApproxCountDistinctFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("approx_count_distinct", AggregateFuncType)
+	fun := ApproxCountDistinct(common.AnyType())
+	fun._bind = func(fun *FunctionV2, args []*Expr) *FunctionData {
+		fun._args[0] = args[0].DataTyp
+		return nil
+	}
+	set.Add(fun)
+	funcList.Add("approx_count_distinct", set)
+}
+
+type QuantileFunc struct {
+}
+
+func (QuantileFunc) Register(funcList FunctionList) {
+	//percentile_cont and percentile_disc are the quantile_cont and quantile_disc
+	//with the WITHIN GROUP
+	set := NewFunctionSet("quantile_cont", AggregateFuncType)
+	set.Add(QuantileAggregate(
+		"quantile_cont",
+		[]common.LType{common.DoubleType(), common.DoubleType()},
+		common.DoubleType(),
+		false,
+		false,
+	))
+	funcList.Add("quantile_cont", set)
+
+	set = NewFunctionSet("quantile_disc", AggregateFuncType)
+	disc := QuantileAggregate(
+		"quantile_disc",
+		[]common.LType{common.AnyType(), common.DoubleType()},
+		common.AnyType(),
+		true,
+		false,
+	)
+	disc._bind = BindQuantileDisc
+	set.Add(disc)
+	funcList.Add("quantile_disc", set)
+
+	set = NewFunctionSet("median", AggregateFuncType)
+	set.Add(QuantileAggregate(
+		"median",
+		[]common.LType{common.DoubleType()},
+		common.DoubleType(),
+		false,
+		false,
+	))
+	funcList.Add("median", set)
+
+	set = NewFunctionSet("approx_quantile", AggregateFuncType)
+	set.Add(ValueAggregate[approxQuantileState](
+		"approx_quantile",
+		[]common.LType{common.DoubleType(), common.DoubleType()},
+		common.DoubleType(),
+		approxQuantileOp{},
+	))
+	funcList.Add("approx_quantile", set)
+}
+
+// BindQuantileDisc binds the quantile_disc on any type.
+// the fraction is still the double.
+func BindQuantileDisc(fun *FunctionV2, args []*Expr) *FunctionData {
+	fun._args[0] = args[0].DataTyp
+	fun._retType = args[0].DataTyp
+	return nil
+}
@@ -54,46 +54,24 @@ func DefaultCastFunc(
 		common.LTID_UTINYINT, common.LTID_USMALLINT, common.LTID_UINTEGER, common.LTID_UBIGINT, common.LTID_HUGEINT,
 		common.LTID_FLOAT, common.LTID_DOUBLE:
 		return NumericCastSwitch(input, src, dst)
-	case common.LTID_POINTER:
-		panic("usp")
 	case common.LTID_UUID:
-		panic("usp")
+		return UuidCastToSwitch(input, src, dst)
 	case common.LTID_DECIMAL:
 		return DecimalCastToSwitch(input, src, dst)
 	case common.LTID_DATE:
-		panic("usp")
+		return DateCastToSwitch(input, src, dst)
 	case common.LTID_TIME,
-		common.LTID_TIME_TZ,
 		common.LTID_TIMESTAMP,
-		common.LTID_TIMESTAMP_TZ,
-		common.LTID_TIMESTAMP_NS,
-		common.LTID_TIMESTAMP_MS,
-		common.LTID_TIMESTAMP_SEC:
-		panic("usp")
+		common.LTID_TIMESTAMP_TZ:
+		return TimestampCastToSwitch(input, src, dst)
 	case common.LTID_INTERVAL:
-		panic("usp")
+		return IntervalCastToSwitch(input, src, dst)
 	case common.LTID_VARCHAR:
 		return StringCastToSwitch(input, src, dst)
 	case common.LTID_BLOB:
-		panic("usp")
-	case common.LTID_BIT:
-		panic("usp")
-	case common.LTID_NULL:
-		panic("usp")
-	case common.LTID_MAP:
-		panic("usp")
-	case common.LTID_STRUCT:
-		panic("usp")
-	case common.LTID_LIST:
-		panic("usp")
-	case common.LTID_UNION:
-		panic("usp")
-	case common.LTID_ENUM:
-		panic("usp")
-	case common.LTID_AGGREGATE_STATE:
-		panic("usp")
+		return BlobCastToSwitch(input, src, dst)
 	default:
-		panic("usp")
+		return nil
 	}
 }
 
@@ -106,184 +84,340 @@ func NumericCastSwitch(
 	case common.LTID_BOOLEAN:
 		ret = BoolCastToSwitch(input, src, dst)
 	case common.LTID_TINYINT:
+		ret._fun = numberCastTo[int8](dst)
 	case common.LTID_SMALLINT:
+		ret._fun = numberCastTo[int16](dst)
 	case common.LTID_INTEGER:
-		ret = IntegerCastToSwitch(input, src, dst)
+		ret._fun = numberCastTo[int32](dst)
 	case common.LTID_BIGINT:
+		ret._fun = numberCastTo[int64](dst)
 	case common.LTID_UTINYINT:
+		ret._fun = numberCastTo[uint8](dst)
 	case common.LTID_USMALLINT:
+		ret._fun = numberCastTo[uint16](dst)
 	case common.LTID_UINTEGER:
+		ret._fun = numberCastTo[uint32](dst)
 	case common.LTID_UBIGINT:
+		ret._fun = numberCastTo[uint64](dst)
 	case common.LTID_HUGEINT:
 		ret = HugeintCastToSwitch(input, src, dst)
 	case common.LTID_FLOAT:
-		ret = FloatCastToSwitch(input, src, dst)
+		ret._fun = numberCastTo[float32](dst)
 	case common.LTID_DOUBLE:
+		ret._fun = numberCastTo[float64](dst)
 	default:
-		panic("usp")
-	}
-	if ret == nil || ret._fun == nil {
-		panic("usp")
+		return nil
 	}
 	return ret
 }
 
-func BoolCastToSwitch(
-	input *BindCastInput,
-	src, dst common.LType,
-) *BoundCastInfo {
-	ret := &BoundCastInfo{}
+// numberCastTo returns the cast from the numeric type T to the dst.
+func numberCastTo[T castNumber](dst common.LType) CastFuncType {
 	switch dst.Id {
 	case common.LTID_BOOLEAN:
+		return MakeCastFunc[T, bool](tryCastNumberToBool[T])
 	case common.LTID_TINYINT:
+		return MakeCastFunc[T, int8](tryCastNumber[T, int8])
 	case common.LTID_SMALLINT:
+		return MakeCastFunc[T, int16](tryCastNumber[T, int16])
 	case common.LTID_INTEGER:
+		return MakeCastFunc[T, int32](tryCastNumber[T, int32])
 	case common.LTID_BIGINT:
+		return MakeCastFunc[T, int64](tryCastNumber[T, int64])
 	case common.LTID_UTINYINT:
+		return MakeCastFunc[T, uint8](tryCastNumber[T, uint8])
 	case common.LTID_USMALLINT:
+		return MakeCastFunc[T, uint16](tryCastNumber[T, uint16])
 	case common.LTID_UINTEGER:
+		return MakeCastFunc[T, uint32](tryCastNumber[T, uint32])
 	case common.LTID_UBIGINT:
+		return MakeCastFunc[T, uint64](tryCastNumber[T, uint64])
 	case common.LTID_HUGEINT:
+		return MakeCastFunc[T, common.Hugeint](tryCastNumberToHugeint[T])
 	case common.LTID_FLOAT:
+		return MakeCastFunc[T, float32](tryCastNumber[T, float32])
 	case common.LTID_DOUBLE:
+		return MakeCastFunc[T, float64](tryCastNumber[T, float64])
+	case common.LTID_DECIMAL:
+		decCast := func(input *T, result *common.Decimal, _ bool) bool {
+			return tryCastNumberToDecimal(input, result, dst.Width, dst.Scale)
+		}
+		return MakeCastFunc[T, common.Decimal](decCast)
+	case common.LTID_VARCHAR:
+		return MakeCastFunc[T, common.String](tryCastToVarchar(formatNumber[T]))
 	default:
-		panic("usp")
+		return nil
 	}
-	return ret
 }
 
-func IntegerCastToSwitch(
+func BoolCastToSwitch(
 	input *BindCastInput,
 	src, dst common.LType,
 ) *BoundCastInfo {
 	ret := &BoundCastInfo{}
 	switch dst.Id {
-	case common.LTID_BOOLEAN:
 	case common.LTID_TINYINT:
+		ret._fun = MakeCastFunc[bool, int8](tryCastBoolToNumber[int8])
 	case common.LTID_SMALLINT:
+		ret._fun = MakeCastFunc[bool, int16](tryCastBoolToNumber[int16])
 	case common.LTID_INTEGER:
-		ret._fun = MakeCastFunc[int32, int32](tryCastInt32ToInt32)
+		ret._fun = MakeCastFunc[bool, int32](tryCastBoolToNumber[int32])
 	case common.LTID_BIGINT:
-		ret._fun = MakeCastFunc[int32, int64](tryCastInt32ToInt64)
+		ret._fun = MakeCastFunc[bool, int64](tryCastBoolToNumber[int64])
 	case common.LTID_UTINYINT:
+		ret._fun = MakeCastFunc[bool, uint8](tryCastBoolToNumber[uint8])
 	case common.LTID_USMALLINT:
+		ret._fun = MakeCastFunc[bool, uint16](tryCastBoolToNumber[uint16])
 	case common.LTID_UINTEGER:
+		ret._fun = MakeCastFunc[bool, uint32](tryCastBoolToNumber[uint32])
 	case common.LTID_UBIGINT:
-	case common.LTID_HUGEINT:
-		ret._fun = MakeCastFunc[int32, common.Hugeint](tryCastInt32ToHugeint)
+		ret._fun = MakeCastFunc[bool, uint64](tryCastBoolToNumber[uint64])
 	case common.LTID_FLOAT:
-		ret._fun = MakeCastFunc[int32, float32](tryCastInt32ToFloat32)
+		ret._fun = MakeCastFunc[bool, float32](tryCastBoolToNumber[float32])
 	case common.LTID_DOUBLE:
-		ret._fun = MakeCastFunc[int32, float64](tryCastInt32ToFloat64)
-	case common.LTID_DECIMAL:
-		decCast := func(input *int32, result *common.Decimal, _ bool) bool {
-			return tryCastInt32ToDecimal(input, result, dst.Scale, true)
-		}
-		ret._fun = MakeCastFunc[int32, common.Decimal](decCast)
+		ret._fun = MakeCastFunc[bool, float64](tryCastBoolToNumber[float64])
+	case common.LTID_VARCHAR:
+		ret._fun = MakeCastFunc[bool, common.String](tryCastToVarchar(formatBool))
 	default:
-		panic("usp")
+		return nil
 	}
 	return ret
 }
 
-func FloatCastToSwitch(
+func HugeintCastToSwitch(
 	input *BindCastInput,
 	src, dst common.LType,
 ) *BoundCastInfo {
 	ret := &BoundCastInfo{}
 	switch dst.Id {
 	case common.LTID_BOOLEAN:
+		ret._fun = MakeCastFunc[common.Hugeint, bool](tryCastHugeintToBool)
 	case common.LTID_TINYINT:
+		ret._fun = MakeCastFunc[common.Hugeint, int8](tryCastHugeintToNumber[int8])
 	case common.LTID_SMALLINT:
+		ret._fun = MakeCastFunc[common.Hugeint, int16](tryCastHugeintToNumber[int16])
 	case common.LTID_INTEGER:
-		ret._fun = MakeCastFunc[float32, int32](tryCastFloat32ToInt32)
+		ret._fun = MakeCastFunc[common.Hugeint, int32](tryCastHugeintToNumber[int32])
 	case common.LTID_BIGINT:
+		ret._fun = MakeCastFunc[common.Hugeint, int64](tryCastHugeintToNumber[int64])
 	case common.LTID_UTINYINT:
+		ret._fun = MakeCastFunc[common.Hugeint, uint8](tryCastHugeintToNumber[uint8])
 	case common.LTID_USMALLINT:
+		ret._fun = MakeCastFunc[common.Hugeint, uint16](tryCastHugeintToNumber[uint16])
 	case common.LTID_UINTEGER:
+		ret._fun = MakeCastFunc[common.Hugeint, uint32](tryCastHugeintToNumber[uint32])
 	case common.LTID_UBIGINT:
-	case common.LTID_HUGEINT:
+		ret._fun = MakeCastFunc[common.Hugeint, uint64](tryCastHugeintToNumber[uint64])
 	case common.LTID_FLOAT:
+		ret._fun = MakeCastFunc[common.Hugeint, float32](tryCastHugeintToNumber[float32])
 	case common.LTID_DOUBLE:
-		ret._fun = MakeCastFunc[float32, float64](tryCastFloat32ToFloat64)
+		ret._fun = MakeCastFunc[common.Hugeint, float64](tryCastHugeintToNumber[float64])
 	case common.LTID_DECIMAL:
-		ret._fun = MakeCastFunc[float32, common.Decimal](tryCastFloat32ToDecimal)
+		decCast := func(input *common.Hugeint, result *common.Decimal, _ bool) bool {
+			return tryCastHugeintToDecimal(input, result, dst.Width, dst.Scale)
+		}
+		ret._fun = MakeCastFunc[common.Hugeint, common.Decimal](decCast)
+	case common.LTID_VARCHAR:
+		ret._fun = MakeCastFunc[common.Hugeint, common.String](tryCastToVarchar(formatHugeint))
 	default:
-		panic("usp")
+		return nil
 	}
 	return ret
 }
 
-func HugeintCastToSwitch(
+func DecimalCastToSwitch(
 	input *BindCastInput,
 	src, dst common.LType,
 ) *BoundCastInfo {
 	ret := &BoundCastInfo{}
 	switch dst.Id {
 	case common.LTID_BOOLEAN:
+		ret._fun = MakeCastFunc[common.Decimal, bool](tryCastDecimalToBool)
 	case common.LTID_TINYINT:
+		ret._fun = MakeCastFunc[common.Decimal, int8](tryCastDecimalToNumber[int8])
 	case common.LTID_SMALLINT:
+		ret._fun = MakeCastFunc[common.Decimal, int16](tryCastDecimalToNumber[int16])
 	case common.LTID_INTEGER:
-		ret._fun = MakeCastFunc[common.Hugeint, int32](tryCastBigintToInt32)
+		ret._fun = MakeCastFunc[common.Decimal, int32](tryCastDecimalToNumber[int32])
 	case common.LTID_BIGINT:
+		ret._fun = MakeCastFunc[common.Decimal, int64](tryCastDecimalToNumber[int64])
 	case common.LTID_UTINYINT:
+		ret._fun = MakeCastFunc[common.Decimal, uint8](tryCastDecimalToNumber[uint8])
 	case common.LTID_USMALLINT:
+		ret._fun = MakeCastFunc[common.Decimal, uint16](tryCastDecimalToNumber[uint16])
 	case common.LTID_UINTEGER:
+		ret._fun = MakeCastFunc[common.Decimal, uint32](tryCastDecimalToNumber[uint32])
 	case common.LTID_UBIGINT:
+		ret._fun = MakeCastFunc[common.Decimal, uint64](tryCastDecimalToNumber[uint64])
 	case common.LTID_HUGEINT:
+		ret._fun = MakeCastFunc[common.Decimal, common.Hugeint](tryCastDecimalToHugeint)
 	case common.LTID_FLOAT:
-		ret._fun = MakeCastFunc[common.Hugeint, float32](tryCastBigintToFloat32)
+		ret._fun = MakeCastFunc[common.Decimal, float32](tryCastDecimalToNumber[float32])
 	case common.LTID_DOUBLE:
+		ret._fun = MakeCastFunc[common.Decimal, float64](tryCastDecimalToNumber[float64])
 	case common.LTID_DECIMAL:
-		ret._fun = MakeCastFunc[common.Hugeint, common.Decimal](tryCastBigintToDecimal)
+		decCast := func(input *common.Decimal, result *common.Decimal, _ bool) bool {
+			return tryCastDecimalToDecimal(input, result, dst.Width, dst.Scale)
+		}
+		ret._fun = MakeCastFunc[common.Decimal, common.Decimal](decCast)
+	case common.LTID_VARCHAR:
+		ret._fun = MakeCastFunc[common.Decimal, common.String](tryCastToVarchar(formatDecimal))
 	default:
-		panic("usp")
+		return nil
 	}
 	return ret
 }
 
-func DecimalCastToSwitch(
+func DateCastToSwitch(
+	input *BindCastInput,
+	src, dst common.LType,
+) *BoundCastInfo {
+	ret := &BoundCastInfo{}
+	switch dst.Id {
+	case common.LTID_TIMESTAMP, common.LTID_TIMESTAMP_TZ:
+		ret._fun = MakeCastFunc[common.Date, int64](tryCastDateToTimestamp)
+	case common.LTID_VARCHAR:
+		ret._fun = MakeCastFunc[common.Date, common.String](tryCastToVarchar(formatDate))
+	default:
+		return nil
+	}
+	return ret
+}
+
+// TimestampCastToSwitch casts the time, timestamp and timestamptz.
+// the session time zone is the UTC.
+func TimestampCastToSwitch(
+	input *BindCastInput,
+	src, dst common.LType,
+) *BoundCastInfo {
+	ret := &BoundCastInfo{}
+	switch dst.Id {
+	case common.LTID_DATE:
+		if src.Id == common.LTID_TIME {
+			return nil
+		}
+		ret._fun = MakeCastFunc[int64, common.Date](tryCastTimestampToDate)
+	case common.LTID_TIME:
+		ret._fun = MakeCastFunc[int64, int64](tryCastTimestampToTime)
+	case common.LTID_TIMESTAMP, common.LTID_TIMESTAMP_TZ:
+		if src.Id == common.LTID_TIME {
+			return nil
+		}
+		ret._fun = MakeCastFunc[int64, int64](tryCastTimestampToTimestamp)
+	case common.LTID_VARCHAR:
+		switch src.Id {
+		case common.LTID_TIME:
+			ret._fun = MakeCastFunc[int64, common.String](tryCastToVarchar(formatTime))
+		case common.LTID_TIMESTAMP_TZ:
+			ret._fun = MakeCastFunc[int64, common.String](tryCastToVarchar(formatTimestampTz))
+		default:
+			ret._fun = MakeCastFunc[int64, common.String](tryCastToVarchar(formatTimestamp))
+		}
+	default:
+		return nil
+	}
+	return ret
+}
+
+func IntervalCastToSwitch(
+	input *BindCastInput,
+	src, dst common.LType,
+) *BoundCastInfo {
+	ret := &BoundCastInfo{}
+	switch dst.Id {
+	case common.LTID_VARCHAR:
+		ret._fun = MakeCastFunc[common.Interval, common.String](tryCastToVarchar(formatInterval))
+	default:
+		return nil
+	}
+	return ret
+}
+
+func UuidCastToSwitch(
+	input *BindCastInput,
+	src, dst common.LType,
+) *BoundCastInfo {
+	ret := &BoundCastInfo{}
+	switch dst.Id {
+	case common.LTID_VARCHAR:
+		ret._fun = MakeCastFunc[common.Hugeint, common.String](tryCastToVarchar(formatUuid))
+	default:
+		return nil
+	}
+	return ret
+}
+
+func BlobCastToSwitch(
+	input *BindCastInput,
+	src, dst common.LType,
+) *BoundCastInfo {
+	ret := &BoundCastInfo{}
+	switch dst.Id {
+	case common.LTID_VARCHAR:
+		ret._fun = MakeCastFunc[common.String, common.String](tryCastToVarchar(formatBlob))
+	default:
+		return nil
+	}
+	return ret
+}
+
+func StringCastToSwitch(
 	input *BindCastInput,
 	src, dst common.LType,
 ) *BoundCastInfo {
 	ret := &BoundCastInfo{}
 	switch dst.Id {
 	case common.LTID_BOOLEAN:
+		ret._fun = MakeCastFunc[common.String, bool](tryCastVarcharToBool)
 	case common.LTID_TINYINT:
+		ret._fun = MakeCastFunc[common.String, int8](tryCastVarcharToNumber[int8])
 	case common.LTID_SMALLINT:
+		ret._fun = MakeCastFunc[common.String, int16](tryCastVarcharToNumber[int16])
 	case common.LTID_INTEGER:
+		ret._fun = MakeCastFunc[common.String, int32](tryCastVarcharToNumber[int32])
 	case common.LTID_BIGINT:
+		ret._fun = MakeCastFunc[common.String, int64](tryCastVarcharToNumber[int64])
 	case common.LTID_UTINYINT:
+		ret._fun = MakeCastFunc[common.String, uint8](tryCastVarcharToNumber[uint8])
 	case common.LTID_USMALLINT:
+		ret._fun = MakeCastFunc[common.String, uint16](tryCastVarcharToNumber[uint16])
 	case common.LTID_UINTEGER:
+		ret._fun = MakeCastFunc[common.String, uint32](tryCastVarcharToNumber[uint32])
 	case common.LTID_UBIGINT:
+		ret._fun = MakeCastFunc[common.String, uint64](tryCastVarcharToNumber[uint64])
 	case common.LTID_HUGEINT:
+		ret._fun = MakeCastFunc[common.String, common.Hugeint](tryCastVarcharToHugeint)
 	case common.LTID_FLOAT:
-		ret._fun = MakeCastFunc[common.Decimal, float32](tryCastDecimalToFloat32)
+		ret._fun = MakeCastFunc[common.String, float32](tryCastVarcharToNumber[float32])
 	case common.LTID_DOUBLE:
+		ret._fun = MakeCastFunc[common.String, float64](tryCastVarcharToNumber[float64])
 	case common.LTID_DECIMAL:
-		decCast := func(input *common.Decimal, result *common.Decimal, _ bool) bool {
-			return tryCastDecimalToDecimal(input, result, src.Scale, dst.Scale, true)
+		decCast := func(input *common.String, result *common.Decimal, _ bool) bool {
+			return tryCastVarcharToDecimal(input, result, dst.Width, dst.Scale)
 		}
-		ret._fun = MakeCastFunc[common.Decimal, common.Decimal](decCast)
-	default:
-		panic("usp")
-	}
-	return ret
-}
-
-func StringCastToSwitch(
-	input *BindCastInput,
-	src, dst common.LType,
-) *BoundCastInfo {
-	ret := &BoundCastInfo{}
-	switch dst.Id {
+		ret._fun = MakeCastFunc[common.String, common.Decimal](decCast)
 	case common.LTID_DATE:
 		ret._fun = MakeCastFunc[common.String, common.Date](tryCastVarcharToDate)
+	case common.LTID_TIMESTAMP:
+		ret._fun = MakeCastFunc[common.String, int64](tryCastVarcharToTimestamp)
+	case common.LTID_TIMESTAMP_TZ:
+		ret._fun = MakeCastFunc[common.String, int64](tryCastVarcharToTimestampTz)
+	case common.LTID_TIME:
+		ret._fun = MakeCastFunc[common.String, int64](tryCastVarcharToTime)
 	case common.LTID_INTERVAL:
 		ret._fun = MakeCastFunc[common.String, common.Interval](tryCastVarcharToInterval)
+	case common.LTID_UUID:
+		ret._fun = MakeCastFunc[common.String, common.Hugeint](tryCastVarcharToUuid)
+	case common.LTID_BLOB:
+		ret._fun = MakeCastFunc[common.String, common.String](tryCastVarcharToBlob)
+	case common.LTID_VARCHAR:
+		//truncate to the width
+		varcharCast := func(input *common.String, result *common.String, _ bool) bool {
+			return tryCastVarcharToVarchar(input, result, dst.Width)
+		}
+		ret._fun = MakeCastFunc[common.String, common.String](varcharCast)
 	default:
-		panic("usp")
+		return nil
 	}
 	return ret
 }
@@ -188,12 +188,46 @@ func (add AddFunc) Func2(lTyp, rTyp common.LType) *FunctionV2 {
 				_funcTyp: ScalarFuncType,
 				_scalar:  BinaryFunction[common.Interval, common.Date, common.Date](binIntervalDateAddOp),
 			}
+		case common.LTID_TIME:
+			return &FunctionV2{
+				_name:    "+",
+				_args:    []common.LType{lTyp, rTyp},
+				_retType: rTyp,
+				_funcTyp: ScalarFuncType,
+				_scalar:  BinaryFunction[common.Interval, int64, int64](binInterTimeAddOp),
+			}
+		case common.LTID_TIMESTAMP, common.LTID_TIMESTAMP_TZ:
+			return &FunctionV2{
+				_name:    "+",
+				_args:    []common.LType{lTyp, rTyp},
+				_retType: rTyp,
+				_funcTyp: ScalarFuncType,
+				_scalar:  BinaryFunction[common.Interval, int64, int64](binInterTimestampAddOp),
+			}
 		default:
 			panic("usp")
 		}
 	case common.LTID_TIME:
+		if rTyp.Id == common.LTID_INTERVAL {
+			return &FunctionV2{
+				_name:    "+",
+				_args:    []common.LType{lTyp, rTyp},
+				_retType: lTyp,
+				_funcTyp: ScalarFuncType,
+				_scalar:  BinaryFunction[int64, common.Interval, int64](binTimeInterAddOp),
+			}
+		}
 		panic("usp")
-	case common.LTID_TIMESTAMP:
+	case common.LTID_TIMESTAMP, common.LTID_TIMESTAMP_TZ:
+		if rTyp.Id == common.LTID_INTERVAL {
+			return &FunctionV2{
+				_name:    "+",
+				_args:    []common.LType{lTyp, rTyp},
+				_retType: lTyp,
+				_funcTyp: ScalarFuncType,
+				_scalar:  BinaryFunction[int64, common.Interval, int64](binTimestampInterAddOp),
+			}
+		}
 		panic("usp")
 	default:
 		panic(fmt.Sprintf("no addFunc for %s %s", lTyp, rTyp))
@@ -218,6 +252,10 @@ func (add AddFunc) Register(funcList FunctionList) {
 	//date|time|timestamp + interval
 	funcs.Add(add.Func2(common.DateType(), common.IntervalType()))
 	funcs.Add(add.Func2(common.IntervalType(), common.DateType()))
+	for _, typ := range common.Times() {
+		funcs.Add(add.Func2(typ, common.IntervalType()))
+		funcs.Add(add.Func2(common.IntervalType(), typ))
+	}
 
 	//funcs.Add(add.Func2(timeLTyp(), intervalLType()))
 	//funcs.Add(add.Func2(intervalLType(), timeLTyp()))
@@ -238,6 +276,7 @@ func negateInterval(input *common.Interval, result *common.Interval) {
 	negateInt32(&input.Months, &result.Months)
 	negateInt32(&input.Days, &result.Days)
 	negateInt32(&input.Year, &result.Year)
+	negateInt64(&input.Micros, &result.Micros)
 }
 
 func DecimalNegateBind(fun *FunctionV2, args []*Expr) *FunctionData {
@@ -349,9 +388,47 @@ func (sub SubFunc) Func2(lTyp, rTyp common.LType) *FunctionV2 {
 			panic("usp")
 		}
 	case common.LTID_TIME:
-		panic("usp")
-	case common.LTID_TIMESTAMP:
-		panic("usp")
+		switch rTyp.Id {
+		case common.LTID_TIME:
+			return &FunctionV2{
+				_name:    "-",
+				_args:    []common.LType{lTyp, rTyp},
+				_retType: common.IntervalType(),
+				_funcTyp: ScalarFuncType,
+				_scalar:  BinaryFunction[int64, int64, common.Interval](binTimeSubOp),
+			}
+		case common.LTID_INTERVAL:
+			return &FunctionV2{
+				_name:    "-",
+				_args:    []common.LType{lTyp, rTyp},
+				_retType: lTyp,
+				_funcTyp: ScalarFuncType,
+				_scalar:  BinaryFunction[int64, common.Interval, int64](binTimeInterSubOp),
+			}
+		default:
+			panic("usp")
+		}
+	case common.LTID_TIMESTAMP, common.LTID_TIMESTAMP_TZ:
+		switch rTyp.Id {
+		case common.LTID_TIMESTAMP, common.LTID_TIMESTAMP_TZ:
+			return &FunctionV2{
+				_name:    "-",
+				_args:    []common.LType{lTyp, rTyp},
+				_retType: common.IntervalType(),
+				_funcTyp: ScalarFuncType,
+				_scalar:  BinaryFunction[int64, int64, common.Interval](binTimestampSubOp),
+			}
+		case common.LTID_INTERVAL:
+			return &FunctionV2{
+				_name:    "-",
+				_args:    []common.LType{lTyp, rTyp},
+				_retType: lTyp,
+				_funcTyp: ScalarFuncType,
+				_scalar:  BinaryFunction[int64, common.Interval, int64](binTimestampInterSubOp),
+			}
+		default:
+			panic("usp")
+		}
 	default:
 		panic(fmt.Sprintf("no addFunc for %s %s", lTyp, rTyp))
 	}
@@ -371,7 +448,11 @@ func (sub SubFunc) Register(funcList FunctionList) {
 	subs.Add(sub.Func2(common.DateType(), common.DateType()))
 	//date - integer
 	subs.Add(sub.Func2(common.DateType(), common.IntegerType()))
-	//timestamp - timestamp
+	//time|timestamp - time|timestamp, time|timestamp - interval
+	for _, typ := range common.Times() {
+		subs.Add(sub.Func2(typ, typ))
+		subs.Add(sub.Func2(typ, common.IntervalType()))
+	}
 	//interval - interval
 	subs.Add(sub.Func2(common.IntervalType(), common.IntervalType()))
 	//date - interval
@@ -492,7 +573,26 @@ func (DevideFunc) Register(funcList FunctionList) {
 		_bind:    BindDecimalDivide,
 	}
 
+	divDouble := &FunctionV2{
+		_name:    ET_Div.String(),
+		_args:    []common.LType{common.DoubleType(), common.DoubleType()},
+		_retType: common.DoubleType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  BinaryFunction[float64, float64, float64](binFloat64DivOp),
+	}
+
+	//integer division truncates the result
+	for _, typ := range mathIntegerTypes() {
+		set.Add(&FunctionV2{
+			_name:    ET_Div.String(),
+			_args:    []common.LType{typ, typ},
+			_retType: typ,
+			_funcTyp: ScalarFuncType,
+			_scalar:  GetScalarIntegerMathFunction(typ.GetInternalType(), ET_Div.String()),
+		})
+	}
 	set.Add(divFloat)
+	set.Add(divDouble)
 	set.Add(divDec)
 
 	funcList.Add(ET_Div.String(), set)
@@ -532,7 +632,7 @@ func (like NotLikeFunc) Register(funcList FunctionList) {
 		_args:    []common.LType{common.VarcharType(), common.VarcharType()},
 		_retType: common.BooleanType(),
 		_funcTyp: ScalarFuncType,
-		//_scalar:  BinaryFunction[String, String, bool](binStringLikeOp),
+		_scalar:  BinaryFunction[common.String, common.String, bool](binStringNotLikeOp),
 	}
 	set := NewFunctionSet(ET_NotLike.String(), ScalarFuncType)
 	set.Add(likeFunc)
@@ -1173,6 +1273,24 @@ func (in InFunc) Register(funcList FunctionList) {
 	funcList.Add(ET_In.String(), set)
 }
 
+// timeCompareFuncs returns the comparisons of the time types.
+// they are compared on the int64 micros.
+func timeCompareFuncs(name string, op CompareOp[int64]) []*FunctionV2 {
+	funcs := make([]*FunctionV2, 0)
+	for _, typ := range common.Times() {
+		funcs = append(funcs, &FunctionV2{
+			_name:    name,
+			_args:    []common.LType{typ, typ},
+			_retType: common.BooleanType(),
+			_funcTyp: ScalarFuncType,
+			_scalar: BinaryFunction[int64, int64, bool](func(left, right *int64, result *bool) {
+				*result = op.operation(left, right)
+			}),
+		})
+	}
+	return funcs
+}
+
 type EqualFunc struct {
 }
 
@@ -1192,6 +1310,7 @@ func (equal EqualFunc) Register(funcList FunctionList) {
 		_args:    []common.LType{common.VarcharType(), common.VarcharType()},
 		_retType: common.BooleanType(),
 		_funcTyp: ScalarFuncType,
+		_scalar:  BinaryFunction[common.String, common.String, bool](binStringEqualOp),
 	}
 
 	equalBool := &FunctionV2{
@@ -1206,6 +1325,17 @@ func (equal EqualFunc) Register(funcList FunctionList) {
 	set.Add(equalStr)
 	set.Add(equalBool)
 
+	set.Add(&FunctionV2{
+		_name:    ET_Equal.String(),
+		_args:    []common.LType{common.DateType(), common.DateType()},
+		_retType: common.BooleanType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  BinaryFunction[common.Date, common.Date, bool](binDateEqualOp),
+	})
+	for _, fun := range timeCompareFuncs(ET_Equal.String(), equalOp[int64]{}) {
+		set.Add(fun)
+	}
+
 	funcList.Add(ET_Equal.String(), set)
 }
 
@@ -1220,6 +1350,7 @@ func (equal NotEqualFunc) Register(funcList FunctionList) {
 		_args:    []common.LType{common.IntegerType(), common.IntegerType()},
 		_retType: common.BooleanType(),
 		_funcTyp: ScalarFuncType,
+		_scalar:  BinaryFunction[int32, int32, bool](binInt32NotEqualOp),
 	}
 
 	notEqualStr := &FunctionV2{
@@ -1227,11 +1358,23 @@ func (equal NotEqualFunc) Register(funcList FunctionList) {
 		_args:    []common.LType{common.VarcharType(), common.VarcharType()},
 		_retType: common.BooleanType(),
 		_funcTyp: ScalarFuncType,
+		_scalar:  BinaryFunction[common.String, common.String, bool](binStringNotEqualOp),
 	}
 
 	set.Add(notEqualFunc1)
 	set.Add(notEqualStr)
 
+	set.Add(&FunctionV2{
+		_name:    ET_NotEqual.String(),
+		_args:    []common.LType{common.DateType(), common.DateType()},
+		_retType: common.BooleanType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  BinaryFunction[common.Date, common.Date, bool](binDateNotEqualOp),
+	})
+	for _, fun := range timeCompareFuncs(ET_NotEqual.String(), notEqualOp[int64]{}) {
+		set.Add(fun)
+	}
+
 	funcList.Add(ET_NotEqual.String(), set)
 }
 
@@ -1320,6 +1463,9 @@ func (Greater) Register(funcList FunctionList) {
 	set.Add(gt3)
 	set.Add(gt4)
 
+	for _, fun := range timeCompareFuncs(ET_Greater.String(), greatOp[int64]{}) {
+		set.Add(fun)
+	}
 	funcList.Add(ET_Greater.String(), set)
 }
 
@@ -1355,6 +1501,9 @@ func (GreaterThan) Register(funcList FunctionList) {
 	set.Add(gtDate)
 	set.Add(gtFloat)
 
+	for _, fun := range timeCompareFuncs(ET_GreaterEqual.String(), greatEqualOp[int64]{}) {
+		set.Add(fun)
+	}
 	funcList.Add(ET_GreaterEqual.String(), set)
 }
 
@@ -1431,6 +1580,9 @@ func (LessFunc) Register(funcList FunctionList) {
 	set.Add(lInt)
 	set.Add(lFloat)
 	set.Add(lDouble)
+	for _, fun := range timeCompareFuncs(ET_Less.String(), lessOp[int64]{}) {
+		set.Add(fun)
+	}
 	funcList.Add(ET_Less.String(), set)
 }
 
@@ -1460,6 +1612,9 @@ func (LessEqualFunc) Register(funcList FunctionList) {
 	set.Add(leDate)
 	set.Add(leInt)
 	set.Add(leFloat)
+	for _, fun := range timeCompareFuncs(ET_LessEqual.String(), lessEqualOp[int64]{}) {
+		set.Add(fun)
+	}
 	funcList.Add(ET_LessEqual.String(), set)
 }
 
@@ -1517,9 +1672,209 @@ func (ExtractFunc) Register(funcList FunctionList) {
 
 	set.Add(extract)
 
+	for _, typ := range common.Times() {
+		op := datePartTimestampOp
+		if typ.Id == common.LTID_TIME {
+			op = datePartTimeOp
+		}
+		set.Add(&FunctionV2{
+			_name:    ET_Extract.String(),
+			_args:    []common.LType{common.VarcharType(), typ},
+			_retType: common.DoubleType(),
+			_funcTyp: ScalarFuncType,
+			_scalar:  BinaryFunction[common.String, int64, float64](op),
+		})
+	}
+	set.Add(&FunctionV2{
+		_name:    ET_Extract.String(),
+		_args:    []common.LType{common.VarcharType(), common.IntervalType()},
+		_retType: common.DoubleType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  BinaryFunction[common.String, common.Interval, float64](datePartIntervalOp),
+	})
+
 	funcList.Add(ET_Extract.String(), set)
 }
 
+type DateTruncFunc struct {
+}
+
+func (DateTruncFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("date_trunc", ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:    "date_trunc",
+		_args:    []common.LType{common.VarcharType(), common.DateType()},
+		_retType: common.TimestampType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  BinaryFunction[common.String, common.Date, int64](dateTruncDateOp),
+	})
+	for _, typ := range []common.LType{common.TimestampType(), common.TimestampTzType()} {
+		set.Add(&FunctionV2{
+			_name:    "date_trunc",
+			_args:    []common.LType{common.VarcharType(), typ},
+			_retType: typ,
+			_funcTyp: ScalarFuncType,
+			_scalar:  BinaryFunction[common.String, int64, int64](dateTruncTimestampOp),
+		})
+	}
+	set.Add(&FunctionV2{
+		_name:    "date_trunc",
+		_args:    []common.LType{common.VarcharType(), common.IntervalType()},
+		_retType: common.IntervalType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  BinaryFunction[common.String, common.Interval, common.Interval](dateTruncIntervalOp),
+	})
+	funcList.Add("date_trunc", set)
+}
+
+type DatePartFunc struct {
+}
+
+func (DatePartFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("date_part", ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:    "date_part",
+		_args:    []common.LType{common.VarcharType(), common.DateType()},
+		_retType: common.DoubleType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  BinaryFunction[common.String, common.Date, float64](datePartDateOp),
+	})
+	for _, typ := range common.Times() {
+		op := datePartTimestampOp
+		if typ.Id == common.LTID_TIME {
+			op = datePartTimeOp
+		}
+		set.Add(&FunctionV2{
+			_name:    "date_part",
+			_args:    []common.LType{common.VarcharType(), typ},
+			_retType: common.DoubleType(),
+			_funcTyp: ScalarFuncType,
+			_scalar:  BinaryFunction[common.String, int64, float64](op),
+		})
+	}
+	set.Add(&FunctionV2{
+		_name:    "date_part",
+		_args:    []common.LType{common.VarcharType(), common.IntervalType()},
+		_retType: common.DoubleType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  BinaryFunction[common.String, common.Interval, float64](datePartIntervalOp),
+	})
+	funcList.Add("date_part", set)
+}
+
+type AgeFunc struct {
+}
+
+func (AgeFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("age", ScalarFuncType)
+	for _, typ := range []common.LType{common.TimestampType(), common.TimestampTzType()} {
+		set.Add(&FunctionV2{
+			_name:    "age",
+			_args:    []common.LType{typ, typ},
+			_retType: common.IntervalType(),
+			_funcTyp: ScalarFuncType,
+			_scalar:  BinaryFunction[int64, int64, common.Interval](ageTimestampOp),
+		})
+	}
+	funcList.Add("age", set)
+}
+
+type DateDiffFunc struct {
+}
+
+func (DateDiffFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("date_diff", ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:    "date_diff",
+		_args:    []common.LType{common.VarcharType(), common.DateType(), common.DateType()},
+		_retType: common.BigintType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  TernaryFunction[common.String, common.Date, common.Date, int64](dateDiffDateOp),
+	})
+	for _, typ := range []common.LType{common.TimestampType(), common.TimestampTzType()} {
+		set.Add(&FunctionV2{
+			_name:    "date_diff",
+			_args:    []common.LType{common.VarcharType(), typ, typ},
+			_retType: common.BigintType(),
+			_funcTyp: ScalarFuncType,
+			_scalar:  TernaryFunction[common.String, int64, int64, int64](dateDiffTimestampOp),
+		})
+	}
+	funcList.Add("date_diff", set)
+}
+
+type MakeDateFunc struct {
+}
+
+func (MakeDateFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("make_date", ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:    "make_date",
+		_args:    []common.LType{common.IntegerType(), common.IntegerType(), common.IntegerType()},
+		_retType: common.DateType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  TernaryFunction[int32, int32, int32, common.Date](makeDateOp),
+	})
+	funcList.Add("make_date", set)
+}
+
+type DateFormatFunc struct {
+}
+
+func (DateFormatFunc) Register(funcList FunctionList) {
+	for name, op := range map[string]BinaryOp[int64, common.String, common.String]{
+		"to_char":  toCharTimestampOp,
+		"strftime": strftimeTimestampOp,
+	} {
+		set := NewFunctionSet(name, ScalarFuncType)
+		for _, typ := range []common.LType{common.TimestampType(), common.TimestampTzType()} {
+			set.Add(&FunctionV2{
+				_name:    name,
+				_args:    []common.LType{typ, common.VarcharType()},
+				_retType: common.VarcharType(),
+				_funcTyp: ScalarFuncType,
+				_scalar:  BinaryFunction[int64, common.String, common.String](op),
+			})
+		}
+		funcList.Add(name, set)
+	}
+}
+
+type DateParseFunc struct {
+}
+
+func (DateParseFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("to_date", ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:    "to_date",
+		_args:    []common.LType{common.VarcharType(), common.VarcharType()},
+		_retType: common.DateType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  BinaryFunction[common.String, common.String, common.Date](toDateOp),
+	})
+	funcList.Add("to_date", set)
+
+	set = NewFunctionSet("to_timestamp", ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:    "to_timestamp",
+		_args:    []common.LType{common.VarcharType(), common.VarcharType()},
+		_retType: common.TimestampTzType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  BinaryFunction[common.String, common.String, int64](toTimestampOp),
+	})
+	funcList.Add("to_timestamp", set)
+
+	set = NewFunctionSet("strptime", ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:    "strptime",
+		_args:    []common.LType{common.VarcharType(), common.VarcharType()},
+		_retType: common.TimestampType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  BinaryFunction[common.String, common.String, int64](strptimeOp),
+	})
+	funcList.Add("strptime", set)
+}
+
 type SubstringFunc struct {
 }
 
@@ -1554,3 +1909,978 @@ func (SubstringFunc) Register(funcList FunctionList) {
 
 	funcList.Add(ET_Substring.String(), set)
 }
+
+type IsNullFunc struct {
+}
+
+func (IsNullFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet(ET_IsNull.String(), ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:         ET_IsNull.String(),
+		_args:         []common.LType{common.AnyType()},
+		_retType:      common.BooleanType(),
+		_funcTyp:      ScalarFuncType,
+		_nullHandling: SpecialHandling,
+		_scalar:       isNullFunc(false),
+	})
+	funcList.Add(ET_IsNull.String(), set)
+}
+
+type IsNotNullFunc struct {
+}
+
+func (IsNotNullFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet(ET_IsNotNull.String(), ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:         ET_IsNotNull.String(),
+		_args:         []common.LType{common.AnyType()},
+		_retType:      common.BooleanType(),
+		_funcTyp:      ScalarFuncType,
+		_nullHandling: SpecialHandling,
+		_scalar:       isNullFunc(true),
+	})
+	funcList.Add(ET_IsNotNull.String(), set)
+}
+
+type IsDistinctFromFunc struct {
+}
+
+func (IsDistinctFromFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet(ET_IsDistinctFrom.String(), ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:         ET_IsDistinctFrom.String(),
+		_args:         []common.LType{common.AnyType(), common.AnyType()},
+		_retType:      common.BooleanType(),
+		_funcTyp:      ScalarFuncType,
+		_nullHandling: SpecialHandling,
+		_scalar:       isDistinctFunc(false),
+	})
+	funcList.Add(ET_IsDistinctFrom.String(), set)
+}
+
+type IsNotDistinctFromFunc struct {
+}
+
+func (IsNotDistinctFromFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet(ET_IsNotDistinctFrom.String(), ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:         ET_IsNotDistinctFrom.String(),
+		_args:         []common.LType{common.AnyType(), common.AnyType()},
+		_retType:      common.BooleanType(),
+		_funcTyp:      ScalarFuncType,
+		_nullHandling: SpecialHandling,
+		_scalar:       isDistinctFunc(true),
+	})
+	funcList.Add(ET_IsNotDistinctFrom.String(), set)
+}
+
+type NullIfFunc struct {
+}
+
+func (NullIfFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet(ET_NullIf.String(), ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:         ET_NullIf.String(),
+		_args:         []common.LType{common.AnyType(), common.AnyType()},
+		_retType:      common.AnyType(),
+		_funcTyp:      ScalarFuncType,
+		_nullHandling: SpecialHandling,
+		_scalar:       nullIfFunc,
+		_bind:         BindFirstArgType,
+	})
+	funcList.Add(ET_NullIf.String(), set)
+}
+
+// BindFirstArgType decides the result type by the first argument.
+func BindFirstArgType(fun *FunctionV2, args []*Expr) *FunctionData {
+	fun._retType = args[0].DataTyp
+	return nil
+}
+
+type CoalesceFunc struct {
+}
+
+// Func returns the COALESCE with argCnt arguments of the typ.
+func (CoalesceFunc) Func(typ common.LType, argCnt int) *FunctionV2 {
+	args := make([]common.LType, argCnt)
+	for i := range args {
+		args[i] = typ
+	}
+	return &FunctionV2{
+		_name:         ET_Coalesce.String(),
+		_args:         args,
+		_retType:      typ,
+		_funcTyp:      ScalarFuncType,
+		_nullHandling: SpecialHandling,
+		_scalar:       coalesceFunc,
+	}
+}
+
+type LengthFunc struct {
+}
+
+func (LengthFunc) Register(funcList FunctionList) {
+	for _, name := range []string{"length", "char_length"} {
+		set := NewFunctionSet(name, ScalarFuncType)
+		set.Add(&FunctionV2{
+			_name:    name,
+			_args:    []common.LType{common.VarcharType()},
+			_retType: common.IntegerType(),
+			_funcTyp: ScalarFuncType,
+			_scalar:  UnaryFunction[common.String, int32](lengthOp),
+		})
+		funcList.Add(name, set)
+	}
+}
+
+type LowerFunc struct {
+}
+
+func (LowerFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("lower", ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:    "lower",
+		_args:    []common.LType{common.VarcharType()},
+		_retType: common.VarcharType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  UnaryFunction[common.String, common.String](lowerOp),
+	})
+	funcList.Add("lower", set)
+}
+
+type UpperFunc struct {
+}
+
+func (UpperFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("upper", ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:    "upper",
+		_args:    []common.LType{common.VarcharType()},
+		_retType: common.VarcharType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  UnaryFunction[common.String, common.String](upperOp),
+	})
+	funcList.Add("upper", set)
+}
+
+type TrimFunc struct {
+}
+
+// Register registers the btrim, ltrim and rtrim.
+// the TRIM([BOTH | LEADING | TRAILING] ...) is rewritten into them by the parser.
+func (TrimFunc) Register(funcList FunctionList) {
+	kases := []struct {
+		name   string
+		op     UnaryOp[common.String, common.String]
+		charOp BinaryOp[common.String, common.String, common.String]
+	}{
+		{"btrim", btrimOp, btrimCharsOp},
+		{"ltrim", ltrimOp, ltrimCharsOp},
+		{"rtrim", rtrimOp, rtrimCharsOp},
+	}
+	for _, kase := range kases {
+		set := NewFunctionSet(kase.name, ScalarFuncType)
+		set.Add(&FunctionV2{
+			_name:    kase.name,
+			_args:    []common.LType{common.VarcharType()},
+			_retType: common.VarcharType(),
+			_funcTyp: ScalarFuncType,
+			_scalar:  UnaryFunction[common.String, common.String](kase.op),
+		})
+		set.Add(&FunctionV2{
+			_name:    kase.name,
+			_args:    []common.LType{common.VarcharType(), common.VarcharType()},
+			_retType: common.VarcharType(),
+			_funcTyp: ScalarFuncType,
+			_scalar:  BinaryFunction[common.String, common.String, common.String](kase.charOp),
+		})
+		funcList.Add(kase.name, set)
+	}
+}
+
+type ConcatFunc struct {
+}
+
+func (ConcatFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("concat", ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:         "concat",
+		_args:         []common.LType{common.AnyType()},
+		_varArgs:      common.AnyType(),
+		_retType:      common.VarcharType(),
+		_funcTyp:      ScalarFuncType,
+		_nullHandling: SpecialHandling,
+		_scalar:       concatFunc,
+		_bind:         BindConcat,
+	})
+	funcList.Add("concat", set)
+
+	wsSet := NewFunctionSet("concat_ws", ScalarFuncType)
+	wsSet.Add(&FunctionV2{
+		_name:         "concat_ws",
+		_args:         []common.LType{common.AnyType(), common.AnyType()},
+		_varArgs:      common.AnyType(),
+		_retType:      common.VarcharType(),
+		_funcTyp:      ScalarFuncType,
+		_nullHandling: SpecialHandling,
+		_scalar:       concatWsFunc,
+		_bind:         BindConcat,
+	})
+	funcList.Add("concat_ws", wsSet)
+
+	//the || returns NULL on the NULL
+	opSet := NewFunctionSet(ET_Concat.String(), ScalarFuncType)
+	opSet.Add(&FunctionV2{
+		_name:    ET_Concat.String(),
+		_args:    []common.LType{common.VarcharType(), common.VarcharType()},
+		_retType: common.VarcharType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  BinaryFunction[common.String, common.String, common.String](concatOp),
+	})
+	funcList.Add(ET_Concat.String(), opSet)
+}
+
+type ReplaceFunc struct {
+}
+
+func (ReplaceFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("replace", ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:    "replace",
+		_args:    []common.LType{common.VarcharType(), common.VarcharType(), common.VarcharType()},
+		_retType: common.VarcharType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  TernaryFunction[common.String, common.String, common.String, common.String](replaceOp),
+	})
+	funcList.Add("replace", set)
+}
+
+type StrposFunc struct {
+}
+
+// Register registers the strpos and the position.
+// the POSITION(sub IN s) is rewritten into the position(s, sub) by the parser.
+func (StrposFunc) Register(funcList FunctionList) {
+	for _, name := range []string{"strpos", "position"} {
+		set := NewFunctionSet(name, ScalarFuncType)
+		set.Add(&FunctionV2{
+			_name:    name,
+			_args:    []common.LType{common.VarcharType(), common.VarcharType()},
+			_retType: common.IntegerType(),
+			_funcTyp: ScalarFuncType,
+			_scalar:  BinaryFunction[common.String, common.String, int32](strposOp),
+		})
+		funcList.Add(name, set)
+	}
+}
+
+type LeftFunc struct {
+}
+
+func (LeftFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("left", ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:    "left",
+		_args:    []common.LType{common.VarcharType(), common.IntegerType()},
+		_retType: common.VarcharType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  BinaryFunction[common.String, int32, common.String](leftOp),
+	})
+	funcList.Add("left", set)
+}
+
+type RightFunc struct {
+}
+
+func (RightFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("right", ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:    "right",
+		_args:    []common.LType{common.VarcharType(), common.IntegerType()},
+		_retType: common.VarcharType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  BinaryFunction[common.String, int32, common.String](rightOp),
+	})
+	funcList.Add("right", set)
+}
+
+type PadFunc struct {
+}
+
+// Register registers the lpad and rpad. the fill is the space by default.
+func (PadFunc) Register(funcList FunctionList) {
+	kases := []struct {
+		name   string
+		op     BinaryOp[common.String, int32, common.String]
+		fillOp TernaryOp[common.String, int32, common.String, common.String]
+	}{
+		{"lpad", lpadOp, lpadFillOp},
+		{"rpad", rpadOp, rpadFillOp},
+	}
+	for _, kase := range kases {
+		set := NewFunctionSet(kase.name, ScalarFuncType)
+		set.Add(&FunctionV2{
+			_name:    kase.name,
+			_args:    []common.LType{common.VarcharType(), common.IntegerType()},
+			_retType: common.VarcharType(),
+			_funcTyp: ScalarFuncType,
+			_scalar:  BinaryFunction[common.String, int32, common.String](kase.op),
+		})
+		set.Add(&FunctionV2{
+			_name:    kase.name,
+			_args:    []common.LType{common.VarcharType(), common.IntegerType(), common.VarcharType()},
+			_retType: common.VarcharType(),
+			_funcTyp: ScalarFuncType,
+			_scalar:  TernaryFunction[common.String, int32, common.String, common.String](kase.fillOp),
+		})
+		funcList.Add(kase.name, set)
+	}
+}
+
+type SplitPartFunc struct {
+}
+
+func (SplitPartFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("split_part", ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:    "split_part",
+		_args:    []common.LType{common.VarcharType(), common.VarcharType(), common.IntegerType()},
+		_retType: common.VarcharType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  TernaryFunction[common.String, common.String, int32, common.String](splitPartOp),
+	})
+	funcList.Add("split_part", set)
+}
+
+type StartsWithFunc struct {
+}
+
+func (StartsWithFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("starts_with", ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:    "starts_with",
+		_args:    []common.LType{common.VarcharType(), common.VarcharType()},
+		_retType: common.BooleanType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  BinaryFunction[common.String, common.String, bool](startsWithOp),
+	})
+	funcList.Add("starts_with", set)
+}
+
+type ReverseFunc struct {
+}
+
+func (ReverseFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("reverse", ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:    "reverse",
+		_args:    []common.LType{common.VarcharType()},
+		_retType: common.VarcharType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  UnaryFunction[common.String, common.String](reverseOp),
+	})
+	funcList.Add("reverse", set)
+}
+
+type RepeatFunc struct {
+}
+
+func (RepeatFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("repeat", ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:    "repeat",
+		_args:    []common.LType{common.VarcharType(), common.IntegerType()},
+		_retType: common.VarcharType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  BinaryFunction[common.String, int32, common.String](repeatOp),
+	})
+	funcList.Add("repeat", set)
+}
+
+type ILikeFunc struct {
+}
+
+func (ILikeFunc) Register(funcList FunctionList) {
+	kases := []struct {
+		et ET_SubTyp
+		op BinaryOp[common.String, common.String, bool]
+	}{
+		{ET_ILike, binStringILikeOp},
+		{ET_NotILike, binStringNotILikeOp},
+	}
+	for _, kase := range kases {
+		set := NewFunctionSet(kase.et.String(), ScalarFuncType)
+		set.Add(&FunctionV2{
+			_name:    kase.et.String(),
+			_args:    []common.LType{common.VarcharType(), common.VarcharType()},
+			_retType: common.BooleanType(),
+			_funcTyp: ScalarFuncType,
+			_scalar:  BinaryFunction[common.String, common.String, bool](kase.op),
+		})
+		funcList.Add(kase.et.String(), set)
+	}
+}
+
+type StringMatchFunc struct {
+}
+
+// Register registers the prefix, suffix and contains.
+// the LIKE with the constant pattern is rewritten into them.
+func (StringMatchFunc) Register(funcList FunctionList) {
+	kases := []struct {
+		name string
+		op   BinaryOp[common.String, common.String, bool]
+	}{
+		{"prefix", prefixOp},
+		{"suffix", suffixOp},
+		{"contains", containsOp},
+	}
+	for _, kase := range kases {
+		set := NewFunctionSet(kase.name, ScalarFuncType)
+		set.Add(&FunctionV2{
+			_name:    kase.name,
+			_args:    []common.LType{common.VarcharType(), common.VarcharType()},
+			_retType: common.BooleanType(),
+			_funcTyp: ScalarFuncType,
+			_scalar:  BinaryFunction[common.String, common.String, bool](kase.op),
+		})
+		funcList.Add(kase.name, set)
+	}
+}
+
+type RegexpMatchFunc struct {
+}
+
+// Register registers the ~, ~*, !~ and !~*.
+func (RegexpMatchFunc) Register(funcList FunctionList) {
+	kases := []struct {
+		et              ET_SubTyp
+		caseInsensitive bool
+		not             bool
+	}{
+		{ET_RegexpMatch, false, false},
+		{ET_NotRegexpMatch, false, true},
+		{ET_RegexpIMatch, true, false},
+		{ET_NotRegexpIMatch, true, true},
+	}
+	for _, kase := range kases {
+		set := NewFunctionSet(kase.et.String(), ScalarFuncType)
+		set.Add(&FunctionV2{
+			_name:    kase.et.String(),
+			_args:    []common.LType{common.VarcharType(), common.VarcharType()},
+			_retType: common.BooleanType(),
+			_funcTyp: ScalarFuncType,
+			_scalar:  regexMatchFunc(kase.caseInsensitive, kase.not),
+		})
+		funcList.Add(kase.et.String(), set)
+	}
+}
+
+type SimilarToEscapeFunc struct {
+}
+
+// Register registers the similar_to_escape that converts
+// the pattern of the SIMILAR TO into the regular expression.
+func (SimilarToEscapeFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("similar_to_escape", ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:    "similar_to_escape",
+		_args:    []common.LType{common.VarcharType()},
+		_retType: common.VarcharType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  UnaryFunction[common.String, common.String](similarToEscapeOp),
+	})
+	funcList.Add("similar_to_escape", set)
+}
+
+type RegexpMatchesFunc struct {
+}
+
+func (RegexpMatchesFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("regexp_matches", ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:    "regexp_matches",
+		_args:    []common.LType{common.VarcharType(), common.VarcharType()},
+		_retType: common.BooleanType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  regexpMatchesFunc,
+	})
+	set.Add(&FunctionV2{
+		_name:    "regexp_matches",
+		_args:    []common.LType{common.VarcharType(), common.VarcharType(), common.VarcharType()},
+		_retType: common.BooleanType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  regexpMatchesFunc,
+	})
+	funcList.Add("regexp_matches", set)
+}
+
+type RegexpReplaceFunc struct {
+}
+
+func (RegexpReplaceFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("regexp_replace", ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:    "regexp_replace",
+		_args:    []common.LType{common.VarcharType(), common.VarcharType(), common.VarcharType()},
+		_retType: common.VarcharType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  regexpReplaceFunc,
+	})
+	set.Add(&FunctionV2{
+		_name:    "regexp_replace",
+		_args:    []common.LType{common.VarcharType(), common.VarcharType(), common.VarcharType(), common.VarcharType()},
+		_retType: common.VarcharType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  regexpReplaceFunc,
+	})
+	funcList.Add("regexp_replace", set)
+}
+
+type RegexpExtractFunc struct {
+}
+
+func (RegexpExtractFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("regexp_extract", ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:    "regexp_extract",
+		_args:    []common.LType{common.VarcharType(), common.VarcharType()},
+		_retType: common.VarcharType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  regexpExtractFunc,
+	})
+	set.Add(&FunctionV2{
+		_name:    "regexp_extract",
+		_args:    []common.LType{common.VarcharType(), common.VarcharType(), common.IntegerType()},
+		_retType: common.VarcharType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  regexpExtractFunc,
+	})
+	funcList.Add("regexp_extract", set)
+}
+
+type AbsFunc struct {
+}
+
+func (AbsFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("abs", ScalarFuncType)
+	for _, typ := range mathIntegerTypes() {
+		set.Add(&FunctionV2{
+			_name:    "abs",
+			_args:    []common.LType{typ},
+			_retType: typ,
+			_funcTyp: ScalarFuncType,
+			_scalar:  GetScalarIntegerMathFunction(typ.GetInternalType(), "abs"),
+		})
+	}
+	set.Add(&FunctionV2{
+		_name:    "abs",
+		_args:    []common.LType{common.FloatType()},
+		_retType: common.FloatType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  UnaryFunction[float32, float32](absFloatOp[float32]),
+	})
+	set.Add(&FunctionV2{
+		_name:    "abs",
+		_args:    []common.LType{common.DoubleType()},
+		_retType: common.DoubleType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  UnaryFunction[float64, float64](absFloatOp[float64]),
+	})
+	set.Add(&FunctionV2{
+		_name:    "abs",
+		_args:    []common.LType{common.DecimalType(common.DecimalMaxWidthInt64, 0)},
+		_retType: common.DecimalType(common.DecimalMaxWidthInt64, 0),
+		_funcTyp: ScalarFuncType,
+		_scalar:  UnaryFunction[common.Decimal, common.Decimal](absDecimalOp),
+		_bind:    BindDecimalArgType,
+	})
+	funcList.Add("abs", set)
+}
+
+// BindDecimalArgType returns the decimal of the argument.
+func BindDecimalArgType(fun *FunctionV2, args []*Expr) *FunctionData {
+	fun._args[0] = args[0].DataTyp
+	fun._retType = args[0].DataTyp
+	return nil
+}
+
+// BindDecimalScaleZero returns the decimal without the digits
+// after the decimal point.
+func BindDecimalScaleZero(fun *FunctionV2, args []*Expr) *FunctionData {
+	fun._args[0] = args[0].DataTyp
+	fun._retType = common.DecimalType(args[0].DataTyp.Width, 0)
+	return nil
+}
+
+type SignFunc struct {
+}
+
+func (SignFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("sign", ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:    "sign",
+		_args:    []common.LType{common.DoubleType()},
+		_retType: common.DoubleType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  UnaryFunction[float64, float64](signFloat64Op),
+	})
+	set.Add(&FunctionV2{
+		_name:    "sign",
+		_args:    []common.LType{common.DecimalType(common.DecimalMaxWidthInt64, 0)},
+		_retType: common.DecimalType(common.DecimalMaxWidthInt64, 0),
+		_funcTyp: ScalarFuncType,
+		_scalar:  UnaryFunction[common.Decimal, common.Decimal](signDecimalOp),
+		_bind:    BindDecimalScaleZero,
+	})
+	funcList.Add("sign", set)
+}
+
+type CeilFunc struct {
+}
+
+func (CeilFunc) Register(funcList FunctionList) {
+	for _, name := range []string{"ceil", "ceiling"} {
+		set := NewFunctionSet(name, ScalarFuncType)
+		set.Add(&FunctionV2{
+			_name:    name,
+			_args:    []common.LType{common.DoubleType()},
+			_retType: common.DoubleType(),
+			_funcTyp: ScalarFuncType,
+			_scalar:  UnaryFunction[float64, float64](ceilFloat64Op),
+		})
+		set.Add(&FunctionV2{
+			_name:    name,
+			_args:    []common.LType{common.DecimalType(common.DecimalMaxWidthInt64, 0)},
+			_retType: common.DecimalType(common.DecimalMaxWidthInt64, 0),
+			_funcTyp: ScalarFuncType,
+			_scalar:  UnaryFunction[common.Decimal, common.Decimal](ceilDecimalOp),
+			_bind:    BindDecimalScaleZero,
+		})
+		funcList.Add(name, set)
+	}
+}
+
+type FloorFunc struct {
+}
+
+func (FloorFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("floor", ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:    "floor",
+		_args:    []common.LType{common.DoubleType()},
+		_retType: common.DoubleType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  UnaryFunction[float64, float64](floorFloat64Op),
+	})
+	set.Add(&FunctionV2{
+		_name:    "floor",
+		_args:    []common.LType{common.DecimalType(common.DecimalMaxWidthInt64, 0)},
+		_retType: common.DecimalType(common.DecimalMaxWidthInt64, 0),
+		_funcTyp: ScalarFuncType,
+		_scalar:  UnaryFunction[common.Decimal, common.Decimal](floorDecimalOp),
+		_bind:    BindDecimalScaleZero,
+	})
+	funcList.Add("floor", set)
+}
+
+type RoundFunc struct {
+}
+
+// Register registers the round(x) and the round(x, n).
+func (RoundFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("round", ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:    "round",
+		_args:    []common.LType{common.DoubleType()},
+		_retType: common.DoubleType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  UnaryFunction[float64, float64](roundFloat64Op),
+	})
+	set.Add(&FunctionV2{
+		_name:    "round",
+		_args:    []common.LType{common.DoubleType(), common.IntegerType()},
+		_retType: common.DoubleType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  BinaryFunction[float64, int32, float64](roundFloat64NOp),
+	})
+	set.Add(&FunctionV2{
+		_name:    "round",
+		_args:    []common.LType{common.DecimalType(common.DecimalMaxWidthInt64, 0)},
+		_retType: common.DecimalType(common.DecimalMaxWidthInt64, 0),
+		_funcTyp: ScalarFuncType,
+		_scalar:  UnaryFunction[common.Decimal, common.Decimal](roundDecimalOp),
+		_bind:    BindDecimalScaleZero,
+	})
+	set.Add(&FunctionV2{
+		_name:    "round",
+		_args:    []common.LType{common.DecimalType(common.DecimalMaxWidthInt64, 0), common.IntegerType()},
+		_retType: common.DecimalType(common.DecimalMaxWidthInt64, 0),
+		_funcTyp: ScalarFuncType,
+		_bind:    BindDecimalRound,
+	})
+	funcList.Add("round", set)
+}
+
+// decimalRoundScale decides the scale of the round(x, n) and the trunc(x, n).
+// the scale is n if n is a constant less than the scale of x.
+func decimalRoundScale(args []*Expr) int {
+	scale := args[0].DataTyp.Scale
+	if args[1].Typ == ET_IConst && int(args[1].Ivalue) < scale {
+		scale = max(int(args[1].Ivalue), 0)
+	}
+	return scale
+}
+
+func BindDecimalRound(fun *FunctionV2, args []*Expr) *FunctionData {
+	scale := decimalRoundScale(args)
+	fun._args[0] = args[0].DataTyp
+	fun._retType = common.DecimalType(args[0].DataTyp.Width, scale)
+	fun._scalar = BinaryFunction[common.Decimal, int32, common.Decimal](roundDecimalNOp(scale))
+	return nil
+}
+
+type TruncFunc struct {
+}
+
+// Register registers the trunc(x) and the trunc(x, n).
+func (TruncFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("trunc", ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:    "trunc",
+		_args:    []common.LType{common.DoubleType()},
+		_retType: common.DoubleType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  UnaryFunction[float64, float64](truncFloat64Op),
+	})
+	set.Add(&FunctionV2{
+		_name:    "trunc",
+		_args:    []common.LType{common.DoubleType(), common.IntegerType()},
+		_retType: common.DoubleType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  BinaryFunction[float64, int32, float64](truncFloat64NOp),
+	})
+	set.Add(&FunctionV2{
+		_name:    "trunc",
+		_args:    []common.LType{common.DecimalType(common.DecimalMaxWidthInt64, 0)},
+		_retType: common.DecimalType(common.DecimalMaxWidthInt64, 0),
+		_funcTyp: ScalarFuncType,
+		_scalar:  UnaryFunction[common.Decimal, common.Decimal](truncDecimalOp),
+		_bind:    BindDecimalScaleZero,
+	})
+	set.Add(&FunctionV2{
+		_name:    "trunc",
+		_args:    []common.LType{common.DecimalType(common.DecimalMaxWidthInt64, 0), common.IntegerType()},
+		_retType: common.DecimalType(common.DecimalMaxWidthInt64, 0),
+		_funcTyp: ScalarFuncType,
+		_bind:    BindDecimalTrunc,
+	})
+	funcList.Add("trunc", set)
+}
+
+func BindDecimalTrunc(fun *FunctionV2, args []*Expr) *FunctionData {
+	scale := decimalRoundScale(args)
+	fun._args[0] = args[0].DataTyp
+	fun._retType = common.DecimalType(args[0].DataTyp.Width, scale)
+	fun._scalar = BinaryFunction[common.Decimal, int32, common.Decimal](truncDecimalNOp(scale))
+	return nil
+}
+
+type ModFunc struct {
+}
+
+// Register registers the % and the mod.
+func (ModFunc) Register(funcList FunctionList) {
+	for _, name := range []string{ET_Mod.String(), "mod"} {
+		set := NewFunctionSet(name, ScalarFuncType)
+		for _, typ := range mathIntegerTypes() {
+			set.Add(&FunctionV2{
+				_name:    name,
+				_args:    []common.LType{typ, typ},
+				_retType: typ,
+				_funcTyp: ScalarFuncType,
+				_scalar:  GetScalarIntegerMathFunction(typ.GetInternalType(), "%"),
+			})
+		}
+		set.Add(&FunctionV2{
+			_name:    name,
+			_args:    []common.LType{common.DoubleType(), common.DoubleType()},
+			_retType: common.DoubleType(),
+			_funcTyp: ScalarFuncType,
+			_scalar:  BinaryFunction[float64, float64, float64](modFloat64Op),
+		})
+		set.Add(&FunctionV2{
+			_name:    name,
+			_args:    []common.LType{common.DecimalType(common.DecimalMaxWidthInt64, 0), common.DecimalType(common.DecimalMaxWidthInt64, 0)},
+			_retType: common.DecimalType(common.DecimalMaxWidthInt64, 0),
+			_funcTyp: ScalarFuncType,
+			_bind:    BindDecimalMod,
+		})
+		funcList.Add(name, set)
+	}
+}
+
+// BindDecimalMod returns the decimal with the max width and
+// the max scale of the arguments.
+func BindDecimalMod(fun *FunctionV2, args []*Expr) *FunctionData {
+	width, scale := 0, 0
+	for i, arg := range args {
+		fun._args[i] = arg.DataTyp
+		width = max(width, arg.DataTyp.Width)
+		scale = max(scale, arg.DataTyp.Scale)
+	}
+	fun._retType = common.DecimalType(width, scale)
+	fun._scalar = BinaryFunction[common.Decimal, common.Decimal, common.Decimal](modDecimalOp(scale))
+	return nil
+}
+
+type PowerFunc struct {
+}
+
+func (PowerFunc) Register(funcList FunctionList) {
+	for _, name := range []string{"power", "pow"} {
+		set := NewFunctionSet(name, ScalarFuncType)
+		set.Add(&FunctionV2{
+			_name:    name,
+			_args:    []common.LType{common.DoubleType(), common.DoubleType()},
+			_retType: common.DoubleType(),
+			_funcTyp: ScalarFuncType,
+			_scalar:  BinaryFunction[float64, float64, float64](powerOp),
+		})
+		funcList.Add(name, set)
+	}
+}
+
+type SqrtFunc struct {
+}
+
+func (SqrtFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("sqrt", ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:    "sqrt",
+		_args:    []common.LType{common.DoubleType()},
+		_retType: common.DoubleType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  UnaryFunction[float64, float64](sqrtOp),
+	})
+	funcList.Add("sqrt", set)
+}
+
+type ExpFunc struct {
+}
+
+func (ExpFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("exp", ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:    "exp",
+		_args:    []common.LType{common.DoubleType()},
+		_retType: common.DoubleType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  UnaryFunction[float64, float64](expOp),
+	})
+	funcList.Add("exp", set)
+}
+
+type LnFunc struct {
+}
+
+func (LnFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("ln", ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:    "ln",
+		_args:    []common.LType{common.DoubleType()},
+		_retType: common.DoubleType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  UnaryFunction[float64, float64](lnOp),
+	})
+	funcList.Add("ln", set)
+}
+
+type LogFunc struct {
+}
+
+// Register registers the log(x) and the log10(x) in base 10
+// and the log(b, x) in base b.
+func (LogFunc) Register(funcList FunctionList) {
+	set := NewFunctionSet("log", ScalarFuncType)
+	set.Add(&FunctionV2{
+		_name:    "log",
+		_args:    []common.LType{common.DoubleType()},
+		_retType: common.DoubleType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  UnaryFunction[float64, float64](log10Op),
+	})
+	set.Add(&FunctionV2{
+		_name:    "log",
+		_args:    []common.LType{common.DoubleType(), common.DoubleType()},
+		_retType: common.DoubleType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  BinaryFunction[float64, float64, float64](logOp),
+	})
+	funcList.Add("log", set)
+
+	set10 := NewFunctionSet("log10", ScalarFuncType)
+	set10.Add(&FunctionV2{
+		_name:    "log10",
+		_args:    []common.LType{common.DoubleType()},
+		_retType: common.DoubleType(),
+		_funcTyp: ScalarFuncType,
+		_scalar:  UnaryFunction[float64, float64](log10Op),
+	})
+	funcList.Add("log10", set10)
+}
+
+type BitwiseFunc struct {
+}
+
+// Register registers the &, |, #, ~, << and >> on the integers.
+func (BitwiseFunc) Register(funcList FunctionList) {
+	for _, et := range []ET_SubTyp{ET_BitAnd, ET_BitOr, ET_BitXor, ET_ShiftLeft, ET_ShiftRight} {
+		set := NewFunctionSet(et.String(), ScalarFuncType)
+		for _, typ := range mathIntegerTypes() {
+			set.Add(&FunctionV2{
+				_name:    et.String(),
+				_args:    []common.LType{typ, typ},
+				_retType: typ,
+				_funcTyp: ScalarFuncType,
+				_scalar:  GetScalarIntegerMathFunction(typ.GetInternalType(), et.String()),
+			})
+		}
+		funcList.Add(et.String(), set)
+	}
+
+	//~ is also the regular expression match on the strings
+	set, ok := funcList[ET_BitNot.String()]
+	if !ok {
+		set = NewFunctionSet(ET_BitNot.String(), ScalarFuncType)
+		funcList.Add(ET_BitNot.String(), set)
+	}
+	for _, typ := range mathIntegerTypes() {
+		set.Add(&FunctionV2{
+			_name:    ET_BitNot.String(),
+			_args:    []common.LType{typ},
+			_retType: typ,
+			_funcTyp: ScalarFuncType,
+			_scalar:  GetScalarIntegerMathFunction(typ.GetInternalType(), ET_BitNot.String()),
+		})
+	}
+}
+
+type GreatestFunc struct {
+}
+
+// Func returns the GREATEST or the LEAST with argCnt arguments of the typ.
+func (GreatestFunc) Func(et ET_SubTyp, typ common.LType, argCnt int) *FunctionV2 {
+	args := make([]common.LType, argCnt)
+	for i := range args {
+		args[i] = typ
+	}
+	return &FunctionV2{
+		_name:         et.String(),
+		_args:         args,
+		_retType:      typ,
+		_funcTyp:      ScalarFuncType,
+		_nullHandling: SpecialHandling,
+		_scalar:       minMaxFunc(et == ET_Greatest),
+	}
+}
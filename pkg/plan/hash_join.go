@@ -51,9 +51,10 @@ type HashJoin struct {
 
 	_buildChunk *chunk.Chunk
 
-	_hjs      HashJoinStage
-	_scan     *Scan
-	_probExec *ExprExec
+	_hjs           HashJoinStage
+	_scan          *Scan
+	_unmatchedScan *UnmatchedScan
+	_probExec      *ExprExec
 	//types of the output Chunk in Scan.Next
 	_scanNextTyps []common.LType
 
@@ -175,7 +176,7 @@ func (scan *Scan) Next(keys, left, result *chunk.Chunk) {
 		return
 	}
 	switch scan._ht._joinType {
-	case LOT_JoinTypeInner:
+	case LOT_JoinTypeInner, LOT_JoinTypeRight:
 		scan.NextInnerJoin(keys, left, result)
 	case LOT_JoinTypeMARK, LOT_JoinTypeAntiMARK:
 		scan.NextMarkJoin(keys, left, result)
@@ -183,7 +184,7 @@ func (scan *Scan) Next(keys, left, result *chunk.Chunk) {
 		scan.NextSemiJoin(keys, left, result)
 	case LOT_JoinTypeANTI:
 		scan.NextAntiJoin(keys, left, result)
-	case LOT_JoinTypeLeft:
+	case LOT_JoinTypeLeft, LOT_JoinTypeOUTER:
 		scan.NextLeftJoin(keys, left, result)
 	default:
 		panic("Unknown join type")
@@ -205,8 +206,7 @@ func (scan *Scan) NextLeftJoin(keys, left, result *chunk.Chunk) {
 			result.Slice(left, sel, remainingCount, 0)
 			for i := left.ColumnCount(); i < result.ColumnCount(); i++ {
 				vec := result.Data[i]
-				vec.SetPhyFormat(chunk.PF_CONST)
-				chunk.SetNullInPhyFormatConst(vec, true)
+				vec.ReferenceValue(&chunk.Value{Typ: vec.Typ(), IsNull: true})
 			}
 		}
 		scan._finished = true
@@ -249,7 +249,6 @@ func (scan *Scan) NextMarkJoin(keys, left, result *chunk.Chunk) {
 	//assertFunc(result.columnCount() ==
 	//	left.columnCount()+1)
 	util.AssertFunc(util.Back(result.Data).Typ().Id == common.LTID_BOOLEAN)
-	util.AssertFunc(scan._ht.count() > 0)
 	scan.ScanKeyMatches(keys)
 	scan.constructMarkJoinResult(keys, left, result)
 	scan._finished = true
@@ -266,7 +265,8 @@ func (scan *Scan) constructMarkJoinResult(keys, left, result *chunk.Chunk) {
 	markSlice := chunk.GetSliceInPhyFormatFlat[bool](markVec)
 	markMask := chunk.GetMaskInPhyFormatFlat(markVec)
 
-	for colIdx := 0; colIdx < keys.ColumnCount(); colIdx++ {
+	//the key in the empty set is false even if it is NULL
+	for colIdx := 0; colIdx < keys.ColumnCount() && scan._ht.count() > 0; colIdx++ {
 		var vdata chunk.UnifiedFormat
 		keys.Data[colIdx].ToUnifiedFormat(keys.Card(), &vdata)
 		if !vdata.Mask.AllValid() {
@@ -371,6 +371,9 @@ func (scan *Scan) InnerJoin(keys *chunk.Chunk, resVec *chunk.SelectVector) int {
 				scan._foundMatch[idx] = true
 			}
 		}
+		if scan._ht.scanUnmatched() {
+			scan._ht.markFoundMatch(scan._pointers, resVec, resCnt)
+		}
 		if resCnt > 0 {
 			return resCnt
 		}
@@ -452,6 +455,9 @@ type JoinHashTable struct {
 	//next pointer offset in tuple
 	_pointerOffset int
 
+	//the found match flag in tuple for the right and full join
+	_foundMatchOffset int
+
 	//size of entry
 	_entrySize int
 
@@ -483,6 +489,9 @@ func NewJoinHashTable(conds []*Expr,
 	layoutTypes := make([]common.LType, 0)
 	layoutTypes = append(layoutTypes, ht._keyTypes...)
 	layoutTypes = append(layoutTypes, ht._buildTypes...)
+	if ht.scanUnmatched() {
+		layoutTypes = append(layoutTypes, common.BooleanType())
+	}
 	layoutTypes = append(layoutTypes, common.HashType())
 	// init layout
 	ht._layout = NewTupleDataLayout(layoutTypes, nil, nil, true, true)
@@ -491,6 +500,9 @@ func NewJoinHashTable(conds []*Expr,
 
 	//?
 	ht._pointerOffset = offsets[len(offsets)-1]
+	if ht.scanUnmatched() {
+		ht._foundMatchOffset = offsets[len(offsets)-2]
+	}
 	ht._entrySize = ht._layout.rowWidth()
 
 	ht._dataCollection = NewTupleDataCollection(ht._layout)
@@ -516,6 +528,11 @@ func (jht *JoinHashTable) Build(keys *chunk.Chunk, payload *chunk.Chunk) {
 	)
 	if addedCnt < keys.Card() {
 		jht._hasNull = true
+		if jht.scanUnmatched() {
+			//the rows with NULL keys never match but are still in the result
+			curSel = chunk.IncrSelectVectorInPhyFormatFlat()
+			addedCnt = keys.Card()
+		}
 	}
 	if addedCnt == 0 {
 		return
@@ -537,6 +554,12 @@ func (jht *JoinHashTable) Build(keys *chunk.Chunk, payload *chunk.Chunk) {
 		sourceChunk.Data[colOffset+i].Reference(payload.Data[i])
 	}
 	colOffset += payload.ColumnCount()
+	if jht.scanUnmatched() {
+		foundMatch := chunk.NewConstVector(common.BooleanType())
+		chunk.GetSliceInPhyFormatConst[bool](foundMatch)[0] = false
+		sourceChunk.Data[colOffset].Reference(foundMatch)
+		colOffset++
+	}
 	sourceChunk.Data[colOffset].Reference(hashValues)
 	sourceChunk.SetCard(keys.Card())
 	if addedCnt < keys.Card() {
@@ -624,6 +647,10 @@ func (jht *JoinHashTable) InitPointerTable() {
 
 func (jht *JoinHashTable) Finalize() {
 	jht.InitPointerTable()
+	if jht.count() == 0 {
+		jht._finalized = true
+		return
+	}
 	hashes := chunk.NewFlatVector(common.HashType(), util.DefaultVectorSize)
 	hashSlice := chunk.GetSliceInPhyFormatFlat[uint64](hashes)
 	iter := NewTupleDataChunkIterator2(
@@ -753,12 +780,15 @@ func (jht *JoinHashTable) Probe(keys *chunk.Chunk) *Scan {
 }
 
 func (jht *JoinHashTable) initScan(keys *chunk.Chunk, curSel **chunk.SelectVector) *Scan {
-	util.AssertFunc(jht.count() > 0)
 	util.AssertFunc(jht._finalized)
 	newScan := NewScan(jht)
 	if jht._joinType != LOT_JoinTypeInner {
 		newScan._foundMatch = make([]bool, util.DefaultVectorSize)
 	}
+	if jht.count() == 0 {
+		//no key matches
+		return newScan
+	}
 
 	newScan._count = jht.prepareKeys(
 		keys,
@@ -773,6 +803,83 @@ func (jht *JoinHashTable) count() int {
 	return jht._dataCollection.Count()
 }
 
+// scanUnmatched decides the rows without match in the hash table
+// are in the result.
+func (jht *JoinHashTable) scanUnmatched() bool {
+	return jht._joinType == LOT_JoinTypeRight || jht._joinType == LOT_JoinTypeOUTER
+}
+
+// markFoundMatch sets the found match flag of the rows.
+func (jht *JoinHashTable) markFoundMatch(pointers *chunk.Vector, sel *chunk.SelectVector, count int) {
+	ptrs := chunk.GetSliceInPhyFormatFlat[unsafe.Pointer](pointers)
+	for i := 0; i < count; i++ {
+		util.Store[bool](true, util.PointerAdd(ptrs[sel.GetIndex(i)], jht._foundMatchOffset))
+	}
+}
+
+// UnmatchedScan emits the rows without match in the hash table.
+// the columns of the probe side are NULL.
+type UnmatchedScan struct {
+	_ht       *JoinHashTable
+	_iter     *TupleDataChunkIterator
+	_pointers *chunk.Vector
+	_finished bool
+}
+
+func NewUnmatchedScan(ht *JoinHashTable) *UnmatchedScan {
+	ret := &UnmatchedScan{
+		_ht:       ht,
+		_pointers: chunk.NewFlatVector(common.PointerType(), util.DefaultVectorSize),
+		_finished: ht.count() == 0,
+	}
+	if !ret._finished {
+		ret._iter = NewTupleDataChunkIterator2(
+			ht._dataCollection,
+			PIN_PRRP_KEEP_PINNED,
+			false,
+		)
+	}
+	return ret
+}
+
+func (scan *UnmatchedScan) Next(result *chunk.Chunk) {
+	ht := scan._ht
+	ptrs := chunk.GetSliceInPhyFormatFlat[unsafe.Pointer](scan._pointers)
+	for !scan._finished {
+		cnt := 0
+		rowLocs := scan._iter.GetRowLocations()
+		for i := 0; i < scan._iter.GetCurrentChunkCount(); i++ {
+			if !util.Load[bool](util.PointerAdd(rowLocs[i], ht._foundMatchOffset)) {
+				ptrs[cnt] = rowLocs[i]
+				cnt++
+			}
+		}
+		scan._finished = !scan._iter.Next()
+		if cnt == 0 {
+			continue
+		}
+
+		probeCount := result.ColumnCount() - len(ht._buildTypes)
+		for i := 0; i < probeCount; i++ {
+			vec := result.Data[i]
+			vec.ReferenceValue(&chunk.Value{Typ: vec.Typ(), IsNull: true})
+		}
+		for i := 0; i < len(ht._buildTypes); i++ {
+			ht._dataCollection.gather(
+				ht._layout,
+				scan._pointers,
+				chunk.IncrSelectVectorInPhyFormatFlat(),
+				cnt,
+				len(ht._keyTypes)+i,
+				result.Data[probeCount+i],
+				chunk.IncrSelectVectorInPhyFormatFlat(),
+			)
+		}
+		result.SetCard(cnt)
+		return
+	}
+}
+
 type JoinScan struct {
 }
 
@@ -1304,6 +1411,17 @@ func TupleDataTemplatedScatterSwitch(
 			colIdx,
 			chunk.Float64ScatterOp{},
 		)
+	case common.BOOL:
+		TupleDataTemplatedScatter[bool](
+			srcFormat,
+			appendSel,
+			cnt,
+			layout,
+			rowLocations,
+			heapLocations,
+			colIdx,
+			chunk.BoolScatterOp{},
+		)
 	case common.INT128:
 		TupleDataTemplatedScatter[common.Hugeint](
 			srcFormat,
@@ -1433,6 +1551,36 @@ func TupleDataTemplatedGatherSwitch(
 			target,
 			targetSel,
 		)
+	case common.DOUBLE:
+		TupleDataTemplatedGather[float64](
+			layout,
+			rowLocs,
+			colIdx,
+			scanSel,
+			scanCnt,
+			target,
+			targetSel,
+		)
+	case common.BOOL:
+		TupleDataTemplatedGather[bool](
+			layout,
+			rowLocs,
+			colIdx,
+			scanSel,
+			scanCnt,
+			target,
+			targetSel,
+		)
+	case common.INT8:
+		TupleDataTemplatedGather[int8](
+			layout,
+			rowLocs,
+			colIdx,
+			scanSel,
+			scanCnt,
+			target,
+			targetSel,
+		)
 	default:
 		panic("usp phy type")
 	}
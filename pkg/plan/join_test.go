@@ -0,0 +1,499 @@
+// Copyright 2023-2024 daviszhen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/daviszhen/plan/pkg/storage"
+	"github.com/daviszhen/plan/pkg/util"
+)
+
+func Test_outerJoin(t *testing.T) {
+	txn, err := storage.GTxnMgr.NewTxn("outer join")
+	require.NoError(t, err)
+	defer storage.GTxnMgr.Rollback(txn)
+	execSQL(t, txn, "create table oj_l (a integer, b varchar)")
+	execSQL(t, txn, "insert into oj_l values (1, 'l1'), (2, 'l2'), (2, 'l2b'), (4, 'l4')")
+	execSQL(t, txn, "create table oj_r (a integer, c varchar)")
+	execSQL(t, txn, "insert into oj_r values (2, 'r2'), (3, 'r3'), (4, 'r4'), (5, 'r5')")
+	execSQL(t, txn, "create table oj_e (a integer, c varchar)")
+
+	kases := []struct {
+		sql  string
+		want [][]string
+	}{
+		{
+			"select oj_l.a, b, oj_r.a, c from oj_l right join oj_r on oj_l.a = oj_r.a",
+			[][]string{{"2", "l2", "2", "r2"}, {"2", "l2b", "2", "r2"}, {"4", "l4", "4", "r4"},
+				{"NULL", "NULL", "3", "r3"}, {"NULL", "NULL", "5", "r5"}},
+		},
+		{
+			"select oj_l.a, b, oj_r.a, c from oj_l full join oj_r on oj_l.a = oj_r.a",
+			[][]string{{"2", "l2", "2", "r2"}, {"2", "l2b", "2", "r2"}, {"4", "l4", "4", "r4"},
+				{"1", "l1", "NULL", "NULL"}, {"NULL", "NULL", "3", "r3"}, {"NULL", "NULL", "5", "r5"}},
+		},
+		//the filter on the null supplying side stays above the join
+		{
+			"select oj_l.a, b, oj_r.a, c from oj_l full join oj_r on oj_l.a = oj_r.a where b is null",
+			[][]string{{"NULL", "NULL", "3", "r3"}, {"NULL", "NULL", "5", "r5"}},
+		},
+		//the null rejecting filter reduces the full join
+		{
+			"select oj_l.a, b, oj_r.a, c from oj_l full join oj_r on oj_l.a = oj_r.a where c = 'r4'",
+			[][]string{{"4", "l4", "4", "r4"}},
+		},
+		{
+			"select oj_l.a, b, oj_r.a, c from oj_l right join oj_r on oj_l.a = oj_r.a and b = 'l2'",
+			[][]string{{"2", "l2", "2", "r2"}, {"NULL", "NULL", "3", "r3"},
+				{"NULL", "NULL", "4", "r4"}, {"NULL", "NULL", "5", "r5"}},
+		},
+		{
+			"select oj_l.a, b, oj_e.a, oj_e.c from oj_l full join oj_e on oj_l.a = oj_e.a",
+			[][]string{{"1", "l1", "NULL", "NULL"}, {"2", "l2", "NULL", "NULL"},
+				{"2", "l2b", "NULL", "NULL"}, {"4", "l4", "NULL", "NULL"}},
+		},
+		{
+			"select oj_e.a, oj_e.c, oj_r.a from oj_e full join oj_r on oj_e.a = oj_r.a",
+			[][]string{{"NULL", "NULL", "2"}, {"NULL", "NULL", "3"}, {"NULL", "NULL", "4"}, {"NULL", "NULL", "5"}},
+		},
+		{
+			"select oj_l.a from oj_l where a not in (select a from oj_e)",
+			[][]string{{"1"}, {"2"}, {"2"}, {"4"}},
+		},
+		//the NULL keys never match
+		{
+			"select x.a, y.a from (select nullif(a, 2) a from oj_l) x full join (select nullif(a, 4) a from oj_r) y on x.a = y.a",
+			[][]string{{"1", "NULL"}, {"NULL", "NULL"}, {"NULL", "NULL"}, {"4", "NULL"},
+				{"NULL", "2"}, {"NULL", "3"}, {"NULL", "NULL"}, {"NULL", "5"}},
+		},
+		{
+			"select oj_r.a, count(oj_l.b) from oj_l right join oj_r on oj_l.a = oj_r.a group by oj_r.a",
+			[][]string{{"2", "2"}, {"4", "1"}, {"3", "0"}, {"5", "0"}},
+		},
+		//count(*) counts the rows padded with the NULL
+		{
+			"select count(*) from oj_l right join oj_r on oj_l.a = oj_r.a",
+			[][]string{{"5"}},
+		},
+		{
+			"select count(*) from oj_l full join oj_r on oj_l.a = oj_r.a",
+			[][]string{{"6"}},
+		},
+		{
+			"select oj_r.a, count(*) from oj_l right join oj_r on oj_l.a = oj_r.a group by oj_r.a",
+			[][]string{{"2", "2"}, {"4", "1"}, {"3", "1"}, {"5", "1"}},
+		},
+		{
+			"select oj_l.a, oj_r.a from oj_l full join oj_r on oj_l.a < oj_r.a",
+			[][]string{{"1", "2"}, {"1", "3"}, {"1", "4"}, {"1", "5"}, {"2", "3"}, {"2", "4"}, {"2", "5"},
+				{"2", "3"}, {"2", "4"}, {"2", "5"}, {"4", "5"}},
+		},
+		{
+			"select oj_l.a, oj_r.a from oj_l full join oj_r on oj_l.a = oj_r.a and c = 'r4'",
+			[][]string{{"4", "4"}, {"1", "NULL"}, {"2", "NULL"}, {"2", "NULL"},
+				{"NULL", "2"}, {"NULL", "3"}, {"NULL", "5"}},
+		},
+	}
+	for _, kase := range kases {
+		rows, err := querySQL(t, txn, kase.sql)
+		require.NoError(t, err, kase.sql)
+		require.ElementsMatch(t, kase.want, rows, kase.sql)
+	}
+}
+
+func Test_usingJoin(t *testing.T) {
+	txn, err := storage.GTxnMgr.NewTxn("using join")
+	require.NoError(t, err)
+	defer storage.GTxnMgr.Rollback(txn)
+	execSQL(t, txn, "create table uj_l (a integer, b varchar)")
+	execSQL(t, txn, "insert into uj_l values (1, 'l1'), (2, 'l2'), (2, 'l2b'), (4, 'l4')")
+	execSQL(t, txn, "create table uj_r (a integer, c varchar)")
+	execSQL(t, txn, "insert into uj_r values (2, 'r2'), (3, 'r3'), (4, 'r4'), (5, 'r5')")
+
+	kases := []struct {
+		sql  string
+		want [][]string
+	}{
+		//the merged column appears once
+		{
+			"select * from uj_l join uj_r using (a)",
+			[][]string{{"2", "l2", "r2"}, {"2", "l2b", "r2"}, {"4", "l4", "r4"}},
+		},
+		{
+			"select * from uj_l natural join uj_r",
+			[][]string{{"2", "l2", "r2"}, {"2", "l2b", "r2"}, {"4", "l4", "r4"}},
+		},
+		{
+			"select a, b, c from uj_l right join uj_r using (a)",
+			[][]string{{"2", "l2", "r2"}, {"2", "l2b", "r2"}, {"4", "l4", "r4"},
+				{"3", "NULL", "r3"}, {"5", "NULL", "r5"}},
+		},
+		{
+			"select * from uj_l natural full join uj_r",
+			[][]string{{"2", "l2", "r2"}, {"2", "l2b", "r2"}, {"4", "l4", "r4"},
+				{"1", "l1", "NULL"}, {"3", "NULL", "r3"}, {"5", "NULL", "r5"}},
+		},
+		{
+			"select uj_l.a, uj_r.a, a from uj_l full join uj_r using (a) where a > 3",
+			[][]string{{"4", "4", "4"}, {"NULL", "5", "5"}},
+		},
+		{
+			"select a, count(*) from uj_l full join uj_r using (a) group by a",
+			[][]string{{"1", "1"}, {"2", "2"}, {"3", "1"}, {"4", "1"}, {"5", "1"}},
+		},
+		{
+			"select a, b, c, q.d from uj_l full join uj_r using (a) left join (select a, c d from uj_r where a > 3) q using (a)",
+			[][]string{{"4", "l4", "r4", "r4"}, {"2", "l2", "r2", "NULL"}, {"2", "l2b", "r2", "NULL"},
+				{"1", "l1", "NULL", "NULL"}, {"5", "NULL", "r5", "r5"}, {"3", "NULL", "r3", "NULL"}},
+		},
+	}
+	for _, kase := range kases {
+		rows, err := querySQL(t, txn, kase.sql)
+		require.NoError(t, err, kase.sql)
+		require.ElementsMatch(t, kase.want, rows, kase.sql)
+	}
+
+	errKases := []struct {
+		sql  string
+		code util.SQLState
+	}{
+		{"select * from uj_l join uj_r using (x)", util.SQLStateUndefinedColumn},
+		{"select * from uj_l join uj_r using (b)", util.SQLStateUndefinedColumn},
+		{"select * from uj_l join uj_r using (a, a)", util.SQLStateDuplicateColumn},
+	}
+	for _, kase := range errKases {
+		_, err = querySQL(t, txn, kase.sql)
+		require.Error(t, err, kase.sql)
+		require.Equal(t, kase.code, util.GetSQLState(err), kase.sql)
+	}
+}
+
+func Test_lateralJoin(t *testing.T) {
+	txn, err := storage.GTxnMgr.NewTxn("lateral join")
+	require.NoError(t, err)
+	defer storage.GTxnMgr.Rollback(txn)
+	execSQL(t, txn, "create table lj_l (a integer, b varchar)")
+	execSQL(t, txn, "insert into lj_l values (1, 'l1'), (2, 'l2'), (2, 'l2b'), (4, 'l4')")
+	execSQL(t, txn, "create table lj_r (a integer, c varchar)")
+	execSQL(t, txn, "insert into lj_r values (2, 'r2'), (3, 'r3'), (4, 'r4'), (5, 'r5')")
+
+	kases := []struct {
+		sql  string
+		want [][]string
+	}{
+		{
+			"select * from lj_l, lateral (select c from lj_r where lj_r.a = lj_l.a) s",
+			[][]string{{"2", "l2", "r2"}, {"2", "l2b", "r2"}, {"4", "l4", "r4"}},
+		},
+		{
+			"select lj_l.a, b, s.c from lj_l left join lateral (select c from lj_r where lj_r.a = lj_l.a) s on true",
+			[][]string{{"2", "l2", "r2"}, {"2", "l2b", "r2"}, {"4", "l4", "r4"}, {"1", "l1", "NULL"}},
+		},
+		{
+			"select lj_l.a, s.c from lj_l cross join lateral (select c from lj_r where lj_r.a > lj_l.a) s where lj_l.a > 1",
+			[][]string{{"2", "r3"}, {"2", "r4"}, {"2", "r5"}, {"2", "r3"}, {"2", "r4"}, {"2", "r5"}, {"4", "r5"}},
+		},
+		{
+			"select lj_l.a, s.c from lj_l join lateral (select c from lj_r where lj_r.a = lj_l.a) s on s.c <> 'r2'",
+			[][]string{{"4", "r4"}},
+		},
+		{
+			"select lj_l.a, s.m from lj_l, lateral (select sum(a) m from lj_r where lj_r.a = lj_l.a) s",
+			[][]string{{"2", "2"}, {"2", "2"}, {"4", "4"}},
+		},
+		{
+			//the row 4 has no match
+			"select lj_l.a, s.c from lj_l left join lateral (select c from lj_r where lj_r.a > lj_l.a and lj_r.a < 4) s on true",
+			[][]string{{"1", "r2"}, {"1", "r3"}, {"2", "r3"}, {"2", "r3"}, {"4", "NULL"}},
+		},
+		{
+			"select lj_l.a, s.c from lj_l left join lateral (select c from lj_r where lj_r.a = lj_l.a) s on s.c <> 'r2'",
+			[][]string{{"1", "NULL"}, {"2", "NULL"}, {"2", "NULL"}, {"4", "r4"}},
+		},
+	}
+	for _, kase := range kases {
+		rows, err := querySQL(t, txn, kase.sql)
+		require.NoError(t, err, kase.sql)
+		require.ElementsMatch(t, kase.want, rows, kase.sql)
+	}
+
+	errKases := []struct {
+		sql  string
+		code util.SQLState
+	}{
+		{"select lj_l.a from lj_l right join lateral (select c from lj_r where lj_r.a = lj_l.a) s on true", util.SQLStateInvalidColumnReference},
+		{"select s.x from lj_l, lateral (select lj_l.a + lj_r.a x from lj_r) s", util.SQLStateFeatureNotSupported},
+	}
+	for _, kase := range errKases {
+		_, err = querySQL(t, txn, kase.sql)
+		require.Error(t, err, kase.sql)
+		require.Equal(t, kase.code, util.GetSQLState(err), kase.sql)
+	}
+}
+
+// joinAlgoOf returns the algorithm of the topmost join in the plan.
+func joinAlgoOf(t *testing.T, cfg *util.Config, txn *storage.Txn, sql string) JoinAlgo {
+	run, err := InitRunner(cfg, txn, sql)
+	require.NoError(t, err, sql)
+	defer run.Close()
+	op := run.op
+	for op.Typ != POT_Join {
+		require.NotEmpty(t, op.Children, sql)
+		op = op.Children[0]
+	}
+	return op.JoinAlgo
+}
+
+func Test_nonEquiJoin(t *testing.T) {
+	txn, err := storage.GTxnMgr.NewTxn("non-equi join")
+	require.NoError(t, err)
+	defer storage.GTxnMgr.Rollback(txn)
+	execSQL(t, txn, "create table nj_l (a integer, b varchar)")
+	execSQL(t, txn, "insert into nj_l values (1, 'l1'), (2, 'l2'), (2, 'l2b'), (4, 'l4')")
+	execSQL(t, txn, "create table nj_r (a integer, c varchar)")
+	execSQL(t, txn, "insert into nj_r values (2, 'r2'), (3, 'r3'), (4, 'r4'), (5, 'r5')")
+	execSQL(t, txn, "create table nj_e (a integer, c varchar)")
+
+	kases := []struct {
+		sql  string
+		algo JoinAlgo
+		want [][]string
+	}{
+		{
+			"select nj_l.a, nj_r.a from nj_l join nj_r on nj_l.a < nj_r.a",
+			JoinAlgoPiecewiseMerge,
+			[][]string{{"1", "2"}, {"1", "3"}, {"1", "4"}, {"1", "5"}, {"2", "3"}, {"2", "4"}, {"2", "5"},
+				{"2", "3"}, {"2", "4"}, {"2", "5"}, {"4", "5"}},
+		},
+		{
+			"select nj_l.a, nj_r.a from nj_l, nj_r where nj_l.a < nj_r.a and nj_r.a < 4",
+			JoinAlgoPiecewiseMerge,
+			[][]string{{"1", "2"}, {"1", "3"}, {"2", "3"}, {"2", "3"}},
+		},
+		{
+			"select nj_l.a, nj_r.a from nj_l join nj_r on nj_r.a between nj_l.a and nj_l.a + 1",
+			JoinAlgoPiecewiseMerge,
+			[][]string{{"1", "2"}, {"2", "2"}, {"2", "3"}, {"2", "2"}, {"2", "3"}, {"4", "4"}, {"4", "5"}},
+		},
+		{
+			"select nj_l.a, nj_r.a from nj_l left join nj_r on nj_l.a > nj_r.a",
+			JoinAlgoPiecewiseMerge,
+			[][]string{{"4", "2"}, {"4", "3"}, {"1", "NULL"}, {"2", "NULL"}, {"2", "NULL"}},
+		},
+		{
+			"select nj_l.a, nj_r.a from nj_l right join nj_r on nj_l.a >= nj_r.a",
+			JoinAlgoPiecewiseMerge,
+			[][]string{{"2", "2"}, {"2", "2"}, {"4", "2"}, {"4", "3"}, {"4", "4"}, {"NULL", "5"}},
+		},
+		{
+			"select nj_l.a, nj_r.a from nj_l full join nj_r on nj_l.a < nj_r.a - 2",
+			JoinAlgoPiecewiseMerge,
+			[][]string{{"1", "4"}, {"1", "5"}, {"2", "5"}, {"2", "5"}, {"4", "NULL"}, {"NULL", "2"}, {"NULL", "3"}},
+		},
+		{
+			"select nj_l.a, nj_r.a, b, c from nj_l left join nj_r on nj_r.a > nj_l.a and c <> 'r5'",
+			JoinAlgoPiecewiseMerge,
+			[][]string{{"1", "2", "l1", "r2"}, {"1", "3", "l1", "r3"}, {"1", "4", "l1", "r4"}, {"2", "3", "l2", "r3"},
+				{"2", "4", "l2", "r4"}, {"2", "3", "l2b", "r3"}, {"2", "4", "l2b", "r4"}, {"4", "NULL", "l4", "NULL"}},
+		},
+		{
+			"select nj_l.a, nj_e.a from nj_l left join nj_e on nj_l.a < nj_e.a",
+			JoinAlgoPiecewiseMerge,
+			[][]string{{"1", "NULL"}, {"2", "NULL"}, {"2", "NULL"}, {"4", "NULL"}},
+		},
+		{
+			"select nj_l.a, nj_r.a from nj_l join nj_r on nj_l.a <> nj_r.a",
+			JoinAlgoNestedLoop,
+			[][]string{{"1", "2"}, {"1", "3"}, {"1", "4"}, {"1", "5"}, {"2", "3"}, {"2", "4"}, {"2", "5"},
+				{"2", "3"}, {"2", "4"}, {"2", "5"}, {"4", "2"}, {"4", "3"}, {"4", "5"}},
+		},
+		{
+			"select nj_l.a, nj_r.a from nj_l join nj_r on nj_l.a = nj_r.a or nj_l.a + 1 = nj_r.a",
+			JoinAlgoNestedLoop,
+			[][]string{{"1", "2"}, {"2", "2"}, {"2", "3"}, {"2", "2"}, {"2", "3"}, {"4", "4"}, {"4", "5"}},
+		},
+		{
+			"select nj_l.a, nj_r.a from nj_l full join nj_r on nj_l.a + nj_r.a = 6",
+			JoinAlgoNestedLoop,
+			[][]string{{"1", "5"}, {"2", "4"}, {"2", "4"}, {"4", "2"}, {"NULL", "3"}},
+		},
+		//the condition on the preserved side stays in the ON
+		{
+			"select nj_l.a, nj_r.a from nj_l left join nj_r on nj_l.a = nj_r.a and nj_l.a > 2",
+			JoinAlgoNestedLoop,
+			[][]string{{"4", "4"}, {"1", "NULL"}, {"2", "NULL"}, {"2", "NULL"}},
+		},
+		{
+			"select nj_l.a, nj_r.a from nj_l left join nj_r on false",
+			JoinAlgoNestedLoop,
+			[][]string{{"1", "NULL"}, {"2", "NULL"}, {"2", "NULL"}, {"4", "NULL"}},
+		},
+		{
+			"select nj_l.a, nj_r.a from nj_l join nj_r on nj_l.a = nj_r.a",
+			JoinAlgoHash,
+			[][]string{{"2", "2"}, {"2", "2"}, {"4", "4"}},
+		},
+	}
+	for _, kase := range kases {
+		require.Equal(t, kase.algo, joinAlgoOf(t, &util.Config{}, txn, kase.sql), kase.sql)
+		rows, err := querySQL(t, txn, kase.sql)
+		require.NoError(t, err, kase.sql)
+		require.ElementsMatch(t, kase.want, rows, kase.sql)
+	}
+
+	//the right side spans several chunks
+	execSQL(t, txn, "create table nj_n (i integer)")
+	execSQL(t, txn, "insert into nj_n values (0), (1), (2), (3), (4), (5), (6), (7), (8), (9)")
+	execSQL(t, txn, "create table nj_big (i integer)")
+	for k := 0; k < 3; k++ {
+		execSQL(t, txn, fmt.Sprintf("insert into nj_big select %d + a.i * 100 + b.i * 10 + c.i from nj_n a, nj_n b, nj_n c", k*1000))
+	}
+	execSQL(t, txn, "create table nj_s (j integer)")
+	execSQL(t, txn, "insert into nj_s values (100), (1500), (2999)")
+	bigKases := []struct {
+		sql  string
+		want [][]string
+	}{
+		{"select count(nj_s.j) from nj_s join nj_big on nj_s.j > nj_big.i", [][]string{{"4599"}}},
+		{"select count(nj_big.i) from nj_big left join nj_s on nj_big.i >= nj_s.j", [][]string{{"4501"}}},
+		{"select count(nj_big.i), count(nj_s.j) from nj_s full join nj_big on nj_s.j + nj_big.i = 3000", [][]string{{"3000", "3"}}},
+		{"select nj_s.j, nj_big.i from nj_s join nj_big on nj_s.j + nj_big.i = 3000", [][]string{{"100", "2900"}, {"1500", "1500"}, {"2999", "1"}}},
+	}
+	for _, kase := range bigKases {
+		rows, err := querySQL(t, txn, kase.sql)
+		require.NoError(t, err, kase.sql)
+		require.ElementsMatch(t, kase.want, rows, kase.sql)
+	}
+}
+
+func Test_sortMergeJoin(t *testing.T) {
+	txn, err := storage.GTxnMgr.NewTxn("sort merge join")
+	require.NoError(t, err)
+	defer storage.GTxnMgr.Rollback(txn)
+	execSQL(t, txn, "create table sm_l (a integer, b varchar)")
+	execSQL(t, txn, "insert into sm_l values (1, 'x'), (2, 'y'), (2, 'z'), (4, 'w'), (6, 'y')")
+	execSQL(t, txn, "create table sm_r (a integer, c varchar)")
+	execSQL(t, txn, "insert into sm_r values (2, 'y'), (2, 'q'), (3, 'r'), (4, 'w'), (6, 's')")
+
+	//the children are sorted on the join key
+	orderedKases := []struct {
+		sql  string
+		algo JoinAlgo
+		want [][]string
+	}{
+		{
+			"select x.a, y.c from (select a from sm_l order by a) x join (select a, c from sm_r order by a) y on x.a = y.a",
+			JoinAlgoSortMerge,
+			[][]string{{"2", "y"}, {"2", "q"}, {"2", "y"}, {"2", "q"}, {"4", "w"}, {"6", "s"}},
+		},
+		{
+			"select x.a, y.c from (select a from sm_l order by a desc) x join (select a, c from sm_r order by a) y on x.a = y.a",
+			JoinAlgoHash,
+			[][]string{{"2", "y"}, {"2", "q"}, {"2", "y"}, {"2", "q"}, {"4", "w"}, {"6", "s"}},
+		},
+		{
+			"select x.a, y.c from (select a from sm_l order by a) x join sm_r y on x.a = y.a",
+			JoinAlgoHash,
+			[][]string{{"2", "y"}, {"2", "q"}, {"2", "y"}, {"2", "q"}, {"4", "w"}, {"6", "s"}},
+		},
+		{
+			//the filter and project keep the order of the top n
+			"select x.a, y.c from (select a from (select a from sm_l order by a limit 10) t where a > 1) x " +
+				"join (select a, c from sm_r order by a) y on x.a = y.a",
+			JoinAlgoSortMerge,
+			[][]string{{"2", "y"}, {"2", "q"}, {"2", "y"}, {"2", "q"}, {"4", "w"}, {"6", "s"}},
+		},
+		{
+			//the inner sort-merge join is sorted on the merge key
+			"select x.a, z.c from (select a from sm_l order by a) x join (select a from sm_r order by a) y on x.a = y.a " +
+				"join (select a, c from sm_r order by a) z on y.a = z.a",
+			JoinAlgoSortMerge,
+			[][]string{{"2", "y"}, {"2", "y"}, {"2", "y"}, {"2", "y"}, {"2", "q"}, {"2", "q"}, {"2", "q"}, {"2", "q"}, {"4", "w"}, {"6", "s"}},
+		},
+	}
+	for _, kase := range orderedKases {
+		require.Equal(t, kase.algo, joinAlgoOf(t, &util.Config{}, txn, kase.sql), kase.sql)
+		rows, err := querySQL(t, txn, kase.sql)
+		require.NoError(t, err, kase.sql)
+		require.ElementsMatch(t, kase.want, rows, kase.sql)
+	}
+
+	//the build side is large
+	cfg := &util.Config{Query: util.QueryOptions{SortMergeJoinRows: -1}}
+	kases := []struct {
+		sql  string
+		want [][]string
+	}{
+		{
+			"select sm_l.a, b, c from sm_l join sm_r on sm_l.a = sm_r.a",
+			[][]string{{"2", "y", "y"}, {"2", "y", "q"}, {"2", "z", "y"}, {"2", "z", "q"}, {"4", "w", "w"}, {"6", "y", "s"}},
+		},
+		{
+			"select sm_l.a, b, c from sm_l left join sm_r on sm_l.a = sm_r.a",
+			[][]string{{"2", "y", "y"}, {"2", "y", "q"}, {"2", "z", "y"}, {"2", "z", "q"}, {"4", "w", "w"}, {"6", "y", "s"},
+				{"1", "x", "NULL"}},
+		},
+		{
+			"select sm_l.a, b, c from sm_l left join sm_r on sm_l.a = sm_r.a and sm_l.b <> sm_r.c",
+			[][]string{{"2", "y", "q"}, {"2", "z", "y"}, {"2", "z", "q"}, {"6", "y", "s"}, {"1", "x", "NULL"}, {"4", "w", "NULL"}},
+		},
+		{
+			"select sm_l.a, b, c from sm_l join sm_r on sm_l.a = sm_r.a and b = c",
+			[][]string{{"2", "y", "y"}, {"4", "w", "w"}},
+		},
+		{
+			"select a, b from sm_l where a in (select a from sm_r)",
+			[][]string{{"2", "y"}, {"2", "z"}, {"4", "w"}, {"6", "y"}},
+		},
+		{
+			"select a, b from sm_l where a not in (select a from sm_r)",
+			[][]string{{"1", "x"}},
+		},
+	}
+	for _, kase := range kases {
+		require.Equal(t, JoinAlgoSortMerge, joinAlgoOf(t, cfg, txn, kase.sql), kase.sql)
+		rows, err := querySQLWithConfig(t, cfg, txn, kase.sql)
+		require.NoError(t, err, kase.sql)
+		require.ElementsMatch(t, kase.want, rows, kase.sql)
+	}
+
+	//the sorted rows span several chunks
+	execSQL(t, txn, "create table sm_n (i integer)")
+	execSQL(t, txn, "insert into sm_n values (0), (1), (2), (3), (4), (5), (6), (7), (8), (9)")
+	execSQL(t, txn, "create table sm_big (i integer, d integer)")
+	for k := 0; k < 3; k++ {
+		execSQL(t, txn, fmt.Sprintf("insert into sm_big select %d + a.i * 100 + b.i * 10 + c.i, c.i from sm_n a, sm_n b, sm_n c", k*1000))
+	}
+	bigKases := []struct {
+		sql  string
+		want [][]string
+	}{
+		{"select count(x.i) from sm_big x join sm_big y on x.i = y.i + 1", [][]string{{"2999"}}},
+		{"select count(x.i), count(sm_n.i) from sm_big x left join sm_n on x.i = sm_n.i * 1000", [][]string{{"3000", "3"}}},
+		{"select count(x.i) from sm_big x join sm_big y on x.d = y.d", [][]string{{"900000"}}},
+		{"select count(x.i) from sm_big x join sm_big y on x.d = y.d and x.i = y.i", [][]string{{"3000"}}},
+		{"select count(i) from sm_big where d in (select i from sm_n where i < 3)", [][]string{{"900"}}},
+	}
+	for _, kase := range bigKases {
+		require.Equal(t, JoinAlgoSortMerge, joinAlgoOf(t, cfg, txn, kase.sql), kase.sql)
+		rows, err := querySQLWithConfig(t, cfg, txn, kase.sql)
+		require.NoError(t, err, kase.sql)
+		require.ElementsMatch(t, kase.want, rows, kase.sql)
+	}
+}
@@ -515,8 +515,10 @@ func (joinOrder *JoinOrderOptimizer) Optimize(root *LogicalOperator) (*LogicalOp
 					}
 					joinOrder.createEdge(filter.Children[0], child, info)
 				}
-			case ET_SubFunc:
-			case ET_And, ET_Or, ET_Equal, ET_NotEqual, ET_Like, ET_GreaterEqual, ET_Less, ET_Greater:
+			case ET_SubFunc, ET_IsNull, ET_IsNotNull, ET_Coalesce, ET_NullIf:
+			case ET_And, ET_Or, ET_Equal, ET_NotEqual, ET_Like, ET_GreaterEqual, ET_Less, ET_LessEqual, ET_Greater,
+				ET_IsDistinctFrom, ET_IsNotDistinctFrom, ET_NotLike, ET_ILike, ET_NotILike,
+				ET_RegexpMatch, ET_NotRegexpMatch, ET_RegexpIMatch, ET_NotRegexpIMatch:
 				joinOrder.createEdge(filter.Children[0], filter.Children[1], info)
 			default:
 				panic(fmt.Sprintf("usp %v", filter.SubTyp))
@@ -1182,6 +1184,18 @@ func (joinOrder *JoinOrderOptimizer) extractJoinRelations(root, parent *LogicalO
 		joinOrder.relationMapping[tableIndex] = uint64(relationId)
 		joinOrder.relations = append(joinOrder.relations, relation)
 		return true, filterOps, err
+	case LOT_RecursiveCTE:
+		tableIndex := op.Index
+		relation := &SingleJoinRelation{op: root, parent: parent}
+		relationId := len(joinOrder.relations)
+		for i := range op.Names {
+			key := ColumnBind{uint64(relationId), uint64(i)}
+			value := ColumnBind{tableIndex, uint64(i)}
+			joinOrder.estimator.AddRelationToColumnMapping(key, value)
+		}
+		joinOrder.relationMapping[tableIndex] = uint64(relationId)
+		joinOrder.relations = append(joinOrder.relations, relation)
+		return true, filterOps, err
 	case LOT_Project:
 		tableIndex := op.Index
 		relation := &SingleJoinRelation{op: root, parent: parent}
@@ -1263,7 +1277,7 @@ func getTableRefers(root *LogicalOperator, set UnorderedSet) {
 	case LOT_Order:
 		collectTableRefersOfExprs(root.OrderBys, set)
 		getTableRefers(root.Children[0], set)
-	case LOT_Scan:
+	case LOT_Scan, LOT_RecursiveCTE:
 		set.insert(root.Index)
 	case LOT_JOIN:
 		//if root.JoinTyp == LOT_JoinTypeMARK {
@@ -200,6 +200,34 @@ func TemplatedMatchOp(
 				noMatchSel,
 				equalHugeintOp{},
 			)
+		case common.DOUBLE:
+			TemplatedMatchType[float64](
+				col,
+				rows,
+				layout._rowWidth,
+				sel,
+				cnt,
+				colOffset,
+				colNo,
+				noMatch,
+				noMatchCnt,
+				noMatchSel,
+				equalOp[float64]{},
+			)
+		case common.BOOL:
+			TemplatedMatchType[bool](
+				col,
+				rows,
+				layout._rowWidth,
+				sel,
+				cnt,
+				colOffset,
+				colNo,
+				noMatch,
+				noMatchCnt,
+				noMatchSel,
+				equalOp[bool]{},
+			)
 		default:
 			panic("usp")
 		}
@@ -0,0 +1,43 @@
+// Copyright 2023-2024 daviszhen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"github.com/daviszhen/plan/pkg/chunk"
+	"github.com/daviszhen/plan/pkg/common"
+)
+
+// MaterializedCTE holds the rows of the cte shared by
+// the scans on it. The first scan executes the cte query
+// and the others read the rows directly.
+type MaterializedCTE struct {
+	_plan         *PhysicalOperator
+	_collection   *ColumnDataCollection
+	_materialized bool
+}
+
+func NewMaterializedCTE(plan *PhysicalOperator, typs []common.LType) *MaterializedCTE {
+	return &MaterializedCTE{
+		_plan:       plan,
+		_collection: NewColumnDataCollection(typs),
+	}
+}
+
+func (cte *MaterializedCTE) Sink(data *chunk.Chunk) {
+	if data.Card() == 0 {
+		return
+	}
+	cte._collection.Append(data)
+}
@@ -0,0 +1,139 @@
+// Copyright 2023-2024 daviszhen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/daviszhen/plan/pkg/storage"
+	"github.com/daviszhen/plan/pkg/util"
+)
+
+func Test_materializedCTE(t *testing.T) {
+	txn, err := storage.GTxnMgr.NewTxn("materialized cte")
+	require.NoError(t, err)
+	defer storage.GTxnMgr.Rollback(txn)
+	execSQL(t, txn, "create table mc_t (g integer, i integer, d decimal(15, 2))")
+	execSQL(t, txn, "insert into mc_t values (1, 1, 1.5), (1, 2, 2.5), (2, 3, 3.5), (2, 4, 0.5), (3, 7, 3)")
+
+	kases := []struct {
+		sql string
+		//count of the scans on the materialized cte
+		scans int
+		want  [][]string
+	}{
+		{
+			"with rev as (select g, sum(d) as total from mc_t group by g) " +
+				"select g, total from rev where total = (select max(total) from rev) order by g",
+			2,
+			[][]string{{"1", "4"}, {"2", "4"}},
+		},
+		{
+			"with c as (select g, i from mc_t where i > 1) " +
+				"select a.g, a.i, b.i from c a, c b where a.g = b.g and a.i < b.i order by a.g",
+			2,
+			[][]string{{"2", "3", "4"}},
+		},
+		{
+			"with c as (select g, i from mc_t) select a.g from c a, c b where a.i = b.i and a.g = 3",
+			2,
+			[][]string{{"3"}},
+		},
+		{
+			"with a as (select g, i from mc_t), b as (select g as h from a where i > 2) " +
+				"select count(*) from a, b where a.g = b.h",
+			2,
+			[][]string{{"5"}},
+		},
+		{
+			"with c as (select g from mc_t) select count(*) from c",
+			0,
+			[][]string{{"5"}},
+		},
+		{
+			"with c as materialized (select g from mc_t where g > 1) select g from c order by g",
+			1,
+			[][]string{{"2"}, {"2"}, {"3"}},
+		},
+		{
+			"with c as not materialized (select g from mc_t group by g) " +
+				"select g from c where g in (select g from c where g > 1) order by g",
+			0,
+			[][]string{{"2"}, {"3"}},
+		},
+		{
+			//each reference is inlined with its own alias
+			"with c as not materialized (select g, i from mc_t where i > 2) " +
+				"select a.g, b.i from c a, c b where a.i = b.i and a.g = 3",
+			0,
+			[][]string{{"3", "7"}},
+		},
+		{
+			"with c(x) as not materialized (select g from mc_t where i < 3) " +
+				"select a.x, c.x from c a join c on a.x = c.x",
+			0,
+			[][]string{{"1", "1"}, {"1", "1"}, {"1", "1"}, {"1", "1"}},
+		},
+		{
+			"select x.g from (with c as (select g from mc_t where i = 7) select g from c) x",
+			0,
+			[][]string{{"3"}},
+		},
+		{
+			//the cte query does not refer the outer query
+			"select o.g, s.m from mc_t o, lateral (with c as materialized (select g, i from mc_t) " +
+				"select sum(i) m from c where c.g = o.g) s",
+			1,
+			[][]string{{"1", "3"}, {"1", "3"}, {"2", "7"}, {"2", "7"}, {"3", "7"}},
+		},
+	}
+	for _, kase := range kases {
+		run, err := InitRunner(&util.Config{}, txn, kase.sql)
+		require.NoError(t, err, kase.sql)
+		scans := findOperator(run.op, func(root *PhysicalOperator) bool {
+			return wantOp(root, POT_Scan) && root.ScanTyp == ScanTypeCTE
+		})
+		require.Len(t, scans, kase.scans, kase.sql)
+		//the scans share the rows
+		for _, scan := range scans {
+			require.Same(t, scans[0].cte, scan.cte, kase.sql)
+		}
+		run.Close()
+
+		rows, err := querySQL(t, txn, kase.sql)
+		require.NoError(t, err, kase.sql)
+		require.Equal(t, kase.want, rows, kase.sql)
+	}
+
+	//the tables in the query are invisible to the cte
+	_, err = querySQL(t, txn, "with c as (select o.g from mc_t) select c.g from mc_t o, c")
+	require.Error(t, err)
+
+	//the materialized cte runs only once. it can not refer the outer query.
+	for _, sql := range []string{
+		"select o.g, s.m from mc_t o, lateral (with c as materialized (select i from mc_t x where x.g = o.g) " +
+			"select sum(i) m from c) s",
+		"select o.g from mc_t o where exists (with c as materialized (select i from mc_t x where x.g = o.g) " +
+			"select * from c where c.i > 3)",
+		"select o.g, s.m from mc_t o, lateral (with c as materialized (select i from mc_t x " +
+			"where x.i in (select y.i from mc_t y where y.g = o.g)) select sum(i) m from c) s",
+	} {
+		_, err = querySQL(t, txn, sql)
+		require.Error(t, err, sql)
+		require.Equal(t, util.SQLStateFeatureNotSupported, util.GetSQLState(err), sql)
+	}
+}
@@ -0,0 +1,469 @@
+// Copyright 2023-2024 daviszhen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"fmt"
+	"math"
+	"unsafe"
+
+	dec "github.com/govalues/decimal"
+
+	"github.com/daviszhen/plan/pkg/chunk"
+	"github.com/daviszhen/plan/pkg/common"
+	"github.com/daviszhen/plan/pkg/util"
+)
+
+// the math functions and the numeric operators.
+
+type mathInteger interface {
+	int8 | int16 | int32 | int64
+}
+
+type mathFloat interface {
+	float32 | float64
+}
+
+func integerOutOfRange[T mathInteger]() error {
+	var zero T
+	name := "bigint"
+	switch any(zero).(type) {
+	case int8:
+		name = "tinyint"
+	case int16:
+		name = "smallint"
+	case int32:
+		name = "integer"
+	}
+	return util.NewSQLError(util.SQLStateNumericValueOutOfRange, "%s out of range", name)
+}
+
+func divisionByZero() error {
+	return util.NewSQLError(util.SQLStateDivisionByZero, "division by zero")
+}
+
+func overflow() error {
+	return util.NewSQLError(util.SQLStateNumericValueOutOfRange, "value out of range: overflow")
+}
+
+// abs
+
+func absOp[T mathInteger](input *T, result *T) {
+	if *input >= 0 {
+		*result = *input
+		return
+	}
+	if -*input < 0 {
+		//the minimum value
+		panic(integerOutOfRange[T]())
+	}
+	*result = -*input
+}
+
+func absFloatOp[T mathFloat](input *T, result *T) {
+	*result = T(math.Abs(float64(*input)))
+}
+
+func absDecimalOp(input *common.Decimal, result *common.Decimal) {
+	result.Decimal = input.Decimal.Abs()
+}
+
+// sign
+
+func signFloat64Op(input *float64, result *float64) {
+	switch {
+	case *input > 0:
+		*result = 1
+	case *input < 0:
+		*result = -1
+	default:
+		*result = 0
+	}
+}
+
+func signDecimalOp(input *common.Decimal, result *common.Decimal) {
+	result.Decimal = dec.MustNew(int64(input.Decimal.Sign()), 0)
+}
+
+// ceil, floor
+
+func ceilFloat64Op(input *float64, result *float64) {
+	*result = math.Ceil(*input)
+}
+
+func ceilDecimalOp(input *common.Decimal, result *common.Decimal) {
+	result.Decimal = input.Decimal.Ceil(0)
+}
+
+func floorFloat64Op(input *float64, result *float64) {
+	*result = math.Floor(*input)
+}
+
+func floorDecimalOp(input *common.Decimal, result *common.Decimal) {
+	result.Decimal = input.Decimal.Floor(0)
+}
+
+// round, trunc
+
+// roundFloat64 rounds the x to n digits after the decimal point.
+// the halfway is rounded away from zero.
+func roundFloat64(x float64, n int32) float64 {
+	p := math.Pow10(int(n))
+	if p == 0 {
+		return 0
+	}
+	r := math.Round(x*p) / p
+	if math.IsInf(r, 0) || math.IsNaN(r) {
+		//x has less than n digits after the decimal point
+		return x
+	}
+	return r
+}
+
+func truncFloat64(x float64, n int32) float64 {
+	p := math.Pow10(int(n))
+	if p == 0 {
+		return 0
+	}
+	r := math.Trunc(x*p) / p
+	if math.IsInf(r, 0) || math.IsNaN(r) {
+		return x
+	}
+	return r
+}
+
+func roundFloat64Op(input *float64, result *float64) {
+	*result = roundFloat64(*input, 0)
+}
+
+func roundFloat64NOp(input *float64, n *int32, result *float64) {
+	*result = roundFloat64(*input, *n)
+}
+
+func truncFloat64Op(input *float64, result *float64) {
+	*result = math.Trunc(*input)
+}
+
+func truncFloat64NOp(input *float64, n *int32, result *float64) {
+	*result = truncFloat64(*input, *n)
+}
+
+// scaleDecimal applies the fun on the d with n digits after the decimal point.
+// negative n is applied on the digits before the decimal point.
+func scaleDecimal(d dec.Decimal, n int, fun func(dec.Decimal, int) dec.Decimal) dec.Decimal {
+	if n >= 0 {
+		return fun(d, n)
+	}
+	if -n >= dec.MaxPrec {
+		return dec.Zero
+	}
+	p := dec.MustNew(int64(math.Pow10(-n)), 0)
+	q, err := d.Quo(p)
+	if err != nil {
+		panic(err)
+	}
+	res, err := fun(q, 0).Mul(p)
+	if err != nil {
+		panic(err)
+	}
+	return res.Trunc(0)
+}
+
+func truncDecimal(d dec.Decimal, n int) dec.Decimal {
+	return d.Trunc(n)
+}
+
+func roundDecimalOp(input *common.Decimal, result *common.Decimal) {
+	result.Decimal = roundDecimal(input.Decimal, 0)
+}
+
+// roundDecimalNOp pads the result to the scale of the result type.
+func roundDecimalNOp(scale int) BinaryOp[common.Decimal, int32, common.Decimal] {
+	return func(input *common.Decimal, n *int32, result *common.Decimal) {
+		result.Decimal = scaleDecimal(input.Decimal, int(*n), roundDecimal).Pad(scale)
+	}
+}
+
+func truncDecimalOp(input *common.Decimal, result *common.Decimal) {
+	result.Decimal = input.Decimal.Trunc(0)
+}
+
+func truncDecimalNOp(scale int) BinaryOp[common.Decimal, int32, common.Decimal] {
+	return func(input *common.Decimal, n *int32, result *common.Decimal) {
+		result.Decimal = scaleDecimal(input.Decimal, int(*n), truncDecimal).Pad(scale)
+	}
+}
+
+// /, %
+
+func divIntegerOp[T mathInteger](left, right *T, result *T) {
+	if *right == 0 {
+		panic(divisionByZero())
+	}
+	if *right == -1 && *left < 0 && -*left < 0 {
+		panic(integerOutOfRange[T]())
+	}
+	*result = *left / *right
+}
+
+func modIntegerOp[T mathInteger](left, right *T, result *T) {
+	if *right == 0 {
+		panic(divisionByZero())
+	}
+	*result = *left % *right
+}
+
+func modFloat64Op(left, right *float64, result *float64) {
+	if *right == 0 {
+		panic(divisionByZero())
+	}
+	*result = math.Mod(*left, *right)
+}
+
+func modDecimalOp(scale int) BinaryOp[common.Decimal, common.Decimal, common.Decimal] {
+	return func(left, right *common.Decimal, result *common.Decimal) {
+		if right.Decimal.IsZero() {
+			panic(divisionByZero())
+		}
+		_, rem, err := left.Decimal.QuoRem(right.Decimal)
+		if err != nil {
+			panic(err)
+		}
+		result.Decimal = rem.Pad(scale)
+	}
+}
+
+// power, sqrt, exp, ln, log
+
+func powerOp(base, exp *float64, result *float64) {
+	if *base == 0 && *exp < 0 {
+		panic(util.NewSQLError(util.SQLStateInvalidArgumentForPower,
+			"zero raised to a negative power is undefined"))
+	}
+	if *base < 0 && math.Floor(*exp) != *exp {
+		panic(util.NewSQLError(util.SQLStateInvalidArgumentForPower,
+			"a negative number raised to a non-integer power yields a complex result"))
+	}
+	*result = math.Pow(*base, *exp)
+	if math.IsInf(*result, 0) && !math.IsInf(*base, 0) && !math.IsInf(*exp, 0) {
+		panic(overflow())
+	}
+}
+
+func sqrtOp(input *float64, result *float64) {
+	if *input < 0 {
+		panic(util.NewSQLError(util.SQLStateInvalidArgumentForPower,
+			"cannot take square root of a negative number"))
+	}
+	*result = math.Sqrt(*input)
+}
+
+func expOp(input *float64, result *float64) {
+	*result = math.Exp(*input)
+	if math.IsInf(*result, 0) && !math.IsInf(*input, 0) {
+		panic(overflow())
+	}
+}
+
+func checkLogArg(x float64) {
+	if x == 0 {
+		panic(util.NewSQLError(util.SQLStateInvalidArgumentForLog,
+			"cannot take logarithm of zero"))
+	}
+	if x < 0 {
+		panic(util.NewSQLError(util.SQLStateInvalidArgumentForLog,
+			"cannot take logarithm of a negative number"))
+	}
+}
+
+func lnOp(input *float64, result *float64) {
+	checkLogArg(*input)
+	*result = math.Log(*input)
+}
+
+func log10Op(input *float64, result *float64) {
+	checkLogArg(*input)
+	*result = math.Log10(*input)
+}
+
+// logOp is the logarithm of the x to the base.
+func logOp(base, x *float64, result *float64) {
+	checkLogArg(*base)
+	checkLogArg(*x)
+	if *base == 1 {
+		panic(divisionByZero())
+	}
+	*result = math.Log(*x) / math.Log(*base)
+}
+
+// &, |, #, ~, <<, >>
+
+func bitAndOp[T mathInteger](left, right *T, result *T) {
+	*result = *left & *right
+}
+
+func bitOrOp[T mathInteger](left, right *T, result *T) {
+	*result = *left | *right
+}
+
+func bitXorOp[T mathInteger](left, right *T, result *T) {
+	*result = *left ^ *right
+}
+
+func bitNotOp[T mathInteger](input *T, result *T) {
+	*result = ^*input
+}
+
+// shiftBits returns the shift count that is in the range of the T.
+func shiftBits[T mathInteger](n T) uint {
+	var zero T
+	return uint(n) & uint(unsafe.Sizeof(zero)*8-1)
+}
+
+func shiftLeftOp[T mathInteger](left, right *T, result *T) {
+	*result = *left << shiftBits(*right)
+}
+
+func shiftRightOp[T mathInteger](left, right *T, result *T) {
+	*result = *left >> shiftBits(*right)
+}
+
+// GREATEST, LEAST
+
+func minMaxFunc(greatest bool) ScalarFunc {
+	return func(input *chunk.Chunk, state *ExprState, result *chunk.Vector) {
+		switch result.Typ().GetInternalType() {
+		case common.BOOL:
+			minMaxLoop[bool](input, result, greatBoolOp{}, greatest)
+		case common.INT8:
+			minMaxLoop[int8](input, result, greatOp[int8]{}, greatest)
+		case common.INT16:
+			minMaxLoop[int16](input, result, greatOp[int16]{}, greatest)
+		case common.INT32:
+			minMaxLoop[int32](input, result, greatOp[int32]{}, greatest)
+		case common.INT64:
+			minMaxLoop[int64](input, result, greatOp[int64]{}, greatest)
+		case common.UINT8:
+			minMaxLoop[uint8](input, result, greatOp[uint8]{}, greatest)
+		case common.UINT16:
+			minMaxLoop[uint16](input, result, greatOp[uint16]{}, greatest)
+		case common.UINT32:
+			minMaxLoop[uint32](input, result, greatOp[uint32]{}, greatest)
+		case common.UINT64:
+			minMaxLoop[uint64](input, result, greatOp[uint64]{}, greatest)
+		case common.FLOAT:
+			minMaxLoop[float32](input, result, greatOp[float32]{}, greatest)
+		case common.DOUBLE:
+			minMaxLoop[float64](input, result, greatOp[float64]{}, greatest)
+		case common.VARCHAR:
+			minMaxLoop[common.String](input, result, greatStrOp{}, greatest)
+		case common.DATE:
+			minMaxLoop[common.Date](input, result, greatDateOp{}, greatest)
+		case common.DECIMAL:
+			minMaxLoop[common.Decimal](input, result, greatDecimalOp{}, greatest)
+		case common.INT128:
+			minMaxLoop[common.Hugeint](input, result, greatHugeintOp{}, greatest)
+		default:
+			panic(fmt.Sprintf("usp %v", result.Typ()))
+		}
+	}
+}
+
+// minMaxLoop returns the largest or smallest non-NULL argument.
+// the result is NULL only if all the arguments are NULL.
+func minMaxLoop[T any](
+	input *chunk.Chunk,
+	result *chunk.Vector,
+	greatOp CompareOp[T],
+	greatest bool,
+) {
+	count := input.Card()
+	args := make([]chunk.UnifiedFormat, input.ColumnCount())
+	slices := make([][]T, input.ColumnCount())
+	for i, vec := range input.Data {
+		vec.ToUnifiedFormat(count, &args[i])
+		slices[i] = chunk.GetSliceInPhyFormatUnifiedFormat[T](&args[i])
+	}
+	result.SetPhyFormat(chunk.PF_FLAT)
+	resSlice := chunk.GetSliceInPhyFormatFlat[T](result)
+	resMask := chunk.GetMaskInPhyFormatFlat(result)
+	for i := 0; i < count; i++ {
+		resMask.SetInvalid(uint64(i))
+		for j := range args {
+			idx := args[j].Sel.GetIndex(i)
+			if !args[j].Mask.RowIsValid(uint64(idx)) {
+				continue
+			}
+			val := &slices[j][idx]
+			if !resMask.RowIsValid(uint64(i)) ||
+				greatest && greatOp.operation(val, &resSlice[i]) ||
+				!greatest && greatOp.operation(&resSlice[i], val) {
+				resSlice[i] = *val
+				resMask.SetValid(uint64(i))
+			}
+		}
+	}
+}
+
+// mathIntegerTypes are the integer types that the math functions
+// and the bitwise operators are registered on.
+func mathIntegerTypes() []common.LType {
+	return []common.LType{
+		common.TinyintType(),
+		common.SmallintType(),
+		common.IntegerType(),
+		common.BigintType(),
+	}
+}
+
+func GetScalarIntegerMathFunction(ptyp common.PhyType, opKind string) ScalarFunc {
+	switch ptyp {
+	case common.INT8:
+		return getScalarIntegerMathFunction[int8](opKind)
+	case common.INT16:
+		return getScalarIntegerMathFunction[int16](opKind)
+	case common.INT32:
+		return getScalarIntegerMathFunction[int32](opKind)
+	case common.INT64:
+		return getScalarIntegerMathFunction[int64](opKind)
+	default:
+		panic(fmt.Sprintf("usp %v", ptyp))
+	}
+}
+
+func getScalarIntegerMathFunction[T mathInteger](opKind string) ScalarFunc {
+	switch opKind {
+	case "abs":
+		return UnaryFunction[T, T](absOp[T])
+	case "/":
+		return BinaryFunction[T, T, T](divIntegerOp[T])
+	case "%":
+		return BinaryFunction[T, T, T](modIntegerOp[T])
+	case "&":
+		return BinaryFunction[T, T, T](bitAndOp[T])
+	case "|":
+		return BinaryFunction[T, T, T](bitOrOp[T])
+	case "#":
+		return BinaryFunction[T, T, T](bitXorOp[T])
+	case "~":
+		return UnaryFunction[T, T](bitNotOp[T])
+	case "<<":
+		return BinaryFunction[T, T, T](shiftLeftOp[T])
+	case ">>":
+		return BinaryFunction[T, T, T](shiftRightOp[T])
+	default:
+		panic(fmt.Sprintf("usp %s", opKind))
+	}
+}
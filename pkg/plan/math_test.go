@@ -0,0 +1,117 @@
+// Copyright 2023-2024 daviszhen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/daviszhen/plan/pkg/storage"
+	"github.com/daviszhen/plan/pkg/util"
+)
+
+func Test_math(t *testing.T) {
+	txn, err := storage.GTxnMgr.NewTxn("math")
+	require.NoError(t, err)
+	defer storage.GTxnMgr.Rollback(txn)
+	execSQL(t, txn, "create table math_t (i integer, b bigint, d decimal(10,3), s varchar)")
+	execSQL(t, txn, "insert into math_t values (-7, 12, -3.455, 'b')")
+
+	kases := []struct {
+		expr string
+		want string
+	}{
+		{"abs(i)", "7"},
+		{"abs((-2.5)::float8)", "2.5"},
+		{"abs(d)", "3.455"},
+		{"sign((-2.5)::float8)", "-1"},
+		{"sign(d)", "-1"},
+		{"ceil((-2.5)::float8)", "-2"},
+		{"ceiling(d)", "-3"},
+		{"floor((-2.5)::float8)", "-3"},
+		{"floor(d)", "-4"},
+		{"round((-2.5)::float8)", "-3"},
+		{"round(d)", "-3"},
+		{"round(d, 2)", "-3.46"},
+		{"round(d, -1)", "0"},
+		{"round(b::decimal(10,0), -1)", "10"},
+		{"round((-2.5)::float8 * 1.15, 1)", "-2.9"},
+		{"trunc((-2.5)::float8)", "-2"},
+		{"trunc(d, 1)", "-3.4"},
+		{"i / 2", "-3"},
+		{"b / 5", "2"},
+		{"i % 3", "-1"},
+		{"mod(b, 5)", "2"},
+		{"mod(d, 2)", "-1.455"},
+		{"(-2.5)::float8 % 2", "-0.5"},
+		{"power(2, 10)", "1024"},
+		{"pow((-2.5)::float8, 2)", "6.25"},
+		{"sqrt(i + 23)", "4"},
+		{"exp(0)", "1"},
+		{"ln(1)", "0"},
+		{"log(1000)", "3"},
+		{"log(2, 8)", "3"},
+		{"greatest(i, b, 3)", "12"},
+		{"least(i, b, 3)", "-7"},
+		{"greatest(d, 1)", "1"},
+		{"least(s, 'a', null)", "a"},
+		{"greatest(null, null)", "NULL"},
+		{"b & 10", "8"},
+		{"b | 3", "15"},
+		{"b # 5", "9"},
+		{"~b", "-13"},
+		{"b << 2", "48"},
+		{"b >> 2", "3"},
+	}
+	for _, kase := range kases {
+		rows, err := querySQL(t, txn, "select "+kase.expr+" from math_t")
+		require.NoError(t, err, kase.expr)
+		require.Equal(t, [][]string{{kase.want}}, rows, kase.expr)
+	}
+	rows, err := querySQL(t, txn, "select i from math_t where b % 5 = 2 and greatest(i, 0) = 0")
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"-7"}}, rows)
+
+	errKases := []struct {
+		expr string
+		code util.SQLState
+	}{
+		{"i / 0", util.SQLStateDivisionByZero},
+		{"(-2.5)::float8 / 0", util.SQLStateDivisionByZero},
+		{"d / 0", util.SQLStateDivisionByZero},
+		{"i % 0", util.SQLStateDivisionByZero},
+		{"mod(d, 0)", util.SQLStateDivisionByZero},
+		{"abs((i - 32761)::smallint)", util.SQLStateNumericValueOutOfRange},
+		{"sqrt((-2.5)::float8)", util.SQLStateInvalidArgumentForPower},
+		{"power(0, (-2.5)::float8)", util.SQLStateInvalidArgumentForPower},
+		{"power((-2.5)::float8, 0.5)", util.SQLStateInvalidArgumentForPower},
+		{"ln(0)", util.SQLStateInvalidArgumentForLog},
+		{"log((-2.5)::float8)", util.SQLStateInvalidArgumentForLog},
+		{"exp(1000)", util.SQLStateNumericValueOutOfRange},
+	}
+	for _, kase := range errKases {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					err = util.RecoverToError(r)
+				}
+				require.Error(t, err, kase.expr)
+				require.Equal(t, kase.code, util.GetSQLState(err), kase.expr)
+			}()
+			_, err = querySQL(t, txn, "select "+kase.expr+" from math_t")
+		}()
+	}
+}
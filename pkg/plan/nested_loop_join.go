@@ -0,0 +1,362 @@
+// Copyright 2023-2024 daviszhen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"sort"
+
+	"github.com/daviszhen/plan/pkg/chunk"
+	"github.com/daviszhen/plan/pkg/common"
+	"github.com/daviszhen/plan/pkg/util"
+)
+
+type NestedLoopJoinStage int
+
+const (
+	NLJ_INIT NestedLoopJoinStage = iota
+	NLJ_PROBE
+	NLJ_SCAN_UNMATCHED
+	NLJ_DONE
+)
+
+// NestedLoopJoin joins the row of the left side with the chunks of
+// the materialized right side and evaluates the join conditions
+// on the row pairs.
+//
+// for the piecewise merge join, the right side is sorted on the operand
+// of the inequality. only the range of the sorted rows that satisfies the
+// inequality is joined with the left row.
+type NestedLoopJoin struct {
+	_stage   NestedLoopJoinStage
+	_joinTyp LOT_JoinType
+
+	_leftTypes  []common.LType
+	_rightTypes []common.LType
+
+	//the materialized right side
+	_rhs *ColumnDataCollection
+
+	//the conditions evaluated on the row pairs
+	_condExec *ExprExec
+
+	//for the piecewise merge join.
+	//the inequality and the operands of it
+	_rangeCond    *Expr
+	_leftKeyExec  *ExprExec
+	_rightKeyExec *ExprExec
+	//the sorted keys of the right side. NULLs are at the end.
+	_rightKeys   []*chunk.Value
+	_nonNullKeys int
+
+	//the right rows that have the match for the right and full join
+	_rightFound []bool
+
+	//the left chunk in probing
+	_input     *chunk.Chunk
+	_leftKeys  []*chunk.Value
+	_leftFound []bool
+	_leftIdx   int
+	//the range of the right rows for the left row
+	_pos int
+	_end int
+	//the unmatched left rows have been output
+	_unmatchedDone bool
+
+	//the next right chunk for scanning the unmatched right rows
+	_scanChunkIdx int
+}
+
+func NewNestedLoopJoin(op *PhysicalOperator) *NestedLoopJoin {
+	nlj := &NestedLoopJoin{
+		_joinTyp: op.JoinTyp,
+	}
+	for _, output := range op.Children[0].Outputs {
+		nlj._leftTypes = append(nlj._leftTypes, output.DataTyp)
+	}
+	for _, output := range op.Children[1].Outputs {
+		nlj._rightTypes = append(nlj._rightTypes, output.DataTyp)
+	}
+	nlj._rhs = NewColumnDataCollection(nlj._rightTypes)
+
+	conds := copyExprs(op.OnConds...)
+	if op.JoinAlgo == JoinAlgoPiecewiseMerge {
+		idx := findRangeCond(conds)
+		util.AssertFunc(idx != -1)
+		nlj._rangeCond = conds[idx]
+		nlj._leftKeyExec = NewExprExec(nlj._rangeCond.Children[0])
+		nlj._rightKeyExec = NewExprExec(nlj._rangeCond.Children[1])
+		conds = util.Erase(conds, idx)
+	}
+	if len(conds) != 0 {
+		nlj._condExec = NewExprExec(combineExprsByAnd(conds...))
+	}
+	return nlj
+}
+
+func (nlj *NestedLoopJoin) Sink(input *chunk.Chunk) {
+	nlj._rhs.Append(input)
+}
+
+// Finalize prepares the right side for probing.
+func (nlj *NestedLoopJoin) Finalize() error {
+	if nlj._rangeCond != nil {
+		err := nlj.sortRight()
+		if err != nil {
+			return err
+		}
+	}
+	if nlj.scanUnmatched() {
+		nlj._rightFound = make([]bool, nlj._rhs.Count())
+	}
+	return nil
+}
+
+// sortRight reorders the right rows on the key of the inequality.
+func (nlj *NestedLoopJoin) sortRight() error {
+	type sortEntry struct {
+		key *chunk.Value
+		pos int
+	}
+	entries := make([]sortEntry, 0, nlj._rhs.Count())
+	keyChunk := &chunk.Chunk{}
+	keyChunk.Init([]common.LType{nlj._rangeCond.Children[1].DataTyp}, util.DefaultVectorSize)
+	for i, data := range nlj._rhs._chunks {
+		keyChunk.Reset()
+		err := nlj._rightKeyExec.executeExprs([]*chunk.Chunk{nil, data, nil}, keyChunk)
+		if err != nil {
+			return err
+		}
+		for j := 0; j < data.Card(); j++ {
+			entries = append(entries, sortEntry{
+				key: keyChunk.Data[0].GetValue(j),
+				pos: i*util.DefaultVectorSize + j,
+			})
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return compareValue(entries[i].key, entries[j].key) < 0
+	})
+
+	//gather the rows in the order of the keys
+	sorted := NewColumnDataCollection(nlj._rightTypes)
+	batch := &chunk.Chunk{}
+	batch.Init(nlj._rightTypes, util.DefaultVectorSize)
+	nlj._rightKeys = make([]*chunk.Value, 0, len(entries))
+	for i, entry := range entries {
+		src := nlj._rhs._chunks[entry.pos/util.DefaultVectorSize]
+		srcIdx := entry.pos % util.DefaultVectorSize
+		dstIdx := i % util.DefaultVectorSize
+		for j, vec := range batch.Data {
+			vec.SetValue(dstIdx, src.Data[j].GetValue(srcIdx))
+		}
+		if dstIdx == util.DefaultVectorSize-1 || i == len(entries)-1 {
+			batch.SetCard(dstIdx + 1)
+			sorted.Append(batch)
+			batch.Reset()
+		}
+		nlj._rightKeys = append(nlj._rightKeys, entry.key)
+		if !entry.key.IsNull {
+			nlj._nonNullKeys++
+		}
+	}
+	nlj._rhs = sorted
+	return nil
+}
+
+func (nlj *NestedLoopJoin) scanUnmatched() bool {
+	return nlj._joinTyp == LOT_JoinTypeRight || nlj._joinTyp == LOT_JoinTypeOUTER
+}
+
+func (nlj *NestedLoopJoin) keepUnmatchedLeft() bool {
+	return nlj._joinTyp == LOT_JoinTypeLeft || nlj._joinTyp == LOT_JoinTypeOUTER
+}
+
+// SetInput starts probing the left chunk.
+func (nlj *NestedLoopJoin) SetInput(input *chunk.Chunk) error {
+	nlj._input = input
+	nlj._leftFound = make([]bool, input.Card())
+	nlj._unmatchedDone = false
+	nlj._leftKeys = nlj._leftKeys[:0]
+	if nlj._rangeCond != nil {
+		keyChunk := &chunk.Chunk{}
+		keyChunk.Init([]common.LType{nlj._rangeCond.Children[0].DataTyp}, util.DefaultVectorSize)
+		err := nlj._leftKeyExec.executeExprs([]*chunk.Chunk{input, nil, nil}, keyChunk)
+		if err != nil {
+			return err
+		}
+		for i := 0; i < input.Card(); i++ {
+			nlj._leftKeys = append(nlj._leftKeys, keyChunk.Data[0].GetValue(i))
+		}
+	}
+	nlj._leftIdx = -1
+	nlj.nextLeftRow()
+	return nil
+}
+
+// nextLeftRow moves to the next left row and decides
+// the range of the right rows for it.
+func (nlj *NestedLoopJoin) nextLeftRow() {
+	nlj._leftIdx++
+	if nlj._leftIdx >= nlj._input.Card() {
+		return
+	}
+	nlj._pos, nlj._end = 0, nlj._rhs.Count()
+	if nlj._rangeCond == nil {
+		return
+	}
+	key := nlj._leftKeys[nlj._leftIdx]
+	if key.IsNull {
+		nlj._pos, nlj._end = 0, 0
+		return
+	}
+	cnt := nlj._nonNullKeys
+	//the first right key >= the left key
+	lower := sort.Search(cnt, func(i int) bool {
+		return compareValue(nlj._rightKeys[i], key) >= 0
+	})
+	//the first right key > the left key
+	upper := sort.Search(cnt, func(i int) bool {
+		return compareValue(nlj._rightKeys[i], key) > 0
+	})
+	switch nlj._rangeCond.SubTyp {
+	case ET_Less:
+		nlj._pos, nlj._end = upper, cnt
+	case ET_LessEqual:
+		nlj._pos, nlj._end = lower, cnt
+	case ET_Greater:
+		nlj._pos, nlj._end = 0, lower
+	case ET_GreaterEqual:
+		nlj._pos, nlj._end = 0, upper
+	default:
+		panic("usp")
+	}
+}
+
+// Next fills the left and right part of the next joined rows.
+// it returns false when the left chunk is over.
+func (nlj *NestedLoopJoin) Next(left, right *chunk.Chunk) (bool, error) {
+	for nlj._leftIdx < nlj._input.Card() {
+		if nlj._pos >= nlj._end {
+			nlj.nextLeftRow()
+			continue
+		}
+		//the right rows in the same chunk
+		chunkIdx := nlj._pos / util.DefaultVectorSize
+		base := chunkIdx * util.DefaultVectorSize
+		data := nlj._rhs._chunks[chunkIdx]
+		begin := nlj._pos - base
+		end := min(nlj._end-base, data.Card())
+		nlj._pos = base + end
+
+		cnt := end - begin
+		sel := chunk.NewSelectVector(util.DefaultVectorSize)
+		for i := 0; i < cnt; i++ {
+			sel.SetIndex(i, begin+i)
+		}
+		nlj.referLeftRow(left, cnt)
+		right.Init(nlj._rightTypes, util.DefaultVectorSize)
+		right.Slice(data, sel, cnt, 0)
+
+		if nlj._condExec != nil {
+			matchSel := chunk.NewSelectVector(util.DefaultVectorSize)
+			matchCnt, err := nlj._condExec.executeSelect([]*chunk.Chunk{left, right, nil}, matchSel)
+			if err != nil {
+				return false, err
+			}
+			if matchCnt == 0 {
+				continue
+			}
+			for i := 0; i < matchCnt; i++ {
+				sel.SetIndex(i, begin+matchSel.GetIndex(i))
+			}
+			cnt = matchCnt
+			nlj.referLeftRow(left, cnt)
+			right.Init(nlj._rightTypes, util.DefaultVectorSize)
+			right.Slice(data, sel, cnt, 0)
+		}
+
+		nlj._leftFound[nlj._leftIdx] = true
+		if nlj._rightFound != nil {
+			for i := 0; i < cnt; i++ {
+				nlj._rightFound[base+sel.GetIndex(i)] = true
+			}
+		}
+		return true, nil
+	}
+
+	//the unmatched left rows padded with NULL
+	if nlj.keepUnmatchedLeft() && !nlj._unmatchedDone {
+		nlj._unmatchedDone = true
+		sel := chunk.NewSelectVector(util.DefaultVectorSize)
+		cnt := 0
+		for i, found := range nlj._leftFound {
+			if !found {
+				sel.SetIndex(cnt, i)
+				cnt++
+			}
+		}
+		if cnt > 0 {
+			left.Init(nlj._leftTypes, util.DefaultVectorSize)
+			left.Slice(nlj._input, sel, cnt, 0)
+			referNulls(right, nlj._rightTypes, cnt)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// NextUnmatched fills the unmatched right rows padded with NULL.
+// it returns false when the right side is over.
+func (nlj *NestedLoopJoin) NextUnmatched(left, right *chunk.Chunk) bool {
+	for nlj._scanChunkIdx < len(nlj._rhs._chunks) {
+		data := nlj._rhs._chunks[nlj._scanChunkIdx]
+		base := nlj._scanChunkIdx * util.DefaultVectorSize
+		nlj._scanChunkIdx++
+		sel := chunk.NewSelectVector(util.DefaultVectorSize)
+		cnt := 0
+		for i := 0; i < data.Card(); i++ {
+			if !nlj._rightFound[base+i] {
+				sel.SetIndex(cnt, i)
+				cnt++
+			}
+		}
+		if cnt == 0 {
+			continue
+		}
+		referNulls(left, nlj._leftTypes, cnt)
+		right.Init(nlj._rightTypes, util.DefaultVectorSize)
+		right.Slice(data, sel, cnt, 0)
+		return true
+	}
+	return false
+}
+
+// referLeftRow makes the left chunk the repeated current left row.
+func (nlj *NestedLoopJoin) referLeftRow(left *chunk.Chunk, cnt int) {
+	left.Init(nlj._leftTypes, util.DefaultVectorSize)
+	for i, vec := range left.Data {
+		chunk.ReferenceInPhyFormatConst(vec, nlj._input.Data[i], nlj._leftIdx, cnt)
+	}
+	left.SetCard(cnt)
+}
+
+// referNulls makes the chunk of the NULLs.
+func referNulls(data *chunk.Chunk, typs []common.LType, cnt int) {
+	data.Init(typs, util.DefaultVectorSize)
+	for _, vec := range data.Data {
+		vec.ReferenceValue(&chunk.Value{Typ: vec.Typ(), IsNull: true})
+	}
+	data.SetCard(cnt)
+}
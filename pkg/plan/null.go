@@ -0,0 +1,372 @@
+// Copyright 2023-2024 daviszhen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"fmt"
+
+	"github.com/daviszhen/plan/pkg/chunk"
+	"github.com/daviszhen/plan/pkg/common"
+)
+
+// the functions that handle the NULL specially.
+// the results of them are never NULL except COALESCE and NULLIF.
+
+type equalIntervalOp struct {
+}
+
+func (e equalIntervalOp) operation(left, right *common.Interval) bool {
+	return left.Equal(right)
+}
+
+// IS NULL, IS NOT NULL
+
+func isNullFunc(not bool) ScalarFunc {
+	return func(input *chunk.Chunk, state *ExprState, result *chunk.Vector) {
+		var uni chunk.UnifiedFormat
+		count := input.Card()
+		input.Data[0].ToUnifiedFormat(count, &uni)
+		result.SetPhyFormat(chunk.PF_FLAT)
+		resSlice := chunk.GetSliceInPhyFormatFlat[bool](result)
+		resMask := chunk.GetMaskInPhyFormatFlat(result)
+		for i := 0; i < count; i++ {
+			idx := uni.Sel.GetIndex(i)
+			resSlice[i] = uni.Mask.RowIsValid(uint64(idx)) == not
+			resMask.SetValid(uint64(i))
+		}
+	}
+}
+
+// IS DISTINCT FROM, IS NOT DISTINCT FROM
+
+func isDistinctFunc(not bool) ScalarFunc {
+	return func(input *chunk.Chunk, state *ExprState, result *chunk.Vector) {
+		left := input.Data[0]
+		right := input.Data[1]
+		count := input.Card()
+		switch left.Typ().GetInternalType() {
+		case common.BOOL:
+			distinctLoop[bool](left, right, result, count, equalOp[bool]{}, not)
+		case common.INT8:
+			distinctLoop[int8](left, right, result, count, equalOp[int8]{}, not)
+		case common.INT16:
+			distinctLoop[int16](left, right, result, count, equalOp[int16]{}, not)
+		case common.INT32:
+			distinctLoop[int32](left, right, result, count, equalOp[int32]{}, not)
+		case common.INT64:
+			distinctLoop[int64](left, right, result, count, equalOp[int64]{}, not)
+		case common.UINT64:
+			distinctLoop[uint64](left, right, result, count, equalOp[uint64]{}, not)
+		case common.FLOAT:
+			distinctLoop[float32](left, right, result, count, equalOp[float32]{}, not)
+		case common.DOUBLE:
+			distinctLoop[float64](left, right, result, count, equalOp[float64]{}, not)
+		case common.VARCHAR:
+			distinctLoop[common.String](left, right, result, count, equalStrOp{}, not)
+		case common.DATE:
+			distinctLoop[common.Date](left, right, result, count, equalDateOp{}, not)
+		case common.DECIMAL:
+			distinctLoop[common.Decimal](left, right, result, count, equalDecimalOp{}, not)
+		case common.INT128:
+			distinctLoop[common.Hugeint](left, right, result, count, equalHugeintOp{}, not)
+		case common.INTERVAL:
+			distinctLoop[common.Interval](left, right, result, count, equalIntervalOp{}, not)
+		default:
+			panic(fmt.Sprintf("usp %v", left.Typ()))
+		}
+	}
+}
+
+// distinctLoop treats the NULL as a comparable value.
+func distinctLoop[T any](
+	left, right, result *chunk.Vector,
+	count int,
+	cmpOp CompareOp[T],
+	not bool,
+) {
+	var ldata, rdata chunk.UnifiedFormat
+	left.ToUnifiedFormat(count, &ldata)
+	right.ToUnifiedFormat(count, &rdata)
+	lSlice := chunk.GetSliceInPhyFormatUnifiedFormat[T](&ldata)
+	rSlice := chunk.GetSliceInPhyFormatUnifiedFormat[T](&rdata)
+	result.SetPhyFormat(chunk.PF_FLAT)
+	resSlice := chunk.GetSliceInPhyFormatFlat[bool](result)
+	resMask := chunk.GetMaskInPhyFormatFlat(result)
+	for i := 0; i < count; i++ {
+		lidx := ldata.Sel.GetIndex(i)
+		ridx := rdata.Sel.GetIndex(i)
+		lvalid := ldata.Mask.RowIsValid(uint64(lidx))
+		rvalid := rdata.Mask.RowIsValid(uint64(ridx))
+		var distinct bool
+		if lvalid && rvalid {
+			distinct = !cmpOp.operation(&lSlice[lidx], &rSlice[ridx])
+		} else {
+			distinct = lvalid != rvalid
+		}
+		resSlice[i] = distinct != not
+		resMask.SetValid(uint64(i))
+	}
+}
+
+// NULLIF
+
+func nullIfFunc(input *chunk.Chunk, state *ExprState, result *chunk.Vector) {
+	left := input.Data[0]
+	right := input.Data[1]
+	count := input.Card()
+	switch left.Typ().GetInternalType() {
+	case common.BOOL:
+		nullIfLoop[bool](left, right, result, count, equalOp[bool]{})
+	case common.INT8:
+		nullIfLoop[int8](left, right, result, count, equalOp[int8]{})
+	case common.INT16:
+		nullIfLoop[int16](left, right, result, count, equalOp[int16]{})
+	case common.INT32:
+		nullIfLoop[int32](left, right, result, count, equalOp[int32]{})
+	case common.INT64:
+		nullIfLoop[int64](left, right, result, count, equalOp[int64]{})
+	case common.UINT64:
+		nullIfLoop[uint64](left, right, result, count, equalOp[uint64]{})
+	case common.FLOAT:
+		nullIfLoop[float32](left, right, result, count, equalOp[float32]{})
+	case common.DOUBLE:
+		nullIfLoop[float64](left, right, result, count, equalOp[float64]{})
+	case common.VARCHAR:
+		nullIfLoop[common.String](left, right, result, count, equalStrOp{})
+	case common.DATE:
+		nullIfLoop[common.Date](left, right, result, count, equalDateOp{})
+	case common.DECIMAL:
+		nullIfLoop[common.Decimal](left, right, result, count, equalDecimalOp{})
+	case common.INT128:
+		nullIfLoop[common.Hugeint](left, right, result, count, equalHugeintOp{})
+	case common.INTERVAL:
+		nullIfLoop[common.Interval](left, right, result, count, equalIntervalOp{})
+	default:
+		panic(fmt.Sprintf("usp %v", left.Typ()))
+	}
+}
+
+// nullIfLoop returns NULL if the left equals to the right.
+// otherwise, returns the left.
+func nullIfLoop[T any](
+	left, right, result *chunk.Vector,
+	count int,
+	cmpOp CompareOp[T],
+) {
+	var ldata, rdata chunk.UnifiedFormat
+	left.ToUnifiedFormat(count, &ldata)
+	right.ToUnifiedFormat(count, &rdata)
+	lSlice := chunk.GetSliceInPhyFormatUnifiedFormat[T](&ldata)
+	rSlice := chunk.GetSliceInPhyFormatUnifiedFormat[T](&rdata)
+	result.SetPhyFormat(chunk.PF_FLAT)
+	resSlice := chunk.GetSliceInPhyFormatFlat[T](result)
+	resMask := chunk.GetMaskInPhyFormatFlat(result)
+	for i := 0; i < count; i++ {
+		lidx := ldata.Sel.GetIndex(i)
+		ridx := rdata.Sel.GetIndex(i)
+		if !ldata.Mask.RowIsValid(uint64(lidx)) ||
+			rdata.Mask.RowIsValid(uint64(ridx)) &&
+				cmpOp.operation(&lSlice[lidx], &rSlice[ridx]) {
+			resMask.SetInvalid(uint64(i))
+			continue
+		}
+		resSlice[i] = lSlice[lidx]
+		resMask.SetValid(uint64(i))
+	}
+}
+
+// COALESCE
+
+func coalesceFunc(input *chunk.Chunk, state *ExprState, result *chunk.Vector) {
+	switch result.Typ().GetInternalType() {
+	case common.BOOL:
+		coalesceLoop[bool](input, result)
+	case common.INT8:
+		coalesceLoop[int8](input, result)
+	case common.INT16:
+		coalesceLoop[int16](input, result)
+	case common.INT32:
+		coalesceLoop[int32](input, result)
+	case common.INT64:
+		coalesceLoop[int64](input, result)
+	case common.UINT64:
+		coalesceLoop[uint64](input, result)
+	case common.FLOAT:
+		coalesceLoop[float32](input, result)
+	case common.DOUBLE:
+		coalesceLoop[float64](input, result)
+	case common.VARCHAR:
+		coalesceLoop[common.String](input, result)
+	case common.DATE:
+		coalesceLoop[common.Date](input, result)
+	case common.DECIMAL:
+		coalesceLoop[common.Decimal](input, result)
+	case common.INT128:
+		coalesceLoop[common.Hugeint](input, result)
+	case common.INTERVAL:
+		coalesceLoop[common.Interval](input, result)
+	default:
+		panic(fmt.Sprintf("usp %v", result.Typ()))
+	}
+}
+
+// coalesceLoop returns the first non-NULL argument.
+func coalesceLoop[T any](input *chunk.Chunk, result *chunk.Vector) {
+	count := input.Card()
+	args := make([]chunk.UnifiedFormat, input.ColumnCount())
+	slices := make([][]T, input.ColumnCount())
+	for i, vec := range input.Data {
+		vec.ToUnifiedFormat(count, &args[i])
+		slices[i] = chunk.GetSliceInPhyFormatUnifiedFormat[T](&args[i])
+	}
+	result.SetPhyFormat(chunk.PF_FLAT)
+	resSlice := chunk.GetSliceInPhyFormatFlat[T](result)
+	resMask := chunk.GetMaskInPhyFormatFlat(result)
+	for i := 0; i < count; i++ {
+		resMask.SetInvalid(uint64(i))
+		for j := range args {
+			idx := args[j].Sel.GetIndex(i)
+			if args[j].Mask.RowIsValid(uint64(idx)) {
+				resSlice[i] = slices[j][idx]
+				resMask.SetValid(uint64(i))
+				break
+			}
+		}
+	}
+}
+
+// selectNullTest splits the rows by the validity of the vector.
+func selectNullTest(
+	vec *chunk.Vector,
+	sel *chunk.SelectVector,
+	count int,
+	trueSel, falseSel *chunk.SelectVector,
+	not bool,
+) int {
+	if sel == nil {
+		sel = chunk.IncrSelectVectorInPhyFormatFlat()
+	}
+	var uni chunk.UnifiedFormat
+	vec.ToUnifiedFormat(count, &uni)
+	trueCount, falseCount := 0, 0
+	for i := 0; i < count; i++ {
+		resIdx := sel.GetIndex(i)
+		idx := uni.Sel.GetIndex(i)
+		if uni.Mask.RowIsValid(uint64(idx)) == not {
+			if trueSel != nil {
+				trueSel.SetIndex(trueCount, resIdx)
+			}
+			trueCount++
+		} else {
+			if falseSel != nil {
+				falseSel.SetIndex(falseCount, resIdx)
+			}
+			falseCount++
+		}
+	}
+	return trueCount
+}
+
+// selectBool splits the rows by the boolean vector.
+// NULL is treated as false.
+func selectBool(
+	vec *chunk.Vector,
+	sel *chunk.SelectVector,
+	count int,
+	trueSel, falseSel *chunk.SelectVector,
+) int {
+	if sel == nil {
+		sel = chunk.IncrSelectVectorInPhyFormatFlat()
+	}
+	var uni chunk.UnifiedFormat
+	vec.ToUnifiedFormat(count, &uni)
+	data := chunk.GetSliceInPhyFormatUnifiedFormat[bool](&uni)
+	trueCount, falseCount := 0, 0
+	for i := 0; i < count; i++ {
+		resIdx := sel.GetIndex(i)
+		idx := uni.Sel.GetIndex(i)
+		if uni.Mask.RowIsValid(uint64(idx)) && data[idx] {
+			if trueSel != nil {
+				trueSel.SetIndex(trueCount, resIdx)
+			}
+			trueCount++
+		} else {
+			if falseSel != nil {
+				falseSel.SetIndex(falseCount, resIdx)
+			}
+			falseCount++
+		}
+	}
+	return trueCount
+}
+
+// isNullRejecting checks the filter is false or NULL when
+// all the columns in it are NULL.
+// only the comparisons, the arithmetic and the strict scalar
+// functions are known to be NULL on the NULL.
+func isNullRejecting(e *Expr) bool {
+	if e.Typ != ET_Func {
+		return false
+	}
+	switch e.SubTyp {
+	case ET_And:
+		return isNullRejecting(e.Children[0]) || isNullRejecting(e.Children[1])
+	case ET_IsNotNull:
+		return isStrict(e.Children[0])
+	}
+	return isStrict(e)
+}
+
+// isStrict checks the expr is NULL if any column in it is NULL.
+func isStrict(e *Expr) bool {
+	switch e.Typ {
+	case ET_Column,
+		ET_IConst,
+		ET_DecConst,
+		ET_SConst,
+		ET_FConst,
+		ET_DateConst,
+		ET_IntervalConst,
+		ET_BConst,
+		ET_NConst:
+		return true
+	case ET_Func:
+	default:
+		return false
+	}
+	switch e.SubTyp {
+	case ET_Equal, ET_NotEqual, ET_Greater, ET_GreaterEqual, ET_Less, ET_LessEqual,
+		ET_Like, ET_NotLike, ET_ILike, ET_NotILike, ET_Between,
+		ET_RegexpMatch, ET_NotRegexpMatch, ET_RegexpIMatch, ET_NotRegexpIMatch,
+		ET_Add, ET_Sub, ET_Mul, ET_Div, ET_Mod,
+		ET_BitAnd, ET_BitOr, ET_BitXor, ET_BitNot, ET_ShiftLeft, ET_ShiftRight,
+		ET_Concat, ET_DateAdd, ET_DateSub, ET_Cast, ET_Extract, ET_Substring:
+	case ET_SubFunc:
+		//concat, greatest, least and so on skip the NULL
+		if e.FunImpl == nil ||
+			e.FunImpl._funcTyp != ScalarFuncType ||
+			e.FunImpl._nullHandling != DefaultNullHandling {
+			return false
+		}
+	default:
+		return false
+	}
+	for _, child := range e.Children {
+		if !isStrict(child) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,96 @@
+// Copyright 2023-2024 daviszhen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/daviszhen/plan/pkg/storage"
+)
+
+func Test_null(t *testing.T) {
+	txn, err := storage.GTxnMgr.NewTxn("null")
+	require.NoError(t, err)
+	defer storage.GTxnMgr.Rollback(txn)
+	execSQL(t, txn, "create table null_t (i integer, s varchar)")
+	execSQL(t, txn, "insert into null_t values (1, 'a'), (2, 'b'), (3, 'c')")
+	execSQL(t, txn, "create table null_t2 (i integer, j integer)")
+	execSQL(t, txn, "insert into null_t2 values (1, 10)")
+	execSQL(t, txn, "create table null_t3 (i integer, s varchar)")
+	execSQL(t, txn, "insert into null_t3 values (1, 'x')")
+
+	kases := []struct {
+		sql  string
+		want [][]string
+	}{
+		{
+			"select i, nullif(i, 2), nullif(i, 2) is null, nullif(i, 2) is not null from null_t",
+			[][]string{{"1", "1", "false", "true"}, {"2", "NULL", "true", "false"}, {"3", "3", "false", "true"}},
+		},
+		{
+			"select coalesce(nullif(i, 2), 0), coalesce(nullif(s, 'b'), nullif(s, 'b'), 'z') from null_t",
+			[][]string{{"1", "a"}, {"0", "z"}, {"3", "c"}},
+		},
+		{
+			"select nullif(i, 2) is distinct from 3, nullif(i, 2) is not distinct from null from null_t",
+			[][]string{{"true", "false"}, {"true", "true"}, {"false", "false"}},
+		},
+		{
+			"select (i = 1) is true, (i = 1) is not false, nullif(i, 2) is unknown from null_t",
+			[][]string{{"true", "true", "false"}, {"false", "false", "true"}, {"false", "false", "false"}},
+		},
+		{
+			"select i from null_t where nullif(i, 2) is null",
+			[][]string{{"2"}},
+		},
+		{
+			"select i from null_t where nullif(i, 1) is not distinct from 3 or nullif(i, 1) is null order by i",
+			[][]string{{"1"}, {"3"}},
+		},
+		{
+			"select null_t.i, null_t2.j, null_t2.j is null from null_t left join null_t2 on null_t.i = null_t2.i",
+			[][]string{{"1", "10", "false"}, {"2", "NULL", "true"}, {"3", "NULL", "true"}},
+		},
+		{
+			//IS NULL on the right side must stay above the left join
+			"select null_t.i from null_t left join null_t2 on null_t.i = null_t2.i where null_t2.j is null",
+			[][]string{{"2"}, {"3"}},
+		},
+		{
+			"select null_t.i from null_t left join null_t2 on null_t.i = null_t2.i where coalesce(null_t2.j, 0) = 0",
+			[][]string{{"2"}, {"3"}},
+		},
+		{
+			//concat and greatest skip the NULL. the left join is kept.
+			"select null_t.i from null_t left join null_t3 on null_t.i = null_t3.i where concat(null_t3.s, 'z') = 'z' order by null_t.i",
+			[][]string{{"2"}, {"3"}},
+		},
+		{
+			"select null_t.i from null_t left join null_t2 on null_t.i = null_t2.i where greatest(null_t2.j, 5) = 5 order by null_t.i",
+			[][]string{{"2"}, {"3"}},
+		},
+		{
+			"select null_t.i from null_t left join null_t3 on null_t.i = null_t3.i where upper(null_t3.s) = 'X'",
+			[][]string{{"1"}},
+		},
+	}
+	for _, kase := range kases {
+		rows, err := querySQL(t, txn, kase.sql)
+		require.NoError(t, err, kase.sql)
+		require.Equal(t, kase.want, rows, kase.sql)
+	}
+}
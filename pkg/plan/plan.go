@@ -121,6 +121,10 @@ const (
 	LOT_CreateSchema LOT = 7
 	LOT_CreateTable  LOT = 8
 	LOT_Insert       LOT = 9
+	LOT_Vacuum       LOT = 10
+	LOT_Export       LOT = 11
+	LOT_Import       LOT = 12
+	LOT_RecursiveCTE LOT = 13
 )
 
 func (lt LOT) String() string {
@@ -145,8 +149,16 @@ func (lt LOT) String() string {
 		return "CreateTable"
 	case LOT_Insert:
 		return "Insert"
+	case LOT_Vacuum:
+		return "Vacuum"
+	case LOT_Export:
+		return "Export"
+	case LOT_Import:
+		return "Import"
+	case LOT_RecursiveCTE:
+		return "RecursiveCTE"
 	default:
-		panic(fmt.Sprintf("usp %d", lt))
+		return fmt.Sprintf("LOT(%d)", lt)
 	}
 }
 
@@ -162,6 +174,7 @@ const (
 	LOT_JoinTypeMARK
 	LOT_JoinTypeAntiMARK
 	LOT_JoinTypeOUTER
+	LOT_JoinTypeRight
 )
 
 func (lojt LOT_JoinType) String() string {
@@ -180,17 +193,51 @@ func (lojt LOT_JoinType) String() string {
 		return "semi"
 	case LOT_JoinTypeANTI:
 		return "anti semi"
+	case LOT_JoinTypeOUTER:
+		return "full"
+	case LOT_JoinTypeRight:
+		return "right"
 	default:
 		panic(fmt.Sprintf("usp %d", lojt))
 	}
 }
 
+// JoinAlgo is the physical algorithm of the join.
+type JoinAlgo int
+
+const (
+	JoinAlgoHash JoinAlgo = iota
+	JoinAlgoCross
+	JoinAlgoNestedLoop
+	JoinAlgoPiecewiseMerge
+	JoinAlgoSortMerge
+)
+
+func (ja JoinAlgo) String() string {
+	switch ja {
+	case JoinAlgoHash:
+		return "hash"
+	case JoinAlgoCross:
+		return "cross product"
+	case JoinAlgoNestedLoop:
+		return "nested loop"
+	case JoinAlgoPiecewiseMerge:
+		return "piecewise merge"
+	case JoinAlgoSortMerge:
+		return "sort merge"
+	default:
+		panic(fmt.Sprintf("usp %d", ja))
+	}
+}
+
 type ScanType int
 
 const (
 	ScanTypeTable      ScanType = 0
 	ScanTypeValuesList ScanType = 1
 	ScanTypeCopyFrom   ScanType = 2
+	ScanTypeWorkTable  ScanType = 3
+	ScanTypeCTE        ScanType = 4
 )
 
 func (st ScanType) String() string {
@@ -201,6 +248,10 @@ func (st ScanType) String() string {
 		return "scan values list"
 	case ScanTypeCopyFrom:
 		return "scan copy from"
+	case ScanTypeWorkTable:
+		return "scan work table"
+	case ScanTypeCTE:
+		return "scan cte"
 	default:
 		panic("usp")
 	}
@@ -218,6 +269,9 @@ type ScanInfo struct {
 	FilePath      string
 	Opts          []*ScanOption
 	Format        string //for CopyFrom
+	//for the data files of EXPORT DATABASE.
+	//the backslashes in the values are doubled.
+	Exported bool
 }
 
 type LogicalOperator struct {
@@ -236,6 +290,8 @@ type LogicalOperator struct {
 	OnConds          []*Expr //for innor join
 	Aggs             []*Expr
 	GroupBys         []*Expr
+	GroupingSets     []GroupingSet //for AGG. nil for the plain GROUP BY
+	GroupingFuncs    [][]int       //for AGG. the arguments of the GROUPING functions
 	OrderBys         []*Expr
 	Limit            *Expr
 	Offset           *Expr
@@ -259,12 +315,18 @@ type LogicalOperator struct {
 	//column seq no in table -> column seq no in Insert
 	ColumnIndexMap []int //for insert
 	ScanInfo       *ScanInfo
+	CTEIndex       uint64             //for the work table scan
+	UnionAll       bool               //for recursive cte
+	CTERoot        *LogicalOperator   //plan of the materialized cte
 	Counts         ColumnBindCountMap `json:"-"`
 	ColRefToPos    ColumnBindPosMap   `json:"-"`
 }
 
 func (lo *LogicalOperator) EstimatedCard(txn *storage.Txn) uint64 {
-	if lo.Typ == LOT_Scan {
+	if lo.Typ == LOT_Scan && lo.ScanTyp == ScanTypeCTE {
+		return lo.CTERoot.EstimatedCard(txn)
+	}
+	if lo.Typ == LOT_Scan && lo.TableEnt != nil {
 		{
 			return lo.TableEnt.GetStats2(0).Count()
 		}
@@ -384,6 +446,9 @@ func (lo *LogicalOperator) Print(tree treeprint.Tree) {
 			node := tree.AddBranch(fmt.Sprintf("groupExprs, index %d", lo.Index))
 			listExprsToTree(node, lo.GroupBys)
 		}
+		if len(lo.GroupingSets) > 0 {
+			tree.AddMetaNode("groupingSets", groupingSetsString(lo.GroupingSets))
+		}
 		if len(lo.Aggs) > 0 {
 			node := tree.AddBranch(fmt.Sprintf("aggExprs, index %d", lo.Index2))
 			listExprsToTree(node, lo.Aggs)
@@ -413,6 +478,16 @@ func (lo *LogicalOperator) Print(tree treeprint.Tree) {
 			consStr = append(consStr, cons.String())
 		}
 		tree.AddMetaNode("constraints", strings.Join(consStr, ","))
+	case LOT_Vacuum:
+		tree = tree.AddBranch(fmt.Sprintf("Vacuum: %v %v", lo.Database, lo.Table))
+	case LOT_Export:
+		tree = tree.AddBranch(fmt.Sprintf("Export: %v %v", lo.ScanInfo.FilePath, lo.ScanInfo.Format))
+	case LOT_Import:
+		tree = tree.AddBranch(fmt.Sprintf("Import: %v", lo.ScanInfo.FilePath))
+	case LOT_RecursiveCTE:
+		tree = tree.AddBranch(fmt.Sprintf("RecursiveCTE: %v %v", lo.Table, lo.UnionAll))
+		printOutputs(tree, lo)
+		tree.AddMetaNode("index", fmt.Sprintf("%d", lo.Index))
 	default:
 		panic(fmt.Sprintf("usp %v", lo.Typ))
 	}
@@ -506,6 +581,7 @@ const (
 	ET_ValuesList           //for insert
 	ET_Join                 //join
 	ET_CTE
+	ET_RecursiveCTE
 
 	ET_Func
 	ET_Subquery
@@ -557,6 +633,28 @@ const (
 	ET_Cast
 	ET_Extract
 	ET_Substring
+	ET_IsNull
+	ET_IsNotNull
+	ET_IsDistinctFrom
+	ET_IsNotDistinctFrom
+	ET_Coalesce
+	ET_NullIf
+	ET_Concat
+	ET_ILike
+	ET_NotILike
+	ET_RegexpMatch
+	ET_NotRegexpMatch
+	ET_RegexpIMatch
+	ET_NotRegexpIMatch
+	ET_Mod
+	ET_BitAnd
+	ET_BitOr
+	ET_BitXor
+	ET_BitNot
+	ET_ShiftLeft
+	ET_ShiftRight
+	ET_Greatest
+	ET_Least
 )
 
 func (et ET_SubTyp) String() string {
@@ -613,6 +711,51 @@ func (et ET_SubTyp) String() string {
 		return "extract"
 	case ET_Substring:
 		return "substring"
+	case ET_IsNull:
+		return "is null"
+	case ET_IsNotNull:
+		return "is not null"
+	case ET_IsDistinctFrom:
+		return "is distinct from"
+	case ET_IsNotDistinctFrom:
+		return "is not distinct from"
+	case ET_Coalesce:
+		return "coalesce"
+	case ET_NullIf:
+		return "nullif"
+	case ET_Concat:
+		return "||"
+	case ET_ILike:
+		return "ilike"
+	case ET_NotILike:
+		return "not ilike"
+	case ET_RegexpMatch:
+		return "~"
+	case ET_NotRegexpMatch:
+		return "!~"
+	case ET_RegexpIMatch:
+		return "~*"
+	case ET_NotRegexpIMatch:
+		return "!~*"
+	case ET_Mod:
+		return "%"
+	case ET_BitAnd:
+		return "&"
+	case ET_BitOr:
+		return "|"
+	case ET_BitXor:
+		return "#"
+	case ET_BitNot:
+		//the same as the regular expression match
+		return "~"
+	case ET_ShiftLeft:
+		return "<<"
+	case ET_ShiftRight:
+		return ">>"
+	case ET_Greatest:
+		return "greatest"
+	case ET_Least:
+		return "least"
 	default:
 		panic(fmt.Sprintf("usp %v", int(et)))
 	}
@@ -662,6 +805,14 @@ func (et ET_SubTyp) isOperator() bool {
 		return true
 	case ET_NotExists:
 		return true
+	case ET_IsNull, ET_IsNotNull, ET_IsDistinctFrom, ET_IsNotDistinctFrom:
+		return true
+	case ET_Concat:
+		return true
+	case ET_ILike, ET_NotILike, ET_RegexpMatch, ET_NotRegexpMatch, ET_RegexpIMatch, ET_NotRegexpIMatch:
+		return true
+	case ET_Mod, ET_BitAnd, ET_BitOr, ET_BitXor, ET_BitNot, ET_ShiftLeft, ET_ShiftRight:
+		return true
 	default:
 		return false
 	}
@@ -673,6 +824,8 @@ const (
 	ET_JoinTypeCross ET_JoinType = iota
 	ET_JoinTypeLeft
 	ET_JoinTypeInner
+	ET_JoinTypeRight
+	ET_JoinTypeFull
 )
 
 type ET_SubqueryType int
@@ -683,6 +836,8 @@ const (
 	ET_SubqueryTypeNotExists
 	ET_SubqueryTypeIn
 	ET_SubqueryTypeNotIn
+	//the lateral subquery in the FROM
+	ET_SubqueryTypeLateral
 )
 
 type Expr struct {
@@ -690,6 +845,8 @@ type Expr struct {
 	SubTyp  ET_SubTyp
 	DataTyp common.LType
 	AggrTyp AggrType
+	//the last child is the FILTER of the aggregate
+	AggrFilter bool
 
 	Children []*Expr
 
@@ -710,6 +867,8 @@ type Expr struct {
 	SubCtx      *BindContext // context for subquery
 	SubqueryTyp ET_SubqueryType
 	CTEIndex    uint64
+	UnionAll    bool       // for recursive cte
+	SharedCte   *sharedCte // for the reference to the shared cte
 
 	BelongCtx   *BindContext // context for table and join
 	On          *Expr        //JoinOn
@@ -741,6 +900,9 @@ func (e *Expr) equal(o *Expr) bool {
 		if e.AggrTyp != o.AggrTyp {
 			return false
 		}
+		if e.AggrFilter != o.AggrFilter {
+			return false
+		}
 		if e.Index != o.Index {
 			return false
 		}
@@ -786,6 +948,9 @@ func (e *Expr) equal(o *Expr) bool {
 		if e.CTEIndex != o.CTEIndex {
 			return false
 		}
+		if e.UnionAll != o.UnionAll {
+			return false
+		}
 		if e.IsOperator != o.IsOperator {
 			return false
 		}
@@ -821,6 +986,7 @@ func (e *Expr) copy() *Expr {
 		SubTyp:      e.SubTyp,
 		DataTyp:     e.DataTyp,
 		AggrTyp:     e.AggrTyp,
+		AggrFilter:  e.AggrFilter,
 		Index:       e.Index,
 		Database:    e.Database,
 		Table:       e.Table,
@@ -838,6 +1004,8 @@ func (e *Expr) copy() *Expr {
 		SubCtx:      e.SubCtx,
 		SubqueryTyp: e.SubqueryTyp,
 		CTEIndex:    e.CTEIndex,
+		UnionAll:    e.UnionAll,
+		SharedCte:   e.SharedCte,
 		BelongCtx:   e.BelongCtx,
 		On:          e.On.copy(),
 		IsOperator:  e.IsOperator,
@@ -865,7 +1033,8 @@ func restoreExpr(e *Expr, index uint64, realExprs []*Expr) *Expr {
 	switch e.Typ {
 	case ET_Column:
 		if index == e.ColRef[0] {
-			e = realExprs[e.ColRef[1]]
+			//the real expr is still used by the operator
+			e = realExprs[e.ColRef[1]].copy()
 		}
 	case ET_SConst, ET_IConst, ET_DateConst, ET_IntervalConst, ET_BConst, ET_FConst, ET_NConst, ET_DecConst:
 	case ET_Func:
@@ -942,6 +1111,42 @@ func decideSide(e *Expr, leftTags, rightTags map[uint64]bool) int {
 	return ret
 }
 
+// isComparison checks the expr compares two operands.
+func isComparison(e *Expr) bool {
+	if e.Typ != ET_Func {
+		return false
+	}
+	switch e.SubTyp {
+	case ET_Equal, ET_NotEqual, ET_In, ET_NotIn, ET_Less, ET_LessEqual, ET_Greater, ET_GreaterEqual,
+		ET_IsDistinctFrom, ET_IsNotDistinctFrom:
+		return len(e.Children) == 2
+	default:
+		return false
+	}
+}
+
+// commuteComparison exchanges the operands of the comparison.
+// the operator is reversed for the inequality.
+func commuteComparison(e *Expr) *Expr {
+	var subTyp ET_SubTyp
+	switch e.SubTyp {
+	case ET_Less:
+		subTyp = ET_Greater
+	case ET_LessEqual:
+		subTyp = ET_GreaterEqual
+	case ET_Greater:
+		subTyp = ET_Less
+	case ET_GreaterEqual:
+		subTyp = ET_LessEqual
+	default:
+		e.Children[0], e.Children[1] = e.Children[1], e.Children[0]
+		return e
+	}
+	ret := gFuncBinder.BindScalarFunc(subTyp.String(), []*Expr{e.Children[1], e.Children[0]}, subTyp, true)
+	ret.Alias = e.Alias
+	return ret
+}
+
 func copyExpr(e *Expr) *Expr {
 	return clone.Clone(e).(*Expr)
 }
@@ -981,6 +1186,12 @@ func (e *Expr) Format(ctx *FormatCtx) {
 			typStr = "cross"
 		case ET_JoinTypeLeft:
 			typStr = "left"
+		case ET_JoinTypeInner:
+			typStr = "inner"
+		case ET_JoinTypeRight:
+			typStr = "right"
+		case ET_JoinTypeFull:
+			typStr = "full"
 		default:
 			panic(fmt.Sprintf("usp join type %d", e.JoinTyp))
 		}
@@ -1037,6 +1248,21 @@ func (e *Expr) Format(ctx *FormatCtx) {
 			e.Children[0].Format(ctx)
 			ctx.Write(")")
 
+		case ET_IsNull, ET_IsNotNull:
+			e.Children[0].Format(ctx)
+			ctx.Writef(" %s", e.SubTyp)
+		case ET_BitNot:
+			ctx.Writef("%s", e.SubTyp)
+			e.Children[0].Format(ctx)
+		case ET_Coalesce, ET_NullIf, ET_Greatest, ET_Least:
+			ctx.Writef("%s(", e.SubTyp)
+			for idx, child := range e.Children {
+				if idx > 0 {
+					ctx.Write(", ")
+				}
+				child.Format(ctx)
+			}
+			ctx.Write(")")
 		case ET_SubFunc:
 			ctx.Writef("%s(", e.Svalue)
 			for idx, child := range e.Children {
@@ -1117,6 +1343,12 @@ func (e *Expr) Print(tree treeprint.Tree, meta string) {
 			typStr = "cross"
 		case ET_JoinTypeLeft:
 			typStr = "left"
+		case ET_JoinTypeInner:
+			typStr = "inner"
+		case ET_JoinTypeRight:
+			typStr = "right"
+		case ET_JoinTypeFull:
+			typStr = "full"
 		default:
 			panic(fmt.Sprintf("usp join type %d", e.JoinTyp))
 		}
@@ -1146,7 +1378,8 @@ func (e *Expr) Print(tree treeprint.Tree, meta string) {
 			if e.Children[0] != nil {
 				e.Children[0].Print(branch, "")
 			}
-		case ET_In, ET_NotIn:
+		case ET_In, ET_NotIn, ET_IsNull, ET_IsNotNull, ET_Coalesce, ET_NullIf,
+			ET_BitNot, ET_Greatest, ET_Least:
 			branch = tree.AddMetaBranch(head, e.SubTyp)
 			for _, child := range e.Children {
 				child.Print(branch, "")
@@ -1202,6 +1435,11 @@ const (
 	POT_CreateSchema POT = 9
 	POT_CreateTable  POT = 10
 	POT_Insert       POT = 11
+	POT_Vacuum       POT = 12
+	POT_Export       POT = 13
+	POT_Import       POT = 14
+	POT_TopN         POT = 15
+	POT_RecursiveCTE POT = 16
 )
 
 var potToStr = map[POT]string{
@@ -1217,13 +1455,18 @@ var potToStr = map[POT]string{
 	POT_CreateSchema: "createSchema",
 	POT_CreateTable:  "createTable",
 	POT_Insert:       "insert",
+	POT_Vacuum:       "vacuum",
+	POT_Export:       "export",
+	POT_Import:       "import",
+	POT_TopN:         "topN",
+	POT_RecursiveCTE: "recursiveCTE",
 }
 
 func (t POT) String() string {
 	if s, has := potToStr[t]; has {
 		return s
 	}
-	panic(fmt.Sprintf("usp %d", t))
+	return fmt.Sprintf("POT(%d)", t)
 }
 
 type PhysicalOperator struct {
@@ -1238,12 +1481,15 @@ type PhysicalOperator struct {
 	Name          string // column
 	Alias         string // alias
 	JoinTyp       LOT_JoinType
+	JoinAlgo      JoinAlgo
 	Outputs       []*Expr
 	Columns       []string // name of project
 	Projects      []*Expr
 	Filters       []*Expr
 	Aggs          []*Expr
 	GroupBys      []*Expr
+	GroupingSets  []GroupingSet
+	GroupingFuncs [][]int
 	OnConds       []*Expr
 	OrderBys      []*Expr
 	Limit         *Expr
@@ -1262,6 +1508,9 @@ type PhysicalOperator struct {
 	//column seq no in table -> column seq no in Insert
 	ColumnIndexMap []int //for insert
 	ScanInfo       *ScanInfo
+	CTEIndex       uint64           //for the work table scan
+	UnionAll       bool             //for recursive cte
+	cte            *MaterializedCTE //for the materialized cte scan
 	Children       []*PhysicalOperator
 	ExecStats      ExecStats
 }
@@ -1350,7 +1599,7 @@ func (po *PhysicalOperator) Print(tree treeprint.Tree) {
 		//}
 
 	case POT_Join:
-		tree = tree.AddBranch(fmt.Sprintf("Join (%v):", po.JoinTyp))
+		tree = tree.AddBranch(fmt.Sprintf("Join (%v, %v):", po.JoinTyp, po.JoinAlgo))
 		printPhyOutputs(tree, po)
 		tree.AddMetaNode("index", fmt.Sprintf("%d", po.Index))
 		if len(po.OnConds) > 0 {
@@ -1367,6 +1616,9 @@ func (po *PhysicalOperator) Print(tree treeprint.Tree) {
 			node := tree.AddBranch(fmt.Sprintf("groupExprs, index %d", po.Index))
 			listExprsToTree(node, po.GroupBys)
 		}
+		if len(po.GroupingSets) > 0 {
+			tree.AddMetaNode("groupingSets", groupingSetsString(po.GroupingSets))
+		}
 		if len(po.Aggs) > 0 {
 			node := tree.AddBranch(fmt.Sprintf("aggExprs, index %d", po.Index2))
 			listExprsToTree(node, po.Aggs)
@@ -1384,6 +1636,15 @@ func (po *PhysicalOperator) Print(tree treeprint.Tree) {
 	case POT_Limit:
 		tree = tree.AddBranch(fmt.Sprintf("Limit: %v", po.Limit.String()))
 		printPhyOutputs(tree, po)
+	case POT_TopN:
+		tree = tree.AddBranch(fmt.Sprintf("TopN: %v", po.Limit.String()))
+		printPhyOutputs(tree, po)
+		node := tree.AddMetaBranch("exprs", "")
+		listExprsToTree(node, po.OrderBys)
+	case POT_RecursiveCTE:
+		tree = tree.AddBranch(fmt.Sprintf("RecursiveCTE: %v %v", po.Table, po.UnionAll))
+		printPhyOutputs(tree, po)
+		tree.AddMetaNode("index", fmt.Sprintf("%d", po.Index))
 	case POT_Stub:
 		tree = tree.AddBranch(fmt.Sprintf("Stub: %v %v", po.Table, po.ChunkCount))
 		printPhyOutputs(tree, po)
@@ -1400,6 +1661,12 @@ func (po *PhysicalOperator) Print(tree treeprint.Tree) {
 		tree.AddMetaNode("constraints", strings.Join(consStr, ","))
 	case POT_Insert:
 		tree = tree.AddBranch(fmt.Sprintf("Insert: %v %v", po.Database, po.Table))
+	case POT_Vacuum:
+		tree = tree.AddBranch(fmt.Sprintf("Vacuum: %v %v", po.Database, po.Table))
+	case POT_Export:
+		tree = tree.AddBranch(fmt.Sprintf("Export: %v %v", po.ScanInfo.FilePath, po.ScanInfo.Format))
+	case POT_Import:
+		tree = tree.AddBranch(fmt.Sprintf("Import: %v", po.ScanInfo.FilePath))
 	default:
 		panic(fmt.Sprintf("usp %v", po.Typ))
 	}
@@ -1617,7 +1884,7 @@ func deceaseDepth(expr *Expr) (*Expr, bool) {
 
 	case ET_Func:
 		switch expr.SubTyp {
-		case ET_And, ET_Equal, ET_Like, ET_GreaterEqual, ET_Less, ET_NotEqual:
+		case ET_And, ET_Equal, ET_Like, ET_Greater, ET_GreaterEqual, ET_Less, ET_LessEqual, ET_NotEqual:
 			left, leftHasCorr := deceaseDepth(expr.Children[0])
 			hasCorCol = hasCorCol || leftHasCorr
 			right, rightHasCorr := deceaseDepth(expr.Children[1])
@@ -1630,7 +1897,9 @@ func deceaseDepth(expr *Expr) (*Expr, bool) {
 				Children: []*Expr{left, right},
 				FunImpl:  expr.FunImpl,
 			}, hasCorCol
-		case ET_SubFunc:
+		case ET_SubFunc, ET_IsNull, ET_IsNotNull, ET_IsDistinctFrom, ET_IsNotDistinctFrom, ET_Coalesce, ET_NullIf, ET_Concat,
+			ET_NotLike, ET_ILike, ET_NotILike, ET_RegexpMatch, ET_NotRegexpMatch, ET_RegexpIMatch, ET_NotRegexpIMatch,
+			ET_Mod, ET_BitAnd, ET_BitOr, ET_BitXor, ET_BitNot, ET_ShiftLeft, ET_ShiftRight, ET_Greatest, ET_Least:
 			args := make([]*Expr, 0, len(expr.Children))
 			for _, child := range expr.Children {
 				newChild, yes := deceaseDepth(child)
@@ -1743,7 +2012,8 @@ func checkColRefPos(e *Expr, root *LogicalOperator) {
 			st := SourceType(e.ColRef.table())
 			switch st {
 			case ThisNode:
-				if !(e.ColRef.table() == root.Index2 && e.ColRef.column() < uint64(len(root.Aggs))) {
+				width := len(root.GroupBys) + len(root.Aggs) + len(root.GroupingFuncs)
+				if !(hasGroupingSets(root) && e.ColRef.column() < uint64(width)) {
 					panic(fmt.Sprintf("no bind %v in scan %v", e.ColRef, root.Index))
 				}
 			case LeftChild:
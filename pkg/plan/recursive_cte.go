@@ -0,0 +1,126 @@
+// Copyright 2023-2024 daviszhen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"github.com/daviszhen/plan/pkg/chunk"
+	"github.com/daviszhen/plan/pkg/common"
+	"github.com/daviszhen/plan/pkg/storage"
+	"github.com/daviszhen/plan/pkg/util"
+)
+
+type RecursiveCTEState int
+
+const (
+	RECURSIVE_CTE_INIT RecursiveCTEState = iota
+	RECURSIVE_CTE_SCAN
+)
+
+// RecursiveCTE collects the rows of the recursive cte.
+// The non-recursive term fills the work table first. Then
+// the recursive term reads the work table and produces the rows
+// of the next iteration until there is no new row.
+type RecursiveCTE struct {
+	_state    RecursiveCTEState
+	_types    []common.LType
+	_unionAll bool
+	//all rows of the recursive cte
+	_result *ColumnDataCollection
+	//rows of the last iteration. it is shared with the
+	//scans in the recursive term.
+	_workTable *ColumnDataCollection
+	//rows of the current iteration
+	_intermediate *ColumnDataCollection
+	//rows have been seen for the UNION
+	_ht        *GroupedAggrHashTable
+	_htState   *AggrHTAppendState
+	_scanState *ColumnDataScanState
+}
+
+func NewRecursiveCTE(typs []common.LType, unionAll bool, workTable *ColumnDataCollection) *RecursiveCTE {
+	ret := &RecursiveCTE{
+		_types:        typs,
+		_unionAll:     unionAll,
+		_result:       NewColumnDataCollection(typs),
+		_workTable:    workTable,
+		_intermediate: NewColumnDataCollection(typs),
+	}
+	//clear the rows of the last execution
+	*ret._workTable = *NewColumnDataCollection(typs)
+	if !unionAll {
+		ret._ht = NewGroupedAggrHashTable(
+			typs,
+			nil,
+			nil,
+			nil,
+			2*util.DefaultVectorSize,
+			storage.GBufferMgr,
+		)
+		ret._htState = NewAggrHTAppendState()
+	}
+	return ret
+}
+
+// Sink appends the rows of the current iteration.
+// For the UNION, the rows have been seen are removed.
+func (cte *RecursiveCTE) Sink(data *chunk.Chunk) {
+	if !cte._unionAll {
+		cte.removeDuplicates(data)
+	}
+	if data.Card() == 0 {
+		return
+	}
+	cte._result.Append(data)
+	cte._intermediate.Append(data)
+}
+
+func (cte *RecursiveCTE) removeDuplicates(data *chunk.Chunk) {
+	if data.Card() == 0 {
+		return
+	}
+	hashes := chunk.NewFlatVector(common.HashType(), util.DefaultVectorSize)
+	data.Hash(hashes)
+	//no aggregate. only the group columns
+	childrenOutput := &chunk.Chunk{}
+	childrenOutput.SetCard(data.Card())
+	newCnt := cte._ht.FindOrCreateGroups(
+		cte._htState,
+		data,
+		hashes,
+		cte._htState._addresses,
+		cte._htState._newGroups,
+		childrenOutput,
+	)
+	data.SliceItself(cte._htState._newGroups, newCnt)
+}
+
+// NextIteration moves the rows of the current iteration
+// into the work table. It returns false if there is no new row.
+func (cte *RecursiveCTE) NextIteration() bool {
+	*cte._workTable = *cte._intermediate
+	cte._intermediate = NewColumnDataCollection(cte._types)
+	return cte._workTable.Count() > 0
+}
+
+func (cte *RecursiveCTE) GetData(read *chunk.Chunk) SourceResult {
+	if cte._scanState == nil {
+		cte._scanState = &ColumnDataScanState{}
+		cte._result.initScan(cte._scanState)
+	}
+	if !cte._result.Scan(cte._scanState, read) {
+		return SrcResDone
+	}
+	return SrcResHaveMoreOutput
+}
@@ -0,0 +1,137 @@
+// Copyright 2023-2024 daviszhen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/daviszhen/plan/pkg/storage"
+	"github.com/daviszhen/plan/pkg/util"
+)
+
+func Test_recursiveCTE(t *testing.T) {
+	txn, err := storage.GTxnMgr.NewTxn("recursive cte")
+	require.NoError(t, err)
+	defer storage.GTxnMgr.Rollback(txn)
+	execSQL(t, txn, "create table rc_one (i integer)")
+	execSQL(t, txn, "insert into rc_one values (1)")
+	execSQL(t, txn, "create table rc_emp (id integer, mgr integer, name varchar)")
+	execSQL(t, txn, "insert into rc_emp values (1, 0, 'ann'), (2, 1, 'bob'), (3, 1, 'cat'), (4, 2, 'dan'), (5, 4, 'eve'), (6, 0, 'fay')")
+	execSQL(t, txn, "create table rc_edge (src integer, dst integer)")
+	execSQL(t, txn, "insert into rc_edge values (1, 2), (2, 3), (3, 1), (3, 4)")
+
+	kases := []struct {
+		sql  string
+		want [][]string
+	}{
+		{
+			"with recursive t(n) as (select i from rc_one union all select n + 1 from t where n < 5) select n from t order by n",
+			[][]string{{"1"}, {"2"}, {"3"}, {"4"}, {"5"}},
+		},
+		{
+			"with recursive t(n) as (select i from rc_one union all select n + 1 from t where n < 100) select count(*), sum(n) from t",
+			[][]string{{"100", "5050"}},
+		},
+		{
+			"with recursive sub(id, name, lvl) as (" +
+				"select id, name, 1 from rc_emp where id = 1 " +
+				"union all " +
+				"select e.id, e.name, s.lvl + 1 from rc_emp e, sub s where e.mgr = s.id" +
+				") select name, lvl from sub order by lvl, name",
+			[][]string{{"ann", "1"}, {"bob", "2"}, {"cat", "2"}, {"dan", "3"}, {"eve", "4"}},
+		},
+		{
+			"with recursive sub(id) as (" +
+				"select id from rc_emp where id = 1 " +
+				"union all " +
+				"select e.id from rc_emp e join sub on e.mgr = sub.id" +
+				") select rc_emp.name from rc_emp, sub where rc_emp.id = sub.id and rc_emp.id > 3 order by rc_emp.name",
+			[][]string{{"dan"}, {"eve"}},
+		},
+		{
+			"with recursive p(id, path) as (" +
+				"select id, name from rc_emp where mgr = 0 " +
+				"union all " +
+				"select e.id, p.path || '/' || e.name from rc_emp e, p where e.mgr = p.id" +
+				") select path from p order by path",
+			[][]string{{"ann"}, {"ann/bob"}, {"ann/bob/dan"}, {"ann/bob/dan/eve"}, {"ann/cat"}, {"fay"}},
+		},
+		{
+			//the cycle ends for UNION
+			"with recursive r(n) as (" +
+				"select src from rc_edge where src = 1 " +
+				"union " +
+				"select rc_edge.dst from rc_edge, r where rc_edge.src = r.n" +
+				") select n from r order by n",
+			[][]string{{"1"}, {"2"}, {"3"}, {"4"}},
+		},
+		{
+			"with recursive r(n) as (" +
+				"select i % 2 from rc_one " +
+				"union " +
+				"select (n + 1) % 2 from r" +
+				") select n from r order by n",
+			[][]string{{"0"}, {"1"}},
+		},
+		{
+			//the work table is empty after the non-recursive term
+			"with recursive t(n) as (select i from rc_one where i > 1 union all select n + 1 from t) select n from t",
+			[][]string{},
+		},
+	}
+	for _, kase := range kases {
+		rows, err := querySQL(t, txn, kase.sql)
+		require.NoError(t, err, kase.sql)
+		require.Equal(t, kase.want, rows, kase.sql)
+	}
+
+	errKases := []struct {
+		sql   string
+		state util.SQLState
+	}{
+		{
+			"with recursive t(n) as (select i from rc_one union all select i, i from rc_one, t) select n from t",
+			util.SQLStateSyntaxError,
+		},
+		{
+			"with recursive t(n) as (select i from rc_one union all select i from rc_one) select n from t",
+			util.SQLStateFeatureNotSupported,
+		},
+		{
+			"with recursive t(n) as (select i from rc_one union all select n + 1 from t where n < 3 order by 1) select n from t",
+			util.SQLStateFeatureNotSupported,
+		},
+	}
+	for _, kase := range errKases {
+		_, err = querySQL(t, txn, kase.sql)
+		require.Error(t, err, kase.sql)
+		require.Equal(t, kase.state, util.GetSQLState(err), kase.sql)
+	}
+
+	//the recursion never ends
+	sql := "with recursive t(n) as (select i from rc_one union all select n + 1 from t) select count(*) from t"
+	cfg := &util.Config{Query: util.QueryOptions{MaxRecursiveIterations: 10}}
+	_, err = querySQLWithConfig(t, cfg, txn, sql)
+	require.Error(t, err)
+	require.Equal(t, util.SQLStateProgramLimitExceeded, util.GetSQLState(err))
+
+	cfg.Query.MaxRecursiveIterations = 20
+	sql = "with recursive t(n) as (select i from rc_one union all select n + 1 from t where n < 20) select count(*) from t"
+	rows, err := querySQLWithConfig(t, cfg, txn, sql)
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"20"}}, rows)
+}
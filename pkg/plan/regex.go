@@ -0,0 +1,271 @@
+// Copyright 2023-2024 daviszhen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/daviszhen/plan/pkg/chunk"
+	"github.com/daviszhen/plan/pkg/common"
+	"github.com/daviszhen/plan/pkg/util"
+)
+
+// ILIKE
+
+func binStringILikeOp(left, right *common.String, result *bool) {
+	*result = wildcardMatch(strings.ToLower(right.String()), strings.ToLower(left.String()))
+}
+
+func binStringNotILikeOp(left, right *common.String, result *bool) {
+	*result = !wildcardMatch(strings.ToLower(right.String()), strings.ToLower(left.String()))
+}
+
+// the specialized kernels for the LIKE with the constant pattern
+
+func prefixOp(left, right *common.String, result *bool) {
+	*result = strings.HasPrefix(left.String(), right.String())
+}
+
+func suffixOp(left, right *common.String, result *bool) {
+	*result = strings.HasSuffix(left.String(), right.String())
+}
+
+func containsOp(left, right *common.String, result *bool) {
+	*result = strings.Contains(left.String(), right.String())
+}
+
+// regexState caches the last compiled pattern in the ExprState.
+// the constant pattern is compiled only once.
+type regexState struct {
+	pattern string
+	flags   string
+	re      *regexp.Regexp
+}
+
+// getRegex returns the compiled pattern with the flags.
+// i: case insensitive. c: case sensitive. g: replace all.
+func getRegex(state *ExprState, pattern, flags string) *regexp.Regexp {
+	if rs, ok := state._funcState.(*regexState); ok &&
+		rs.pattern == pattern && rs.flags == flags {
+		return rs.re
+	}
+	prefix := ""
+	for _, flag := range flags {
+		switch flag {
+		case 'i':
+			prefix = "(?i)"
+		case 'c':
+			prefix = ""
+		case 'g':
+		default:
+			panic(util.NewSQLError(util.SQLStateInvalidParameterValue, "invalid regular expression option: \"%c\"", flag))
+		}
+	}
+	re, err := regexp.Compile(prefix + pattern)
+	if err != nil {
+		panic(util.NewSQLError(util.SQLStateInvalidRegularExpression, "invalid regular expression: %v", err))
+	}
+	state._funcState = &regexState{
+		pattern: pattern,
+		flags:   flags,
+		re:      re,
+	}
+	return re
+}
+
+// regexLoop calls the fun on the rows without NULL arguments.
+// the result of the row with NULL arguments is NULL.
+func regexLoop[R any](
+	input *chunk.Chunk,
+	result *chunk.Vector,
+	fun func(args []*common.String, row int, res *R),
+) {
+	count := input.Card()
+	colCnt := input.ColumnCount()
+	formats := make([]chunk.UnifiedFormat, colCnt)
+	slices := make([][]common.String, colCnt)
+	for i, vec := range input.Data {
+		vec.ToUnifiedFormat(count, &formats[i])
+		if vec.Typ().GetInternalType() == common.VARCHAR {
+			slices[i] = chunk.GetSliceInPhyFormatUnifiedFormat[common.String](&formats[i])
+		}
+	}
+	result.SetPhyFormat(chunk.PF_FLAT)
+	resSlice := chunk.GetSliceInPhyFormatFlat[R](result)
+	resMask := chunk.GetMaskInPhyFormatFlat(result)
+	args := make([]*common.String, colCnt)
+	for i := 0; i < count; i++ {
+		valid := true
+		for j := range formats {
+			idx := formats[j].Sel.GetIndex(i)
+			if !formats[j].Mask.RowIsValid(uint64(idx)) {
+				valid = false
+				break
+			}
+			if slices[j] != nil {
+				args[j] = &slices[j][idx]
+			}
+		}
+		if !valid {
+			resMask.SetInvalid(uint64(i))
+			continue
+		}
+		resMask.SetValid(uint64(i))
+		fun(args, i, &resSlice[i])
+	}
+}
+
+// regexMatchFunc implements the ~, ~*, !~, !~*.
+func regexMatchFunc(caseInsensitive, not bool) ScalarFunc {
+	flags := ""
+	if caseInsensitive {
+		flags = "i"
+	}
+	return func(input *chunk.Chunk, state *ExprState, result *chunk.Vector) {
+		regexLoop[bool](input, result, func(args []*common.String, _ int, res *bool) {
+			re := getRegex(state, args[1].String(), flags)
+			*res = re.MatchString(args[0].String()) != not
+		})
+	}
+}
+
+// regexpMatchesFunc implements the regexp_matches(s, pattern [, flags]).
+func regexpMatchesFunc(input *chunk.Chunk, state *ExprState, result *chunk.Vector) {
+	regexLoop[bool](input, result, func(args []*common.String, _ int, res *bool) {
+		flags := ""
+		if len(args) > 2 {
+			flags = args[2].String()
+		}
+		re := getRegex(state, args[1].String(), flags)
+		*res = re.MatchString(args[0].String())
+	})
+}
+
+// regexpReplaceFunc implements the regexp_replace(s, pattern, replacement [, flags]).
+// it replaces the first match only without the flag g.
+func regexpReplaceFunc(input *chunk.Chunk, state *ExprState, result *chunk.Vector) {
+	regexLoop[common.String](input, result, func(args []*common.String, _ int, res *common.String) {
+		flags := ""
+		if len(args) > 3 {
+			flags = args[3].String()
+		}
+		re := getRegex(state, args[1].String(), flags)
+		s := args[0].String()
+		repl := convertReplacement(args[2].String())
+		if strings.ContainsRune(flags, 'g') {
+			newString(re.ReplaceAllString(s, repl), res)
+			return
+		}
+		loc := re.FindStringSubmatchIndex(s)
+		if loc == nil {
+			newString(s, res)
+			return
+		}
+		dst := re.ExpandString(nil, repl, s, loc)
+		newString(s[:loc[0]]+string(dst)+s[loc[1]:], res)
+	})
+}
+
+// regexpExtractFunc implements the regexp_extract(s, pattern [, group]).
+// it returns the empty string if there is no match.
+func regexpExtractFunc(input *chunk.Chunk, state *ExprState, result *chunk.Vector) {
+	var group []int32
+	var groupData chunk.UnifiedFormat
+	if input.ColumnCount() > 2 {
+		input.Data[2].ToUnifiedFormat(input.Card(), &groupData)
+		group = chunk.GetSliceInPhyFormatUnifiedFormat[int32](&groupData)
+	}
+	regexLoop[common.String](input, result, func(args []*common.String, row int, res *common.String) {
+		re := getRegex(state, args[1].String(), "")
+		g := 0
+		if group != nil {
+			g = int(group[groupData.Sel.GetIndex(row)])
+		}
+		if g < 0 || g > re.NumSubexp() {
+			panic(util.NewSQLError(util.SQLStateInvalidParameterValue, "group index %d is out of range", g))
+		}
+		match := re.FindStringSubmatch(args[0].String())
+		if match == nil {
+			*res = common.String{}
+			return
+		}
+		newString(match[g], res)
+	})
+}
+
+// convertReplacement converts the \1 and \& in the replacement into the ${1} and ${0}.
+func convertReplacement(repl string) string {
+	sb := strings.Builder{}
+	for i := 0; i < len(repl); i++ {
+		c := repl[i]
+		switch {
+		case c == '$':
+			sb.WriteString("$$")
+		case c == '\\' && i+1 < len(repl):
+			next := repl[i+1]
+			switch {
+			case next >= '0' && next <= '9':
+				sb.WriteString("${")
+				sb.WriteByte(next)
+				sb.WriteString("}")
+			case next == '&':
+				sb.WriteString("${0}")
+			default:
+				sb.WriteByte(next)
+			}
+			i++
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return sb.String()
+}
+
+// similarToEscapeOp converts the pattern of the SIMILAR TO into the regular expression.
+// the % matches any string and the _ matches any character.
+func similarToEscapeOp(input *common.String, result *common.String) {
+	pattern := input.String()
+	sb := strings.Builder{}
+	sb.WriteString("^(?:")
+	inBracket := false
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch {
+		case c == '\\' && i+1 < len(pattern):
+			sb.WriteString(regexp.QuoteMeta(pattern[i+1 : i+2]))
+			i++
+		case inBracket:
+			if c == ']' {
+				inBracket = false
+			}
+			sb.WriteByte(c)
+		case c == '[':
+			inBracket = true
+			sb.WriteByte(c)
+		case c == '%':
+			sb.WriteString("(?s:.*)")
+		case c == '_':
+			sb.WriteString("(?s:.)")
+		case c == '.' || c == '^' || c == '$':
+			sb.WriteByte('\\')
+			sb.WriteByte(c)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	sb.WriteString(")$")
+	newString(sb.String(), result)
+}
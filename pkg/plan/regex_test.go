@@ -0,0 +1,122 @@
+// Copyright 2023-2024 daviszhen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/daviszhen/plan/pkg/common"
+	"github.com/daviszhen/plan/pkg/storage"
+	"github.com/daviszhen/plan/pkg/util"
+)
+
+func Test_regex(t *testing.T) {
+	txn, err := storage.GTxnMgr.NewTxn("regex")
+	require.NoError(t, err)
+	defer storage.GTxnMgr.Rollback(txn)
+	execSQL(t, txn, "create table regex_t (i integer, s varchar)")
+	execSQL(t, txn, "insert into regex_t values (1, 'Hello World'), (2, 'abc123'), (3, 'foo.bar')")
+
+	kases := []struct {
+		sql  string
+		want [][]string
+	}{
+		{
+			"select s ilike 'hello%', s not ilike '%WORLD', s not like 'abc%' from regex_t",
+			[][]string{{"true", "false", "true"}, {"false", "true", "false"}, {"false", "true", "true"}},
+		},
+		{
+			"select s similar to '%(123|bar)', s not similar to 'abc%', s similar to 'foo_bar' from regex_t",
+			[][]string{{"false", "true", "false"}, {"true", "false", "false"}, {"true", "true", "true"}},
+		},
+		{
+			"select s ~ '^[a-z]+[0-9]+$', s ~* '^hello', s !~ 'o', s !~* 'WORLD' from regex_t",
+			[][]string{{"false", "true", "false", "false"}, {"true", "false", "true", "true"}, {"false", "false", "false", "true"}},
+		},
+		{
+			`select regexp_matches(s, 'o\.b'), regexp_matches(s, 'WORLD', 'i') from regex_t`,
+			[][]string{{"false", "true"}, {"false", "false"}, {"true", "false"}},
+		},
+		{
+			`select regexp_replace(s, 'o', '0'), regexp_replace(s, 'o', '0', 'g'), regexp_replace(s, '([a-z]+)([0-9]+)', '\2\1') from regex_t`,
+			[][]string{{"Hell0 World", "Hell0 W0rld", "Hello World"}, {"abc123", "abc123", "123abc"}, {"f0o.bar", "f00.bar", "foo.bar"}},
+		},
+		{
+			`select regexp_extract(s, '[0-9]+'), regexp_extract(s, '([a-z]+)\.([a-z]+)', 2) from regex_t`,
+			[][]string{{"", ""}, {"123", ""}, {"", "bar"}},
+		},
+		{"select i from regex_t where s ilike '%O%'", [][]string{{"1"}, {"3"}}},
+		{"select i from regex_t where s ~ '[0-9]'", [][]string{{"2"}}},
+		{"select i from regex_t where s like 'abc%'", [][]string{{"2"}}},
+		{"select i from regex_t where s like '%bar'", [][]string{{"3"}}},
+		{"select i from regex_t where s like '%o W%'", [][]string{{"1"}}},
+		{"select i from regex_t where s like 'foo.bar'", [][]string{{"3"}}},
+	}
+	for _, kase := range kases {
+		rows, err := querySQL(t, txn, kase.sql)
+		require.NoError(t, err, kase.sql)
+		require.Equal(t, kase.want, rows, kase.sql)
+	}
+
+	errKases := []struct {
+		sql  string
+		code util.SQLState
+	}{
+		{"select s ~ '(' from regex_t", util.SQLStateInvalidRegularExpression},
+		{"select regexp_matches(s, 'a', 'x') from regex_t", util.SQLStateInvalidParameterValue},
+	}
+	for _, kase := range errKases {
+		func() {
+			defer func() {
+				err := util.RecoverToError(recover())
+				require.Equal(t, kase.code, util.GetSQLState(err), kase.sql)
+			}()
+			_, _ = querySQL(t, txn, kase.sql)
+		}()
+	}
+}
+
+func Test_rewriteLike(t *testing.T) {
+	kases := []struct {
+		pattern string
+		subTyp  ET_SubTyp
+		name    string
+		arg     string
+	}{
+		{"abc", ET_Equal, ET_Equal.String(), "abc"},
+		{"abc%", ET_SubFunc, "prefix", "abc"},
+		{"%abc", ET_SubFunc, "suffix", "abc"},
+		{"%abc%", ET_SubFunc, "contains", "abc"},
+		{"a%c", ET_Like, ET_Like.String(), "a%c"},
+		{"a_c%", ET_Like, ET_Like.String(), "a_c%"},
+	}
+	for _, kase := range kases {
+		like := &Expr{
+			Typ:    ET_Func,
+			SubTyp: ET_Like,
+			Svalue: ET_Like.String(),
+			Children: []*Expr{
+				{Typ: ET_Column, DataTyp: common.VarcharType()},
+				{Typ: ET_SConst, DataTyp: common.VarcharType(), Svalue: kase.pattern},
+			},
+		}
+		ret := rewriteLike(like)
+		require.Equal(t, kase.subTyp, ret.SubTyp, kase.pattern)
+		require.Equal(t, kase.name, ret.Svalue, kase.pattern)
+		require.Equal(t, kase.arg, ret.Children[1].Svalue, kase.pattern)
+	}
+}
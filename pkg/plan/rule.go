@@ -15,6 +15,9 @@
 package plan
 
 import (
+	"strings"
+
+	"github.com/daviszhen/plan/pkg/common"
 	"github.com/daviszhen/plan/pkg/util"
 )
 
@@ -66,7 +69,7 @@ func distributeExpr(expr *Expr) *Expr {
 	} else {
 		newRoot = combineExprsByAnd(candidates...)
 	}
-	if len(newRoot.Children) == 1 {
+	if newRoot.SubTyp == ET_And && len(newRoot.Children) == 1 {
 		return newRoot.Children[0]
 	}
 	return newRoot
@@ -102,3 +105,68 @@ func distributeExprs(exprs ...*Expr) []*Expr {
 	}
 	return res
 }
+
+// rewriteLike rewrites the LIKE with the constant pattern into the cheaper functions.
+//
+//	s LIKE 'abc'   => s = 'abc'
+//	s LIKE 'abc%'  => prefix(s, 'abc')
+//	s LIKE '%abc'  => suffix(s, 'abc')
+//	s LIKE '%abc%' => contains(s, 'abc')
+func rewriteLike(expr *Expr) *Expr {
+	if expr == nil || expr.Typ != ET_Func {
+		return expr
+	}
+	for i, child := range expr.Children {
+		expr.Children[i] = rewriteLike(child)
+	}
+	if expr.SubTyp != ET_Like || expr.Children[1].Typ != ET_SConst {
+		return expr
+	}
+	pattern := expr.Children[1].Svalue
+	if strings.ContainsAny(pattern, "_\\") {
+		return expr
+	}
+	lead := strings.HasPrefix(pattern, "%")
+	trail := strings.HasSuffix(pattern, "%")
+	inner := strings.Trim(pattern, "%")
+	if len(inner) == 0 || strings.Contains(inner, "%") {
+		return expr
+	}
+	args := []*Expr{
+		expr.Children[0],
+		{
+			Typ:     ET_SConst,
+			DataTyp: common.VarcharType(),
+			Svalue:  inner,
+		},
+	}
+	funBinder := FunctionBinder{}
+	switch {
+	case !lead && !trail:
+		return funBinder.BindScalarFunc(ET_Equal.String(), args, ET_Equal, true)
+	case !lead && trail:
+		return funBinder.BindScalarFunc("prefix", args, ET_SubFunc, false)
+	case lead && !trail:
+		return funBinder.BindScalarFunc("suffix", args, ET_SubFunc, false)
+	default:
+		return funBinder.BindScalarFunc("contains", args, ET_SubFunc, false)
+	}
+}
+
+func rewriteLikes(root *LogicalOperator) {
+	if root == nil {
+		return
+	}
+	for i, e := range root.Filters {
+		root.Filters[i] = rewriteLike(e)
+	}
+	for i, e := range root.OnConds {
+		root.OnConds[i] = rewriteLike(e)
+	}
+	for i, e := range root.Projects {
+		root.Projects[i] = rewriteLike(e)
+	}
+	for _, child := range root.Children {
+		rewriteLikes(child)
+	}
+}
@@ -28,6 +28,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/govalues/decimal"
 	wire "github.com/jeroenrinzema/psql-wire"
 	"github.com/lib/pq/oid"
 	pg_query "github.com/pganalyze/pg_query_go/v5"
@@ -187,33 +188,47 @@ func RunDDL(cfg *util.Config) error {
 	return nil
 }
 
-func InitRunner(cfg *util.Config, txn *storage.Txn, query string) (*Runner, error) {
+// InitRunner builds the runner of the query.
+// the panic in the builder is returned as the error.
+func InitRunner(cfg *util.Config, txn *storage.Txn, query string) (run *Runner, err error) {
 	if cfg == nil {
 		return nil, fmt.Errorf("config is nil")
 	}
+	defer func() {
+		if r := recover(); r != nil {
+			run, err = nil, util.RecoverToError(r)
+		}
+	}()
 
-	//parse
-	stmts, err := parser.Parse(query)
-	if err != nil {
-		return nil, err
-	}
+	var root *PhysicalOperator
+	if dbStmt := parser.ParseDatabaseStmt(query); dbStmt != nil {
+		root, err = genDatabasePhyPlan(cfg, txn, dbStmt)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		//parse
+		stmts, err := parser.Parse(query)
+		if err != nil {
+			return nil, util.NewSQLError(util.SQLStateSyntaxError, "%v", err)
+		}
 
-	if len(stmts) != 1 {
-		return nil, fmt.Errorf("multiple statements in one request")
-	}
+		if len(stmts) != 1 {
+			return nil, fmt.Errorf("multiple statements in one request")
+		}
 
-	//gen plan
-	var root *PhysicalOperator
-	root, err = genDDLPhyPlan(txn, stmts[0])
-	if err != nil {
-		return nil, err
+		//gen plan
+		root, err = genDDLPhyPlan(cfg, txn, stmts[0])
+		if err != nil {
+			return nil, err
+		}
 	}
 	if root == nil {
 		return nil, fmt.Errorf("nil plan")
 	}
 
 	//gen runner
-	run := &Runner{
+	run = &Runner{
 		op:    root,
 		state: &OperatorState{},
 		cfg:   cfg,
@@ -279,7 +294,7 @@ func execQuery(cfg *util.Config, id int, ast *pg_query.SelectStmt) (err error) {
 	}()
 
 	var root *PhysicalOperator
-	root, err = genPhyPlan(txn, ast)
+	root, err = genPhyPlan(cfg, txn, ast)
 	if err != nil {
 		return err
 	}
@@ -331,7 +346,7 @@ func runDDl(cfg *util.Config, ddl *pg_query.RawStmt) error {
 		}
 	}()
 
-	root, err = genDDLPhyPlan(txn, ddl)
+	root, err = genDDLPhyPlan(cfg, txn, ddl)
 	if err != nil {
 		return err
 	}
@@ -341,8 +356,9 @@ func runDDl(cfg *util.Config, ddl *pg_query.RawStmt) error {
 	return execOps(cfg, txn, nil, nil, []*PhysicalOperator{root})
 }
 
-func genDDLPhyPlan(txn *storage.Txn, ddl *pg_query.RawStmt) (*PhysicalOperator, error) {
+func genDDLPhyPlan(cfg *util.Config, txn *storage.Txn, ddl *pg_query.RawStmt) (*PhysicalOperator, error) {
 	builder := NewBuilder(txn)
+	builder.sortMergeJoinRows = cfg.Query.SortMergeJoinThreshold()
 	lp, err := builder.buildDDL(txn, ddl, builder.rootCtx, 0)
 	if err != nil {
 		return nil, err
@@ -357,8 +373,19 @@ func genDDLPhyPlan(txn *storage.Txn, ddl *pg_query.RawStmt) (*PhysicalOperator,
 	return pp, nil
 }
 
-func genPhyPlan(txn *storage.Txn, ast *pg_query.SelectStmt) (*PhysicalOperator, error) {
+func genDatabasePhyPlan(cfg *util.Config, txn *storage.Txn, stmt *parser.DatabaseStmt) (*PhysicalOperator, error) {
+	builder := NewBuilder(txn)
+	builder.sortMergeJoinRows = cfg.Query.SortMergeJoinThreshold()
+	lp, err := builder.buildDatabase(txn, stmt, builder.rootCtx, 0)
+	if err != nil {
+		return nil, err
+	}
+	return builder.CreatePhyPlan(lp)
+}
+
+func genPhyPlan(cfg *util.Config, txn *storage.Txn, ast *pg_query.SelectStmt) (*PhysicalOperator, error) {
 	builder := NewBuilder(txn)
+	builder.sortMergeJoinRows = cfg.Query.SortMergeJoinThreshold()
 	err := builder.buildSelect(ast, builder.rootCtx, 0)
 	if err != nil {
 		return nil, err
@@ -582,6 +609,12 @@ type Runner struct {
 	//for order
 	localSort *LocalSort
 
+	//for top n
+	topN *TopN
+
+	//for recursive cte
+	recursiveCte *RecursiveCTE
+
 	//for hash aggr
 	hAggr *HashAggr
 
@@ -589,6 +622,10 @@ type Runner struct {
 	cross *CrossProduct
 	//for hash join
 	hjoin *HashJoin
+	//for nested loop join and piecewise merge join
+	nljoin *NestedLoopJoin
+	//for sort-merge join
+	smjoin *SortMergeJoin
 
 	//for scan
 	pqFile        source.ParquetFile
@@ -618,7 +655,7 @@ func (run *Runner) Columns() wire.Columns {
 	for _, output := range run.op.Outputs {
 		col := wire.Column{
 			//Name:  output.Name,
-			Oid:   oid.T_varchar, //FIXME:
+			Oid:   typeOid(output.DataTyp),
 			Width: int16(output.DataTyp.Width),
 		}
 		cols = append(cols, col)
@@ -626,9 +663,33 @@ func (run *Runner) Columns() wire.Columns {
 	return cols
 }
 
+// typeOid returns the postgres type of the output column.
+// the types are sent as the varchar except the time types.
+func typeOid(typ common.LType) oid.Oid {
+	switch typ.Id {
+	case common.LTID_TIME:
+		return oid.T_time
+	case common.LTID_TIMESTAMP:
+		return oid.T_timestamp
+	case common.LTID_TIMESTAMP_TZ:
+		return oid.T_timestamptz
+	case common.LTID_INTERVAL:
+		return oid.T_interval
+	default:
+		return oid.T_varchar //FIXME:
+	}
+}
+
+// Run executes the plan and writes the result.
+// the panic in the executor is returned as the error.
 func (run *Runner) Run(
 	ctx context.Context,
-	writer wire.DataWriter) error {
+	writer wire.DataWriter) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = util.RecoverToError(r)
+		}
+	}()
 	if run.cfg.Debug.PrintPlan {
 		fmt.Println(run.op.String())
 	}
@@ -701,6 +762,10 @@ func (run *Runner) Init() error {
 		return run.orderInit()
 	case POT_Limit:
 		return run.limitInit()
+	case POT_TopN:
+		return run.topNInit()
+	case POT_RecursiveCTE:
+		return run.recursiveCteInit()
 	case POT_Stub:
 		return run.stubInit()
 	case POT_CreateSchema:
@@ -709,6 +774,12 @@ func (run *Runner) Init() error {
 		return run.createTableInit()
 	case POT_Insert:
 		return run.insertInit()
+	case POT_Vacuum:
+		return run.vacuumInit()
+	case POT_Export:
+		return run.exportInit()
+	case POT_Import:
+		return run.importInit()
 	default:
 		panic("usp")
 	}
@@ -734,6 +805,10 @@ func (run *Runner) Execute(input, output *chunk.Chunk, state *OperatorState) (Op
 		return run.orderExec(output, state)
 	case POT_Limit:
 		return run.limitExec(output, state)
+	case POT_TopN:
+		return run.topNExec(output, state)
+	case POT_RecursiveCTE:
+		return run.recursiveCteExec(output, state)
 	case POT_Stub:
 		return run.stubExec(output, state)
 	case POT_CreateSchema:
@@ -742,6 +817,12 @@ func (run *Runner) Execute(input, output *chunk.Chunk, state *OperatorState) (Op
 		return run.createTableExec(output, state)
 	case POT_Insert:
 		return run.insertExec(output, state)
+	case POT_Vacuum:
+		return run.vacuumExec(output, state)
+	case POT_Export:
+		return run.exportExec(output, state)
+	case POT_Import:
+		return run.importExec(output, state)
 	default:
 		panic("usp")
 	}
@@ -790,6 +871,10 @@ func (run *Runner) Close() error {
 		return run.orderClose()
 	case POT_Limit:
 		return run.limitClose()
+	case POT_TopN:
+		return run.topNClose()
+	case POT_RecursiveCTE:
+		return run.recursiveCteClose()
 	case POT_Stub:
 		return run.stubClose()
 	case POT_CreateSchema:
@@ -798,6 +883,12 @@ func (run *Runner) Close() error {
 		return run.createTableClose()
 	case POT_Insert:
 		return run.insertClose()
+	case POT_Vacuum:
+		return run.vacuumClose()
+	case POT_Export:
+		return run.exportClose()
+	case POT_Import:
+		return run.importClose()
 	default:
 		panic("usp")
 	}
@@ -914,7 +1005,7 @@ func (run *Runner) createTableExec(output *chunk.Chunk, state *OperatorState) (O
 		if ifNotExists {
 			return Done, nil
 		} else {
-			return InvalidOpResult, fmt.Errorf("table %s already exits", table)
+			return InvalidOpResult, util.NewSQLError(util.SQLStateDuplicateTable, "table %s already exits", table)
 		}
 	}
 	info := storage.NewDataTableInfo3(schema, table, run.op.ColDefs, run.op.Constraints)
@@ -929,6 +1020,40 @@ func (run *Runner) createTableClose() error {
 	return nil
 }
 
+func (run *Runner) vacuumInit() error {
+	return nil
+}
+
+func (run *Runner) vacuumExec(output *chunk.Chunk, state *OperatorState) (OperatorResult, error) {
+	tables := make([]*storage.DataTable, 0)
+	if len(run.op.Table) == 0 {
+		storage.GCatalog.ScanSchemas(func(schEnt *storage.CatalogEntry) {
+			schEnt.Scan(storage.CatalogTypeTable, func(tabEnt *storage.CatalogEntry) {
+				tables = append(tables, tabEnt.GetStorage())
+			})
+		})
+	} else {
+		schema := run.op.Database
+		if len(schema) == 0 {
+			schema = "public"
+		}
+		tabEnt := storage.GCatalog.GetEntry(run.Txn, storage.CatalogTypeTable, schema, run.op.Table)
+		if tabEnt == nil {
+			return InvalidOpResult, util.NewSQLError(util.SQLStateUndefinedTable, "no table %s in schema %s", run.op.Table, schema)
+		}
+		tables = append(tables, tabEnt.GetStorage())
+	}
+	_, err := storage.GTxnMgr.Vacuum(run.Txn, tables)
+	if err != nil {
+		return InvalidOpResult, err
+	}
+	return Done, nil
+}
+
+func (run *Runner) vacuumClose() error {
+	return nil
+}
+
 func (run *Runner) createSchemaInit() error {
 	return nil
 }
@@ -941,7 +1066,7 @@ func (run *Runner) createSchemaExec(output *chunk.Chunk, state *OperatorState) (
 		if ifNotExists {
 			return Done, nil
 		} else {
-			return InvalidOpResult, fmt.Errorf("schema %s already exists", name)
+			return InvalidOpResult, util.NewSQLError(util.SQLStateDuplicateSchema, "schema %s already exists", name)
 		}
 	}
 	_, err := storage.GCatalog.CreateSchema(run.Txn, name)
@@ -1188,6 +1313,212 @@ func (run *Runner) orderClose() error {
 	return nil
 }
 
+func (run *Runner) topNInit() error {
+	keyTypes := make([]common.LType, 0)
+	realOrderByExprs := make([]*Expr, 0)
+	for _, by := range run.op.OrderBys {
+		child := by.Children[0]
+		keyTypes = append(keyTypes, child.DataTyp)
+		realOrderByExprs = append(realOrderByExprs, child)
+	}
+
+	payLoadTypes := make([]common.LType, 0)
+	for _, proj := range run.op.Projects {
+		payLoadTypes = append(payLoadTypes, proj.DataTyp)
+	}
+
+	run.topN = NewTopN(
+		NewSortLayout(run.op.OrderBys),
+		payLoadTypes,
+		run.op.Limit,
+		run.op.Offset,
+	)
+
+	run.state = &OperatorState{
+		keyTypes:     keyTypes,
+		payloadTypes: payLoadTypes,
+		orderKeyExec: NewExprExec(realOrderByExprs...),
+		projExec:     NewExprExec(run.op.Projects...),
+		outputExec:   NewExprExec(run.op.Outputs...),
+	}
+	return nil
+}
+
+func (run *Runner) topNExec(output *chunk.Chunk, state *OperatorState) (OperatorResult, error) {
+	var err error
+	var res OperatorResult
+	if run.topN._state == TOPN_INIT {
+		for {
+			childChunk := &chunk.Chunk{}
+			res, err = run.execChild(run.children[0], childChunk, state)
+			if err != nil {
+				return 0, err
+			}
+			if res == InvalidOpResult {
+				return InvalidOpResult, nil
+			}
+			if res == Done {
+				break
+			}
+			if childChunk.Card() == 0 {
+				continue
+			}
+
+			//evaluate order by expr
+			key := &chunk.Chunk{}
+			key.Init(run.state.keyTypes, util.DefaultVectorSize)
+			err = run.state.orderKeyExec.executeExprs(
+				[]*chunk.Chunk{childChunk, nil, nil},
+				key,
+			)
+			if err != nil {
+				return 0, err
+			}
+
+			//evaluate payload expr
+			payload := &chunk.Chunk{}
+			payload.Init(run.state.payloadTypes, util.DefaultVectorSize)
+			err = run.state.projExec.executeExprs(
+				[]*chunk.Chunk{childChunk, nil, nil},
+				payload,
+			)
+			if err != nil {
+				return 0, err
+			}
+
+			run.topN.Sink(key, payload)
+		}
+		run.topN.Finalize()
+		run.topN._state = TOPN_SCAN
+	}
+
+	if run.topN._state == TOPN_SCAN {
+		read := &chunk.Chunk{}
+		read.Init(run.topN._payloadTypes, util.DefaultVectorSize)
+		if run.topN.GetData(read) == SrcResDone {
+			return Done, nil
+		}
+
+		//evaluate output
+		err = run.state.outputExec.executeExprs([]*chunk.Chunk{read, nil, nil}, output)
+		if err != nil {
+			return InvalidOpResult, err
+		}
+	}
+
+	if output.Card() == 0 {
+		return Done, nil
+	}
+	return haveMoreOutput, nil
+}
+
+func (run *Runner) topNClose() error {
+	run.topN = nil
+	return nil
+}
+
+func (run *Runner) recursiveCteInit() error {
+	run.recursiveCte = NewRecursiveCTE(run.op.Types, run.op.UnionAll, run.op.collection)
+	run.state = &OperatorState{}
+	return nil
+}
+
+func (run *Runner) recursiveCteExec(output *chunk.Chunk, state *OperatorState) (OperatorResult, error) {
+	var err error
+	var res OperatorResult
+	if run.recursiveCte._state == RECURSIVE_CTE_INIT {
+		//non-recursive term
+		res, err = run.sinkRecursiveCteTerm(run.children[0], state)
+		if err != nil {
+			return InvalidOpResult, err
+		}
+		if res == InvalidOpResult {
+			return InvalidOpResult, nil
+		}
+
+		//recursive term
+		limit := run.cfg.Query.RecursiveIterationLimit()
+		for iter := 0; run.recursiveCte.NextIteration(); iter++ {
+			if limit >= 0 && iter >= limit {
+				return InvalidOpResult, util.NewSQLError(util.SQLStateProgramLimitExceeded,
+					"recursive cte exceeds %d iterations", limit)
+			}
+			if iter > 0 {
+				//the recursive term reruns on the new work table
+				err = run.resetChild(1)
+				if err != nil {
+					return InvalidOpResult, err
+				}
+			}
+			res, err = run.sinkRecursiveCteTerm(run.children[1], state)
+			if err != nil {
+				return InvalidOpResult, err
+			}
+			if res == InvalidOpResult {
+				return InvalidOpResult, nil
+			}
+		}
+		run.recursiveCte._state = RECURSIVE_CTE_SCAN
+	}
+
+	read := &chunk.Chunk{}
+	read.Init(run.recursiveCte._types, util.DefaultVectorSize)
+	if run.recursiveCte.GetData(read) == SrcResDone {
+		return Done, nil
+	}
+	output.ReferenceIndice(read, run.outputIndice)
+	if output.Card() == 0 {
+		return Done, nil
+	}
+	return haveMoreOutput, nil
+}
+
+func (run *Runner) sinkRecursiveCteTerm(child *Runner, state *OperatorState) (OperatorResult, error) {
+	for {
+		childChunk := &chunk.Chunk{}
+		res, err := run.execChild(child, childChunk, state)
+		if err != nil {
+			return InvalidOpResult, err
+		}
+		if res == InvalidOpResult {
+			return InvalidOpResult, nil
+		}
+		if res == Done {
+			return Done, nil
+		}
+		if childChunk.Card() == 0 {
+			continue
+		}
+		run.recursiveCte.Sink(childChunk)
+	}
+}
+
+// resetChild replaces the child with a new one
+// to execute it again.
+func (run *Runner) resetChild(idx int) error {
+	err := run.children[idx].Close()
+	if err != nil {
+		return err
+	}
+	childRun := &Runner{
+		op:    run.op.Children[idx],
+		Txn:   run.Txn,
+		state: &OperatorState{},
+		cfg:   run.cfg,
+	}
+	err = childRun.Init()
+	if err != nil {
+		return err
+	}
+	run.children[idx] = childRun
+	return nil
+}
+
+func (run *Runner) recursiveCteClose() error {
+	run.recursiveCte = nil
+	return nil
+}
+
 func (run *Runner) filterInit() error {
 	var err error
 	var filterExec *ExprExec
@@ -1301,6 +1632,7 @@ func (run *Runner) filterClose() error {
 }
 
 func (run *Runner) aggrInit() error {
+	var err error
 	run.state = &OperatorState{}
 	//if len(run.op.GroupBys) == 0 /*&& groupingSet*/ {
 	//	run.hAggr = NewHashAggr(
@@ -1343,8 +1675,8 @@ func (run *Runner) aggrInit() error {
 			run.outputTypes,
 			run.op.Aggs,
 			run.op.GroupBys,
-			nil,
-			nil,
+			run.op.GroupingSets,
+			run.op.GroupingFuncs,
 			refChildrenOutput,
 		)
 		if run.op.Children[0].Typ == POT_Filter {
@@ -1357,7 +1689,10 @@ func (run *Runner) aggrInit() error {
 		groupExprs = append(groupExprs, run.hAggr._groupedAggrData._refChildrenOutput...)
 		run.state.groupbyWithParamsExec = NewExprExec(groupExprs...)
 		run.state.groupbyExec = NewExprExec(run.hAggr._groupedAggrData._groups...)
-		run.state.filterExec = NewExprExec(run.op.Filters...)
+		run.state.filterExec, err = initFilterExec(run.op.Filters)
+		if err != nil {
+			return err
+		}
 		run.state.filterSel = chunk.NewSelectVector(util.DefaultVectorSize)
 		run.state.outputExec = NewExprExec(run.op.Outputs...)
 
@@ -1447,9 +1782,11 @@ func (run *Runner) aggrExec(output *chunk.Chunk, state *OperatorState) (Operator
 			groupAddAggrTypes := make([]common.LType, 0)
 			groupAddAggrTypes = append(groupAddAggrTypes, run.hAggr._groupedAggrData._groupTypes...)
 			groupAddAggrTypes = append(groupAddAggrTypes, run.hAggr._groupedAggrData._aggrReturnTypes...)
+			for range run.hAggr._groupedAggrData._groupingFuncs {
+				groupAddAggrTypes = append(groupAddAggrTypes, common.BigintType())
+			}
 			groupAndAggrChunk := &chunk.Chunk{}
 			groupAndAggrChunk.Init(groupAddAggrTypes, util.DefaultVectorSize)
-			util.AssertFunc(len(run.hAggr._groupedAggrData._groupingFuncs) == 0)
 			childChunk := &chunk.Chunk{}
 			childChunk.Init(run.hAggr._groupedAggrData._childrenOutputTypes, util.DefaultVectorSize)
 			res = run.hAggr.GetData(run.state.haScanState, groupAndAggrChunk, childChunk)
@@ -1479,6 +1816,10 @@ func (run *Runner) aggrExec(output *chunk.Chunk, state *OperatorState) (Operator
 				filterInputChunk.Data[i].Reference(groupAndAggrChunk.Data[run.hAggr._groupedAggrData.GroupCount()+i])
 			}
 			filterInputChunk.SetCard(groupAndAggrChunk.Card())
+			if len(run.op.GroupingSets) > 0 || len(run.op.GroupingFuncs) > 0 {
+				//the filters refer the groups and the GROUPING functions also
+				filterInputChunk = groupAndAggrChunk
+			}
 			var count int
 			count, err = state.filterExec.executeSelect([]*chunk.Chunk{childChunk, nil, filterInputChunk}, state.filterSel)
 			if err != nil {
@@ -1584,6 +1925,9 @@ func (run *Runner) aggrExec(output *chunk.Chunk, state *OperatorState) (Operator
 }
 
 func (run *Runner) aggrClose() error {
+	if run.hAggr != nil {
+		run.hAggr.Destroy()
+	}
 	run.hAggr = nil
 	return nil
 }
@@ -1592,9 +1936,14 @@ func (run *Runner) joinInit() error {
 	run.state = &OperatorState{
 		outputExec: NewExprExec(run.op.Outputs...),
 	}
-	if len(run.op.OnConds) != 0 {
+	switch run.op.JoinAlgo {
+	case JoinAlgoHash:
 		run.hjoin = NewHashJoin(run.op, run.op.OnConds)
-	} else {
+	case JoinAlgoNestedLoop, JoinAlgoPiecewiseMerge:
+		run.nljoin = NewNestedLoopJoin(run.op)
+	case JoinAlgoSortMerge:
+		run.smjoin = NewSortMergeJoin(run.op)
+	case JoinAlgoCross:
 		types := make([]common.LType, len(run.op.Children[1].Outputs))
 		for i, e := range run.op.Children[1].Outputs {
 			types[i] = e.DataTyp
@@ -1612,16 +1961,180 @@ func (run *Runner) joinInit() error {
 		run.cross = NewCrossProduct(types)
 		run.cross._crossExec._outputExec = run.state.outputExec
 		run.cross._crossExec._outputPosMap = outputPosMap
+	default:
+		panic(fmt.Sprintf("usp join algorithm %v", run.op.JoinAlgo))
 	}
 
 	return nil
 }
 
 func (run *Runner) joinExec(output *chunk.Chunk, state *OperatorState) (OperatorResult, error) {
-	if run.cross == nil {
-		return run.hashJoinExec(output, state)
-	} else {
+	switch run.op.JoinAlgo {
+	case JoinAlgoNestedLoop, JoinAlgoPiecewiseMerge:
+		return run.nestedLoopJoinExec(output, state)
+	case JoinAlgoSortMerge:
+		return run.sortMergeJoinExec(output, state)
+	case JoinAlgoCross:
 		return run.crossProductExec(output, state)
+	default:
+		return run.hashJoinExec(output, state)
+	}
+}
+
+func (run *Runner) nestedLoopJoinExec(output *chunk.Chunk, state *OperatorState) (OperatorResult, error) {
+	//1. materialize the right child
+	if run.nljoin._stage == NLJ_INIT {
+		for {
+			rightChunk := &chunk.Chunk{}
+			res, err := run.execChild(run.children[1], rightChunk, state)
+			if err != nil {
+				return 0, err
+			}
+			if res == InvalidOpResult {
+				return InvalidOpResult, nil
+			}
+			if res == Done {
+				break
+			}
+			if rightChunk.Card() == 0 {
+				continue
+			}
+			run.nljoin.Sink(rightChunk)
+		}
+		err := run.nljoin.Finalize()
+		if err != nil {
+			return 0, err
+		}
+		run.nljoin._stage = NLJ_PROBE
+	}
+
+	leftChunk := &chunk.Chunk{}
+	rightChunk := &chunk.Chunk{}
+	for {
+		switch run.nljoin._stage {
+		case NLJ_PROBE:
+			//2. join the left chunk with the right side
+			if run.nljoin._input == nil {
+				input := &chunk.Chunk{}
+				res, err := run.execChild(run.children[0], input, state)
+				if err != nil {
+					return 0, err
+				}
+				switch res {
+				case Done:
+					if run.nljoin.scanUnmatched() {
+						run.nljoin._stage = NLJ_SCAN_UNMATCHED
+					} else {
+						run.nljoin._stage = NLJ_DONE
+					}
+					continue
+				case InvalidOpResult:
+					return InvalidOpResult, nil
+				}
+				if input.Card() == 0 {
+					continue
+				}
+				err = run.nljoin.SetInput(input)
+				if err != nil {
+					return 0, err
+				}
+			}
+			more, err := run.nljoin.Next(leftChunk, rightChunk)
+			if err != nil {
+				return 0, err
+			}
+			if !more {
+				run.nljoin._input = nil
+				continue
+			}
+		case NLJ_SCAN_UNMATCHED:
+			//3. the unmatched rows of the right side
+			if !run.nljoin.NextUnmatched(leftChunk, rightChunk) {
+				run.nljoin._stage = NLJ_DONE
+				continue
+			}
+		default:
+			return Done, nil
+		}
+		err := run.state.outputExec.executeExprs(
+			[]*chunk.Chunk{leftChunk, rightChunk, nil},
+			output,
+		)
+		if err != nil {
+			return 0, err
+		}
+		return haveMoreOutput, nil
+	}
+}
+
+func (run *Runner) sortMergeJoinExec(output *chunk.Chunk, state *OperatorState) (OperatorResult, error) {
+	//1. sort both children
+	if run.smjoin._stage == SMJ_INIT {
+		for i, child := range run.children {
+			for {
+				childChunk := &chunk.Chunk{}
+				res, err := run.execChild(child, childChunk, state)
+				if err != nil {
+					return 0, err
+				}
+				if res == InvalidOpResult {
+					return InvalidOpResult, nil
+				}
+				if res == Done {
+					break
+				}
+				if childChunk.Card() == 0 {
+					continue
+				}
+				if i == 0 {
+					err = run.smjoin.SinkLeft(childChunk)
+				} else {
+					err = run.smjoin.SinkRight(childChunk)
+				}
+				if err != nil {
+					return 0, err
+				}
+			}
+		}
+		run.smjoin.Finalize()
+		run.smjoin._stage = SMJ_MERGE
+	}
+
+	leftChunk := &chunk.Chunk{}
+	rightChunk := &chunk.Chunk{}
+	for {
+		switch run.smjoin._stage {
+		case SMJ_MERGE:
+			//2. merge the sorted rows
+			more, err := run.smjoin.Next(leftChunk, rightChunk)
+			if err != nil {
+				return 0, err
+			}
+			if !more {
+				if run.smjoin.scanLeft() {
+					run.smjoin._stage = SMJ_SCAN_LEFT
+				} else {
+					run.smjoin._stage = SMJ_DONE
+				}
+				continue
+			}
+		case SMJ_SCAN_LEFT:
+			//3. the left rows decided after the merging
+			if !run.smjoin.NextLeft(leftChunk, rightChunk) {
+				run.smjoin._stage = SMJ_DONE
+				continue
+			}
+		default:
+			return Done, nil
+		}
+		err := run.state.outputExec.executeExprs(
+			[]*chunk.Chunk{leftChunk, rightChunk, nil},
+			output,
+		)
+		if err != nil {
+			return 0, err
+		}
+		return haveMoreOutput, nil
 	}
 }
 
@@ -1634,6 +2147,10 @@ func (run *Runner) hashJoinExec(output *chunk.Chunk, state *OperatorState) (Oper
 	if res == InvalidOpResult {
 		return InvalidOpResult, nil
 	}
+	//3. scan the unmatched rows in the hash table
+	if run.hjoin._hjs == HJS_SCAN_HT {
+		return run.hashJoinScanUnmatched(output)
+	}
 	//2. probe stage
 	//probe
 	if run.hjoin._hjs == HJS_BUILD || run.hjoin._hjs == HJS_PROBE {
@@ -1664,6 +2181,10 @@ func (run *Runner) hashJoinExec(output *chunk.Chunk, state *OperatorState) (Oper
 		}
 		switch res {
 		case Done:
+			if run.hjoin._ht.scanUnmatched() {
+				run.hjoin._hjs = HJS_SCAN_HT
+				return run.hashJoinScanUnmatched(output)
+			}
 			return Done, nil
 		case InvalidOpResult:
 			return InvalidOpResult, nil
@@ -1696,6 +2217,26 @@ func (run *Runner) hashJoinExec(output *chunk.Chunk, state *OperatorState) (Oper
 	return 0, nil
 }
 
+// hashJoinScanUnmatched outputs the rows without match
+// in the hash table for the right and full join.
+func (run *Runner) hashJoinScanUnmatched(output *chunk.Chunk) (OperatorResult, error) {
+	if run.hjoin._unmatchedScan == nil {
+		run.hjoin._unmatchedScan = NewUnmatchedScan(run.hjoin._ht)
+	}
+	nextChunk := chunk.Chunk{}
+	nextChunk.Init(run.hjoin._scanNextTyps, util.DefaultVectorSize)
+	run.hjoin._unmatchedScan.Next(&nextChunk)
+	if nextChunk.Card() == 0 {
+		run.hjoin._hjs = HJS_DONE
+		return Done, nil
+	}
+	err := run.evalJoinOutput(&nextChunk, output)
+	if err != nil {
+		return 0, err
+	}
+	return haveMoreOutput, nil
+}
+
 func (run *Runner) crossProductExec(output *chunk.Chunk, state *OperatorState) (OperatorResult, error) {
 	res, err := run.crossBuild(state)
 	if err != nil {
@@ -1855,6 +2396,8 @@ func (run *Runner) joinBuildHashTable(state *OperatorState) (OperatorResult, err
 
 func (run *Runner) joinClose() error {
 	run.hjoin = nil
+	run.nljoin = nil
+	run.smjoin = nil
 	run.cross = nil
 	return nil
 }
@@ -1914,7 +2457,7 @@ func (run *Runner) scanInit() error {
 		{
 			tabEnt := storage.GCatalog.GetEntry(run.Txn, storage.CatalogTypeTable, run.op.Database, run.op.Table)
 			if tabEnt == nil {
-				return fmt.Errorf("no table %s in schema %s", run.op.Database, run.op.Table)
+				return util.NewSQLError(util.SQLStateUndefinedTable, "no table %s in schema %s", run.op.Table, run.op.Database)
 			}
 			run.tabEnt = tabEnt
 			col2Idx := tabEnt.GetColumn2Idx()
@@ -1925,7 +2468,7 @@ func (run *Runner) scanInit() error {
 					run.colIndice = append(run.colIndice, idx)
 					run.readedColTyps = append(run.readedColTyps, typs[idx])
 				} else {
-					return fmt.Errorf("no such column %s in %s.%s", col, run.op.Database, run.op.Table)
+					return util.NewSQLError(util.SQLStateUndefinedColumn, "no such column %s in %s.%s", col, run.op.Database, run.op.Table)
 				}
 			}
 		}
@@ -1941,7 +2484,7 @@ func (run *Runner) scanInit() error {
 			//		run.colIndice = append(run.colIndice, idx)
 			//		run.readedColTyps = append(run.readedColTyps, cat.Types[idx])
 			//	} else {
-			//		return fmt.Errorf("no such column %s in %s.%s", col, run.op.Database, run.op.Table)
+			//		return util.NewSQLError(util.SQLStateUndefinedColumn, "no such column %s in %s.%s", col, run.op.Database, run.op.Table)
 			//	}
 			//}
 			//
@@ -1979,10 +2522,20 @@ func (run *Runner) scanInit() error {
 				run.colIndice = append(run.colIndice, idx)
 				run.readedColTyps = append(run.readedColTyps, run.op.Types[idx])
 			} else {
-				return fmt.Errorf("no such column %s in %s.%s", col, run.op.Database, run.op.Table)
+				return util.NewSQLError(util.SQLStateUndefinedColumn, "no such column %s in %s.%s", col, run.op.Database, run.op.Table)
 			}
 		}
 		run.readedColTyps = run.op.Types
+	case ScanTypeWorkTable, ScanTypeCTE:
+		run.colIndice = make([]int, 0)
+		for _, col := range run.op.Columns {
+			if idx, has := run.op.ColName2Idx[col]; has {
+				run.colIndice = append(run.colIndice, idx)
+				run.readedColTyps = append(run.readedColTyps, run.op.Types[idx])
+			} else {
+				return util.NewSQLError(util.SQLStateUndefinedColumn, "no such column %s in %s", col, run.op.Table)
+			}
+		}
 	case ScanTypeCopyFrom:
 		run.colIndice = run.op.ScanInfo.ColumnIds
 		run.readedColTyps = run.op.ScanInfo.ReturnedTypes
@@ -2100,6 +2653,14 @@ func (run *Runner) scanRows(output *chunk.Chunk, state *OperatorState, maxCnt in
 		if err != nil {
 			return false, err
 		}
+	case ScanTypeWorkTable:
+		run.readCollection(readed)
+	case ScanTypeCTE:
+		err = run.materializeCte(state)
+		if err != nil {
+			return false, err
+		}
+		run.readCollection(readed)
 	case ScanTypeCopyFrom:
 		//read table
 		switch run.op.ScanInfo.Format {
@@ -2154,7 +2715,7 @@ func (run *Runner) scanClose() error {
 			//}
 		}
 
-	case ScanTypeValuesList:
+	case ScanTypeValuesList, ScanTypeWorkTable, ScanTypeCTE:
 		return nil
 	case ScanTypeCopyFrom:
 		switch run.op.ScanInfo.Format {
@@ -2215,6 +2776,12 @@ func (run *Runner) readParquetTable(output *chunk.Chunk, state *OperatorState, m
 }
 
 func (run *Runner) readCsvTable(output *chunk.Chunk, state *OperatorState, maxCnt int) error {
+	var nullOpt *ScanOption
+	exported := false
+	if run.op.ScanInfo != nil {
+		nullOpt = getFormatFun("null", run.op.ScanInfo.Opts)
+		exported = run.op.ScanInfo.Exported
+	}
 	rowCont := 0
 	for i := 0; i < maxCnt; i++ {
 		//read line
@@ -2234,6 +2801,15 @@ func (run *Runner) readCsvTable(output *chunk.Chunk, state *OperatorState, maxCn
 			field := line[idx]
 			//[row i, col j] = field
 			vec := output.Data[j]
+			if nullOpt != nil && field == nullOpt.Opt {
+				vec.SetValue(i, &chunk.Value{Typ: vec.Typ(), IsNull: true})
+				continue
+			}
+			if exported {
+				//the doubled backslash is one backslash in the value.
+				//the null string has a single one.
+				field = strings.ReplaceAll(field, exportEscape+exportEscape, exportEscape)
+			}
 			val, err := fieldToValue(field, vec.Typ())
 			if err != nil {
 				return err
@@ -2271,6 +2847,63 @@ func (run *Runner) readValues(output *chunk.Chunk, state *OperatorState, maxCnt
 	return nil
 }
 
+// readCollection reads the rows of the work table
+// or the materialized cte.
+func (run *Runner) readCollection(output *chunk.Chunk) {
+	if run.state.colScanState == nil {
+		run.state.colScanState = &ColumnDataScanState{}
+		run.op.collection.initScan(run.state.colScanState)
+	}
+	data := &chunk.Chunk{}
+	data.Init(run.op.Types, util.DefaultVectorSize)
+	if !run.op.collection.Scan(run.state.colScanState, data) {
+		return
+	}
+	output.ReferenceIndice(data, run.colIndice)
+}
+
+// materializeCte executes the cte query once.
+// The rows are shared by the scans on the cte.
+func (run *Runner) materializeCte(state *OperatorState) (err error) {
+	cte := run.op.cte
+	if cte._materialized {
+		return nil
+	}
+	cteRun := &Runner{
+		op:    cte._plan,
+		Txn:   run.Txn,
+		state: &OperatorState{},
+		cfg:   run.cfg,
+	}
+	err = cteRun.Init()
+	if err != nil {
+		return err
+	}
+	//close the cte query on every path and keep the first error
+	defer func() {
+		closeErr := cteRun.Close()
+		if err == nil {
+			err = closeErr
+		}
+	}()
+	for {
+		data := &chunk.Chunk{}
+		res, err := run.execChild(cteRun, data, state)
+		if err != nil {
+			return err
+		}
+		if res == InvalidOpResult {
+			return fmt.Errorf("invalid result of the cte %s", run.op.Table)
+		}
+		if res == Done {
+			break
+		}
+		cte.Sink(data)
+	}
+	cte._materialized = true
+	return nil
+}
+
 func fieldToValue(field string, lTyp common.LType) (*chunk.Value, error) {
 	var err error
 	val := &chunk.Value{
@@ -2290,8 +2923,33 @@ func fieldToValue(field string, lTyp common.LType) (*chunk.Value, error) {
 		if err != nil {
 			return nil, err
 		}
+	case common.LTID_BIGINT:
+		val.I64, err = strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+	case common.LTID_DECIMAL:
+		_, err = decimal.ParseExact(field, lTyp.Scale)
+		if err != nil {
+			return nil, err
+		}
+		val.Str = field
 	case common.LTID_VARCHAR:
 		val.Str = field
+	case common.LTID_TIMESTAMP, common.LTID_TIMESTAMP_TZ:
+		var ok bool
+		val.I64, ok = common.ParseTimestamp(field, lTyp.Id == common.LTID_TIMESTAMP_TZ)
+		if !ok {
+			return nil, util.NewSQLError(util.SQLStateInvalidDatetimeFormat,
+				"invalid input syntax for type %s: \"%s\"", sqlTypeName(lTyp), field)
+		}
+	case common.LTID_TIME:
+		var ok bool
+		val.I64, ok = common.ParseTime(field)
+		if !ok {
+			return nil, util.NewSQLError(util.SQLStateInvalidDatetimeFormat,
+				"invalid input syntax for type %s: \"%s\"", sqlTypeName(lTyp), field)
+		}
 	default:
 		panic("usp")
 	}
@@ -2352,6 +3010,17 @@ func parquetColToValue(field any, lTyp common.LType) (*chunk.Value, error) {
 			panic("usp")
 		}
 
+	case common.LTID_TIMESTAMP, common.LTID_TIMESTAMP_TZ, common.LTID_TIME:
+		//the micros since the epoch or the midnight
+		switch v := field.(type) {
+		case int64:
+			val.I64 = v
+		case int32:
+			//TIME_MILLIS
+			val.I64 = int64(v) * 1000
+		default:
+			panic("usp")
+		}
 	default:
 		panic("usp")
 	}
@@ -113,7 +113,7 @@ func preparePhyPlan(t *testing.T, id int) (*util.Config, *PhysicalOperator) {
 	conf := loadTestConfig()
 	stmts, err := genStmts(conf, id)
 	require.NoError(t, err)
-	phyPlan, err := genPhyPlan(nil, stmts[0].GetStmt().GetSelectStmt())
+	phyPlan, err := genPhyPlan(conf, nil, stmts[0].GetStmt().GetSelectStmt())
 	require.NoError(t, err)
 	return conf, phyPlan
 }
@@ -146,7 +146,7 @@ func Test_1g_q18_proj_aggr_filter(t *testing.T) {
 	ops := findOperator(
 		pplan,
 		func(root *PhysicalOperator) bool {
-			return wantOp(root, POT_Order)
+			return wantOp(root, POT_TopN)
 		},
 	)
 
@@ -287,7 +287,7 @@ func Test_1g_q10(t *testing.T) {
 		pplan,
 		func(root *PhysicalOperator) bool {
 
-			return wantOp(root, POT_Limit)
+			return wantOp(root, POT_TopN)
 
 		},
 	)
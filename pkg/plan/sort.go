@@ -1038,6 +1038,18 @@ func RadixScatter(
 			offset,
 			int32Encoder{},
 		)
+	case common.INT64:
+		TemplatedRadixScatter[int64](
+			&vdata,
+			sel,
+			serCount,
+			keyLocs,
+			desc,
+			hasNull,
+			nullsFirst,
+			offset,
+			int64Encoder{},
+		)
 	case common.VARCHAR:
 		RadixScatterStringVector(
 			&vdata,
@@ -2954,6 +2966,18 @@ func (i int32Encoder) TypeSize() int {
 	return 4
 }
 
+type int64Encoder struct {
+}
+
+func (i int64Encoder) EncodeData(ptr unsafe.Pointer, value *int64) {
+	util.Store[uint64](BSWAP64(uint64(*value)), ptr)
+	util.Store[uint8](FlipSign(util.Load[uint8](ptr)), ptr)
+}
+
+func (i int64Encoder) TypeSize() int {
+	return 8
+}
+
 // actually it int64
 type intEncoder struct {
 }
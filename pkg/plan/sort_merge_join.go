@@ -0,0 +1,386 @@
+// Copyright 2023-2024 daviszhen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"github.com/daviszhen/plan/pkg/chunk"
+	"github.com/daviszhen/plan/pkg/common"
+	"github.com/daviszhen/plan/pkg/util"
+)
+
+type SortMergeJoinStage int
+
+const (
+	SMJ_INIT SortMergeJoinStage = iota
+	SMJ_MERGE
+	SMJ_SCAN_LEFT
+	SMJ_DONE
+)
+
+// MergeSide is the input of the sort-merge join.
+// the rows are sorted by the LocalSort on the merge keys.
+// the payload of the row is the columns of the child and the keys.
+type MergeSide struct {
+	_types    []common.LType
+	_keyTypes []common.LType
+	_keyExec  *ExprExec
+	_sort     *LocalSort
+	//the positions of the columns of the child in the payload
+	_cols  []int
+	_count int
+
+	//the sorted rows. all chunks are full except the last one.
+	_data []*chunk.Chunk
+	//the keys of the sorted rows
+	_keys [][]*chunk.Value
+}
+
+func NewMergeSide(types []common.LType, keys []*Expr) *MergeSide {
+	side := &MergeSide{
+		_types:   types,
+		_keyExec: NewExprExec(keys...),
+	}
+	for i := range types {
+		side._cols = append(side._cols, i)
+	}
+	orders := make([]*Expr, 0, len(keys))
+	for _, key := range keys {
+		side._keyTypes = append(side._keyTypes, key.DataTyp)
+		orders = append(orders, &Expr{
+			Typ:      ET_Orderby,
+			DataTyp:  key.DataTyp,
+			Children: []*Expr{key},
+		})
+	}
+	payloadTypes := append(util.CopyTo(types), side._keyTypes...)
+	side._sort = NewLocalSort(
+		NewSortLayout(orders),
+		NewRowLayout(payloadTypes, nil),
+	)
+	return side
+}
+
+// Sink evaluates the keys of the input and appends the rows into the LocalSort.
+// the input is the first or the second one of the inputs of the ExprExec.
+func (side *MergeSide) Sink(inputs []*chunk.Chunk, input *chunk.Chunk) error {
+	key := &chunk.Chunk{}
+	key.Init(side._keyTypes, util.DefaultVectorSize)
+	err := side._keyExec.executeExprs(inputs, key)
+	if err != nil {
+		return err
+	}
+	payload := &chunk.Chunk{}
+	payload.Init(append(util.CopyTo(side._types), side._keyTypes...), util.DefaultVectorSize)
+	for i, vec := range input.Data {
+		payload.Data[i].Reference(vec)
+	}
+	for i, vec := range key.Data {
+		payload.Data[len(input.Data)+i].Reference(vec)
+	}
+	payload.SetCard(input.Card())
+	side._sort.SinkChunk(key, payload)
+	side._count += input.Card()
+	return nil
+}
+
+// Finalize sorts the rows and reads them in the order of the keys.
+func (side *MergeSide) Finalize() {
+	if side._count == 0 {
+		return
+	}
+	side._sort.Sort(true)
+	scanner := NewPayloadScanner(
+		side._sort._sortedBlocks[0]._payloadData,
+		side._sort,
+		true,
+	)
+	colCnt := len(side._types)
+	for scanner.Remaining() > 0 {
+		data := &chunk.Chunk{}
+		data.Init(append(util.CopyTo(side._types), side._keyTypes...), util.DefaultVectorSize)
+		scanner.Scan(data)
+		for i := 0; i < data.Card(); i++ {
+			key := make([]*chunk.Value, len(side._keyTypes))
+			for j := range key {
+				key[j] = data.Data[colCnt+j].GetValue(i)
+			}
+			side._keys = append(side._keys, key)
+		}
+		side._data = append(side._data, data)
+	}
+	side._sort = nil
+}
+
+// compareMergeKeys compares the keys in the order of the LocalSort.
+// NULLs are first.
+func compareMergeKeys(a, b []*chunk.Value) int {
+	for i := range a {
+		if a[i].IsNull && b[i].IsNull {
+			continue
+		} else if a[i].IsNull {
+			return -1
+		} else if b[i].IsNull {
+			return 1
+		}
+		if ret := compareValue(a[i], b[i]); ret != 0 {
+			return ret
+		}
+	}
+	return 0
+}
+
+func hasNullKey(key []*chunk.Value) bool {
+	for _, val := range key {
+		if val.IsNull {
+			return true
+		}
+	}
+	return false
+}
+
+// SortMergeJoin sorts both sides on the equalities and merges them.
+// the row pairs with the same keys are evaluated with the other conditions.
+type SortMergeJoin struct {
+	_stage   SortMergeJoinStage
+	_joinTyp LOT_JoinType
+
+	_left  *MergeSide
+	_right *MergeSide
+
+	//the conditions except the merge keys
+	_condExec *ExprExec
+
+	//the left rows that have the match
+	_leftFound []bool
+
+	//the current left row
+	_leftIdx int
+	//the right rows with the same key as the current left row
+	_groupBegin int
+	_groupEnd   int
+	_inGroup    bool
+	//the next right row in the group
+	_rightIdx int
+
+	//the next left chunk for scanning the left rows at last
+	_scanChunkIdx int
+}
+
+func NewSortMergeJoin(op *PhysicalOperator) *SortMergeJoin {
+	smj := &SortMergeJoin{
+		_joinTyp: op.JoinTyp,
+	}
+	leftKeys := make([]*Expr, 0)
+	rightKeys := make([]*Expr, 0)
+	others := make([]*Expr, 0)
+	for _, cond := range copyExprs(op.OnConds...) {
+		if isMergeKey(cond) {
+			leftKeys = append(leftKeys, cond.Children[0])
+			rightKeys = append(rightKeys, cond.Children[1])
+		} else {
+			others = append(others, cond)
+		}
+	}
+	util.AssertFunc(len(leftKeys) != 0)
+	if len(others) != 0 {
+		smj._condExec = NewExprExec(combineExprsByAnd(others...))
+	}
+
+	leftTypes := make([]common.LType, 0)
+	for _, output := range op.Children[0].Outputs {
+		leftTypes = append(leftTypes, output.DataTyp)
+	}
+	rightTypes := make([]common.LType, 0)
+	for _, output := range op.Children[1].Outputs {
+		rightTypes = append(rightTypes, output.DataTyp)
+	}
+	smj._left = NewMergeSide(leftTypes, leftKeys)
+	smj._right = NewMergeSide(rightTypes, rightKeys)
+	return smj
+}
+
+// SinkLeft collects the chunk of the left child.
+func (smj *SortMergeJoin) SinkLeft(input *chunk.Chunk) error {
+	return smj._left.Sink([]*chunk.Chunk{input, nil, nil}, input)
+}
+
+// SinkRight collects the chunk of the right child.
+func (smj *SortMergeJoin) SinkRight(input *chunk.Chunk) error {
+	return smj._right.Sink([]*chunk.Chunk{nil, input, nil}, input)
+}
+
+// Finalize sorts both sides.
+func (smj *SortMergeJoin) Finalize() {
+	smj._left.Finalize()
+	smj._right.Finalize()
+	smj._leftFound = make([]bool, smj._left._count)
+}
+
+// leftOnly checks the join outputs the left rows only.
+func (smj *SortMergeJoin) leftOnly() bool {
+	return smj._joinTyp == LOT_JoinTypeSEMI || smj._joinTyp == LOT_JoinTypeANTI
+}
+
+// nextPairs collects the row pairs with the same keys.
+// the left (right) rows of the pairs are in the same left (right) chunk.
+// it returns the count of the pairs and the chunk of the pairs.
+func (smj *SortMergeJoin) nextPairs(lsel, rsel *chunk.SelectVector) (int, int, int) {
+	leftKeys := smj._left._keys
+	rightKeys := smj._right._keys
+	cnt := 0
+	lchunk, rchunk := -1, -1
+	for smj._leftIdx < len(leftKeys) {
+		if !smj._inGroup {
+			key := leftKeys[smj._leftIdx]
+			if hasNullKey(key) {
+				smj._leftIdx++
+				continue
+			}
+			//skip the right rows with the smaller keys
+			for smj._groupEnd < len(rightKeys) &&
+				compareMergeKeys(rightKeys[smj._groupEnd], key) < 0 {
+				smj._groupEnd++
+			}
+			smj._groupBegin = smj._groupEnd
+			for smj._groupEnd < len(rightKeys) &&
+				compareMergeKeys(rightKeys[smj._groupEnd], key) == 0 {
+				smj._groupEnd++
+			}
+			if smj._groupBegin == smj._groupEnd {
+				smj._leftIdx++
+				continue
+			}
+			smj._inGroup = true
+			smj._rightIdx = smj._groupBegin
+		}
+
+		end := smj._groupEnd
+		if smj.leftOnly() && smj._condExec == nil {
+			//one pair is enough
+			end = min(end, smj._groupBegin+1)
+		}
+		for smj._rightIdx < end {
+			lc := smj._leftIdx / util.DefaultVectorSize
+			rc := smj._rightIdx / util.DefaultVectorSize
+			if cnt > 0 && (lc != lchunk || rc != rchunk || cnt == util.DefaultVectorSize) {
+				return cnt, lchunk, rchunk
+			}
+			lchunk, rchunk = lc, rc
+			lsel.SetIndex(cnt, smj._leftIdx%util.DefaultVectorSize)
+			rsel.SetIndex(cnt, smj._rightIdx%util.DefaultVectorSize)
+			cnt++
+			smj._rightIdx++
+		}
+
+		//the next left row with the same key reuses the group
+		smj._leftIdx++
+		if smj._leftIdx < len(leftKeys) &&
+			compareMergeKeys(leftKeys[smj._leftIdx], leftKeys[smj._leftIdx-1]) == 0 {
+			smj._rightIdx = smj._groupBegin
+		} else {
+			smj._inGroup = false
+		}
+	}
+	return cnt, lchunk, rchunk
+}
+
+// Next fills the left and right part of the next joined rows.
+// it returns false when the merging is over.
+func (smj *SortMergeJoin) Next(left, right *chunk.Chunk) (bool, error) {
+	lsel := chunk.NewSelectVector(util.DefaultVectorSize)
+	rsel := chunk.NewSelectVector(util.DefaultVectorSize)
+	for {
+		cnt, lchunk, rchunk := smj.nextPairs(lsel, rsel)
+		if cnt == 0 {
+			return false, nil
+		}
+		smj.slicePairs(left, right, lchunk, rchunk, lsel, rsel, cnt)
+		if smj._condExec != nil {
+			matchSel := chunk.NewSelectVector(util.DefaultVectorSize)
+			matchCnt, err := smj._condExec.executeSelect([]*chunk.Chunk{left, right, nil}, matchSel)
+			if err != nil {
+				return false, err
+			}
+			if matchCnt == 0 {
+				continue
+			}
+			if matchCnt != cnt {
+				for i := 0; i < matchCnt; i++ {
+					idx := matchSel.GetIndex(i)
+					lsel.SetIndex(i, lsel.GetIndex(idx))
+					rsel.SetIndex(i, rsel.GetIndex(idx))
+				}
+				cnt = matchCnt
+				smj.slicePairs(left, right, lchunk, rchunk, lsel, rsel, cnt)
+			}
+		}
+		base := lchunk * util.DefaultVectorSize
+		for i := 0; i < cnt; i++ {
+			smj._leftFound[base+lsel.GetIndex(i)] = true
+		}
+		if !smj.leftOnly() {
+			return true, nil
+		}
+	}
+}
+
+func (smj *SortMergeJoin) slicePairs(
+	left, right *chunk.Chunk,
+	lchunk, rchunk int,
+	lsel, rsel *chunk.SelectVector,
+	cnt int) {
+	left.Init(smj._left._types, util.DefaultVectorSize)
+	left.SliceIndice(smj._left._data[lchunk], lsel, cnt, 0, smj._left._cols)
+	right.Init(smj._right._types, util.DefaultVectorSize)
+	right.SliceIndice(smj._right._data[rchunk], rsel, cnt, 0, smj._right._cols)
+}
+
+func (smj *SortMergeJoin) scanLeft() bool {
+	switch smj._joinTyp {
+	case LOT_JoinTypeLeft, LOT_JoinTypeSEMI, LOT_JoinTypeANTI:
+		return true
+	default:
+		return false
+	}
+}
+
+// NextLeft fills the left rows after the merging.
+// the unmatched rows padded with NULL for the left join.
+// the matched rows for the semi join. the unmatched rows for the anti join.
+// it returns false when the left side is over.
+func (smj *SortMergeJoin) NextLeft(left, right *chunk.Chunk) bool {
+	want := smj._joinTyp == LOT_JoinTypeSEMI
+	for smj._scanChunkIdx < len(smj._left._data) {
+		data := smj._left._data[smj._scanChunkIdx]
+		base := smj._scanChunkIdx * util.DefaultVectorSize
+		smj._scanChunkIdx++
+		sel := chunk.NewSelectVector(util.DefaultVectorSize)
+		cnt := 0
+		for i := 0; i < data.Card(); i++ {
+			if smj._leftFound[base+i] == want {
+				sel.SetIndex(cnt, i)
+				cnt++
+			}
+		}
+		if cnt == 0 {
+			continue
+		}
+		left.Init(smj._left._types, util.DefaultVectorSize)
+		left.SliceIndice(data, sel, cnt, 0, smj._left._cols)
+		referNulls(right, smj._right._types, cnt)
+		return true
+	}
+	return false
+}
@@ -18,12 +18,14 @@ import (
 	"fmt"
 	"math"
 	"sort"
+	"strings"
 	"testing"
 	"unsafe"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/daviszhen/plan/pkg/storage"
 	"github.com/daviszhen/plan/pkg/util"
 )
 
@@ -460,3 +462,65 @@ func Test_localsort(t *testing.T) {
 	ops[0].Children[0] = stubOp
 	runOps(t, conf, nil, ops)
 }
+
+func Test_topN(t *testing.T) {
+	txn, err := storage.GTxnMgr.NewTxn("top n")
+	require.NoError(t, err)
+	defer storage.GTxnMgr.Rollback(txn)
+	execSQL(t, txn, "create table tn_t (i integer, s varchar)")
+	//more rows than a chunk. the strings share a long prefix.
+	values := make([]string, 0)
+	for k := 0; k < 3000; k++ {
+		values = append(values, fmt.Sprintf("(%d, 'a_long_common_prefix_%d')", k*7919%3000, k%37))
+	}
+	execSQL(t, txn, "insert into tn_t values "+strings.Join(values, ","))
+
+	rows, err := querySQL(t, txn, "select i from tn_t order by i limit 3")
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"0"}, {"1"}, {"2"}}, rows)
+
+	//every row replaces the worst one in the heap
+	execSQL(t, txn, "create table tn_asc (i integer, d decimal(10, 2), dt date, s varchar)")
+	values = values[:0]
+	for k := 0; k < 5000; k++ {
+		str := "null"
+		if k%10 != 0 {
+			str = fmt.Sprintf("'s_%d'", k)
+		}
+		values = append(values, fmt.Sprintf("(%d, %d.25, '2024-01-%02d', %s)", k, k, k%28+1, str))
+	}
+	execSQL(t, txn, "insert into tn_asc values "+strings.Join(values, ","))
+
+	kases := []struct {
+		order string
+		limit int
+	}{
+		{"select i, s from tn_t order by i desc", 5},
+		{"select s, i from tn_t order by s desc, i", 10},
+		{"select i + 1, s from tn_t order by s, i desc", 2100},
+		{"select i from tn_t order by i", 5000},
+		{"select i from tn_t order by i", 0},
+		{"select i, d, dt, s from tn_asc order by i desc", 3},
+		{"select s, d, dt from tn_asc order by i desc", 2500},
+		{"select s, i from tn_asc order by s desc, i", 7},
+	}
+	for _, kase := range kases {
+		sql := fmt.Sprintf("%s limit %d", kase.order, kase.limit)
+		run, err := InitRunner(&util.Config{}, txn, sql)
+		require.NoError(t, err, sql)
+		require.Len(t, findOperator(run.op, func(root *PhysicalOperator) bool {
+			return wantOp(root, POT_TopN)
+		}), 1, sql)
+		require.Empty(t, findOperator(run.op, func(root *PhysicalOperator) bool {
+			return wantOp(root, POT_Order)
+		}), sql)
+		run.Close()
+
+		//same as the full sort
+		want, err := querySQL(t, txn, kase.order)
+		require.NoError(t, err, kase.order)
+		got, err := querySQL(t, txn, sql)
+		require.NoError(t, err, sql)
+		require.Equal(t, want[:min(kase.limit, len(want))], got, sql)
+	}
+}
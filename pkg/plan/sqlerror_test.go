@@ -0,0 +1,63 @@
+// Copyright 2023-2024 daviszhen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/daviszhen/plan/pkg/storage"
+	"github.com/daviszhen/plan/pkg/util"
+)
+
+func Test_sql_error(t *testing.T) {
+	kases := []struct {
+		sql  string
+		code util.SQLState
+	}{
+		{"selec 1", util.SQLStateSyntaxError},
+		{"select * from no_such_table", util.SQLStateUndefinedTable},
+		{"select 1", util.SQLStateFeatureNotSupported},
+		{"select cast(a as json) from sqlerr_t", util.SQLStateFeatureNotSupported},
+		{"select b from sqlerr_t", util.SQLStateUndefinedColumn},
+	}
+	for _, kase := range kases {
+		txn, err := storage.GTxnMgr.NewTxn(kase.sql)
+		require.NoError(t, err)
+		execSQL(t, txn, "create table sqlerr_t (a integer)")
+		_, err = InitRunner(&util.Config{}, txn, kase.sql)
+		storage.GTxnMgr.Rollback(txn)
+		require.Error(t, err, kase.sql)
+		var sqlErr *util.SQLError
+		require.True(t, errors.As(err, &sqlErr), kase.sql)
+		require.Equal(t, kase.code, sqlErr.Code, kase.sql)
+	}
+}
+
+func Test_recover_to_error(t *testing.T) {
+	err := util.RecoverToError(util.NewSQLError(util.SQLStateDivisionByZero, "division by zero"))
+	require.Equal(t, util.SQLStateDivisionByZero, util.GetSQLState(err))
+
+	err = util.RecoverToError("usp typename")
+	require.Equal(t, util.SQLStateFeatureNotSupported, util.GetSQLState(err))
+
+	err = util.RecoverToError("index out of range")
+	require.Equal(t, util.SQLStateInternalError, util.GetSQLState(err))
+	var sqlErr *util.SQLError
+	require.True(t, errors.As(err, &sqlErr))
+	require.NotEmpty(t, sqlErr.Stack)
+}
@@ -0,0 +1,288 @@
+// Copyright 2023-2024 daviszhen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/daviszhen/plan/pkg/chunk"
+	"github.com/daviszhen/plan/pkg/common"
+	"github.com/daviszhen/plan/pkg/util"
+)
+
+//the string functions count the characters in UTF-8.
+
+func lengthOp(input *common.String, result *int32) {
+	*result = int32(utf8.RuneCount(input.DataSlice()))
+}
+
+func lowerOp(input *common.String, result *common.String) {
+	newString(strings.ToLower(input.String()), result)
+}
+
+func upperOp(input *common.String, result *common.String) {
+	newString(strings.ToUpper(input.String()), result)
+}
+
+func reverseOp(input *common.String, result *common.String) {
+	runes := []rune(input.String())
+	for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+		runes[i], runes[j] = runes[j], runes[i]
+	}
+	newString(string(runes), result)
+}
+
+// trim
+
+// the trim without characters removes the spaces only.
+// the tabs and the newlines are kept as in PostgreSQL.
+
+func btrimOp(input *common.String, result *common.String) {
+	newString(strings.Trim(input.String(), " "), result)
+}
+
+func ltrimOp(input *common.String, result *common.String) {
+	newString(strings.TrimLeft(input.String(), " "), result)
+}
+
+func rtrimOp(input *common.String, result *common.String) {
+	newString(strings.TrimRight(input.String(), " "), result)
+}
+
+func btrimCharsOp(input, chars *common.String, result *common.String) {
+	newString(strings.Trim(input.String(), chars.String()), result)
+}
+
+func ltrimCharsOp(input, chars *common.String, result *common.String) {
+	newString(strings.TrimLeft(input.String(), chars.String()), result)
+}
+
+func rtrimCharsOp(input, chars *common.String, result *common.String) {
+	newString(strings.TrimRight(input.String(), chars.String()), result)
+}
+
+// concat
+
+func concatOp(left, right *common.String, result *common.String) {
+	newString(left.String()+right.String(), result)
+}
+
+// concatFunc concatenates the arguments and skips the NULL.
+func concatFunc(input *chunk.Chunk, state *ExprState, result *chunk.Vector) {
+	count := input.Card()
+	args := make([]chunk.UnifiedFormat, input.ColumnCount())
+	slices := make([][]common.String, input.ColumnCount())
+	for i, vec := range input.Data {
+		vec.ToUnifiedFormat(count, &args[i])
+		slices[i] = chunk.GetSliceInPhyFormatUnifiedFormat[common.String](&args[i])
+	}
+	result.SetPhyFormat(chunk.PF_FLAT)
+	resSlice := chunk.GetSliceInPhyFormatFlat[common.String](result)
+	resMask := chunk.GetMaskInPhyFormatFlat(result)
+	builder := strings.Builder{}
+	for i := 0; i < count; i++ {
+		builder.Reset()
+		for j := range args {
+			idx := args[j].Sel.GetIndex(i)
+			if args[j].Mask.RowIsValid(uint64(idx)) {
+				builder.Write(slices[j][idx].DataSlice())
+			}
+		}
+		newString(builder.String(), &resSlice[i])
+		resMask.SetValid(uint64(i))
+	}
+}
+
+// concatWsFunc concatenates the arguments after the separator
+// with the separator and skips the NULL.
+// NULL if the separator is NULL.
+func concatWsFunc(input *chunk.Chunk, state *ExprState, result *chunk.Vector) {
+	count := input.Card()
+	args := make([]chunk.UnifiedFormat, input.ColumnCount())
+	slices := make([][]common.String, input.ColumnCount())
+	for i, vec := range input.Data {
+		vec.ToUnifiedFormat(count, &args[i])
+		slices[i] = chunk.GetSliceInPhyFormatUnifiedFormat[common.String](&args[i])
+	}
+	result.SetPhyFormat(chunk.PF_FLAT)
+	resSlice := chunk.GetSliceInPhyFormatFlat[common.String](result)
+	resMask := chunk.GetMaskInPhyFormatFlat(result)
+	builder := strings.Builder{}
+	for i := 0; i < count; i++ {
+		sepIdx := args[0].Sel.GetIndex(i)
+		if !args[0].Mask.RowIsValid(uint64(sepIdx)) {
+			resMask.SetInvalid(uint64(i))
+			continue
+		}
+		builder.Reset()
+		first := true
+		for j := 1; j < len(args); j++ {
+			idx := args[j].Sel.GetIndex(i)
+			if !args[j].Mask.RowIsValid(uint64(idx)) {
+				continue
+			}
+			if !first {
+				builder.Write(slices[0][sepIdx].DataSlice())
+			}
+			builder.Write(slices[j][idx].DataSlice())
+			first = false
+		}
+		newString(builder.String(), &resSlice[i])
+		resMask.SetValid(uint64(i))
+	}
+}
+
+// BindConcat casts the arguments to the varchar.
+func BindConcat(fun *FunctionV2, args []*Expr) *FunctionData {
+	var err error
+	for i := range args {
+		args[i], err = AddCastToType(args[i], common.VarcharType(), false)
+		if err != nil {
+			panic(err)
+		}
+	}
+	return nil
+}
+
+// search
+
+// replaceOp keeps the input if the from is empty.
+func replaceOp(input, from, to *common.String, result *common.String) {
+	if from.Length() == 0 {
+		newString(input.String(), result)
+		return
+	}
+	newString(strings.ReplaceAll(input.String(), from.String(), to.String()), result)
+}
+
+// strposOp returns the position of the first character of the sub in the input.
+// 0 if the sub is not in the input.
+func strposOp(input, sub *common.String, result *int32) {
+	s := input.String()
+	idx := strings.Index(s, sub.String())
+	if idx < 0 {
+		*result = 0
+		return
+	}
+	*result = int32(utf8.RuneCountInString(s[:idx])) + 1
+}
+
+func startsWithOp(input, prefix *common.String, result *bool) {
+	*result = strings.HasPrefix(input.String(), prefix.String())
+}
+
+// splitPartOp returns the n-th field of the input split by the delimiter.
+// the negative n counts from the end.
+func splitPartOp(input, delim *common.String, n *int32, result *common.String) {
+	if *n == 0 {
+		panic(util.NewSQLError(util.SQLStateInvalidParameterValue, "field position must not be zero"))
+	}
+	s := input.String()
+	var parts []string
+	if delim.Length() == 0 {
+		parts = []string{s}
+	} else {
+		parts = strings.Split(s, delim.String())
+	}
+	idx := int(*n) - 1
+	if *n < 0 {
+		idx = len(parts) + int(*n)
+	}
+	if idx < 0 || idx >= len(parts) {
+		*result = common.String{}
+		return
+	}
+	newString(parts[idx], result)
+}
+
+// slice
+
+// leftOp returns the first n characters.
+// the negative n returns all but the last |n| characters.
+func leftOp(input *common.String, n *int32, result *common.String) {
+	runes := []rune(input.String())
+	cnt := int(*n)
+	if cnt < 0 {
+		cnt = max(len(runes)+cnt, 0)
+	}
+	newString(string(runes[:min(cnt, len(runes))]), result)
+}
+
+// rightOp returns the last n characters.
+// the negative n returns all but the first |n| characters.
+func rightOp(input *common.String, n *int32, result *common.String) {
+	runes := []rune(input.String())
+	cnt := int(*n)
+	if cnt < 0 {
+		cnt = max(len(runes)+cnt, 0)
+	}
+	newString(string(runes[len(runes)-min(cnt, len(runes)):]), result)
+}
+
+func repeatOp(input *common.String, n *int32, result *common.String) {
+	if *n <= 0 {
+		*result = common.String{}
+		return
+	}
+	newString(strings.Repeat(input.String(), int(*n)), result)
+}
+
+// pad
+
+// padString fills the input up to the length with the fill.
+// the input longer than the length is truncated.
+func padString(input *common.String, length int32, fill string, left bool, result *common.String) {
+	runes := []rune(input.String())
+	cnt := int(length)
+	if cnt <= 0 {
+		*result = common.String{}
+		return
+	}
+	if cnt <= len(runes) {
+		newString(string(runes[:cnt]), result)
+		return
+	}
+	fillRunes := []rune(fill)
+	if len(fillRunes) == 0 {
+		newString(string(runes), result)
+		return
+	}
+	pad := make([]rune, 0, cnt-len(runes))
+	for i := 0; len(pad) < cnt-len(runes); i++ {
+		pad = append(pad, fillRunes[i%len(fillRunes)])
+	}
+	if left {
+		newString(string(pad)+string(runes), result)
+	} else {
+		newString(string(runes)+string(pad), result)
+	}
+}
+
+func lpadOp(input *common.String, length *int32, result *common.String) {
+	padString(input, *length, " ", true, result)
+}
+
+func rpadOp(input *common.String, length *int32, result *common.String) {
+	padString(input, *length, " ", false, result)
+}
+
+func lpadFillOp(input *common.String, length *int32, fill *common.String, result *common.String) {
+	padString(input, *length, fill.String(), true, result)
+}
+
+func rpadFillOp(input *common.String, length *int32, fill *common.String, result *common.String) {
+	padString(input, *length, fill.String(), false, result)
+}
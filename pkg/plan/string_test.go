@@ -0,0 +1,98 @@
+// Copyright 2023-2024 daviszhen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/daviszhen/plan/pkg/storage"
+)
+
+func Test_stringFuncs(t *testing.T) {
+	txn, err := storage.GTxnMgr.NewTxn("string")
+	require.NoError(t, err)
+	defer storage.GTxnMgr.Rollback(txn)
+	execSQL(t, txn, "create table string_t (i integer, s varchar)")
+	execSQL(t, txn, "insert into string_t values (2, ' héllo wörld ')")
+
+	kases := []struct {
+		expr string
+		want string
+	}{
+		{"length(s)", "13"},
+		{"char_length(s)", "13"},
+		{"lower('HÉllo')", "héllo"},
+		{"upper(s)", " HÉLLO WÖRLD "},
+		{"trim(s)", "héllo wörld"},
+		{"ltrim(s)", "héllo wörld "},
+		{"rtrim(s)", " héllo wörld"},
+		{"trim(both ' h' from s)", "éllo wörld"},
+		{"trim(leading ' ' from s)", "héllo wörld "},
+		{"trim(trailing 'd ' from s)", " héllo wörl"},
+		{"concat(s, i, 'x')", " héllo wörld 2x"},
+		{"concat('a', nullif(i, 2), 'b')", "ab"},
+		{"concat_ws('-', s, nullif(i, 2), 'x', i)", " héllo wörld -x-2"},
+		{"concat_ws(nullif(i, 2), 'a', 'b')", "NULL"},
+		{"'a' || i", "a2"},
+		{"'a' || nullif(i, 2)", "NULL"},
+		{"replace(s, 'l', 'L')", " héLLo wörLd "},
+		{"replace(s, '', 'x')", " héllo wörld "},
+		{"position('wö' in s)", "8"},
+		{"strpos(s, 'x')", "0"},
+		{"left(s, 3)", " hé"},
+		{"left(s, -8)", " héll"},
+		{"right(s, 3)", "ld "},
+		{"right(s, -9)", "rld "},
+		{"lpad('ab', 5)", "   ab"},
+		{"lpad('ab', 5, 'xy')", "xyxab"},
+		{"rpad('äb', 4, 'ö')", "äböö"},
+		{"rpad(s, 3)", " hé"},
+		{"split_part('a,b,c', ',', i)", "b"},
+		{"split_part('a,b,c', ',', -1)", "c"},
+		{"split_part('a,b,c', ',', 4)", ""},
+		{"starts_with(s, ' hé')", "true"},
+		{"reverse('héllo')", "olléh"},
+		{"repeat('ab', i)", "abab"},
+		{"repeat('ab', -1)", ""},
+	}
+	for _, kase := range kases {
+		rows, err := querySQL(t, txn, "select "+kase.expr+" from string_t")
+		require.NoError(t, err, kase.expr)
+		require.Equal(t, [][]string{{kase.want}}, rows, kase.expr)
+	}
+
+	rows, err := querySQL(t, txn, "select i from string_t where starts_with(trim(s), 'hé') and length(s) > 5")
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"2"}}, rows)
+
+	//the tabs and the newlines are not trimmed
+	execSQL(t, txn, "create table string_ws (s varchar)")
+	execSQL(t, txn, "insert into string_ws values (' \t a \n ')")
+	for expr, want := range map[string]string{
+		"trim(s)":  "\t a \n",
+		"ltrim(s)": "\t a \n ",
+		"rtrim(s)": " \t a \n",
+	} {
+		rows, err = querySQL(t, txn, "select "+expr+" from string_ws")
+		require.NoError(t, err, expr)
+		require.Equal(t, [][]string{{want}}, rows, expr)
+	}
+
+	require.PanicsWithError(t, "field position must not be zero", func() {
+		_, _ = querySQL(t, txn, "select split_part(s, ',', 0) from string_t")
+	})
+}
@@ -0,0 +1,89 @@
+// Copyright 2023-2024 daviszhen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/daviszhen/plan/pkg/common"
+	"github.com/daviszhen/plan/pkg/storage"
+	"github.com/daviszhen/plan/pkg/util"
+)
+
+func Test_timestamp(t *testing.T) {
+	txn, err := storage.GTxnMgr.NewTxn("timestamp")
+	require.NoError(t, err)
+	defer storage.GTxnMgr.Rollback(txn)
+	execSQL(t, txn, "create table ts_t (i integer, ts timestamp, t time, tz timestamptz)")
+	execSQL(t, txn, "insert into ts_t values (1, '2024-01-02 03:04:05', '03:04:05', '2024-01-02 03:04:05+02')")
+
+	kases := []struct {
+		expr string
+		want string
+	}{
+		{"ts", "2024-01-02 03:04:05"},
+		{"t", "03:04:05"},
+		{"tz", "2024-01-02 01:04:05+00"},
+		{"ts + interval '1 day 2 hours'", "2024-01-03 05:04:05"},
+		{"interval '1 month' + ts", "2024-02-02 03:04:05"},
+		{"ts - interval '30 minutes'", "2024-01-02 02:34:05"},
+		{"tz + interval '1 year'", "2025-01-02 01:04:05+00"},
+		{"t + interval '21 hours'", "00:04:05"},
+		{"t - interval '04:00:00'", "23:04:05"},
+		{"ts - '2023-01-01'::timestamp", "366 days 03:04:05"},
+		{"t - '01:00'::time", "02:04:05"},
+		{"ts < '2024-01-03'", "true"},
+		{"tz = '2024-01-02 01:04:05+00'::timestamptz", "true"},
+		{"t <> '03:04:05'", "false"},
+	}
+	for _, kase := range kases {
+		rows, err := querySQL(t, txn, "select "+kase.expr+" from ts_t")
+		require.NoError(t, err, kase.expr)
+		require.Equal(t, [][]string{{kase.want}}, rows, kase.expr)
+	}
+
+	execSQL(t, txn, "insert into ts_t values (2, '2023-05-06 07:08:09.5', '23:59:00', '2024-01-01 00:00:00+00')")
+	rows, err := querySQL(t, txn, "select i from ts_t where ts > '2024-01-01'")
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"1"}}, rows)
+	rows, err = querySQL(t, txn, "select i from ts_t where t >= '12:00' and tz < '2024-01-02'")
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"2"}}, rows)
+	rows, err = querySQL(t, txn, "select ts from ts_t order by ts")
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"2023-05-06 07:08:09.5"}, {"2024-01-02 03:04:05"}}, rows)
+	rows, err = querySQL(t, txn, "select t from ts_t order by t desc")
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"23:59:00"}, {"03:04:05"}}, rows)
+
+	_, err = querySQL(t, txn, "create table ts_iv (iv interval)")
+	require.Equal(t, util.SQLStateFeatureNotSupported, util.GetSQLState(err))
+}
+
+func Test_timestampFieldToValue(t *testing.T) {
+	val, err := fieldToValue("2024-01-02 03:04:05", common.TimestampType())
+	require.NoError(t, err)
+	require.Equal(t, "2024-01-02 03:04:05", val.String())
+	val, err = fieldToValue("2024-01-02 03:04:05+02", common.TimestampTzType())
+	require.NoError(t, err)
+	require.Equal(t, "2024-01-02 01:04:05+00", val.String())
+	val, err = fieldToValue("03:04:05", common.TimeType())
+	require.NoError(t, err)
+	require.Equal(t, "03:04:05", val.String())
+	_, err = fieldToValue("03:04:05", common.TimestampType())
+	require.Equal(t, util.SQLStateInvalidDatetimeFormat, util.GetSQLState(err))
+}
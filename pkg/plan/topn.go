@@ -0,0 +1,318 @@
+// Copyright 2023-2024 daviszhen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package plan
+
+import (
+	"bytes"
+	"container/heap"
+	"sort"
+	"strings"
+	"unsafe"
+
+	"github.com/daviszhen/plan/pkg/chunk"
+	"github.com/daviszhen/plan/pkg/common"
+	"github.com/daviszhen/plan/pkg/util"
+)
+
+type TopNState int
+
+const (
+	TOPN_INIT TopNState = iota
+	TOPN_SCAN
+)
+
+// TopN keeps the best limit+offset rows in a bounded heap.
+// The rows are compared on the radix encoded sort keys
+// like the LocalSort does. The payload of the rows in the
+// heap is kept in a columnar buffer.
+type TopN struct {
+	_state        TopNState
+	_sortLayout   *SortLayout
+	_payloadTypes []common.LType
+	_limit        uint64
+	_offset       uint64
+	_heap         *topNHeap
+	_keyLocs      []unsafe.Pointer
+	_sel          *chunk.SelectVector
+	//payload of the rows in the heap and the rows replaced.
+	//the replaced ones are dropped by the compaction.
+	_payload *ColumnDataCollection
+	//sorted rows after the sink
+	_result     []*topNEntry
+	_scanOffset int
+}
+
+type topNEntry struct {
+	//radix encoded sort key
+	_key []byte
+	//full values of the var len sort columns
+	_blobs []*chunk.Value
+	//position of the payload in the buffer
+	_pos int
+	//the row in the sink. the full values are
+	//read from it when they are needed.
+	_src    *chunk.Chunk
+	_srcRow int
+}
+
+// blob returns the full value of the var len sort column.
+func (ent *topNEntry) blob(layout *SortLayout, sortCol int) *chunk.Value {
+	if ent._blobs == nil {
+		for col := 0; col < layout._columnCount; col++ {
+			if !layout._constantSize[col] {
+				ent._blobs = append(ent._blobs, ent._src.Data[col].GetValue(ent._srcRow))
+			}
+		}
+	}
+	return ent._blobs[layout._sortingToBlobCol[sortCol]]
+}
+
+// topNHeap is a max heap. The top is the worst row in it.
+type topNHeap struct {
+	_layout  *SortLayout
+	_entries []*topNEntry
+}
+
+func (h *topNHeap) Len() int {
+	return len(h._entries)
+}
+
+func (h *topNHeap) Less(i, j int) bool {
+	return compareTopNEntry(h._layout, h._entries[i], h._entries[j]) > 0
+}
+
+func (h *topNHeap) Swap(i, j int) {
+	h._entries[i], h._entries[j] = h._entries[j], h._entries[i]
+}
+
+func (h *topNHeap) Push(x any) {
+	h._entries = append(h._entries, x.(*topNEntry))
+}
+
+func (h *topNHeap) Pop() any {
+	n := len(h._entries)
+	ret := h._entries[n-1]
+	h._entries = h._entries[:n-1]
+	return ret
+}
+
+// compareTopNEntry compares the sort keys column by column.
+// The var len column falls back to the full value when
+// the prefixes are same.
+func compareTopNEntry(layout *SortLayout, a, b *topNEntry) int {
+	offset := 0
+	for i := 0; i < layout._columnCount; i++ {
+		size := layout._columnSizes[i]
+		ret := bytes.Compare(a._key[offset:offset+size], b._key[offset:offset+size])
+		if ret != 0 {
+			return ret
+		}
+		if !layout._constantSize[i] {
+			lval := a.blob(layout, i)
+			rval := b.blob(layout, i)
+			if !lval.IsNull && !rval.IsNull {
+				ret = strings.Compare(lval.Str, rval.Str)
+				if layout._orderTypes[i] == OT_DESC {
+					ret = -ret
+				}
+				if ret != 0 {
+					return ret
+				}
+			}
+		}
+		offset += size
+	}
+	return 0
+}
+
+func NewTopN(slayout *SortLayout, payloadTypes []common.LType, limitExpr, offsetExpr *Expr) *TopN {
+	ret := &TopN{
+		_sortLayout:   slayout,
+		_payloadTypes: payloadTypes,
+		_limit:        uint64(limitExpr.Ivalue),
+		_heap:         &topNHeap{_layout: slayout},
+		_keyLocs:      make([]unsafe.Pointer, util.DefaultVectorSize),
+		_sel:          chunk.IncrSelectVectorInPhyFormatFlat(),
+		_payload:      NewColumnDataCollection(payloadTypes),
+	}
+	if offsetExpr != nil {
+		ret._offset = uint64(offsetExpr.Ivalue)
+	}
+	return ret
+}
+
+func (topn *TopN) heapSize() int {
+	return int(topn._limit + topn._offset)
+}
+
+// Sink puts the rows that better than the worst one
+// into the heap. The sort keys are compared before
+// the payload of the row is copied.
+func (topn *TopN) Sink(key, payload *chunk.Chunk) {
+	util.AssertFunc(key.Card() == payload.Card())
+	cnt := key.Card()
+	if cnt == 0 || topn._limit == 0 {
+		return
+	}
+	layout := topn._sortLayout
+	keySize := layout._comparisonSize
+	keyBuf := util.CMalloc(cnt * keySize)
+	defer util.CFree(keyBuf)
+	for i := 0; i < cnt; i++ {
+		topn._keyLocs[i] = util.PointerAdd(keyBuf, i*keySize)
+	}
+	//encode the sort keys
+	for sortCol := 0; sortCol < key.ColumnCount(); sortCol++ {
+		RadixScatter(
+			key.Data[sortCol],
+			cnt,
+			topn._sel,
+			cnt,
+			topn._keyLocs,
+			layout._orderTypes[sortCol] == OT_DESC,
+			layout._hasNull[sortCol],
+			layout._orderByNullTypes[sortCol] == OBNT_NULLS_FIRST,
+			layout._prefixLengths[sortCol],
+			layout._columnSizes[sortCol],
+			0,
+		)
+	}
+	keys := util.PointerToSlice[byte](keyBuf, cnt*keySize)
+
+	//the rows put into the heap
+	sel := chunk.NewSelectVector(cnt)
+	selCnt := 0
+	base := topn._payload.Count()
+	for i := 0; i < cnt; i++ {
+		ent := &topNEntry{
+			_key:    keys[i*keySize : (i+1)*keySize],
+			_src:    key,
+			_srcRow: i,
+		}
+		full := topn._heap.Len() >= topn.heapSize()
+		if full && compareTopNEntry(layout, ent, topn._heap._entries[0]) >= 0 {
+			//not better than the worst one
+			continue
+		}
+
+		//the key buffer and the key chunk are gone after the sink
+		ent._key = bytes.Clone(ent._key)
+		for sortCol := 0; sortCol < key.ColumnCount(); sortCol++ {
+			if !layout._constantSize[sortCol] {
+				ent.blob(layout, sortCol)
+				break
+			}
+		}
+		ent._src = nil
+		ent._pos = base + selCnt
+		sel.SetIndex(selCnt, i)
+		selCnt++
+		if full {
+			topn._heap._entries[0] = ent
+			heap.Fix(topn._heap, 0)
+		} else {
+			heap.Push(topn._heap, ent)
+		}
+	}
+	if selCnt == 0 {
+		return
+	}
+	rows := &chunk.Chunk{}
+	rows.Init(topn._payloadTypes, util.DefaultVectorSize)
+	rows.Slice(payload, sel, selCnt, 0)
+	topn._payload.Append(rows)
+
+	//the replaced rows are more than the rows in the heap
+	dead := topn._payload.Count() - topn._heap.Len()
+	if dead >= util.DefaultVectorSize && dead > topn._heap.Len() {
+		topn.compact()
+	}
+}
+
+// compact copies the payload of the rows in the heap
+// into a new buffer.
+func (topn *TopN) compact() {
+	entries := topn._heap._entries
+	buf := NewColumnDataCollection(topn._payloadTypes)
+	for start := 0; start < len(entries); start += util.DefaultVectorSize {
+		end := min(start+util.DefaultVectorSize, len(entries))
+		rows := &chunk.Chunk{}
+		rows.Init(topn._payloadTypes, util.DefaultVectorSize)
+		topn.gather(entries[start:end], rows)
+		for i, ent := range entries[start:end] {
+			ent._pos = start + i
+		}
+		buf.Append(rows)
+	}
+	topn._payload = buf
+}
+
+// gather copies the payload of the entries into the output.
+// the consecutive entries in the same chunk of the buffer
+// are copied together.
+func (topn *TopN) gather(entries []*topNEntry, output *chunk.Chunk) {
+	sel := chunk.NewSelectVector(util.DefaultVectorSize)
+	for i := 0; i < len(entries); {
+		chunkIdx := entries[i]._pos / util.DefaultVectorSize
+		j := i
+		for ; j < len(entries) && entries[j]._pos/util.DefaultVectorSize == chunkIdx; j++ {
+			sel.SetIndex(j-i, entries[j]._pos%util.DefaultVectorSize)
+		}
+		rows := &chunk.Chunk{}
+		rows.Init(topn._payloadTypes, util.DefaultVectorSize)
+		rows.Slice(topn._payload._chunks[chunkIdx], sel, j-i, 0)
+		appendRows(output, rows)
+		i = j
+	}
+}
+
+// appendRows copies the rows of the input to the end of the output.
+func appendRows(output, input *chunk.Chunk) {
+	for i := 0; i < output.ColumnCount(); i++ {
+		var data chunk.UnifiedFormat
+		input.Data[i].ToUnifiedFormat(input.Card(), &data)
+		ColumnDataCopySwitch(
+			&ColumnDataMetaData{_dst: output, _vecIdx: i},
+			&data,
+			input.Data[i],
+			0,
+			input.Card(),
+		)
+	}
+	output.Count += input.Card()
+}
+
+// Finalize sorts the rows in the heap and
+// skips the offset.
+func (topn *TopN) Finalize() {
+	entries := topn._heap._entries
+	sort.SliceStable(entries, func(i, j int) bool {
+		return compareTopNEntry(topn._sortLayout, entries[i], entries[j]) < 0
+	})
+	if uint64(len(entries)) > topn._offset {
+		topn._result = entries[topn._offset:]
+	}
+	topn._heap._entries = nil
+}
+
+func (topn *TopN) GetData(read *chunk.Chunk) SourceResult {
+	if topn._scanOffset >= len(topn._result) {
+		return SrcResDone
+	}
+	cnt := min(len(topn._result)-topn._scanOffset, util.DefaultVectorSize)
+	topn.gather(topn._result[topn._scanOffset:topn._scanOffset+cnt], read)
+	topn._scanOffset += cnt
+	return SrcResHaveMoreOutput
+}
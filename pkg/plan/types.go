@@ -15,6 +15,7 @@
 package plan
 
 import (
+	"fmt"
 	"math"
 )
 
@@ -34,3 +35,26 @@ const (
 	IWC_JOINON
 	IWC_VALUES //for insert ... values
 )
+
+func (iwc InWhichClause) String() string {
+	switch iwc {
+	case IWC_SELECT:
+		return "SELECT"
+	case IWC_WHERE:
+		return "WHERE"
+	case IWC_GROUP:
+		return "GROUP BY"
+	case IWC_HAVING:
+		return "HAVING"
+	case IWC_ORDER:
+		return "ORDER BY"
+	case IWC_LIMIT:
+		return "LIMIT"
+	case IWC_JOINON:
+		return "JOIN conditions"
+	case IWC_VALUES:
+		return "VALUES"
+	default:
+		return fmt.Sprintf("clause %d", int(iwc))
+	}
+}
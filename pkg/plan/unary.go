@@ -15,8 +15,6 @@
 package plan
 
 import (
-	"fmt"
-
 	"github.com/daviszhen/plan/pkg/chunk"
 	"github.com/daviszhen/plan/pkg/util"
 )
@@ -86,10 +84,12 @@ func VectorTryCastOperator[T, R any](
 	idx int,
 	data *UnaryData,
 	op CastOp[T, R]) {
-	ret := op(input, result, false)
+	ret := op(input, result, data._tryCastData._strict)
 	if !ret {
-		err := fmt.Sprintf("VectorTryCastOperator[%d]: operation failed", idx)
-		data._tryCastData._errorMsg = &err
+		//keep the first failed value
+		if data._tryCastData._allConverted {
+			*data._tryCastData._errorMsg = castValueString(input)
+		}
 		data._tryCastData._allConverted = false
 		mask.SetInvalid(uint64(idx))
 	}
@@ -97,7 +97,7 @@ func VectorTryCastOperator[T, R any](
 
 func UnaryFunction[T any, R any](
 	op UnaryOp[T, R]) ScalarFunc {
-	wrapper := &UnaryOperatorWrapper[T, R]{}
+	wrapper := &UnaryOperatorWrapper[T, R]{op: op}
 	temp := func(input *chunk.Chunk, state *ExprState, result *chunk.Vector) {
 		unaryExecStandard[T, R](
 			input.Data[0],
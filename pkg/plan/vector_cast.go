@@ -54,18 +54,20 @@ func TemplatedTryCastLoop[T any, R any](
 	op UnaryOp2[T, R],
 ) bool {
 	input := &VectorTryCastData{
-		_result:   res,
-		_strict:   params._strict,
-		_errorMsg: &params._errorMsg,
+		_result:       res,
+		_strict:       params._strict,
+		_errorMsg:     &params._errorMsg,
+		_allConverted: true,
 	}
 	data := &UnaryData{
 		_tryCastData: input,
 	}
+	//the failed rows are set to NULL
 	unaryGenericExec[T, R](
 		src, res,
 		count,
 		data,
-		false,
+		true,
 		op)
 	return data._tryCastData._allConverted
 }
@@ -0,0 +1,237 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/daviszhen/plan/pkg/util"
+)
+
+// the archived wal segment is named by the iteration of the checkpoint
+// that ends it. the segment N has the entries between the checkpoint N-1
+// and the checkpoint N.
+const WAL_SEGMENT_EXT = ".wal"
+
+var errRecoveryTargetReached = errors.New("recovery target reached")
+
+// RecoveryTarget is the point where the recovery stops.
+// the zero value replays all the archived wal.
+type RecoveryTarget struct {
+	//the commits after it are not replayed.
+	//the commit ids increase across the restarts
+	//when the wal archiving is enabled.
+	CommitId TxnType
+	//the commits after it are not replayed.
+	Time time.Time
+}
+
+func (target *RecoveryTarget) reached(commitId TxnType, ts int64) bool {
+	if target.CommitId != 0 && commitId > target.CommitId {
+		return true
+	}
+	if !target.Time.IsZero() && ts > target.Time.UnixNano() {
+		return true
+	}
+	return false
+}
+
+func walSegmentName(iteration uint64) string {
+	return fmt.Sprintf("%020d%s", iteration, WAL_SEGMENT_EXT)
+}
+
+// ListWalSegments returns the segments in the dir that are archived
+// after the checkpoint iteration in the order of the iteration.
+func ListWalSegments(dir string, iteration uint64) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	iters := make([]uint64, 0)
+	for _, ent := range entries {
+		name := ent.Name()
+		if ent.IsDir() || !strings.HasSuffix(name, WAL_SEGMENT_EXT) {
+			continue
+		}
+		iter, err := strconv.ParseUint(strings.TrimSuffix(name, WAL_SEGMENT_EXT), 10, 64)
+		if err != nil {
+			continue
+		}
+		if iter > iteration {
+			iters = append(iters, iter)
+		}
+	}
+	slices.Sort(iters)
+	segments := make([]string, 0, len(iters))
+	for _, iter := range iters {
+		segments = append(segments, filepath.Join(dir, walSegmentName(iter)))
+	}
+	return segments, nil
+}
+
+// SetWalArchiveDir enables the wal archiving. the checkpoint moves
+// the wal into the dir instead of truncating it.
+func (storage *StorageMgr) SetWalArchiveDir(dir string) {
+	storage._walArchiveDir = dir
+}
+
+// SetRecoveryTarget makes the LoadDatabase replay the archived wal
+// on top of the database file up to the target.
+// the recovered database is checkpointed and starts a new history.
+// the archiving is disabled after the recovery, so it does not mix
+// with the archived segments beyond the target.
+func (storage *StorageMgr) SetRecoveryTarget(target *RecoveryTarget) {
+	storage._recoveryTarget = target
+}
+
+// lastCommitId returns the largest commit id in the wal
+// and the archived segments.
+func (storage *StorageMgr) lastCommitId(walPath string) (TxnType, error) {
+	segments, err := ListWalSegments(storage._walArchiveDir, 0)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	if util.FileIsValid(walPath) {
+		segments = append(segments, walPath)
+	}
+	//the newest segment that has commits has the largest one
+	for i := len(segments) - 1; i >= 0; i-- {
+		last := TxnType(0)
+		_, _, err = scanWal(segments[i],
+			func(walTyp uint8, payload util.Deserialize) error {
+				if walTyp != WAL_FLUSH {
+					return nil
+				}
+				if commitId, _, ok := readCommitInfo(payload); ok {
+					last = max(last, commitId)
+				}
+				return nil
+			})
+		if err != nil {
+			return 0, err
+		}
+		if last != 0 {
+			return last, nil
+		}
+	}
+	return 0, nil
+}
+
+func (storage *StorageMgr) iteration() uint64 {
+	if fBlockMgr, ok := storage._blockMgr.(*FileBlockMgr); ok {
+		return fBlockMgr._iterationCount
+	}
+	return 0
+}
+
+// resetWal empties the wal after the checkpoint.
+func (storage *StorageMgr) resetWal(iteration uint64) error {
+	if len(storage._walArchiveDir) == 0 {
+		return storage._wal.Truncate(0)
+	}
+	err := os.MkdirAll(storage._walArchiveDir, 0755)
+	if err != nil {
+		return err
+	}
+	return storage._wal.Archive(
+		filepath.Join(storage._walArchiveDir, walSegmentName(iteration)))
+}
+
+// recover replays the archived wal and the wal of the database
+// up to the recovery target, then checkpoints the recovered database.
+func (storage *StorageMgr) recover(walPath string) error {
+	if storage._readOnly {
+		return fmt.Errorf("can not recover read only database")
+	}
+	if len(storage._walArchiveDir) == 0 {
+		return fmt.Errorf("no wal archive dir for recovery")
+	}
+	segments, err := ListWalSegments(storage._walArchiveDir, storage.iteration())
+	if err != nil {
+		return err
+	}
+	if util.FileIsValid(walPath) {
+		segments = append(segments, walPath)
+	}
+	err = ReplaySegments(segments, storage._recoveryTarget)
+	if err != nil {
+		return err
+	}
+	storage._recoveryTarget = nil
+	storage._walArchiveDir = ""
+
+	//the entries of the wal have been replayed or are beyond the target
+	storage._wal, err = NewWriteAheadLog(walPath)
+	if err != nil {
+		return err
+	}
+	err = storage._wal.Truncate(0)
+	if err != nil {
+		return err
+	}
+	ckp := NewCheckpointWriter(storage)
+	return ckp.CreateCheckpoint()
+}
+
+// ReplaySegments replays the committed entries of the wal segments in order
+// until the target is reached.
+func ReplaySegments(segments []string, target *RecoveryTarget) error {
+	fmt.Println("Recover...")
+	start := time.Now()
+	defer func() {
+		fmt.Println("Recover done", time.Since(start))
+	}()
+
+	id := 0
+	txn, err := GTxnMgr.NewTxn(fmt.Sprintf("recover-%d", id))
+	if err != nil {
+		return err
+	}
+	state := NewReplayState(nil)
+	for _, path := range segments {
+		validSize, fileSize, err := scanWal(path,
+			func(walTyp uint8, payload util.Deserialize) error {
+				switch walTyp {
+				case WAL_FLUSH:
+					commitId, ts, ok := readCommitInfo(payload)
+					if ok && target.reached(commitId, ts) {
+						return errRecoveryTargetReached
+					}
+					err := GTxnMgr.Commit(txn)
+					if err != nil {
+						return err
+					}
+					id++
+					txn, err = GTxnMgr.NewTxn(fmt.Sprintf("recover-%d", id))
+					return err
+				case WAL_CHECKPOINT:
+					//the data of the checkpoint comes from the replay
+					return nil
+				default:
+					state._source = payload
+					return state.ReplayEntry(txn, walTyp)
+				}
+			})
+		if errors.Is(err, errRecoveryTargetReached) {
+			fmt.Println("recovery target reached in", path)
+			break
+		}
+		if err != nil {
+			GTxnMgr.Rollback(txn)
+			return err
+		}
+		//the segments after the torn one can not be replayed
+		if validSize < fileSize {
+			fmt.Println("stop recovery at the torn segment", path)
+			break
+		}
+	}
+	//the entries after the last flush are not committed
+	GTxnMgr.Rollback(txn)
+	return nil
+}
@@ -0,0 +1,159 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/daviszhen/plan/pkg/chunk"
+	"github.com/daviszhen/plan/pkg/common"
+	"github.com/daviszhen/plan/pkg/util"
+)
+
+// openTestDatabase replaces the global storage with the database at path.
+func openTestDatabase(t *testing.T, path string, archiveDir string, target *RecoveryTarget) {
+	GTxnMgr = NewTxnMgr()
+	GCatalog = NewCatalog()
+	GStorageMgr = nil
+	require.NoError(t, GCatalog.Init())
+	GStorageMgr = NewStorageMgr(path, false)
+	GStorageMgr.SetWalArchiveDir(archiveDir)
+	GStorageMgr.SetRecoveryTarget(target)
+	require.NoError(t, GStorageMgr.LoadDatabase())
+}
+
+// insertTestRows inserts the rows and returns the commit id.
+func insertTestRows(t *testing.T, table *DataTable, start int) TxnType {
+	txn, err := GTxnMgr.NewTxn("insert")
+	require.NoError(t, err)
+	data := &chunk.Chunk{}
+	data.Init(table.GetTypes(), STANDARD_VECTOR_SIZE)
+	vals := chunk.GetSliceInPhyFormatFlat[int32](data.Data[0])
+	for j := 0; j < STANDARD_VECTOR_SIZE; j++ {
+		vals[j] = int32(start + j)
+	}
+	data.SetCard(STANDARD_VECTOR_SIZE)
+	state := &LocalAppendState{}
+	table.InitLocalAppend(txn, state)
+	require.NoError(t, table.LocalAppend(txn, state, data, false))
+	table.FinalizeLocalAppend(txn, state)
+	require.NoError(t, GTxnMgr.Commit(txn))
+	return txn._commitId
+}
+
+func countTestRows(t *testing.T) int {
+	txn, err := GTxnMgr.NewTxn("count")
+	require.NoError(t, err)
+	defer GTxnMgr.Rollback(txn)
+	tabEnt := GCatalog.GetEntry(txn, CatalogTypeTable, "pitr", "t")
+	if tabEnt == nil {
+		return -1
+	}
+	cnt := 0
+	tabEnt.GetStorage()._rowGroups.Scan(txn, func(data *chunk.Chunk) bool {
+		cnt += data.Card()
+		return true
+	})
+	return cnt
+}
+
+func copyTestFile(t *testing.T, src, dst string) {
+	content, err := os.ReadFile(src)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(dst, content, 0755))
+}
+
+func Test_wal_archive_recovery(t *testing.T) {
+	oldTxnMgr, oldCatalog, oldStorageMgr := GTxnMgr, GCatalog, GStorageMgr
+	defer func() {
+		GTxnMgr, GCatalog, GStorageMgr = oldTxnMgr, oldCatalog, oldStorageMgr
+	}()
+
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "db")
+	archiveDir := filepath.Join(dir, "archive")
+	basePath := filepath.Join(dir, "base")
+	openTestDatabase(t, dbPath, archiveDir, nil)
+
+	//1. base backup
+	require.NoError(t, GStorageMgr.CreateCheckpoint(false, true))
+	copyTestFile(t, dbPath, basePath)
+
+	//2. three commits. the checkpoint archives the first two.
+	txn, err := GTxnMgr.NewTxn("create")
+	require.NoError(t, err)
+	_, err = GCatalog.CreateSchema(txn, "pitr")
+	require.NoError(t, err)
+	colDefs := []*ColumnDefinition{{Name: "a", Type: common.IntegerType()}}
+	tabEnt, err := GCatalog.CreateTable(txn, NewDataTableInfo3("pitr", "t", colDefs, nil))
+	require.NoError(t, err)
+	require.NoError(t, GTxnMgr.Commit(txn))
+	table := tabEnt.GetStorage()
+
+	firstId := insertTestRows(t, table, 0)
+	target := time.Now()
+	time.Sleep(time.Millisecond)
+	secondId := insertTestRows(t, table, STANDARD_VECTOR_SIZE)
+	require.NoError(t, GStorageMgr.CreateCheckpoint(false, true))
+	thirdId := insertTestRows(t, table, 2*STANDARD_VECTOR_SIZE)
+	require.Equal(t, 3*STANDARD_VECTOR_SIZE, countTestRows(t))
+	require.Less(t, firstId, secondId)
+	require.Less(t, secondId, thirdId)
+
+	segments, err := ListWalSegments(archiveDir, 0)
+	require.NoError(t, err)
+	require.Len(t, segments, 2)
+
+	//3. recover to the time between the first and the second insert
+	recPath := filepath.Join(dir, "rec1")
+	copyTestFile(t, basePath, recPath)
+	openTestDatabase(t, recPath, archiveDir, &RecoveryTarget{Time: target})
+	require.Equal(t, STANDARD_VECTOR_SIZE, countTestRows(t))
+
+	//the recovered database is checkpointed
+	openTestDatabase(t, recPath, "", nil)
+	require.Equal(t, STANDARD_VECTOR_SIZE, countTestRows(t))
+
+	//4. recover all the archived segments
+	recPath = filepath.Join(dir, "rec2")
+	copyTestFile(t, basePath, recPath)
+	openTestDatabase(t, recPath, archiveDir, &RecoveryTarget{})
+	require.Equal(t, 2*STANDARD_VECTOR_SIZE, countTestRows(t))
+
+	//5. the wal of the database is replayed after the archived segments
+	recPath = filepath.Join(dir, "rec3")
+	copyTestFile(t, basePath, recPath)
+	copyTestFile(t, dbPath+".wal", recPath+".wal")
+	openTestDatabase(t, recPath, archiveDir, &RecoveryTarget{})
+	require.Equal(t, 3*STANDARD_VECTOR_SIZE, countTestRows(t))
+
+	//6. recover to the commit id of the first insert
+	recPath = filepath.Join(dir, "rec4")
+	copyTestFile(t, basePath, recPath)
+	openTestDatabase(t, recPath, archiveDir, &RecoveryTarget{CommitId: firstId})
+	require.Equal(t, STANDARD_VECTOR_SIZE, countTestRows(t))
+
+	//7. the commit ids continue after the restart
+	require.NoError(t, Open(&util.StorageOptions{Path: dbPath, WalArchiveDir: archiveDir}))
+	txn, err = GTxnMgr.NewTxn("table")
+	require.NoError(t, err)
+	tabEnt = GCatalog.GetEntry(txn, CatalogTypeTable, "pitr", "t")
+	GTxnMgr.Rollback(txn)
+	fourthId := insertTestRows(t, tabEnt.GetStorage(), 3*STANDARD_VECTOR_SIZE)
+	require.Less(t, thirdId, fourthId)
+	require.NoError(t, GStorageMgr.CreateCheckpoint(false, true))
+
+	//the commits of the new run are beyond the target
+	recPath = filepath.Join(dir, "rec5")
+	copyTestFile(t, basePath, recPath)
+	require.NoError(t, Open(&util.StorageOptions{
+		Path:                   recPath,
+		WalArchiveDir:          archiveDir,
+		Recover:                true,
+		RecoveryTargetCommitId: uint64(thirdId),
+	}))
+	require.Equal(t, 3*STANDARD_VECTOR_SIZE, countTestRows(t))
+}
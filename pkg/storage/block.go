@@ -223,7 +223,7 @@ const (
 	MAGIC_BYTE_OFFSET uint64 = BLOCK_HEADER_SIZE
 	FLAG_COUNT        uint64 = 4
 	magic                    = "plan"
-	VERSION_NUMBER    uint64 = 1
+	VERSION_NUMBER    uint64 = 2
 )
 
 type MainHeader struct {
@@ -262,7 +262,11 @@ func (header *MainHeader) Serialize(serial util.Serialize) error {
 }
 
 func (header *MainHeader) Deserialize(deserial util.Deserialize) error {
-	err := util.Read[uint64](&header._versionNumber, deserial)
+	err := deserial.ReadData(header._magicByte[:], int(MAGIC_BYTE_SIZE))
+	if err != nil {
+		return err
+	}
+	err = util.Read[uint64](&header._versionNumber, deserial)
 	if err != nil {
 		return err
 	}
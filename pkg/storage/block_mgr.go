@@ -5,6 +5,8 @@ import (
 	"os"
 	"sync"
 
+	"github.com/tidwall/btree"
+
 	"github.com/daviszhen/plan/pkg/util"
 )
 
@@ -194,7 +196,7 @@ type FileBlockMgr struct {
 	_readOnly       bool
 	_handle         *os.File
 	_headerBuffer   *FileBuffer
-	_freeList       map[BlockID]bool
+	_freeList       btree.Set[BlockID] //sorted. the smallest one is allocated first
 	_multiUseBlocks map[BlockID]uint32
 	_modifiedBlocks map[BlockID]bool
 	_metaBlock      BlockID
@@ -213,7 +215,6 @@ func NewFileBlockMgr(
 		_blocks:         make(map[BlockID]*BlockHandle),
 		_metaBlocks:     make(map[BlockID]*BlockHandle),
 		_path:           path,
-		_freeList:       make(map[BlockID]bool),
 		_multiUseBlocks: make(map[BlockID]uint32),
 		_modifiedBlocks: make(map[BlockID]bool),
 	}
@@ -424,10 +425,15 @@ func (mgr *FileBlockMgr) LoadExistingDatabase() error {
 	if err != nil {
 		return err
 	}
-	_, err = DeserializeMainHeader(mgr._headerBuffer)
+	mainHeader, err := DeserializeMainHeader(mgr._headerBuffer)
 	if err != nil {
 		return err
 	}
+	if string(mainHeader._magicByte[:]) != magic ||
+		mainHeader._versionNumber != VERSION_NUMBER {
+		return fmt.Errorf("database file %s has version %d. version %d expected",
+			mgr._path, mainHeader._versionNumber, VERSION_NUMBER)
+	}
 
 	//2. read database header
 	var h1, h2 DatabaseHeader
@@ -485,14 +491,14 @@ func (mgr *FileBlockMgr) LoadFreeList() error {
 	if err != nil {
 		return err
 	}
-	mgr._freeList = make(map[BlockID]bool)
+	mgr._freeList.Clear()
 	for i := uint64(0); i < freeListCount; i++ {
 		var id BlockID
 		err = util.Read[BlockID](&id, reader)
 		if err != nil {
 			return err
 		}
-		mgr._freeList[id] = true
+		mgr._freeList.Insert(id)
 	}
 	var multiUseBlocksCount uint64
 	err = util.Read[uint64](&multiUseBlocksCount, reader)
@@ -520,10 +526,14 @@ func (mgr *FileBlockMgr) WriteHeader(header *DatabaseHeader) error {
 	mgr._iterationCount++
 	header._iteration = mgr._iterationCount
 
+	//the blocks that are free in the previous header
+	//can be cut off the end of the file.
+	truncated := mgr.TruncateFreeBlocks()
+
 	freeListBlocks := mgr.GetFreeListBlocks()
 
 	for id := range mgr._modifiedBlocks {
-		mgr._freeList[id] = true
+		mgr._freeList.Insert(id)
 	}
 	mgr._modifiedBlocks = make(map[BlockID]bool)
 
@@ -535,16 +545,17 @@ func (mgr *FileBlockMgr) WriteHeader(header *DatabaseHeader) error {
 			mgr._modifiedBlocks[blockId] = true
 		}
 		err := util.Write[uint64](
-			uint64(len(mgr._freeList)),
+			uint64(mgr._freeList.Len()),
 			writer)
 		if err != nil {
 			return err
 		}
-		for blockId := range mgr._freeList {
+		mgr._freeList.Scan(func(blockId BlockID) bool {
 			err = util.Write[BlockID](blockId, writer)
-			if err != nil {
-				return err
-			}
+			return err == nil
+		})
+		if err != nil {
+			return err
 		}
 		err = util.Write[uint64](
 			uint64(len(mgr._multiUseBlocks)),
@@ -592,16 +603,44 @@ func (mgr *FileBlockMgr) WriteHeader(header *DatabaseHeader) error {
 		return err
 	}
 	mgr._activeHeader = (mgr._activeHeader + 1) % 2
-	return mgr._handle.Sync()
+	err = mgr._handle.Sync()
+	if err != nil {
+		return err
+	}
+	if truncated {
+		//the new header does not refer to the blocks beyond the max block
+		return mgr._handle.Truncate(
+			int64(BLOCK_START + uint64(mgr._maxBlock)*BLOCK_ALLOC_SIZE))
+	}
+	return nil
+}
+
+// TruncateFreeBlocks removes the free blocks at the tail of the file
+// from the free list and decreases the max block.
+// it reports whether the file can be shrunk.
+func (mgr *FileBlockMgr) TruncateFreeBlocks() bool {
+	mgr._blockLock.Lock()
+	defer mgr._blockLock.Unlock()
+	truncated := false
+	for {
+		last, ok := mgr._freeList.Max()
+		if !ok || last != mgr._maxBlock-1 {
+			break
+		}
+		mgr._freeList.Delete(last)
+		mgr._maxBlock--
+		truncated = true
+	}
+	return truncated
 }
 
 func (mgr *FileBlockMgr) GetFreeListBlocks() []BlockID {
 	freeListBlocks := make([]BlockID, 0)
 
-	if len(mgr._freeList) != 0 ||
+	if mgr._freeList.Len() != 0 ||
 		len(mgr._multiUseBlocks) != 0 ||
 		len(mgr._modifiedBlocks) != 0 {
-		freeListSize := 8 + 8*(len(mgr._freeList)+len(mgr._modifiedBlocks))
+		freeListSize := 8 + 8*(mgr._freeList.Len()+len(mgr._modifiedBlocks))
 		multiUseBlocksSize := 8 + (8+4)*len(mgr._multiUseBlocks)
 		totalSize := freeListSize + multiUseBlocksSize
 		spaceInBlock := int(BLOCK_SIZE - 4*8)
@@ -617,14 +656,9 @@ func (mgr *FileBlockMgr) GetFreeListBlocks() []BlockID {
 func (mgr *FileBlockMgr) GetFreeBlockId() BlockID {
 	mgr._blockLock.Lock()
 	defer mgr._blockLock.Unlock()
-	block := BlockID(0)
-	if len(mgr._freeList) != 0 {
-		for id := range mgr._freeList {
-			block = id
-			break
-		}
-		delete(mgr._freeList, block)
-	} else {
+	//the smallest one keeps the tail of the file free
+	block, ok := mgr._freeList.PopMin()
+	if !ok {
 		block = mgr._maxBlock
 		mgr._maxBlock++
 	}
@@ -652,7 +686,7 @@ func (mgr *FileBlockMgr) MarkBlockAsModified(id BlockID) {
 		}
 		return
 	}
-	if _, ok := mgr._freeList[id]; ok {
+	if mgr._freeList.Contains(id) {
 		panic(fmt.Sprintf("%d should not be in free list", id))
 	}
 	mgr._modifiedBlocks[id] = true
@@ -665,11 +699,11 @@ func (mgr *FileBlockMgr) IsRootBlock(root BlockID) bool {
 func (mgr *FileBlockMgr) MarkBlockAsFree(id BlockID) {
 	mgr._blockLock.Lock()
 	defer mgr._blockLock.Unlock()
-	if _, ok := mgr._freeList[id]; ok {
+	if mgr._freeList.Contains(id) {
 		panic(fmt.Sprintf("%d already in free list", id))
 	}
 	delete(mgr._multiUseBlocks, id)
-	mgr._freeList[id] = true
+	mgr._freeList.Insert(id)
 }
 
 func (mgr *FileBlockMgr) IncreaseBlockReferenceCount(id BlockID) {
@@ -695,7 +729,7 @@ func (mgr *FileBlockMgr) TotalBlocks() uint64 {
 func (mgr *FileBlockMgr) FreeBlocks() uint64 {
 	mgr._blockLock.Lock()
 	defer mgr._blockLock.Unlock()
-	return uint64(len(mgr._freeList))
+	return uint64(mgr._freeList.Len())
 }
 
 type PartialBlockState struct {
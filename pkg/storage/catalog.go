@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"slices"
 	"strings"
 	"sync"
 	"sync/atomic"
@@ -87,6 +88,10 @@ func (cat *Catalog) ScanSchemas(fun func(ent *CatalogEntry)) {
 	cat._schemas.Scan(fun)
 }
 
+func (cat *Catalog) ScanSchemasWithTxn(txn *Txn, fun func(ent *CatalogEntry)) {
+	cat._schemas.ScanWithTxn(txn, fun)
+}
+
 func (cat *Catalog) createSchemaInternal(txn *Txn, schemas []string) error {
 	var err error
 	for _, schema := range schemas {
@@ -426,6 +431,23 @@ func (set *CatalogSet) Scan(fun func(ent *CatalogEntry)) {
 	}
 }
 
+// ScanWithTxn scans the entries visible to the txn in creation order
+func (set *CatalogSet) ScanWithTxn(txn *Txn, fun func(ent *CatalogEntry)) {
+	set._catalogLock.Lock()
+	defer set._catalogLock.Unlock()
+	ids := make([]IdxType, 0, len(set._entries))
+	for id := range set._entries {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+	for _, id := range ids {
+		cur := set.GetEntryForTxn(txn, set._entries[id]._entry)
+		if !cur._deleted {
+			fun(cur)
+		}
+	}
+}
+
 func (set *CatalogSet) GetCommittedEntry(ent *CatalogEntry) *CatalogEntry {
 	cur := ent
 	for cur._child != nil {
@@ -466,6 +488,10 @@ type CatalogEntry struct {
 	_constraints []Constraint
 }
 
+func (ent *CatalogEntry) GetName() string {
+	return ent._name
+}
+
 func (ent *CatalogEntry) GetStorage() *DataTable {
 	return ent._storage
 }
@@ -624,6 +650,11 @@ func (ent *CatalogEntry) Scan(typ uint8, fun func(ent *CatalogEntry)) {
 	set.Scan(fun)
 }
 
+func (ent *CatalogEntry) ScanWithTxn(txn *Txn, typ uint8, fun func(ent *CatalogEntry)) {
+	set := ent.GetCatalogSet(typ)
+	set.ScanWithTxn(txn, fun)
+}
+
 func (ent *CatalogEntry) GetEntry(
 	txn *Txn,
 	typ uint8,
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/daviszhen/plan/pkg/common"
+)
+
+// CreateSchemaSQL returns the ddl that recreates the schema entry.
+func (ent *CatalogEntry) CreateSchemaSQL() string {
+	return fmt.Sprintf("CREATE SCHEMA IF NOT EXISTS %s;", QuoteIdent(ent._name))
+}
+
+// CreateTableSQL returns the ddl that recreates the table entry
+// with its columns and constraints.
+func (ent *CatalogEntry) CreateTableSQL() (string, error) {
+	notNull := make(map[int]bool)
+	tableCons := make([]string, 0)
+	for _, cons := range ent._constraints {
+		switch cons._typ {
+		case ConstraintTypeNotNull:
+			notNull[cons._notNullIndex] = true
+		case ConstraintTypeUnique:
+			names := cons._uniqueNames
+			if len(names) == 0 {
+				names = []string{ent._colDefs[cons._uniqueIndex].Name}
+			}
+			quoted := make([]string, 0, len(names))
+			for _, name := range names {
+				quoted = append(quoted, QuoteIdent(name))
+			}
+			kind := "UNIQUE"
+			if cons._isPrimaryKey {
+				kind = "PRIMARY KEY"
+			}
+			tableCons = append(tableCons,
+				fmt.Sprintf("%s (%s)", kind, strings.Join(quoted, ", ")))
+		default:
+			return "", fmt.Errorf("unsupport constraint type %d in export", cons._typ)
+		}
+	}
+
+	elems := make([]string, 0, len(ent._colDefs)+len(tableCons))
+	for i, colDef := range ent._colDefs {
+		typName, err := SQLTypeName(colDef.Type)
+		if err != nil {
+			return "", err
+		}
+		elem := fmt.Sprintf("%s %s", QuoteIdent(colDef.Name), typName)
+		if notNull[i] {
+			elem += " NOT NULL"
+		}
+		elems = append(elems, elem)
+	}
+	elems = append(elems, tableCons...)
+	return fmt.Sprintf("CREATE TABLE %s.%s (\n\t%s\n);",
+		QuoteIdent(ent._schName),
+		QuoteIdent(ent._name),
+		strings.Join(elems, ",\n\t"),
+	), nil
+}
+
+// SQLTypeName returns the type name in the ddl.
+func SQLTypeName(typ common.LType) (string, error) {
+	switch typ.Id {
+	case common.LTID_BOOLEAN:
+		return "boolean", nil
+	case common.LTID_SMALLINT:
+		return "smallint", nil
+	case common.LTID_INTEGER:
+		return "integer", nil
+	case common.LTID_BIGINT:
+		return "bigint", nil
+	case common.LTID_FLOAT:
+		return "real", nil
+	case common.LTID_DOUBLE:
+		return "double precision", nil
+	case common.LTID_VARCHAR:
+		if typ.Width > 0 {
+			return fmt.Sprintf("varchar(%d)", typ.Width), nil
+		}
+		return "varchar", nil
+	case common.LTID_DECIMAL:
+		return fmt.Sprintf("decimal(%d,%d)", typ.Width, typ.Scale), nil
+	case common.LTID_DATE:
+		return "date", nil
+	case common.LTID_TIME:
+		return "time", nil
+	case common.LTID_TIMESTAMP:
+		return "timestamp", nil
+	case common.LTID_TIMESTAMP_TZ:
+		return "timestamptz", nil
+	case common.LTID_INTERVAL:
+		return "interval", nil
+	case common.LTID_BLOB:
+		return "bytea", nil
+	case common.LTID_UUID:
+		return "uuid", nil
+	default:
+		return "", fmt.Errorf("unsupport type %s in ddl", typ)
+	}
+}
+
+// QuoteIdent quotes the identifier to keep its case.
+func QuoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
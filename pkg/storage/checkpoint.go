@@ -85,7 +85,7 @@ func (ckpWriter *CheckpointWriter) CreateCheckpoint() error {
 		return err
 	}
 
-	err = wal.Truncate(0)
+	err = ckpWriter._storage.resetWal(header._iteration)
 	if err != nil {
 		return err
 	}
@@ -163,6 +163,7 @@ type ColumnCheckpointState struct {
 	_dataPointers    []*DataPointer
 	_partialBlockMgr *PartialBlockMgr
 	_globalStats     BaseStats
+	_validityState   *ColumnCheckpointState
 }
 
 func (state *ColumnCheckpointState) GetStats() *BaseStats {
@@ -274,7 +275,6 @@ func (ckp *ColumnDataCheckpointer) Checkpoint(
 }
 
 func (ckp *ColumnDataCheckpointer) WriteToDisk() error {
-	//FIXME: check persistent segment
 	compress := ckp._compressFuncs[0]
 
 	state := compress._initCompress(ckp)
@@ -286,6 +286,14 @@ func (ckp *ColumnDataCheckpointer) WriteToDisk() error {
 		return err
 	}
 	compress._compressFinalize(state)
+	//every checkpoint rewrites all the segments of the column.
+	//the blocks of the old persistent segments are not referenced
+	//any more and are freed after the checkpoint. otherwise,
+	//every checkpoint leaks them.
+	for _, node := range ckp._nodes {
+		seg := node._node.(*ColumnSegment)
+		seg.CommitDropSegment(ckp._state._partialBlockMgr._blockMgr)
+	}
 	ckp._nodes = nil
 	return nil
 }
@@ -120,6 +120,7 @@ func (column *ColumnData) AppendData(
 	vdata *chunk.UnifiedFormat,
 	cnt IdxType) {
 	offset := IdxType(0)
+	total := cnt
 	column._count += cnt
 	for {
 		copied := state._current.Append(state, vdata, offset, cnt)
@@ -140,7 +141,7 @@ func (column *ColumnData) AppendData(
 		cnt -= copied
 	}
 	if column._cdType == ColumnDataTypeStandard {
-		column._validity.AppendData(&column._validity._stats._stats, state._childAppends[0], vdata, cnt)
+		column._validity.AppendData(&column._validity._stats._stats, state._childAppends[0], vdata, total)
 	}
 }
 
@@ -156,6 +157,9 @@ func (column *ColumnData) SetStart(newStart IdxType) {
 		offset += IdxType(seg.Count())
 	}
 	column._data.Reinitialize(lock)
+	if column._cdType == ColumnDataTypeStandard {
+		column._validity.SetStart(newStart)
+	}
 }
 
 func (column *ColumnData) InitScan(state *ColumnScanState) {
@@ -170,6 +174,9 @@ func (column *ColumnData) InitScan(state *ColumnScanState) {
 	state._version = column._version
 	state._scanState = nil
 	state._lastOffset = 0
+	if column._cdType == ColumnDataTypeStandard && len(state._childStates) != 0 {
+		column._validity.InitScan(&state._childStates[0])
+	}
 }
 
 func (column *ColumnData) Skip(
@@ -286,7 +293,13 @@ func (column *ColumnData) ScanVector2(
 		}
 	}
 	state._internalIdx = state._rowIdx
-	return initialRemaining - remaining
+	scanned := initialRemaining - remaining
+	if column._cdType == ColumnDataTypeStandard && len(state._childStates) != 0 {
+		//the validity has the same rows as the data
+		result.Mask = &util.Bitmap{}
+		column._validity.ScanVector2(&state._childStates[0], result, scanned)
+	}
+	return scanned
 }
 
 func (column *ColumnData) InitScanWithOffset(
@@ -300,6 +313,9 @@ func (column *ColumnData) InitScanWithOffset(
 	state._version = column._version
 	state._scanState = nil
 	state._lastOffset = 0
+	if column._cdType == ColumnDataTypeStandard && len(state._childStates) != 0 {
+		column._validity.InitScanWithOffset(&state._childStates[0], rowIdx)
+	}
 }
 
 func (column *ColumnData) GetSegment(rowNumber IdxType) *ColumnSegment {
@@ -374,6 +390,9 @@ func (column *ColumnData) RevertAppend(startRow IdxType) {
 	column._count = startRow - column._start
 	seg.SetNext(ColumnSegment{})
 	seg.RevertAppend(startRow)
+	if column._cdType == ColumnDataTypeStandard {
+		column._validity.RevertAppend(startRow)
+	}
 }
 
 func (column *ColumnData) FilterScanCommitted(
@@ -410,6 +429,14 @@ func (column *ColumnData) Checkpoint(
 	mgr *PartialBlockMgr) (*ColumnCheckpointState, error) {
 	state := column.CreateCheckpointState(rg, mgr)
 	state._globalStats = NewEmptyBaseStats(column._typ)
+	if column._cdType == ColumnDataTypeStandard {
+		validityState, err := column._validity.Checkpoint(rg, mgr)
+		if err != nil {
+			return nil, err
+		}
+		state._validityState = validityState
+		state._globalStats.Merge(validityState.GetStats())
+	}
 
 	lock := column._data.Lock()
 	defer lock.Unlock()
@@ -517,6 +544,9 @@ func (column *ColumnData) DeserializeColumn(
 		)
 		column._data.AppendSegment(nil, seg)
 	}
+	if column._cdType == ColumnDataTypeStandard {
+		return column._validity.DeserializeColumn(src)
+	}
 	return nil
 }
 
@@ -1663,6 +1693,18 @@ func (segment *ColumnSegment) ConvertToPersistent(
 	}
 }
 
+// CommitDropSegment marks the block of the persistent segment as modified.
+// the block becomes free after the next database header is written.
+func (segment *ColumnSegment) CommitDropSegment(blkMgr BlockMgr) {
+	if segment._segType != SegmentTypePersistent ||
+		segment._blockId == -1 {
+		return
+	}
+	blkMgr.MarkBlockAsModified(segment._blockId)
+	//the block id may be reused by the new data
+	blkMgr.UnregisterBlock(segment._blockId, false)
+}
+
 type ColumnAppendState struct {
 	_current      *ColumnSegment
 	_childAppends []*ColumnAppendState
@@ -157,7 +157,7 @@ func GetUncompressedCompressFunction(
 	var cfun *CompressFunction
 	switch typ {
 	case common.INT32, common.INT64, common.UINT64,
-		common.BIT, common.DECIMAL, common.DATE:
+		common.DECIMAL, common.DATE:
 		cfun = &CompressFunction{
 			_typ:              CompressTypeUncompressed,
 			_dataType:         typ,
@@ -172,6 +172,21 @@ func GetUncompressedCompressFunction(
 			_compress:         Compress,
 			_compressFinalize: FinalizeCompress,
 		}
+	case common.BIT:
+		cfun = &CompressFunction{
+			_typ:              CompressTypeUncompressed,
+			_dataType:         typ,
+			_initAppend:       FixedSizeInitAppend,
+			_append:           ValidityAppend,
+			_finalizeAppend:   FixedSizeFinalizeAppend,
+			_initScan:         FixedSizeInitScan,
+			_scanVector:       ValidityScan,
+			_scanPartial:      ValidityScanPartial,
+			_skip:             EmptySkip,
+			_initCompress:     InitCompress,
+			_compress:         Compress,
+			_compressFinalize: FinalizeCompress,
+		}
 	case common.VARCHAR:
 		cfun = &CompressFunction{
 			_typ:              CompressTypeUncompressed,
@@ -482,6 +497,65 @@ func FixedSizeScanPartial(
 		srcSlice[:scanCount*IdxType(pTyp.Size())])
 }
 
+// ValidityAppend saves the validity of the rows.
+// one byte for a row. false for the null.
+func ValidityAppend(
+	state *CompressAppendState,
+	segment *ColumnSegment,
+	stats *SegmentStats,
+	data *chunk.UnifiedFormat,
+	offset IdxType,
+	count IdxType,
+) IdxType {
+	target := util.PointerAdd(
+		state._handle.Ptr(),
+		int(segment.Count())*common.BoolSize)
+	maxTupleCount := segment._segmentSize / IdxType(common.BoolSize)
+	copyCount := min(count, maxTupleCount-IdxType(segment.Count()))
+	dstSlice := util.PointerToSlice[bool](target, int(copyCount))
+	for i := IdxType(0); i < copyCount; i++ {
+		srcIdx := data.Sel.GetIndex(int(offset + i))
+		dstSlice[i] = data.Mask.RowIsValid(uint64(srcIdx))
+		if dstSlice[i] {
+			stats._stats.Set(StatsInfoCanHaveValidValues)
+		} else {
+			stats._stats.Set(StatsInfoCanHaveNullValues)
+		}
+	}
+	segment.AddCount(uint64(copyCount))
+	return copyCount
+}
+
+// ValidityScan fills the mask of the result.
+// the data of the result is unchanged.
+func ValidityScan(
+	segment *ColumnSegment,
+	state *ColumnScanState,
+	scanCount IdxType,
+	result *chunk.Vector,
+) {
+	//the mask may be shared with other vectors
+	result.Mask = &util.Bitmap{}
+	ValidityScanPartial(segment, state, scanCount, result, 0)
+}
+
+func ValidityScanPartial(
+	segment *ColumnSegment,
+	state *ColumnScanState,
+	scanCount IdxType,
+	result *chunk.Vector,
+	resultOffset IdxType,
+) {
+	start := segment.GetRelativeIndex(state._rowIdx)
+	srcPtr := util.PointerAdd(
+		state._scanState._handle.Ptr(),
+		int(segment.GetBlockOffset()+start*IdxType(common.BoolSize)))
+	srcSlice := util.PointerToSlice[bool](srcPtr, int(scanCount))
+	for i := IdxType(0); i < scanCount; i++ {
+		result.Mask.Set(uint64(resultOffset+i), srcSlice[i])
+	}
+}
+
 func EmptySkip(
 	segment *ColumnSegment,
 	state *ColumnScanState,
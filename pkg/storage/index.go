@@ -327,7 +327,7 @@ func (idx *Index) Insert(
 			//rowId := rowIdsSlice[i]
 			idx._btree.Delete(keys[i])
 		}
-		return fmt.Errorf("duplicate key")
+		return util.NewSQLError(util.SQLStateUniqueViolation, "duplicate key")
 	}
 
 	return nil
@@ -587,11 +587,38 @@ func (idx *Index) Delete2(
 	return nil
 }
 
+// Vacuum removes all keys from the index.
 func (idx *Index) Vacuum() {
 	state := &IndexLock{}
 	idx.InitLock(state)
 	defer state._indexLock.Unlock()
-	//do nothing
+	idx._btree.Scan(func(item *IndexKey) bool {
+		util.CFree(item._data)
+		return true
+	})
+	idx._btree.Clear()
+}
+
+// NewEmpty returns an empty index on the same columns.
+func (idx *Index) NewEmpty() *Index {
+	return NewIndex(
+		idx._typ,
+		idx._blockMgr,
+		idx._columnIds,
+		idx._logicalTypes,
+		idx._constraintType,
+		nil,
+	)
+}
+
+// ReplaceKeys takes the keys of the other index and frees the
+// keys of its own. The other index is empty after that.
+func (idx *Index) ReplaceKeys(other *Index) {
+	state := &IndexLock{}
+	idx.InitLock(state)
+	idx._btree, other._btree = other._btree, idx._btree
+	state._indexLock.Unlock()
+	other.Vacuum()
 }
 
 func (idx *Index) IsUnique() bool {
@@ -0,0 +1,15 @@
+package storage
+
+import (
+	"os"
+	"testing"
+
+	"github.com/daviszhen/plan/pkg/util"
+)
+
+func TestMain(m *testing.M) {
+	if err := Open(&util.StorageOptions{}); err != nil {
+		panic(err)
+	}
+	os.Exit(m.Run())
+}
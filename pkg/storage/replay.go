@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"os"
 	"time"
 
 	"github.com/daviszhen/plan/pkg/chunk"
@@ -42,9 +43,8 @@ func (state *ReplayState) ReplayEntry(txn *Txn, walTyp uint8) error {
 	case WAL_CHECKPOINT:
 		return state.replayCheckpoint(txn)
 	default:
-		panic("usp")
+		return fmt.Errorf("unknown wal type %d", walTyp)
 	}
-	return nil
 }
 
 func (state *ReplayState) replayUseTable(txn *Txn) error {
@@ -194,17 +194,56 @@ func (state *ReplayState) replayCreateTable(txn *Txn) error {
 	return err
 }
 
-func Replay(path string) (bool, error) {
+// scanWal calls fn for every intact record of the wal at path. It stops
+// at the clean end of the log or at the first torn or corrupted record,
+// fails on the wal in an unknown format, and returns the end offset of
+// the last intact record along with the size of the file.
+func scanWal(
+	path string,
+	fn func(walTyp uint8, payload util.Deserialize) error,
+) (int64, int64, error) {
+	reader, err := NewBufferedFileReader(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer reader.Close()
+	err = reader.ReadHeader()
+	for err == nil {
+		var payload []byte
+		payload, err = reader.ReadRecord()
+		if err != nil {
+			break
+		}
+		deserial := NewBufferedDeserializer(payload)
+		walTyp := uint8(0)
+		err = util.Read[uint8](&walTyp, deserial)
+		if err != nil {
+			return 0, 0, err
+		}
+		err = fn(walTyp, deserial)
+		if err != nil {
+			return 0, 0, err
+		}
+	}
+	if errors.Is(err, io.EOF) ||
+		errors.Is(err, errWalTornRecord) ||
+		errors.Is(err, errWalCorruptRecord) {
+		if !errors.Is(err, io.EOF) {
+			fmt.Println("stop replay at", reader.Offset(), err)
+		}
+		return reader.Offset(), reader._size, nil
+	}
+	return 0, 0, err
+}
+
+// Replay replays the committed entries of the wal.
+// The wal is kept unchanged if the database is read only.
+func Replay(path string, readOnly bool) (bool, error) {
 	fmt.Println("Replay...")
 	start := time.Now()
 	defer func() {
 		fmt.Println("Replay done", time.Since(start))
 	}()
-	reader, err := NewBufferedFileReader(path)
-	if err != nil {
-		return false, err
-	}
-	defer reader.Close()
 
 	id := 0
 	txn, err := GTxnMgr.NewTxn(fmt.Sprintf("replay-%d", id))
@@ -213,28 +252,19 @@ func Replay(path string) (bool, error) {
 	}
 
 	//1. find checkpoint
-	ckpState := NewReplayState(reader)
+	ckpState := NewReplayState(nil)
 	ckpState._deserializeOnly = true
-	cnt := 0
-	for {
-		walTyp := uint8(0)
-		err = util.Read[uint8](&walTyp, reader)
-		//fmt.Println("find ckp stage", cnt, walTyp, walType(walTyp), err)
-		cnt++
-		if err != nil {
-			if errors.Is(err, io.EOF) {
-				//done
-				err = nil
-				break
-			}
-		}
-		if walTyp == WAL_FLUSH {
-		} else {
-			err = ckpState.ReplayEntry(txn, walTyp)
-			if err != nil {
-				return false, err
+	validSize, fileSize, err := scanWal(path,
+		func(walTyp uint8, payload util.Deserialize) error {
+			if walTyp == WAL_FLUSH {
+				return nil
 			}
-		}
+			ckpState._source = payload
+			return ckpState.ReplayEntry(txn, walTyp)
+		})
+	if err != nil {
+		GTxnMgr.Rollback(txn)
+		return false, err
 	}
 	if ckpState._checkpointId != -1 {
 		if GStorageMgr.IsCheckpointClean(ckpState._checkpointId) {
@@ -246,47 +276,37 @@ func Replay(path string) (bool, error) {
 		}
 	}
 
-	reader.Close()
-
-	reader2, err := NewBufferedFileReader(path)
-	if err != nil {
-		return false, err
-	}
-
-	//2. replay wal
-	state := NewReplayState(reader2)
-	defer reader2.Close()
-
-	for {
-		walTyp := uint8(0)
-		err = util.Read[uint8](&walTyp, reader2)
-		//fmt.Println("replay wal", txn.String(), walTyp, walType(walTyp), err)
+	//drop the torn or corrupted tail
+	if validSize < fileSize && !readOnly {
+		fmt.Println("truncate wal from", fileSize, "to", validSize)
+		err = os.Truncate(path, validSize)
 		if err != nil {
-			if errors.Is(err, io.EOF) {
-				//done
-				err = nil
-				GTxnMgr.Rollback(txn)
-				break
-			}
 			return false, err
 		}
-		if walTyp == WAL_FLUSH {
-			err = GTxnMgr.Commit(txn)
-			if err != nil {
-				return false, err
-			}
-			id++
-			txn, err = GTxnMgr.NewTxn(fmt.Sprintf("replay-%d", id))
-			if err != nil {
-				return false, err
+	}
+
+	//2. replay wal
+	state := NewReplayState(nil)
+	_, _, err = scanWal(path,
+		func(walTyp uint8, payload util.Deserialize) error {
+			if walTyp == WAL_FLUSH {
+				err := GTxnMgr.Commit(txn)
+				if err != nil {
+					return err
+				}
+				id++
+				txn, err = GTxnMgr.NewTxn(fmt.Sprintf("replay-%d", id))
+				return err
 			}
-		} else {
 			//replay
-			err = state.ReplayEntry(txn, walTyp)
-			if err != nil {
-				return false, err
-			}
-		}
+			state._source = payload
+			return state.ReplayEntry(txn, walTyp)
+		})
+	if err != nil {
+		GTxnMgr.Rollback(txn)
+		return false, err
 	}
+	//the entries after the last flush are not committed
+	GTxnMgr.Rollback(txn)
 	return false, nil
 }
@@ -992,6 +992,28 @@ func (collect *RowGroupCollection) FinalizeAppend(txn *Txn, state *TableAppendSt
 	state._startRowGroup = nil
 }
 
+// FinalizeCommittedAppend is same as FinalizeAppend except that
+// the appended rows have no version info and are visible to all txns.
+func (collect *RowGroupCollection) FinalizeCommittedAppend(state *TableAppendState) {
+	remaining := state._totalAppendCount
+	rowGroup := state._startRowGroup
+	for remaining > 0 {
+		appendCnt := min(remaining,
+			IdxType(ROW_GROUP_SIZE-rowGroup.Count()))
+		rowGroup.SetCount(rowGroup.Count() + uint64(appendCnt))
+		remaining -= appendCnt
+		next := collect._rowGroups.GetNextSegment(nil, rowGroup)
+		if next != nil {
+			rowGroup = next.(*RowGroup)
+		} else {
+			rowGroup = nil
+		}
+	}
+	collect._totalRows.Add(uint64(state._totalAppendCount))
+	state._totalAppendCount = 0
+	state._startRowGroup = nil
+}
+
 func (collect *RowGroupCollection) MergeStorage(data *RowGroupCollection) {
 	idx := collect._rowStart + IdxType(collect._totalRows.Load())
 	segments := data._rowGroups.MoveSegments(nil)
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"sync"
+	"time"
 
 	"github.com/liyue201/gostl/ds/map"
 
@@ -27,10 +28,38 @@ func init() {
 	if err := GCatalog.Init(); err != nil {
 		panic(err)
 	}
-	GStorageMgr = NewStorageMgr(defaultDbPath, false)
-	if err := GStorageMgr.LoadDatabase(); err != nil {
-		panic(err)
+}
+
+// Open loads the database into the global storage.
+// The database is not loaded on the import of the package. The offline
+// tools like the verifier read the file without replaying or changing it.
+func Open(opts *util.StorageOptions) error {
+	path := opts.Path
+	if len(path) == 0 {
+		path = defaultDbPath
+	}
+	GTxnMgr = NewTxnMgr()
+	GCatalog = NewCatalog()
+	GStorageMgr = nil
+	if err := GCatalog.Init(); err != nil {
+		return err
+	}
+	GStorageMgr = NewStorageMgr(path, opts.ReadOnly)
+	GStorageMgr.SetWalArchiveDir(opts.WalArchiveDir)
+	if opts.Recover {
+		target := &RecoveryTarget{
+			CommitId: TxnType(opts.RecoveryTargetCommitId),
+		}
+		if len(opts.RecoveryTargetTime) != 0 {
+			var err error
+			target.Time, err = time.Parse(time.RFC3339Nano, opts.RecoveryTargetTime)
+			if err != nil {
+				return fmt.Errorf("invalid recovery target time: %w", err)
+			}
+		}
+		GStorageMgr.SetRecoveryTarget(target)
 	}
+	return GStorageMgr.LoadDatabase()
 }
 
 type LocalTableStorage struct {
@@ -395,6 +424,10 @@ type StorageMgr struct {
 	_readOnly bool
 	_wal      *WriteAheadLog
 	_blockMgr BlockMgr
+	//the wal is archived into it on checkpoint if it is not empty
+	_walArchiveDir string
+	//replay the archived wal up to it on loading if it is not nil
+	_recoveryTarget *RecoveryTarget
 }
 
 func NewStorageMgr(path string, readOnly bool) *StorageMgr {
@@ -450,12 +483,24 @@ func (storage *StorageMgr) LoadDatabase() error {
 			return err
 		}
 		storage._blockMgr.ClearMetaBlockHandles()
-		if util.FileIsValid(walPath) {
-			truncateWal, err = Replay(walPath)
+		//the commit ids restart with the process. they continue from
+		//the archived ones to be a recovery target.
+		if len(storage._walArchiveDir) != 0 {
+			lastId, err := storage.lastCommitId(walPath)
 			if err != nil {
 				return err
 			}
+			GTxnMgr.AdvanceCommitId(lastId)
 		}
+		if storage._recoveryTarget == nil && util.FileIsValid(walPath) {
+			truncateWal, err = Replay(walPath, storage._readOnly)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	if storage._recoveryTarget != nil {
+		return storage.recover(walPath)
 	}
 	//init wal
 	if !storage._readOnly {
@@ -464,7 +509,7 @@ func (storage *StorageMgr) LoadDatabase() error {
 			return err
 		}
 		if truncateWal {
-			err = storage._wal.Truncate(0)
+			err = storage.resetWal(storage.iteration())
 			if err != nil {
 				return err
 			}
@@ -498,7 +543,9 @@ func (storage *StorageMgr) AutomaticCheckpoint(estWalBytes uint64) bool {
 }
 
 func (storage *StorageMgr) GenStorageCommitState(txn *Txn, ckp bool) *StorageCommitState {
-	return NewStorageCommitState(storage, ckp)
+	state := NewStorageCommitState(storage, ckp)
+	state._commitId = txn._commitId
+	return state
 }
 
 func (storage *StorageMgr) IsCheckpointClean(id BlockID) bool {
@@ -538,6 +585,7 @@ type StorageCommitState struct {
 	_initWritten IdxType
 	_log         *WriteAheadLog
 	_ckp         bool
+	_commitId    TxnType
 }
 
 func NewStorageCommitState(
@@ -561,7 +609,7 @@ func NewStorageCommitState(
 
 func (state *StorageCommitState) FlushCommit() error {
 	if state._log != nil {
-		err := state._log.Flush()
+		err := state._log.FlushCommit(state._commitId)
 		if err != nil {
 			return err
 		}
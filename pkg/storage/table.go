@@ -768,7 +768,7 @@ func (table *DataTable) VerifyAppendConstraints(
 			return false
 		})
 		if violated {
-			return fmt.Errorf("violate unique")
+			return util.NewSQLError(util.SQLStateUniqueViolation, "violate unique")
 		}
 	}
 
@@ -778,7 +778,7 @@ func (table *DataTable) VerifyAppendConstraints(
 		}
 		if cons._typ == ConstraintTypeNotNull {
 			if chunk.HasNull(data.Data[cons._notNullIndex], data.Card()) {
-				return fmt.Errorf("violate not null")
+				return util.NewSQLError(util.SQLStateNotNullViolation, "violate not null")
 			}
 		}
 	}
@@ -793,7 +793,7 @@ func (table *DataTable) VerifyUpdateConstraints(
 			for i2, colId := range colIds {
 				if colId == IdxType(cons._notNullIndex) {
 					if chunk.HasNull(updates.Data[i2], updates.Card()) {
-						return fmt.Errorf("violate not null")
+						return util.NewSQLError(util.SQLStateNotNullViolation, "violate not null")
 					}
 				}
 			}
@@ -872,8 +872,8 @@ func (state *ColumnScanState) NextInternal(count IdxType) {
 
 func (state *ColumnScanState) Next(count IdxType) {
 	state.NextInternal(count)
-	for _, childState := range state._childStates {
-		childState.Next(count)
+	for i := range state._childStates {
+		state._childStates[i].Next(count)
 	}
 }
 
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"path/filepath"
 	"testing"
 	"time"
 
@@ -888,3 +889,185 @@ func NewInt32ConstVector(v int32, null bool) *chunk.Vector {
 	chunk.SetNullInPhyFormatConst(vec, null)
 	return vec
 }
+
+func Test_null_values(t *testing.T) {
+	oldTxnMgr, oldCatalog, oldStorageMgr := GTxnMgr, GCatalog, GStorageMgr
+	defer func() {
+		GTxnMgr, GCatalog, GStorageMgr = oldTxnMgr, oldCatalog, oldStorageMgr
+	}()
+
+	dbPath := filepath.Join(t.TempDir(), "db")
+	openTestDatabase(t, dbPath, "", nil)
+	txn, err := GTxnMgr.NewTxn("create")
+	require.NoError(t, err)
+	_, err = GCatalog.CreateSchema(txn, "nulls")
+	require.NoError(t, err)
+	colDefs := []*ColumnDefinition{
+		{Name: "a", Type: common.IntegerType()},
+		{Name: "b", Type: common.VarcharType()},
+	}
+	tabEnt, err := GCatalog.CreateTable(txn, NewDataTableInfo3("nulls", "t", colDefs, nil))
+	require.NoError(t, err)
+	require.NoError(t, GTxnMgr.Commit(txn))
+
+	//a is null in every third row. b is null in every fifth row.
+	insert := func(start int) {
+		table := tabEnt.GetStorage()
+		txn, err := GTxnMgr.NewTxn("insert")
+		require.NoError(t, err)
+		data := &chunk.Chunk{}
+		data.Init(table.GetTypes(), STANDARD_VECTOR_SIZE)
+		for j := 0; j < STANDARD_VECTOR_SIZE; j++ {
+			row := start + j
+			data.Data[0].SetValue(j, &chunk.Value{
+				Typ:    common.IntegerType(),
+				I64:    int64(row),
+				IsNull: row%3 == 0,
+			})
+			data.Data[1].SetValue(j, &chunk.Value{
+				Typ:    common.VarcharType(),
+				Str:    fmt.Sprintf("%d", row),
+				IsNull: row%5 == 0,
+			})
+		}
+		data.SetCard(STANDARD_VECTOR_SIZE)
+		state := &LocalAppendState{}
+		table.InitLocalAppend(txn, state)
+		require.NoError(t, table.LocalAppend(txn, state, data, false))
+		table.FinalizeLocalAppend(txn, state)
+		require.NoError(t, GTxnMgr.Commit(txn))
+	}
+	check := func(rows int) {
+		txn, err := GTxnMgr.NewTxn("check")
+		require.NoError(t, err)
+		defer GTxnMgr.Rollback(txn)
+		tabEnt = GCatalog.GetEntry(txn, CatalogTypeTable, "nulls", "t")
+		require.NotNil(t, tabEnt)
+		cnt := 0
+		tabEnt.GetStorage()._rowGroups.Scan(txn, func(data *chunk.Chunk) bool {
+			for j := 0; j < data.Card(); j++ {
+				row := cnt + j
+				a := data.Data[0].GetValue(j)
+				require.Equal(t, row%3 == 0, a.IsNull, "row %d", row)
+				if !a.IsNull {
+					require.Equal(t, int64(row), a.I64)
+				}
+				b := data.Data[1].GetValue(j)
+				require.Equal(t, row%5 == 0, b.IsNull, "row %d", row)
+				if !b.IsNull {
+					require.Equal(t, fmt.Sprintf("%d", row), b.Str)
+				}
+			}
+			cnt += data.Card()
+			return true
+		})
+		require.Equal(t, rows, cnt)
+	}
+
+	insert(0)
+	check(STANDARD_VECTOR_SIZE)
+
+	//checkpoint and restart
+	require.NoError(t, GStorageMgr.CreateCheckpoint(false, true))
+	report, err := VerifyDatabase(dbPath)
+	require.NoError(t, err)
+	require.True(t, report.OK(), report.String())
+	openTestDatabase(t, dbPath, "", nil)
+	check(STANDARD_VECTOR_SIZE)
+
+	//replay the wal
+	insert(STANDARD_VECTOR_SIZE)
+	openTestDatabase(t, dbPath, "", nil)
+	check(2 * STANDARD_VECTOR_SIZE)
+}
+
+func Test_timestamp_values(t *testing.T) {
+	oldTxnMgr, oldCatalog, oldStorageMgr := GTxnMgr, GCatalog, GStorageMgr
+	defer func() {
+		GTxnMgr, GCatalog, GStorageMgr = oldTxnMgr, oldCatalog, oldStorageMgr
+	}()
+
+	dbPath := filepath.Join(t.TempDir(), "db")
+	openTestDatabase(t, dbPath, "", nil)
+	txn, err := GTxnMgr.NewTxn("create")
+	require.NoError(t, err)
+	_, err = GCatalog.CreateSchema(txn, "times")
+	require.NoError(t, err)
+	colDefs := []*ColumnDefinition{
+		{Name: "ts", Type: common.TimestampType()},
+		{Name: "t", Type: common.TimeType()},
+		{Name: "tz", Type: common.TimestampTzType()},
+	}
+	tabEnt, err := GCatalog.CreateTable(txn, NewDataTableInfo3("times", "t", colDefs, nil))
+	require.NoError(t, err)
+	require.NoError(t, GTxnMgr.Commit(txn))
+
+	//the micros do not fit in 32 bits. ts is null in every seventh row.
+	tsOf := func(row int) int64 { return int64(row)*86_400_000_000 - 1 }
+	timeOf := func(row int) int64 { return int64(row) * 3_000_000 % 86_400_000_000 }
+	insert := func(start int) {
+		table := tabEnt.GetStorage()
+		txn, err := GTxnMgr.NewTxn("insert")
+		require.NoError(t, err)
+		data := &chunk.Chunk{}
+		data.Init(table.GetTypes(), STANDARD_VECTOR_SIZE)
+		for j := 0; j < STANDARD_VECTOR_SIZE; j++ {
+			row := start + j
+			data.Data[0].SetValue(j, &chunk.Value{
+				Typ:    common.TimestampType(),
+				I64:    tsOf(row),
+				IsNull: row%7 == 0,
+			})
+			data.Data[1].SetValue(j, &chunk.Value{
+				Typ: common.TimeType(),
+				I64: timeOf(row),
+			})
+			data.Data[2].SetValue(j, &chunk.Value{
+				Typ: common.TimestampTzType(),
+				I64: -tsOf(row),
+			})
+		}
+		data.SetCard(STANDARD_VECTOR_SIZE)
+		state := &LocalAppendState{}
+		table.InitLocalAppend(txn, state)
+		require.NoError(t, table.LocalAppend(txn, state, data, false))
+		table.FinalizeLocalAppend(txn, state)
+		require.NoError(t, GTxnMgr.Commit(txn))
+	}
+	check := func(rows int) {
+		txn, err := GTxnMgr.NewTxn("check")
+		require.NoError(t, err)
+		defer GTxnMgr.Rollback(txn)
+		tabEnt = GCatalog.GetEntry(txn, CatalogTypeTable, "times", "t")
+		require.NotNil(t, tabEnt)
+		cnt := 0
+		tabEnt.GetStorage()._rowGroups.Scan(txn, func(data *chunk.Chunk) bool {
+			for j := 0; j < data.Card(); j++ {
+				row := cnt + j
+				ts := data.Data[0].GetValue(j)
+				require.Equal(t, row%7 == 0, ts.IsNull, "row %d", row)
+				if !ts.IsNull {
+					require.Equal(t, tsOf(row), ts.I64, "row %d", row)
+				}
+				require.Equal(t, timeOf(row), data.Data[1].GetValue(j).I64, "row %d", row)
+				require.Equal(t, -tsOf(row), data.Data[2].GetValue(j).I64, "row %d", row)
+			}
+			cnt += data.Card()
+			return true
+		})
+		require.Equal(t, rows, cnt)
+	}
+
+	insert(0)
+	check(STANDARD_VECTOR_SIZE)
+
+	//checkpoint and restart
+	require.NoError(t, GStorageMgr.CreateCheckpoint(false, true))
+	openTestDatabase(t, dbPath, "", nil)
+	check(STANDARD_VECTOR_SIZE)
+
+	//replay the wal
+	insert(STANDARD_VECTOR_SIZE)
+	openTestDatabase(t, dbPath, "", nil)
+	check(2 * STANDARD_VECTOR_SIZE)
+}
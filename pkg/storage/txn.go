@@ -6,6 +6,7 @@ import (
 	"slices"
 	"sync"
 	"sync/atomic"
+	"time"
 	"unsafe"
 
 	"github.com/daviszhen/plan/pkg/chunk"
@@ -85,6 +86,7 @@ func (txnMgr *TxnMgr) NewTxn(name string) (*Txn, error) {
 		_commitId:   0,
 		_undoBuffer: UndoBuffer{},
 		_storage:    nil,
+		_beginTime:  time.Now().UTC(),
 	}
 	txn._activeQuery.Store(MAXIMUM_QUERY_ID)
 	txn._storage = NewLocalStorage(txn)
@@ -106,6 +108,7 @@ func (txnMgr *TxnMgr) NewTxn2(name string, id, start TxnType) (*Txn, error) {
 		_commitId:   0,
 		_undoBuffer: UndoBuffer{},
 		_storage:    nil,
+		_beginTime:  time.Now().UTC(),
 	}
 	txn._activeQuery.Store(MAXIMUM_QUERY_ID)
 	txn._storage = NewLocalStorage(txn)
@@ -113,6 +116,13 @@ func (txnMgr *TxnMgr) NewTxn2(name string, id, start TxnType) (*Txn, error) {
 	return txn, nil
 }
 
+// AdvanceCommitId makes the next commit id larger than the id.
+func (txnMgr *TxnMgr) AdvanceCommitId(id TxnType) {
+	txnMgr._lock.Lock()
+	defer txnMgr._lock.Unlock()
+	txnMgr._curStartTs = max(txnMgr._curStartTs, id+1)
+}
+
 func (txnMgr *TxnMgr) Checkpoint(txn *Txn, force bool) error {
 	txnMgr._lock.Lock()
 	defer txnMgr._lock.Unlock()
@@ -302,12 +312,19 @@ type Txn struct {
 	_activeQuery atomic.Uint64
 	//when the txn finished. for GC
 	_highestActiveQuery atomic.Uint64
+	//the wall clock time when the txn began.
+	//now() returns it in the txn.
+	_beginTime time.Time
 }
 
 func (txn *Txn) String() string {
 	return fmt.Sprintf("[%s %d : %d %d]", txn._name, txn._id, txn._startTime, txn._commitId)
 }
 
+func (txn *Txn) BeginTime() time.Time {
+	return txn._beginTime
+}
+
 func (txn *Txn) Commit(commitId TxnType, ckp bool) error {
 	txn._commitId = commitId
 
@@ -0,0 +1,198 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/daviszhen/plan/pkg/chunk"
+)
+
+const (
+	//a row group is rewritten when the ratio of its deleted rows reaches it
+	VACUUM_DELETE_RATIO = 0.2
+	//the checkpoint rounds after the tables are compacted.
+	//the first one writes the compacted data at the end of the file,
+	//the second one moves the data to the free blocks in the front,
+	//the third one cuts off the free blocks at the end of the file.
+	VACUUM_CHECKPOINT_ROUNDS = 3
+)
+
+// Vacuum compacts the tables and shrinks the database file.
+// the tables are rewritten without the deleted rows and the database
+// is checkpointed. the row ids are changed by the vacuum, so it can not be
+// run while other txns are active.
+func (txnMgr *TxnMgr) Vacuum(txn *Txn, tables []*DataTable) (IdxType, error) {
+	txnMgr._lock.Lock()
+	defer txnMgr._lock.Unlock()
+	if txnMgr._threadIsCheckpointing {
+		return 0, fmt.Errorf("can not vacuum: another txn is checkpointing")
+	}
+	ckpLock := NewCheckpointLock(txnMgr)
+	ckpLock.Lock()
+	defer ckpLock.Unlock()
+
+	if txn.Changed() {
+		return 0, fmt.Errorf("can not vacuum: txn has local changes")
+	}
+	if !txnMgr.CanCheckpoint(txn) {
+		return 0, fmt.Errorf("can not vacuum: there are other txns")
+	}
+
+	removed := IdxType(0)
+	for _, table := range tables {
+		cnt, err := table.Vacuum(txn)
+		if err != nil {
+			return removed, err
+		}
+		removed += cnt
+	}
+
+	if GStorageMgr == nil {
+		return removed, nil
+	}
+	for i := 0; i < VACUUM_CHECKPOINT_ROUNDS; i++ {
+		err := GStorageMgr.CreateCheckpoint(false, true)
+		if err != nil {
+			return removed, err
+		}
+	}
+	return removed, nil
+}
+
+// Vacuum rewrites the row groups of the table when some of them have
+// too many deleted rows or can be merged.
+// the deleted rows are removed, the indexes are rebuilt with the new
+// row ids and the stats are recomputed.
+// it returns the count of the removed rows.
+func (table *DataTable) Vacuum(txn *Txn) (IdxType, error) {
+	table._appendLock.Lock()
+	defer table._appendLock.Unlock()
+
+	oldRows := table._rowGroups
+	if !oldRows.NeedsVacuum(txn) {
+		return 0, nil
+	}
+
+	newRows := NewRowGroupCollection(
+		table._info,
+		oldRows._blockMgr,
+		oldRows._types,
+		0,
+		0,
+	)
+	newRows.InitializeEmpty()
+
+	//the indexes are rebuilt aside and swapped in on success.
+	//the old ones are kept if the vacuum fails.
+	newIndexes := &TableIndexList{}
+	table._info._indexes.Scan(func(index *Index) bool {
+		newIndexes.AddIndex(index.NewEmpty())
+		return false
+	})
+
+	//the new rows are visible to all txns.
+	//they do not have the version info.
+	var err error
+	var state *TableAppendState
+	oldRows.Scan(txn, func(data *chunk.Chunk) bool {
+		if state == nil {
+			state = &TableAppendState{}
+			newRows.InitAppend(txn, state, 0)
+		}
+		err = AppendToIndexes(newIndexes,
+			data, uint64(state._currentRow))
+		if err != nil {
+			return false
+		}
+		newRows.Append(data, state)
+		return true
+	})
+	if err != nil {
+		newIndexes.Scan(func(index *Index) bool {
+			index.Vacuum()
+			return false
+		})
+		return 0, err
+	}
+	if state != nil {
+		newRows.FinalizeCommittedAppend(state)
+	}
+
+	i := 0
+	table._info._indexes.Scan(func(index *Index) bool {
+		index.ReplaceKeys(newIndexes._indexes[i])
+		i++
+		return false
+	})
+
+	removed := IdxType(oldRows._totalRows.Load() - newRows._totalRows.Load())
+	oldRows.CommitDropRowGroups()
+	table._rowGroups = newRows
+	table._info._card.Store(newRows._totalRows.Load())
+	return removed, nil
+}
+
+// NeedsVacuum reports whether a row group has too many deleted rows
+// or the row groups can be merged into fewer ones.
+func (collect *RowGroupCollection) NeedsVacuum(txn *Txn) bool {
+	lock := collect._rowGroups.Lock()
+	defer lock.Unlock()
+	cnt := collect._rowGroups.GetSegmentCount(lock)
+	totalRows := IdxType(collect._totalRows.Load())
+	if cnt > (totalRows+ROW_GROUP_SIZE-1)/ROW_GROUP_SIZE {
+		return true
+	}
+	for i := IdxType(0); i < cnt; i++ {
+		rg := collect._rowGroups.GetSegmentByIndex(lock, i).(*RowGroup)
+		count := IdxType(rg.Count())
+		deleted := count - rg.VisibleCount(txn)
+		if deleted > 0 &&
+			float64(deleted) >= float64(count)*VACUUM_DELETE_RATIO {
+			return true
+		}
+	}
+	return false
+}
+
+// CommitDropRowGroups releases the blocks of the row groups.
+func (collect *RowGroupCollection) CommitDropRowGroups() {
+	lock := collect._rowGroups.Lock()
+	defer lock.Unlock()
+	cnt := collect._rowGroups.GetSegmentCount(lock)
+	for i := IdxType(0); i < cnt; i++ {
+		rg := collect._rowGroups.GetSegmentByIndex(lock, i).(*RowGroup)
+		rg.CommitDrop()
+	}
+}
+
+// VisibleCount returns the count of the rows that are visible to the txn.
+func (rg *RowGroup) VisibleCount(txn *Txn) IdxType {
+	count := IdxType(rg.Count())
+	sel := chunk.NewSelectVector(STANDARD_VECTOR_SIZE)
+	visible := IdxType(0)
+	for vecIdx := IdxType(0); vecIdx*STANDARD_VECTOR_SIZE < count; vecIdx++ {
+		maxCount := min(IdxType(STANDARD_VECTOR_SIZE),
+			count-vecIdx*STANDARD_VECTOR_SIZE)
+		visible += rg.GetSelVector(txn, vecIdx, sel, maxCount)
+	}
+	return visible
+}
+
+func (rg *RowGroup) CommitDrop() {
+	for i := 0; i < len(rg._collect._types); i++ {
+		rg.GetColumn(i).CommitDropColumn()
+	}
+}
+
+// CommitDropColumn releases the blocks of the persistent segments.
+func (column *ColumnData) CommitDropColumn() {
+	lock := column._data.Lock()
+	defer lock.Unlock()
+	cnt := column._data.GetSegmentCount(lock)
+	for i := IdxType(0); i < cnt; i++ {
+		seg := column._data.GetSegmentByIndex(lock, i).(*ColumnSegment)
+		seg.CommitDropSegment(column._blockMgr)
+	}
+	if column._validity != nil {
+		column._validity.CommitDropColumn()
+	}
+}
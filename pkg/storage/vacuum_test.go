@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/daviszhen/plan/pkg/chunk"
+	"github.com/daviszhen/plan/pkg/common"
+)
+
+func Test_vacuum_table(t *testing.T) {
+	//the txns leaked by the other tests block the vacuum
+	oldTxnMgr, oldCatalog, oldStorageMgr := GTxnMgr, GCatalog, GStorageMgr
+	defer func() {
+		GTxnMgr, GCatalog, GStorageMgr = oldTxnMgr, oldCatalog, oldStorageMgr
+	}()
+	dbPath := filepath.Join(t.TempDir(), "db")
+	openTestDatabase(t, dbPath, "", nil)
+
+	colDefs := []*ColumnDefinition{
+		{
+			Name: "a",
+			Type: common.IntegerType(),
+		},
+		{
+			Name: "b",
+			Type: common.VarcharType(),
+		},
+	}
+	txn, err := GTxnMgr.NewTxn("create")
+	require.NoError(t, err)
+	_, err = GCatalog.CreateSchema(txn, "test")
+	require.NoError(t, err)
+	tabEnt, err := GCatalog.CreateTable(txn, NewDataTableInfo3("test", "vacuum", colDefs,
+		[]*Constraint{NewUniqueIndexConstraint2([]string{"a"}, false)}))
+	require.NoError(t, err)
+	require.NoError(t, GTxnMgr.Commit(txn))
+	table := tabEnt.GetStorage()
+
+	//1. insert. the rows take several blocks
+	const rowCount = 3 * STANDARD_VECTOR_SIZE
+	padding := strings.Repeat("x", 200)
+	txn, err = GTxnMgr.NewTxn("insert")
+	require.NoError(t, err)
+	for i := 0; i < rowCount/STANDARD_VECTOR_SIZE; i++ {
+		data := &chunk.Chunk{}
+		data.Init(table.GetTypes(), STANDARD_VECTOR_SIZE)
+		vals := chunk.GetSliceInPhyFormatFlat[int32](data.Data[0])
+		for j := 0; j < STANDARD_VECTOR_SIZE; j++ {
+			vals[j] = int32(i*STANDARD_VECTOR_SIZE + j)
+			data.Data[1].SetValue(j, &chunk.Value{
+				Typ: common.VarcharType(),
+				Str: fmt.Sprintf("%d%s", vals[j], padding),
+			})
+		}
+		data.SetCard(STANDARD_VECTOR_SIZE)
+		state := &LocalAppendState{}
+		table.InitLocalAppend(txn, state)
+		require.NoError(t, table.LocalAppend(txn, state, data, false))
+		table.FinalizeLocalAppend(txn, state)
+	}
+	require.NoError(t, GTxnMgr.Commit(txn))
+
+	//2. delete the odd values
+	txn, err = GTxnMgr.NewTxn("delete")
+	require.NoError(t, err)
+	scanState := NewTableScanState()
+	table.InitScan(txn, scanState, []IdxType{COLUMN_IDENTIFIER_ROW_ID, 0})
+	deleted := 0
+	for {
+		result := &chunk.Chunk{}
+		result.Init([]common.LType{common.BigintType(), common.IntegerType()}, STANDARD_VECTOR_SIZE)
+		table.Scan(txn, result, scanState)
+		if result.Card() == 0 {
+			break
+		}
+		rowIds := chunk.NewFlatVector(common.BigintType(), STANDARD_VECTOR_SIZE)
+		ids := chunk.GetSliceInPhyFormatFlat[RowType](rowIds)
+		cnt := 0
+		for i := 0; i < result.Card(); i++ {
+			if result.Data[1].GetValue(i).I64%2 == 1 {
+				ids[cnt] = RowType(result.Data[0].GetValue(i).I64)
+				cnt++
+			}
+		}
+		require.Equal(t, IdxType(cnt), table.Delete(txn, rowIds, IdxType(cnt)))
+		deleted += cnt
+	}
+	require.NoError(t, GTxnMgr.Commit(txn))
+
+	//the deleted rows are still in the file.
+	//every checkpoint rewrites the data into the blocks
+	//that are not used by the last one.
+	for i := 0; i < 2; i++ {
+		require.NoError(t, GStorageMgr.CreateCheckpoint(false, true))
+	}
+	sizeBefore := testFileSize(t, dbPath)
+
+	//3. vacuum
+	txn, err = GTxnMgr.NewTxn("vacuum")
+	require.NoError(t, err)
+	removed, err := GTxnMgr.Vacuum(txn, []*DataTable{table})
+	require.NoError(t, err)
+	require.Equal(t, IdxType(deleted), removed)
+	require.Equal(t, uint64(rowCount-deleted), table._rowGroups._totalRows.Load())
+
+	//the rows are compacted
+	expect := int64(0)
+	table._rowGroups.Scan(txn, func(data *chunk.Chunk) bool {
+		for i := 0; i < data.Card(); i++ {
+			require.Equal(t, expect, data.Data[0].GetValue(i).I64)
+			expect += 2
+		}
+		return true
+	})
+	require.Equal(t, int64(rowCount), expect)
+
+	//the index refers to the new row ids
+	table._info._indexes.Scan(func(index *Index) bool {
+		require.Equal(t, rowCount-deleted, index._btree.Len())
+		seen := make(map[uint64]bool)
+		index._btree.Scan(func(item *IndexKey) bool {
+			require.Less(t, item._val, uint64(rowCount-deleted))
+			seen[item._val] = true
+			return true
+		})
+		require.Len(t, seen, rowCount-deleted)
+		return false
+	})
+
+	//nothing to do at the second time
+	removed, err = table.Vacuum(txn)
+	require.NoError(t, err)
+	require.Equal(t, IdxType(0), removed)
+	require.NoError(t, GTxnMgr.Commit(txn))
+
+	sizeAfter := testFileSize(t, dbPath)
+	t.Log("file size", sizeBefore, "->", sizeAfter)
+	require.Less(t, sizeAfter, sizeBefore)
+
+	report, err := VerifyDatabase(dbPath)
+	require.NoError(t, err)
+	t.Log(report.String())
+	require.True(t, report.OK())
+	require.Equal(t, 1, report.Tables)
+	require.Equal(t, uint64(rowCount-deleted), report.IndexKeys)
+}
+
+func testFileSize(t *testing.T, path string) int64 {
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	return info.Size()
+}
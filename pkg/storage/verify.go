@@ -0,0 +1,642 @@
+package storage
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unsafe"
+
+	"github.com/daviszhen/plan/pkg/common"
+	"github.com/daviszhen/plan/pkg/util"
+)
+
+const (
+	blockKindFreeList = "free list"
+	blockKindMeta     = "meta"
+	blockKindData     = "data"
+)
+
+// VerifyReport is the result of an offline check of a database file.
+type VerifyReport struct {
+	Path         string
+	Iteration    uint64
+	TotalBlocks  uint64
+	FreeBlocks   int
+	MetaBlocks   int
+	DataBlocks   int
+	LeakedBlocks []BlockID
+	Schemas      int
+	Tables       int
+	RowGroups    int
+	DataPointers int
+	Indexes      int
+	IndexKeys    uint64
+	Errors       []string
+	Warnings     []string
+}
+
+func (report *VerifyReport) OK() bool {
+	return len(report.Errors) == 0
+}
+
+func (report *VerifyReport) addError(format string, args ...any) {
+	report.Errors = append(report.Errors, fmt.Sprintf(format, args...))
+}
+
+func (report *VerifyReport) addWarning(format string, args ...any) {
+	report.Warnings = append(report.Warnings, fmt.Sprintf(format, args...))
+}
+
+func (report *VerifyReport) String() string {
+	bb := strings.Builder{}
+	bb.WriteString(fmt.Sprintf("database: %s\n", report.Path))
+	bb.WriteString(fmt.Sprintf("iteration: %d\n", report.Iteration))
+	bb.WriteString(fmt.Sprintf("blocks: total %d free %d meta %d data %d leaked %d\n",
+		report.TotalBlocks,
+		report.FreeBlocks,
+		report.MetaBlocks,
+		report.DataBlocks,
+		len(report.LeakedBlocks)))
+	bb.WriteString(fmt.Sprintf("catalog: schemas %d tables %d row groups %d data pointers %d\n",
+		report.Schemas,
+		report.Tables,
+		report.RowGroups,
+		report.DataPointers))
+	bb.WriteString(fmt.Sprintf("indexes: %d keys %d\n", report.Indexes, report.IndexKeys))
+	if len(report.LeakedBlocks) != 0 {
+		bb.WriteString(fmt.Sprintf("leaked blocks: %v\n", report.LeakedBlocks))
+	}
+	for _, w := range report.Warnings {
+		bb.WriteString("warning: ")
+		bb.WriteString(w)
+		bb.WriteByte('\n')
+	}
+	for _, e := range report.Errors {
+		bb.WriteString("error: ")
+		bb.WriteString(e)
+		bb.WriteByte('\n')
+	}
+	if report.OK() {
+		bb.WriteString("result: ok\n")
+	} else {
+		bb.WriteString(fmt.Sprintf("result: %d errors\n", len(report.Errors)))
+	}
+	return bb.String()
+}
+
+// VerifyDatabase checks the database file at path without changing it.
+// The corruptions are collected in the report, the error is only for
+// the file that can not be checked at all.
+// It validates the checksum of every block, walks the free list and the
+// checkpoint metadata (schemas, tables, row groups, data pointers and
+// index roots), and detects blocks that are leaked or referenced twice.
+// Blocks are read directly instead of through the buffer manager, so a
+// corrupted block is reported rather than panicking.
+func VerifyDatabase(path string) (*VerifyReport, error) {
+	if !util.FileIsValid(path) {
+		return nil, fmt.Errorf("database %s not found", path)
+	}
+	mgr := NewFileBlockMgr(GBufferMgr, path, true)
+	verifier := &dbVerifier{
+		_mgr:     mgr,
+		_checked: make(map[BlockID]bool),
+		_refs:    make(map[BlockID]string),
+		_report: &VerifyReport{
+			Path: path,
+		},
+	}
+	err := verifier.protect(mgr.LoadExistingDatabase)
+	if mgr._handle != nil {
+		defer mgr._handle.Close()
+	}
+	if err != nil {
+		verifier._report.addError("load database: %v", err)
+		return verifier._report, nil
+	}
+	verifier._report.Iteration = mgr._iterationCount
+	verifier._report.TotalBlocks = uint64(mgr._maxBlock)
+	verifier.verify()
+	return verifier._report, nil
+}
+
+// dbVerifier keeps the ids of the blocks instead of the blocks.
+// the memory does not grow with the size of the database file.
+type dbVerifier struct {
+	_mgr *FileBlockMgr
+	//blocks that pass the checksum validation
+	_checked  map[BlockID]bool
+	_refs     map[BlockID]string
+	_freeList map[BlockID]bool
+	_report   *VerifyReport
+}
+
+// readBlock loads the block from the file and validates its checksum.
+// the caller closes the block.
+func (verifier *dbVerifier) readBlock(id BlockID) (*Block, error) {
+	if id < 0 || id >= verifier._mgr._maxBlock {
+		return nil, fmt.Errorf("block %d out of range [0,%d)", id, verifier._mgr._maxBlock)
+	}
+	block := NewBlock(verifier._mgr._bufferMgr._bufferAlloc, id)
+	err := verifier._mgr.Read(block)
+	if err != nil {
+		block.Close()
+		return nil, fmt.Errorf("block %d: %w", id, err)
+	}
+	verifier._checked[id] = true
+	return block, nil
+}
+
+// checkBlock validates the checksum of the block.
+func (verifier *dbVerifier) checkBlock(id BlockID) error {
+	if verifier._checked[id] {
+		return nil
+	}
+	block, err := verifier.readBlock(id)
+	if err != nil {
+		return err
+	}
+	block.Close()
+	return nil
+}
+
+// reference records that the block is used as kind. Meta blocks are
+// shared by many readers, any other block must be used once unless it
+// is recorded as a multi use block.
+func (verifier *dbVerifier) reference(id BlockID, kind string) {
+	prev, has := verifier._refs[id]
+	if !has {
+		verifier._refs[id] = kind
+		if verifier._freeList[id] {
+			verifier._report.addError("block %d used as %s is in the free list", id, kind)
+		}
+		return
+	}
+	if prev != kind {
+		verifier._report.addError("block %d used as both %s and %s", id, prev, kind)
+		return
+	}
+	if kind == blockKindMeta {
+		return
+	}
+	if _, multi := verifier._mgr._multiUseBlocks[id]; !multi {
+		verifier._report.addError("%s block %d referenced more than once", kind, id)
+	}
+}
+
+func (verifier *dbVerifier) verify() {
+	report := verifier._report
+	mgr := verifier._mgr
+	//LoadExistingDatabase has loaded the free list
+	verifier._freeList = make(map[BlockID]bool)
+	mgr._freeList.Scan(func(id BlockID) bool {
+		if id < 0 || id >= mgr._maxBlock {
+			report.addError("free block %d out of range [0,%d)", id, mgr._maxBlock)
+		}
+		verifier._freeList[id] = true
+		return true
+	})
+	report.FreeBlocks = len(verifier._freeList)
+
+	if mgr._freeListId != -1 {
+		reader, err := verifier.newMetaReader(mgr._freeListId, 0, blockKindFreeList)
+		if err == nil {
+			err = reader.skipChain()
+			reader.Close()
+		}
+		if err != nil {
+			report.addError("free list: %v", err)
+		}
+	}
+
+	if mgr._metaBlock != -1 {
+		verifier.verifyCheckpoint()
+	}
+
+	//every block is either free or referenced
+	for id := BlockID(0); id < mgr._maxBlock; id++ {
+		kind, used := verifier._refs[id]
+		switch {
+		case used && kind == blockKindMeta:
+			report.MetaBlocks++
+		case used && kind == blockKindData:
+			report.DataBlocks++
+		case !used && !verifier._freeList[id]:
+			report.LeakedBlocks = append(report.LeakedBlocks, id)
+		}
+		if err := verifier.checkBlock(id); err != nil {
+			if used {
+				report.addError("%v", err)
+			} else {
+				report.addWarning("unused %v", err)
+			}
+		}
+	}
+	if len(report.LeakedBlocks) != 0 {
+		slices.Sort(report.LeakedBlocks)
+		//leaked blocks waste space but do not corrupt the data
+		report.addWarning("%d blocks are neither free nor referenced", len(report.LeakedBlocks))
+	}
+}
+
+func (verifier *dbVerifier) verifyCheckpoint() {
+	report := verifier._report
+	reader, err := verifier.newMetaReader(verifier._mgr._metaBlock, 0, blockKindMeta)
+	if err != nil {
+		report.addError("meta block: %v", err)
+		return
+	}
+	defer reader.Close()
+	err = verifier.protect(func() error {
+		schCnt := uint32(0)
+		err := util.Read[uint32](&schCnt, reader)
+		if err != nil {
+			return err
+		}
+		for i := uint32(0); i < schCnt; i++ {
+			err = verifier.verifySchema(reader)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		report.addError("checkpoint: %v", err)
+	}
+}
+
+func (verifier *dbVerifier) verifySchema(reader *verifyMetaReader) error {
+	schEnt := &CatalogEntry{}
+	err := schEnt.Deserialize(reader)
+	if err != nil {
+		return err
+	}
+	verifier._report.Schemas++
+	fReader, err := NewFieldReader(reader)
+	if err != nil {
+		return err
+	}
+	tblCnt := uint32(0)
+	err = ReadRequired[uint32](&tblCnt, fReader)
+	if err != nil {
+		return err
+	}
+	fReader.Finalize()
+	for i := uint32(0); i < tblCnt; i++ {
+		err = verifier.verifyTable(reader)
+		if err != nil {
+			return fmt.Errorf("schema %s: %w", schEnt._name, err)
+		}
+	}
+	return nil
+}
+
+func (verifier *dbVerifier) verifyTable(reader *verifyMetaReader) error {
+	report := verifier._report
+	tabEnt := &CatalogEntry{}
+	err := tabEnt.Deserialize(reader)
+	if err != nil {
+		return err
+	}
+	report.Tables++
+
+	bid := BlockID(0)
+	offset := uint64(0)
+	totalRows := uint64(0)
+	indexesCount := uint64(0)
+	err = util.Read[BlockID](&bid, reader)
+	if err != nil {
+		return err
+	}
+	err = util.Read[uint64](&offset, reader)
+	if err != nil {
+		return err
+	}
+	err = util.Read[uint64](&totalRows, reader)
+	if err != nil {
+		return err
+	}
+	err = util.Read[uint64](&indexesCount, reader)
+	if err != nil {
+		return err
+	}
+	idxPtrs := make([]BlockPointer, indexesCount)
+	for i := uint64(0); i < indexesCount; i++ {
+		err = util.Read[BlockID](&idxPtrs[i]._blockId, reader)
+		if err != nil {
+			return err
+		}
+		err = util.Read[uint32](&idxPtrs[i]._offset, reader)
+		if err != nil {
+			return err
+		}
+	}
+
+	//the table data is independent of the following tables.
+	//report its errors and go on.
+	name := tabEnt._schName + "." + tabEnt._name
+	err = verifier.protect(func() error {
+		return verifier.verifyTableData(tabEnt, bid, offset, totalRows)
+	})
+	if err != nil {
+		report.addError("table %s: %v", name, err)
+	}
+	for i, ptr := range idxPtrs {
+		err = verifier.protect(func() error {
+			return verifier.verifyIndex(ptr)
+		})
+		if err != nil {
+			report.addError("table %s index %d: %v", name, i, err)
+		}
+	}
+	return nil
+}
+
+func (verifier *dbVerifier) verifyTableData(
+	tabEnt *CatalogEntry,
+	bid BlockID,
+	offset uint64,
+	totalRows uint64,
+) error {
+	report := verifier._report
+	reader, err := verifier.newMetaReader(bid, offset, blockKindMeta)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	stats := &TableStats{}
+	err = stats.Deserialize(reader, tabEnt._colDefs)
+	if err != nil {
+		return err
+	}
+	rgCount := IdxType(0)
+	err = util.Read[IdxType](&rgCount, reader)
+	if err != nil {
+		return err
+	}
+	types := make([]common.LType, len(tabEnt._colDefs))
+	for i, def := range tabEnt._colDefs {
+		types[i] = def.Type
+	}
+	rows := uint64(0)
+	nextStart := uint64(0)
+	for i := IdxType(0); i < rgCount; i++ {
+		rgPtr, err := RowGroupDeserialize(reader, types)
+		if err != nil {
+			return fmt.Errorf("row group %d: %w", i, err)
+		}
+		report.RowGroups++
+		if rgPtr._rowStart != nextStart {
+			report.addError("table %s row group %d starts at %d, expect %d",
+				tabEnt._name, i, rgPtr._rowStart, nextStart)
+		}
+		nextStart = rgPtr._rowStart + rgPtr._tupleCount
+		rows = max(rows, nextStart)
+		for j, colPtr := range rgPtr._dataPointers {
+			err = verifier.verifyColumn(colPtr, tabEnt._colDefs[j].Type, rgPtr)
+			if err != nil {
+				return fmt.Errorf("row group %d column %s: %w",
+					i, tabEnt._colDefs[j].Name, err)
+			}
+		}
+	}
+	if rows != totalRows {
+		report.addError("table %s has %d rows in row groups, expect %d",
+			tabEnt._name, rows, totalRows)
+	}
+	return nil
+}
+
+func (verifier *dbVerifier) verifyColumn(
+	colPtr *BlockPointer,
+	typ common.LType,
+	rgPtr *RowGroupPointer,
+) error {
+	reader, err := verifier.newMetaReader(colPtr._blockId, uint64(colPtr._offset), blockKindMeta)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	err = verifier.verifyDataPointers(reader, typ, rgPtr)
+	if err != nil {
+		return err
+	}
+	//the validity of the column follows the data
+	err = verifier.verifyDataPointers(reader, common.MakeLType(common.LTID_VALIDITY), rgPtr)
+	if err != nil {
+		return fmt.Errorf("validity: %w", err)
+	}
+	return nil
+}
+
+func (verifier *dbVerifier) verifyDataPointers(
+	reader *verifyMetaReader,
+	typ common.LType,
+	rgPtr *RowGroupPointer,
+) error {
+	dataPtrCnt := IdxType(0)
+	err := util.Read[IdxType](&dataPtrCnt, reader)
+	if err != nil {
+		return err
+	}
+	tupleCount := uint64(0)
+	for i := IdxType(0); i < dataPtrCnt; i++ {
+		dataPtr := &DataPointer{}
+		err = util.Read[uint64](&dataPtr._rowStart, reader)
+		if err != nil {
+			return err
+		}
+		err = util.Read[uint64](&dataPtr._tupleCount, reader)
+		if err != nil {
+			return err
+		}
+		err = util.Read[BlockID](&dataPtr._blockPtr._blockId, reader)
+		if err != nil {
+			return err
+		}
+		err = util.Read[uint32](&dataPtr._blockPtr._offset, reader)
+		if err != nil {
+			return err
+		}
+		err = dataPtr._stats.Deserialize(reader, typ)
+		if err != nil {
+			return err
+		}
+		verifier._report.DataPointers++
+		if dataPtr._rowStart != rgPtr._rowStart+tupleCount {
+			return fmt.Errorf("data pointer %d starts at %d, expect %d",
+				i, dataPtr._rowStart, rgPtr._rowStart+tupleCount)
+		}
+		tupleCount += dataPtr._tupleCount
+		if uint64(dataPtr._blockPtr._offset) >= BLOCK_SIZE {
+			return fmt.Errorf("data pointer %d has invalid offset %d",
+				i, dataPtr._blockPtr._offset)
+		}
+		err = verifier.checkBlock(dataPtr._blockPtr._blockId)
+		if err != nil {
+			return fmt.Errorf("data pointer %d: %w", i, err)
+		}
+		verifier.reference(dataPtr._blockPtr._blockId, blockKindData)
+	}
+	if tupleCount != rgPtr._tupleCount {
+		return fmt.Errorf("data pointers have %d rows, expect %d",
+			tupleCount, rgPtr._tupleCount)
+	}
+	return nil
+}
+
+func (verifier *dbVerifier) verifyIndex(ptr BlockPointer) error {
+	reader, err := verifier.newMetaReader(ptr._blockId, uint64(ptr._offset), blockKindMeta)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	verifier._report.Indexes++
+	var cnt uint32
+	err = util.Read[uint32](&cnt, reader)
+	if err != nil {
+		return err
+	}
+	var val uint64
+	var keyLen uint32
+	key := make([]byte, 0)
+	for i := uint32(0); i < cnt; i++ {
+		err = util.Read[uint64](&val, reader)
+		if err != nil {
+			return err
+		}
+		err = util.Read[uint32](&keyLen, reader)
+		if err != nil {
+			return err
+		}
+		if keyLen > uint32(BLOCK_SIZE) {
+			return fmt.Errorf("index key %d has invalid length %d", i, keyLen)
+		}
+		key = slices.Grow(key[:0], int(keyLen))[:keyLen]
+		err = reader.ReadData(key, int(keyLen))
+		if err != nil {
+			return err
+		}
+		verifier._report.IndexKeys++
+	}
+	return nil
+}
+
+// protect converts the panics of deserializing a corrupted structure
+// into an error.
+func (verifier *dbVerifier) protect(fn func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return fn()
+}
+
+var _ util.Deserialize = new(verifyMetaReader)
+
+// verifyMetaReader reads a chain of meta blocks like MetaBlockReader
+// and records every block it visits.
+type verifyMetaReader struct {
+	_verifier  *dbVerifier
+	_kind      string
+	_block     *Block
+	_offset    uint64
+	_nextBlock BlockID
+	_visited   map[BlockID]bool
+}
+
+func (verifier *dbVerifier) newMetaReader(
+	id BlockID,
+	offset uint64,
+	kind string,
+) (*verifyMetaReader, error) {
+	reader := &verifyMetaReader{
+		_verifier:  verifier,
+		_kind:      kind,
+		_nextBlock: -1,
+		_visited:   make(map[BlockID]bool),
+	}
+	err := reader.readNewBlock(id)
+	if err != nil {
+		return nil, err
+	}
+	if offset != 0 {
+		if offset < uint64(unsafe.Sizeof(BlockID(0))) || offset > reader._block._size {
+			reader.Close()
+			return nil, fmt.Errorf("invalid offset %d in block %d", offset, id)
+		}
+		reader._offset = offset
+	}
+	return reader, nil
+}
+
+func (reader *verifyMetaReader) readNewBlock(id BlockID) error {
+	if reader._visited[id] {
+		return fmt.Errorf("meta block chain has a cycle at block %d", id)
+	}
+	reader._visited[id] = true
+	block, err := reader._verifier.readBlock(id)
+	if err != nil {
+		return err
+	}
+	reader._verifier.reference(id, reader._kind)
+	//only the current block of the chain is kept
+	reader.Close()
+	reader._block = block
+	reader._nextBlock = util.Load[BlockID](block._buffer)
+	if reader._nextBlock < -1 {
+		return fmt.Errorf("block %d has invalid next block %d", id, reader._nextBlock)
+	}
+	reader._offset = uint64(unsafe.Sizeof(BlockID(0)))
+	return nil
+}
+
+// skipChain visits the remaining blocks of the chain.
+func (reader *verifyMetaReader) skipChain() error {
+	for reader._nextBlock != -1 {
+		err := reader.readNewBlock(reader._nextBlock)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (reader *verifyMetaReader) ReadData(buffer []byte, readSize int) error {
+	pos := 0
+	for reader._offset+uint64(readSize) > reader._block._size {
+		toRead := int(reader._block._size - reader._offset)
+		if toRead > 0 {
+			src := util.PointerToSlice[byte](
+				util.PointerAdd(reader._block._buffer, int(reader._offset)),
+				toRead,
+			)
+			copy(buffer[pos:], src)
+			readSize -= toRead
+			pos += toRead
+		}
+		if reader._nextBlock == -1 {
+			return fmt.Errorf("read past the end of meta block %d", reader._block.id)
+		}
+		err := reader.readNewBlock(reader._nextBlock)
+		if err != nil {
+			return err
+		}
+	}
+	src := util.PointerToSlice[byte](
+		util.PointerAdd(reader._block._buffer, int(reader._offset)),
+		readSize,
+	)
+	copy(buffer[pos:], src)
+	reader._offset += uint64(readSize)
+	return nil
+}
+
+func (reader *verifyMetaReader) Close() error {
+	if reader._block != nil {
+		reader._block.Close()
+		reader._block = nil
+	}
+	return nil
+}
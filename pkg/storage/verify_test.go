@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/daviszhen/plan/pkg/common"
+)
+
+func Test_verify_database(t *testing.T) {
+	err := GStorageMgr.CreateCheckpoint(false, true)
+	require.NoError(t, err)
+
+	report, err := VerifyDatabase(defaultDbPath)
+	require.NoError(t, err)
+	t.Log(report.String())
+	require.True(t, report.OK())
+
+	//corrupt every block in a copy of the database
+	content, err := os.ReadFile(defaultDbPath)
+	require.NoError(t, err)
+	if report.TotalBlocks == 0 {
+		return
+	}
+	for id := uint64(0); id < report.TotalBlocks; id++ {
+		content[BLOCK_START+id*BLOCK_ALLOC_SIZE+BLOCK_HEADER_SIZE+16] ^= 0xff
+	}
+	path := filepath.Join(t.TempDir(), "corrupt")
+	require.NoError(t, os.WriteFile(path, content, 0755))
+	report, err = VerifyDatabase(path)
+	require.NoError(t, err)
+	t.Log(report.String())
+	require.False(t, report.OK())
+}
+
+func Test_verify_read_only(t *testing.T) {
+	oldTxnMgr, oldCatalog, oldStorageMgr := GTxnMgr, GCatalog, GStorageMgr
+	defer func() {
+		GTxnMgr, GCatalog, GStorageMgr = oldTxnMgr, oldCatalog, oldStorageMgr
+	}()
+
+	//a database with the uncheckpointed wal and a torn tail
+	dbPath := filepath.Join(t.TempDir(), "db")
+	openTestDatabase(t, dbPath, "", nil)
+	txn, err := GTxnMgr.NewTxn("create")
+	require.NoError(t, err)
+	_, err = GCatalog.CreateSchema(txn, "verify")
+	require.NoError(t, err)
+	require.NoError(t, GTxnMgr.Commit(txn))
+	f, err := os.OpenFile(dbPath+".wal", os.O_APPEND|os.O_WRONLY, 0755)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{1, 2, 3})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	db, err := os.ReadFile(dbPath)
+	require.NoError(t, err)
+	wal, err := os.ReadFile(dbPath + ".wal")
+	require.NoError(t, err)
+
+	report, err := VerifyDatabase(dbPath)
+	require.NoError(t, err)
+	require.True(t, report.OK())
+
+	//the files are unchanged
+	kept, err := os.ReadFile(dbPath)
+	require.NoError(t, err)
+	require.Equal(t, db, kept)
+	kept, err = os.ReadFile(dbPath + ".wal")
+	require.NoError(t, err)
+	require.Equal(t, wal, kept)
+}
+
+// Test_checkpoint_block_reuse checks the normal checkpoints neither leak
+// nor free twice the blocks. Every checkpoint rewrites the data of the
+// table, so the blocks of the old segments are freed by the checkpoint.
+func Test_checkpoint_block_reuse(t *testing.T) {
+	oldTxnMgr, oldCatalog, oldStorageMgr := GTxnMgr, GCatalog, GStorageMgr
+	defer func() {
+		GTxnMgr, GCatalog, GStorageMgr = oldTxnMgr, oldCatalog, oldStorageMgr
+	}()
+
+	dbPath := filepath.Join(t.TempDir(), "db")
+	openTestDatabase(t, dbPath, "", nil)
+	checkBlocks := func() *VerifyReport {
+		report, err := VerifyDatabase(dbPath)
+		require.NoError(t, err)
+		require.True(t, report.OK(), report.String())
+		require.Empty(t, report.LeakedBlocks, report.String())
+		return report
+	}
+
+	//the table data writer of the empty database writes nothing
+	require.NoError(t, GStorageMgr.CreateCheckpoint(false, true))
+	checkBlocks()
+
+	txn, err := GTxnMgr.NewTxn("create")
+	require.NoError(t, err)
+	_, err = GCatalog.CreateSchema(txn, "pitr")
+	require.NoError(t, err)
+	colDefs := []*ColumnDefinition{{Name: "a", Type: common.IntegerType()}}
+	tabEnt, err := GCatalog.CreateTable(txn, NewDataTableInfo3("pitr", "t", colDefs, nil))
+	require.NoError(t, err)
+	require.NoError(t, GTxnMgr.Commit(txn))
+
+	var total uint64
+	for i := 0; i < 5; i++ {
+		insertTestRows(t, tabEnt.GetStorage(), i*STANDARD_VECTOR_SIZE)
+		require.NoError(t, GStorageMgr.CreateCheckpoint(false, true))
+		report := checkBlocks()
+		if i == 0 {
+			total = report.TotalBlocks
+		}
+	}
+
+	//restart and checkpoint the same data again
+	openTestDatabase(t, dbPath, "", nil)
+	require.Equal(t, 5*STANDARD_VECTOR_SIZE, countTestRows(t))
+	for i := 0; i < 3; i++ {
+		require.NoError(t, GStorageMgr.CreateCheckpoint(false, true))
+		report := checkBlocks()
+		//the freed blocks are reused
+		require.Less(t, report.TotalBlocks, 5*total+8, report.String())
+	}
+}
@@ -1,9 +1,14 @@
 package storage
 
 import (
+	"bytes"
+	"encoding/binary"
+	"errors"
 	"fmt"
+	"hash/crc32"
 	"io"
 	"os"
+	"time"
 
 	"github.com/daviszhen/plan/pkg/chunk"
 	"github.com/daviszhen/plan/pkg/util"
@@ -37,8 +42,39 @@ const (
 	WAL_FLUSH         uint8 = 100
 )
 
+// every wal record is framed as
+//
+//	| payload length (uint32) | crc32 of payload (uint32) | payload |
+//
+// the payload starts with the wal type. the frame lets the replay
+// detect a torn write at the tail or a corrupted record.
+const (
+	WAL_RECORD_HEADER_SIZE = 8
+	WAL_MAX_RECORD_SIZE    = 1 << 30
+)
+
+// the wal file starts with a header
+//
+//	| magic "PWAL" | version (uint32) |
+//
+// the wal written before the framing has no header. it is
+// rejected by the replay instead of being dropped as a torn tail.
+const (
+	WAL_MAGIC            = "PWAL"
+	WAL_VERSION          = uint32(1)
+	WAL_FILE_HEADER_SIZE = 8
+)
+
+var (
+	errWalTornRecord    = errors.New("torn wal record")
+	errWalCorruptRecord = errors.New("corrupt wal record")
+	errWalUnknownFormat = errors.New("unknown wal format")
+)
+
 func walType(walTyp uint8) string {
 	switch walTyp {
+	case WAL_CREATE_TABLE:
+		return "WAL_CREATE_TABLE"
 	case WAL_CREATE_SCHEMA:
 		return "WAL_CREATE_SCHEMA"
 	case WAL_USE_TABLE:
@@ -73,9 +109,35 @@ func NewWriteAheadLog(path string) (*WriteAheadLog, error) {
 		return nil, err
 	}
 	log._writer = writer
+	err = log.writeHeader()
+	if err != nil {
+		return nil, err
+	}
 	return log, nil
 }
 
+// writeHeader writes the header into the empty wal.
+// the torn header is rewritten.
+func (log *WriteAheadLog) writeHeader() error {
+	sz, err := log._writer.GetFileSize()
+	if err != nil {
+		return err
+	}
+	if sz >= WAL_FILE_HEADER_SIZE {
+		return nil
+	}
+	if sz != 0 {
+		err = log._writer.Truncate(0)
+		if err != nil {
+			return err
+		}
+	}
+	header := make([]byte, WAL_FILE_HEADER_SIZE)
+	copy(header, WAL_MAGIC)
+	binary.LittleEndian.PutUint32(header[4:], WAL_VERSION)
+	return log._writer.WriteData(header, len(header))
+}
+
 func (log *WriteAheadLog) Close() error {
 	err := log._writer.Close()
 	if err != nil {
@@ -84,45 +146,59 @@ func (log *WriteAheadLog) Close() error {
 	return nil
 }
 
-func (log *WriteAheadLog) WriteSetTable(schema string, table string) error {
-	if log._skipWriting {
-		return nil
-	}
-	err := util.Write[uint8](WAL_USE_TABLE, log._writer)
+// writeRecord serializes one wal entry into a framed record and
+// appends it to the log in a single write.
+func (log *WriteAheadLog) writeRecord(
+	walTyp uint8,
+	fill func(serial util.Serialize) error,
+) error {
+	buf := make([]byte, WAL_RECORD_HEADER_SIZE, 64)
+	serial := NewBufferedSerialize(buf)
+	err := util.Write[uint8](walTyp, serial)
 	if err != nil {
 		return err
 	}
-	err = util.WriteString(schema, log._writer)
-	if err != nil {
-		return err
+	if fill != nil {
+		err = fill(serial)
+		if err != nil {
+			return err
+		}
 	}
-	err = util.WriteString(table, log._writer)
-	if err != nil {
-		return err
+	record := serial._data.Bytes()
+	payload := record[WAL_RECORD_HEADER_SIZE:]
+	if len(payload) > WAL_MAX_RECORD_SIZE {
+		return fmt.Errorf("wal record too large: %d", len(payload))
 	}
-	return err
+	binary.LittleEndian.PutUint32(record[0:4], uint32(len(payload)))
+	binary.LittleEndian.PutUint32(record[4:8], crc32.ChecksumIEEE(payload))
+	return log._writer.WriteData(record, len(record))
 }
 
-func (log *WriteAheadLog) WriteInsert(data *chunk.Chunk) error {
+func (log *WriteAheadLog) WriteSetTable(schema string, table string) error {
 	if log._skipWriting {
 		return nil
 	}
-	err := util.Write[uint8](WAL_INSERT_TUPLE, log._writer)
-	if err != nil {
-		return err
+	return log.writeRecord(WAL_USE_TABLE, func(serial util.Serialize) error {
+		err := util.WriteString(schema, serial)
+		if err != nil {
+			return err
+		}
+		return util.WriteString(table, serial)
+	})
+}
+
+func (log *WriteAheadLog) WriteInsert(data *chunk.Chunk) error {
+	if log._skipWriting {
+		return nil
 	}
-	return data.Serialize(log._writer)
+	return log.writeRecord(WAL_INSERT_TUPLE, data.Serialize)
 }
 
 func (log *WriteAheadLog) WriteDelete(data *chunk.Chunk) error {
 	if log._skipWriting {
 		return nil
 	}
-	err := util.Write[uint8](WAL_DELETE_TUPLE, log._writer)
-	if err != nil {
-		return err
-	}
-	return data.Serialize(log._writer)
+	return log.writeRecord(WAL_DELETE_TUPLE, data.Serialize)
 }
 
 func (log *WriteAheadLog) WriteUpdate(data *chunk.Chunk, colIdx []IdxType) error {
@@ -130,21 +206,19 @@ func (log *WriteAheadLog) WriteUpdate(data *chunk.Chunk, colIdx []IdxType) error
 		return nil
 	}
 
-	err := util.Write[uint8](WAL_UPDATE_TUPLE, log._writer)
-	if err != nil {
-		return err
-	}
-	err = util.Write[IdxType](IdxType(len(colIdx)), log._writer)
-	if err != nil {
-		return err
-	}
-	for _, idx := range colIdx {
-		err = util.Write[IdxType](idx, log._writer)
+	return log.writeRecord(WAL_UPDATE_TUPLE, func(serial util.Serialize) error {
+		err := util.Write[IdxType](IdxType(len(colIdx)), serial)
 		if err != nil {
 			return err
 		}
-	}
-	return data.Serialize(log._writer)
+		for _, idx := range colIdx {
+			err = util.Write[IdxType](idx, serial)
+			if err != nil {
+				return err
+			}
+		}
+		return data.Serialize(serial)
+	})
 }
 
 func (log *WriteAheadLog) Flush() error {
@@ -152,15 +226,56 @@ func (log *WriteAheadLog) Flush() error {
 		return nil
 	}
 
-	err := util.Write[uint8](WAL_FLUSH, log._writer)
+	err := log.writeRecord(WAL_FLUSH, nil)
+	if err != nil {
+		return err
+	}
+	return log._writer.Sync()
+}
+
+// FlushCommit ends the entries of the committed txn with the commit id
+// and the commit time. the recovery stops at them.
+func (log *WriteAheadLog) FlushCommit(commitId TxnType) error {
+	if log._skipWriting {
+		return nil
+	}
+
+	err := log.writeRecord(WAL_FLUSH, func(serial util.Serialize) error {
+		err := util.Write[TxnType](commitId, serial)
+		if err != nil {
+			return err
+		}
+		return util.Write[int64](time.Now().UnixNano(), serial)
+	})
 	if err != nil {
 		return err
 	}
 	return log._writer.Sync()
 }
 
+// readCommitInfo reads the commit id and the commit time of the flush entry.
+// the flush entry written by the checkpoint has none of them.
+func readCommitInfo(payload util.Deserialize) (TxnType, int64, bool) {
+	commitId := TxnType(0)
+	err := util.Read[TxnType](&commitId, payload)
+	if err != nil {
+		return 0, 0, false
+	}
+	ts := int64(0)
+	err = util.Read[int64](&ts, payload)
+	if err != nil {
+		return 0, 0, false
+	}
+	return commitId, ts, true
+}
+
+// Truncate cuts the wal to the size. The header is kept.
 func (log *WriteAheadLog) Truncate(sz int64) error {
-	return log._writer.Truncate(uint64(sz))
+	err := log._writer.Truncate(uint64(max(sz, WAL_FILE_HEADER_SIZE)))
+	if err != nil {
+		return err
+	}
+	return log.writeHeader()
 }
 
 func (log *WriteAheadLog) Delete() error {
@@ -175,43 +290,51 @@ func (log *WriteAheadLog) Delete() error {
 	return os.Remove(log._path)
 }
 
-func (log *WriteAheadLog) GetWalSize() int64 {
-	sz, _ := log._writer.GetFileSize()
-	return sz
-}
-
-func (log *WriteAheadLog) WriteCheckpoint(block BlockID) error {
-	err := util.Write[uint8](WAL_CHECKPOINT, log._writer)
+// Archive moves the wal file to the dst and continues with an empty one.
+func (log *WriteAheadLog) Archive(dst string) error {
+	if util.FileIsValid(dst) {
+		return fmt.Errorf("wal segment %s already archived", dst)
+	}
+	err := log._writer.Close()
 	if err != nil {
 		return err
 	}
-	err = util.Write[BlockID](block, log._writer)
+	err = os.Rename(log._path, dst)
 	if err != nil {
 		return err
 	}
-	return nil
+	log._writer, err = NewBufferedFileWriter(log._path)
+	if err != nil {
+		return err
+	}
+	return log.writeHeader()
+}
+
+func (log *WriteAheadLog) GetWalSize() int64 {
+	sz, _ := log._writer.GetFileSize()
+	return sz
+}
+
+func (log *WriteAheadLog) WriteCheckpoint(block BlockID) error {
+	return log.writeRecord(WAL_CHECKPOINT, func(serial util.Serialize) error {
+		return util.Write[BlockID](block, serial)
+	})
 }
 
 func (log *WriteAheadLog) WriteCreateSchema(ent *CatalogEntry) error {
 	if log._skipWriting {
 		return nil
 	}
-	err := util.Write[uint8](WAL_CREATE_SCHEMA, log._writer)
-	if err != nil {
-		return err
-	}
-	return util.WriteString(ent._name, log._writer)
+	return log.writeRecord(WAL_CREATE_SCHEMA, func(serial util.Serialize) error {
+		return util.WriteString(ent._name, serial)
+	})
 }
 
 func (log *WriteAheadLog) WriteCreateTable(ent *CatalogEntry) error {
 	if log._skipWriting {
 		return nil
 	}
-	err := util.Write[uint8](WAL_CREATE_TABLE, log._writer)
-	if err != nil {
-		return err
-	}
-	return ent.Serialize(log._writer)
+	return log.writeRecord(WAL_CREATE_TABLE, ent.Serialize)
 }
 
 var _ util.Serialize = new(BufferedFileWriter)
@@ -286,8 +409,10 @@ func (writer *BufferedFileWriter) Close() error {
 var _ util.Deserialize = new(BufferedFileReader)
 
 type BufferedFileReader struct {
-	_path string
-	_file *os.File
+	_path   string
+	_file   *os.File
+	_size   int64
+	_offset int64
 }
 
 func NewBufferedFileReader(path string) (*BufferedFileReader, error) {
@@ -299,8 +424,13 @@ func NewBufferedFileReader(path string) (*BufferedFileReader, error) {
 	if err != nil {
 		return nil, err
 	}
-	finfo, _ := reader._file.Stat()
-	fmt.Println(path, finfo.Size())
+	finfo, err := reader._file.Stat()
+	if err != nil {
+		reader._file.Close()
+		return nil, err
+	}
+	reader._size = finfo.Size()
+	fmt.Println(path, reader._size)
 	reader._file.Seek(0, io.SeekStart)
 	return reader, nil
 }
@@ -314,9 +444,84 @@ func (reader *BufferedFileReader) ReadData(buffer []byte, len int) error {
 		}
 		r += n
 	}
+	reader._offset += int64(len)
+	return nil
+}
+
+// ReadHeader checks the header of the wal. It returns io.EOF for the
+// empty wal, errWalTornRecord for the torn header and errWalUnknownFormat
+// for the wal without the header or with an unknown version.
+func (reader *BufferedFileReader) ReadHeader() error {
+	var header [WAL_FILE_HEADER_SIZE]byte
+	n, err := io.ReadFull(reader._file, header[:])
+	if err != nil &&
+		!errors.Is(err, io.EOF) &&
+		!errors.Is(err, io.ErrUnexpectedEOF) {
+		return err
+	}
+	if n == 0 {
+		return io.EOF
+	}
+	magic := header[:min(n, len(WAL_MAGIC))]
+	if !bytes.HasPrefix([]byte(WAL_MAGIC), magic) {
+		return fmt.Errorf("%w: %s has no header", errWalUnknownFormat, reader._path)
+	}
+	if n < WAL_FILE_HEADER_SIZE {
+		return errWalTornRecord
+	}
+	version := binary.LittleEndian.Uint32(header[4:])
+	if version != WAL_VERSION {
+		return fmt.Errorf("%w: %s has version %d", errWalUnknownFormat, reader._path, version)
+	}
+	reader._offset = WAL_FILE_HEADER_SIZE
 	return nil
 }
 
+// ReadRecord reads the next framed wal record and returns its payload.
+// It returns io.EOF at the clean end of the log, errWalTornRecord if the
+// log ends in the middle of a record and errWalCorruptRecord if the
+// record fails the length or checksum validation. The offset only
+// advances past intact records.
+func (reader *BufferedFileReader) ReadRecord() ([]byte, error) {
+	var header [WAL_RECORD_HEADER_SIZE]byte
+	n, err := io.ReadFull(reader._file, header[:])
+	if err != nil {
+		if errors.Is(err, io.EOF) && n == 0 {
+			return nil, io.EOF
+		}
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errWalTornRecord
+		}
+		return nil, err
+	}
+	sz := binary.LittleEndian.Uint32(header[0:4])
+	sum := binary.LittleEndian.Uint32(header[4:8])
+	if sz == 0 || sz > WAL_MAX_RECORD_SIZE {
+		return nil, errWalCorruptRecord
+	}
+	if reader._offset+WAL_RECORD_HEADER_SIZE+int64(sz) > reader._size {
+		return nil, errWalTornRecord
+	}
+	payload := make([]byte, sz)
+	_, err = io.ReadFull(reader._file, payload)
+	if err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errWalTornRecord
+		}
+		return nil, err
+	}
+	if crc32.ChecksumIEEE(payload) != sum {
+		return nil, errWalCorruptRecord
+	}
+	reader._offset += WAL_RECORD_HEADER_SIZE + int64(sz)
+	return payload, nil
+}
+
+// Offset returns the end offset of the last record read.
+func (reader *BufferedFileReader) Offset() int64 {
+	return reader._offset
+}
+
 func (reader *BufferedFileReader) Close() error {
 	return reader._file.Close()
 }
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/daviszhen/plan/pkg/chunk"
+	"github.com/daviszhen/plan/pkg/common"
+	"github.com/daviszhen/plan/pkg/util"
+)
+
+// writeTestWal fills a wal with a few records and returns the end offset
+// of every record.
+func writeTestWal(t *testing.T, path string) []int64 {
+	wal, err := NewWriteAheadLog(path)
+	require.NoError(t, err)
+	defer wal.Close()
+
+	data := &chunk.Chunk{}
+	data.Init([]common.LType{common.IntegerType()}, testVectorSize)
+	data.Data[0] = NewInt32ConstVector(7, false)
+	data.SetCard(testVectorSize)
+
+	ends := make([]int64, 0)
+	steps := []func() error{
+		func() error { return wal.WriteCreateSchema(&CatalogEntry{_name: "s"}) },
+		func() error { return wal.WriteSetTable("s", "t") },
+		func() error { return wal.WriteInsert(data) },
+		func() error { return wal.Flush() },
+		func() error { return wal.WriteDelete(data) },
+		func() error { return wal.Flush() },
+	}
+	for _, step := range steps {
+		require.NoError(t, step())
+		ends = append(ends, wal.GetWalSize())
+	}
+	return ends
+}
+
+func countWalRecords(t *testing.T, path string) (int, int64, int64) {
+	cnt := 0
+	validSize, fileSize, err := scanWal(path,
+		func(walTyp uint8, payload util.Deserialize) error {
+			state := NewReplayState(payload)
+			state._deserializeOnly = true
+			cnt++
+			if walTyp == WAL_FLUSH {
+				return nil
+			}
+			return state.ReplayEntry(nil, walTyp)
+		})
+	require.NoError(t, err)
+	return cnt, validSize, fileSize
+}
+
+func Test_wal_truncate_at_every_offset(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "full.wal")
+	ends := writeTestWal(t, path)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, util.Back(ends), int64(len(content)))
+
+	cut := filepath.Join(dir, "cut.wal")
+	for off := 0; off <= len(content); off++ {
+		require.NoError(t, os.WriteFile(cut, content[:off], 0755))
+		expectCnt := 0
+		expectSize := int64(0)
+		if off >= WAL_FILE_HEADER_SIZE {
+			expectSize = WAL_FILE_HEADER_SIZE
+		}
+		for _, end := range ends {
+			if end <= int64(off) {
+				expectCnt++
+				expectSize = end
+			}
+		}
+		cnt, validSize, fileSize := countWalRecords(t, cut)
+		require.Equal(t, expectCnt, cnt, "offset %d", off)
+		require.Equal(t, expectSize, validSize, "offset %d", off)
+		require.Equal(t, int64(off), fileSize)
+	}
+}
+
+func Test_wal_corrupt_record(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "full.wal")
+	ends := writeTestWal(t, path)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	//flip a bit inside the payload of the insert record
+	content[ends[1]+WAL_RECORD_HEADER_SIZE+1] ^= 0x10
+	require.NoError(t, os.WriteFile(path, content, 0755))
+	cnt, validSize, _ := countWalRecords(t, path)
+	require.Equal(t, 2, cnt)
+	require.Equal(t, ends[1], validSize)
+
+	//break the length of the first record
+	for i := 0; i < 4; i++ {
+		content[WAL_FILE_HEADER_SIZE+i] = 0
+	}
+	require.NoError(t, os.WriteFile(path, content, 0755))
+	cnt, validSize, _ = countWalRecords(t, path)
+	require.Equal(t, 0, cnt)
+	require.Equal(t, int64(WAL_FILE_HEADER_SIZE), validSize)
+}
+
+func Test_wal_unknown_format(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "full.wal")
+	writeTestWal(t, path)
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	//the wal without the header is written by the old version.
+	//it is rejected and kept as it is.
+	old := filepath.Join(dir, "old.wal")
+	require.NoError(t, os.WriteFile(old, content[WAL_FILE_HEADER_SIZE:], 0755))
+	_, _, err = scanWal(old, func(uint8, util.Deserialize) error { return nil })
+	require.ErrorIs(t, err, errWalUnknownFormat)
+	_, err = Replay(old, false)
+	require.ErrorIs(t, err, errWalUnknownFormat)
+	kept, err := os.ReadFile(old)
+	require.NoError(t, err)
+	require.Equal(t, content[WAL_FILE_HEADER_SIZE:], kept)
+
+	//the newer version
+	content[4]++
+	require.NoError(t, os.WriteFile(path, content, 0755))
+	_, _, err = scanWal(path, func(uint8, util.Deserialize) error { return nil })
+	require.ErrorIs(t, err, errWalUnknownFormat)
+}
+
+func Test_wal_replay_read_only(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ro.wal")
+	wal, err := NewWriteAheadLog(path)
+	require.NoError(t, err)
+	require.NoError(t, wal.WriteCreateSchema(&CatalogEntry{_name: "wal_ro"}))
+	require.NoError(t, wal.Flush())
+	require.NoError(t, wal.Close())
+
+	//the torn tail is kept for the read only database
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0755)
+	require.NoError(t, err)
+	_, err = f.Write([]byte{1, 2, 3})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+
+	_, err = Replay(path, true)
+	require.NoError(t, err)
+	kept, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, content, kept)
+}
@@ -3,6 +3,7 @@ package storage
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"strings"
 	"unsafe"
 
@@ -93,6 +94,12 @@ func (writer *MetaBlockWriter) Flush() error {
 }
 
 func (writer *MetaBlockWriter) AdvanceBlock() error {
+	if len(writer._writtenBlocks) == 0 && writer._offset == BlockIDSize {
+		//nothing has been written. no one refers to the block.
+		//e.g. the table data writer of the database without tables.
+		writer._blockMgr.MarkBlockAsFree(writer._block.id)
+		return nil
+	}
 	writer._writtenBlocks[writer._block.id] = true
 	if writer._offset > BlockIDSize {
 		err := writer._blockMgr.Write(writer._block)
@@ -291,7 +298,7 @@ func NewBufferedDeserializer(buf []byte) *BufferedDeserializer {
 }
 
 func (deseril *BufferedDeserializer) ReadData(buffer []byte, len int) error {
-	_, err := deseril._data.Read(buffer[:len])
+	_, err := io.ReadFull(deseril._data, buffer[:len])
 	return err
 }
 
@@ -455,6 +462,9 @@ func (writer *RowGroupWriter) WriteColumnDataPointers(state *ColumnCheckpointSta
 			return err
 		}
 	}
+	if state._validityState != nil {
+		return writer.WriteColumnDataPointers(state._validityState)
+	}
 	return err
 }
 
@@ -51,7 +51,58 @@ type DebugOptions struct {
 	Count             int  `tag:"count"`
 }
 
+// DefaultMaxRecursiveIterations is used if the MaxRecursiveIterations is 0.
+const DefaultMaxRecursiveIterations = 100000
+
+// DefaultSortMergeJoinRows is used if the SortMergeJoinRows is 0.
+const DefaultSortMergeJoinRows = 1 << 22
+
+type QueryOptions struct {
+	//the iterations of the recursive cte. -1 means no limit.
+	MaxRecursiveIterations int `tag:"maxRecursiveIterations"`
+	//the row count of the build side from which
+	//the sort-merge join replaces the hash join. -1 means any size.
+	SortMergeJoinRows int `tag:"sortMergeJoinRows"`
+}
+
+// RecursiveIterationLimit returns the limit of the iterations
+// of the recursive cte. -1 means no limit.
+func (opts *QueryOptions) RecursiveIterationLimit() int {
+	if opts.MaxRecursiveIterations == 0 {
+		return DefaultMaxRecursiveIterations
+	}
+	return opts.MaxRecursiveIterations
+}
+
+// SortMergeJoinThreshold returns the row count of the build side
+// from which the sort-merge join replaces the hash join.
+func (opts *QueryOptions) SortMergeJoinThreshold() uint64 {
+	if opts.SortMergeJoinRows == 0 {
+		return DefaultSortMergeJoinRows
+	}
+	if opts.SortMergeJoinRows < 0 {
+		return 0
+	}
+	return uint64(opts.SortMergeJoinRows)
+}
+
+type StorageOptions struct {
+	//the database file. the default is /tmp/default
+	Path     string `tag:"path"`
+	ReadOnly bool   `tag:"readOnly"`
+	//the checkpoint archives the wal into it if it is not empty
+	WalArchiveDir string `tag:"walArchiveDir"`
+	//replay the archived wal on loading up to the target.
+	//no target means all the archived wal.
+	Recover bool `tag:"recover"`
+	//the time in RFC3339
+	RecoveryTargetTime     string `tag:"recoveryTargetTime"`
+	RecoveryTargetCommitId uint64 `tag:"recoveryTargetCommitId"`
+}
+
 type Config struct {
-	Tpch1g Tpch1g       `tag:"tpch1g"`
-	Debug  DebugOptions `tag:"debug"`
+	Tpch1g  Tpch1g         `tag:"tpch1g"`
+	Debug   DebugOptions   `tag:"debug"`
+	Query   QueryOptions   `tag:"query"`
+	Storage StorageOptions `tag:"storage"`
 }
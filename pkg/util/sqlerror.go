@@ -0,0 +1,128 @@
+// Copyright 2023-2024 daviszhen
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package util
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+)
+
+// SQLState is the error code of the postgres.
+type SQLState string
+
+const (
+	SQLStateFeatureNotSupported       SQLState = "0A000"
+	SQLStateNumericValueOutOfRange    SQLState = "22003"
+	SQLStateInvalidDatetimeFormat     SQLState = "22007"
+	SQLStateDatetimeFieldOverflow     SQLState = "22008"
+	SQLStateDivisionByZero            SQLState = "22012"
+	SQLStateInvalidParameterValue     SQLState = "22023"
+	SQLStateInvalidRegularExpression  SQLState = "2201B"
+	SQLStateInvalidArgumentForLog     SQLState = "2201E"
+	SQLStateInvalidArgumentForPower   SQLState = "2201F"
+	SQLStateInvalidTextRepresentation SQLState = "22P02"
+	SQLStateNotNullViolation          SQLState = "23502"
+	SQLStateUniqueViolation           SQLState = "23505"
+	SQLStateSyntaxError               SQLState = "42601"
+	SQLStateDuplicateColumn           SQLState = "42701"
+	SQLStateAmbiguousColumn           SQLState = "42702"
+	SQLStateUndefinedColumn           SQLState = "42703"
+	SQLStateGroupingError             SQLState = "42803"
+	SQLStateDatatypeMismatch          SQLState = "42804"
+	SQLStateWrongObjectType           SQLState = "42809"
+	SQLStateUndefinedFunction         SQLState = "42883"
+	SQLStateUndefinedTable            SQLState = "42P01"
+	SQLStateDuplicateSchema           SQLState = "42P06"
+	SQLStateDuplicateTable            SQLState = "42P07"
+	SQLStateInvalidColumnReference    SQLState = "42P10"
+	SQLStateUndefinedSchema           SQLState = "3F000"
+	SQLStateProgramLimitExceeded      SQLState = "54000"
+	SQLStateInternalError             SQLState = "XX000"
+)
+
+// SQLError is the error returned to the client with the sqlstate.
+type SQLError struct {
+	Code SQLState
+	Msg  string
+	//the panic stack for the internal error
+	Stack string
+}
+
+func (e *SQLError) Error() string {
+	return e.Msg
+}
+
+func NewSQLError(code SQLState, format string, args ...any) *SQLError {
+	return &SQLError{
+		Code: code,
+		Msg:  fmt.Sprintf(format, args...),
+	}
+}
+
+// GetSQLState returns the sqlstate of the err.
+// the error on the unsupported features without sqlstate is
+// feature_not_supported. others are internal errors.
+func GetSQLState(err error) SQLState {
+	var sqlErr *SQLError
+	if errors.As(err, &sqlErr) {
+		return sqlErr.Code
+	}
+	if isUnsupported(err.Error()) {
+		return SQLStateFeatureNotSupported
+	}
+	return SQLStateInternalError
+}
+
+func isUnsupported(msg string) bool {
+	return strings.HasPrefix(msg, "usp") ||
+		strings.Contains(msg, "unsupport")
+}
+
+// RecoverToError converts the value from the recover into the error.
+// the panic on the unsupported features is feature_not_supported,
+// others are internal errors with the stack.
+func RecoverToError(r any) error {
+	var err error
+	switch val := r.(type) {
+	case *SQLError:
+		return val
+	case runtime.Error:
+		if strings.Contains(val.Error(), "divide by zero") {
+			return NewSQLError(SQLStateDivisionByZero, "division by zero")
+		}
+		err = val
+	case error:
+		err = val
+	default:
+		err = fmt.Errorf("%v", val)
+	}
+	var sqlErr *SQLError
+	if errors.As(err, &sqlErr) {
+		return sqlErr
+	}
+	msg := err.Error()
+	if isUnsupported(msg) {
+		return NewSQLError(SQLStateFeatureNotSupported, "feature not supported: %s", msg)
+	}
+	buf := make([]byte, 8192)
+	n := runtime.Stack(buf, false)
+	return &SQLError{
+		Code:  SQLStateInternalError,
+		Msg:   fmt.Sprintf("internal error: %s", msg),
+		Stack: string(buf[:n]),
+	}
+}